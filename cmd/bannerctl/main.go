@@ -0,0 +1,76 @@
+package main
+
+import (
+	"avito/assignment/internal/bannerctl"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const usage = `usage: bannerctl sync [flags]
+
+Reads banner specs from a directory of yaml files, prints the plan of creates,
+updates and deletes against the live service and applies it with -apply.
+The admin token is taken from -token or the BANNERCTL_TOKEN environment variable.
+`
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "sync" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	dir := flags.String("dir", "./banners", "directory with banner specs")
+	url := flags.String("url", "http://localhost:8892", "banner service address")
+	token := flags.String("token", os.Getenv("BANNERCTL_TOKEN"), "admin token, BANNERCTL_TOKEN by default, required")
+	apply := flags.Bool("apply", false, "apply the plan, otherwise only print it")
+	prune := flags.Bool("prune", false, "delete live banners that have no spec")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of a single request")
+	_ = flags.Parse(os.Args[2:])
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "bannerctl: -token or BANNERCTL_TOKEN is required")
+		os.Exit(2)
+	}
+
+	if err := sync(*dir, *url, *token, *apply, *prune, *timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "bannerctl: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func sync(dir, url, token string, apply, prune bool, timeout time.Duration) error {
+	ctx := context.Background()
+
+	specs, err := bannerctl.LoadSpecs(dir)
+	if err != nil {
+		return err
+	}
+
+	client := bannerctl.NewClient(url, token, timeout)
+	registry, err := client.GetRegistry(ctx)
+	if err != nil {
+		return err
+	}
+	live, err := client.GetLiveBanners(ctx)
+	if err != nil {
+		return err
+	}
+
+	plan := bannerctl.BuildPlan(specs, registry, live, prune)
+	plan.Format(os.Stdout)
+	if len(plan.Conflicts) != 0 {
+		return bannerctl.ErrPlanConflicts
+	}
+	if !apply || plan.Empty() {
+		return nil
+	}
+
+	return bannerctl.Apply(ctx, client, plan, os.Stdout)
+}
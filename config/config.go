@@ -13,6 +13,9 @@ type Config struct {
 	Server struct {
 		Host string `validate:"required"`
 	}
+	Grpc struct {
+		Host string `validate:"required"`
+	}
 	OpenTelemetry struct {
 		URL         string `validate:"required"`
 		ServiceName string `validate:"required"`
@@ -33,8 +36,89 @@ type Config struct {
 		PoolTimeout  int    `validate:"required"`
 		Password     string `validate:"required"`
 	}
+	Auth struct {
+		// Admins персональные токены админов. Запрос с таким токеном получает роль админа и подписывается именем админа,
+		// по общему токену роли админов не различить
+		Admins []struct {
+			Name  string `validate:"required"`
+			Token string `validate:"required"`
+		} `validate:"dive"`
+	}
+	Stream struct {
+		HeartbeatSeconds int   `validate:"required"`
+		ReplayLimit      int64 `validate:"required"`
+	}
+	Webhooks struct {
+		PollIntervalMs     int `validate:"required"`
+		BatchSize          int `validate:"required"`
+		MaxAttempts        int `validate:"required"`
+		BaseBackoffSeconds int `validate:"required"`
+		MaxBackoffSeconds  int `validate:"required"`
+		TimeoutSeconds     int `validate:"required"`
+		// LeaseSeconds на сколько диспетчер забирает пачку доставок, должно хватать на отправку всей пачки
+		LeaseSeconds int `validate:"required"`
+	}
+	Jobs struct {
+		Workers            int `validate:"required"`
+		PollIntervalMs     int `validate:"required"`
+		LeaseSeconds       int `validate:"required"`
+		MaxAttempts        int `validate:"required"`
+		BaseBackoffSeconds int `validate:"required"`
+		MaxBackoffSeconds  int `validate:"required"`
+	}
+	BulkDelete struct {
+		BatchSize int `validate:"required"`
+		// BatchAttempts сколько раз пробуем пачку, упавшую на сбое базы или редиса, прежде чем отдать ошибку раннеру
+		BatchAttempts  int `validate:"required"`
+		RetryBackoffMs int `validate:"required"`
+	}
+	Trash struct {
+		// RetentionHours сколько баннер лежит в корзине до удаления насовсем
+		RetentionHours       int `validate:"required"`
+		PurgeIntervalSeconds int `validate:"required"`
+		PurgeBatchSize       int `validate:"required"`
+	}
+	Idempotency struct {
+		// TTLSeconds сколько хранится ответ на запрос с Idempotency-Key
+		TTLSeconds int `validate:"required"`
+		// LockSeconds сколько ключ считается занятым выполняющейся попыткой, если реплика упала, не дописав ответ
+		LockSeconds int `validate:"required"`
+	}
+	Drafts struct {
+		// ApprovalFeatureIds черновики баннеров этих фич публикуются только после одобрения вторым админом,
+		// напрямую такие баннеры не патчатся
+		ApprovalFeatureIds []int64
+	}
+	BulkPatch struct {
+		MaxBanners int `validate:"required"`
+	}
+	Search struct {
+		// Language конфигурация текстового поиска постгреса, например russian или simple
+		Language string `validate:"required"`
+		// ReindexBatchSize сколько строк пересобирает одна транзакция задачи переиндексации после смены языка
+		ReindexBatchSize int `validate:"required"`
+	}
+	Pagination struct {
+		// CursorSecret ключ HMAC курсоров GET /banner, общий для всех реплик. После смены ключа выданные курсоры не принимаются
+		CursorSecret string `validate:"required"`
+	}
+	Export struct {
+		BatchSize int `validate:"required"`
+		// TimeoutSeconds сколько может идти одна выгрузка, дальше курсор закрывается и выгрузка обрывается
+		TimeoutSeconds int `validate:"required"`
+	}
+	Snapshots struct {
+		Dir string `validate:"required"`
+	}
+	Registries struct {
+		SlugCacheTTLSeconds int `validate:"required"`
+	}
 	BannerSettings struct {
 		BannerTTLSeconds int `validate:"required"`
+		Templates        struct {
+			StrictMode bool
+			Variables  []string
+		}
 	}
 }
 
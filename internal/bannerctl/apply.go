@@ -0,0 +1,57 @@
+package bannerctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxBatchActions столько операций сервис принимает в одном батче
+const maxBatchActions = 100
+
+var ErrPlanConflicts = errors.New("plan has conflicts, resolve them before apply")
+
+// Apply выполняет план через POST /banner/batch. Пока план влезает в один батч, он применяется целиком или никак,
+// поэтому баннеры могут обменяться парами тэг-фича. Больший план режется на батчи по порядку, и при ошибке
+// уже примененные батчи не откатываются, повторный sync досчитает оставшееся
+func Apply(ctx context.Context, client *Client, plan *Plan, w io.Writer) error {
+	if len(plan.Conflicts) != 0 {
+		return ErrPlanConflicts
+	}
+
+	for start := 0; start < len(plan.Actions); start += maxBatchActions {
+		actions := plan.Actions[start:min(start+maxBatchActions, len(plan.Actions))]
+		operations := make([]BatchOperation, len(actions))
+		for i, action := range actions {
+			switch action.Type {
+			case ActionCreate:
+				operations[i] = createOperation(action.Desired)
+			case ActionUpdate:
+				operations[i] = updateOperation(action.BannerId, action.Desired, action.Diffs)
+			case ActionDelete:
+				operations[i] = deleteOperation(action.BannerId)
+			}
+		}
+
+		results, err := client.Batch(ctx, operations)
+		if err != nil {
+			return fmt.Errorf("batch failed after %d of %d actions: %w", start, len(plan.Actions), err)
+		}
+		if len(results) != len(operations) {
+			return fmt.Errorf("batch returned %d results for %d actions", len(results), len(operations))
+		}
+		for i, action := range actions {
+			switch action.Type {
+			case ActionCreate:
+				fmt.Fprintf(w, "created banner %d (%s)\n", results[i].BannerId, action.Desired.Source)
+			case ActionUpdate:
+				fmt.Fprintf(w, "updated banner %d (%s)\n", action.BannerId, action.Desired.Source)
+			case ActionDelete:
+				fmt.Fprintf(w, "deleted banner %d\n", action.BannerId)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package bannerctl
+
+import (
+	"avito/assignment/internal/models"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const registryPageSize = 1000
+
+// Client ходит в админское api сервиса баннеров с токеном админа
+type Client struct {
+	baseUrl string
+	token   string
+	http    *http.Client
+}
+
+func NewClient(baseUrl, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseUrl: strings.TrimRight(baseUrl, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Registry слаги и алиасы тэгов и фич, известные сервису, слаг -> айди
+type Registry struct {
+	Tags     map[string]int64
+	Features map[string]int64
+}
+
+// Live баннер в том виде, в котором его отдает выгрузка
+type Live struct {
+	BannerId  models.BannerId  `json:"banner_id"`
+	TagIds    []models.TagId   `json:"tag_ids"`
+	FeatureId models.FeatureId `json:"feature_id"`
+	Content   struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+		Url   string `json:"url"`
+	} `json:"content"`
+	IsActive bool `json:"is_active"`
+}
+
+type bannerRequest struct {
+	TagIds    *[]models.TagId   `json:"tag_ids,omitempty"`
+	FeatureId *models.FeatureId `json:"feature_id,omitempty"`
+	Content   *contentRequest   `json:"content,omitempty"`
+	IsActive  *bool             `json:"is_active,omitempty"`
+}
+
+type contentRequest struct {
+	Title *string `json:"title,omitempty"`
+	Text  *string `json:"text,omitempty"`
+	Url   *string `json:"url,omitempty"`
+}
+
+func (c *Client) GetRegistry(ctx context.Context) (*Registry, error) {
+	registry := &Registry{}
+	var err error
+	if registry.Tags, err = c.getSlugs(ctx, "/tags"); err != nil {
+		return nil, err
+	}
+	if registry.Features, err = c.getSlugs(ctx, "/features"); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+func (c *Client) getSlugs(ctx context.Context, path string) (map[string]int64, error) {
+	slugs := make(map[string]int64)
+	for offset := 0; ; offset += registryPageSize {
+		var entries []struct {
+			Id      int64    `json:"id"`
+			Slug    string   `json:"slug"`
+			Aliases []string `json:"aliases"`
+		}
+		err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s?limit=%d&offset=%d", path, registryPageSize, offset), nil, &entries)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			slugs[entry.Slug] = entry.Id
+			for _, alias := range entry.Aliases {
+				slugs[alias] = entry.Id
+			}
+		}
+		if len(entries) < registryPageSize {
+			return slugs, nil
+		}
+	}
+}
+
+// GetLiveBanners все баннеры сервиса через потоковую выгрузку
+func (c *Client) GetLiveBanners(ctx context.Context) ([]Live, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/banner/export?format=ndjson", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var banners []Live
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var banner Live
+		if err = json.Unmarshal(scanner.Bytes(), &banner); err != nil {
+			return nil, fmt.Errorf("GET /banner/export: %w", err)
+		}
+		banners = append(banners, banner)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("GET /banner/export: %w", err)
+	}
+	return banners, nil
+}
+
+// BatchOperation операция POST /banner/batch
+type BatchOperation struct {
+	Op       string          `json:"op"`
+	BannerId models.BannerId `json:"banner_id,omitempty"`
+	Banner   *bannerRequest  `json:"banner,omitempty"`
+}
+
+// BatchResult результат операции батча, для create - айди нового баннера
+type BatchResult struct {
+	Op       string          `json:"op"`
+	BannerId models.BannerId `json:"banner_id"`
+}
+
+// Batch выполняет операции одной транзакцией, при ошибке не применяется ни одна
+func (c *Client) Batch(ctx context.Context, operations []BatchOperation) ([]BatchResult, error) {
+	var response struct {
+		Results []BatchResult `json:"results"`
+	}
+	request := struct {
+		Operations []BatchOperation `json:"operations"`
+	}{Operations: operations}
+	if err := c.do(ctx, http.MethodPost, "/banner/batch", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+func createOperation(desired *Desired) BatchOperation {
+	return BatchOperation{Op: "add", Banner: &bannerRequest{
+		TagIds:    &desired.TagIds,
+		FeatureId: &desired.FeatureId,
+		Content:   &contentRequest{Title: &desired.Title, Text: &desired.Text, Url: &desired.Url},
+		IsActive:  &desired.IsActive,
+	}}
+}
+
+// updateOperation шлет только изменившиеся поля
+func updateOperation(bannerId models.BannerId, desired *Desired, diffs []FieldDiff) BatchOperation {
+	request := &bannerRequest{}
+	content := &contentRequest{}
+	for _, diff := range diffs {
+		switch diff.Field {
+		case FieldTagIds:
+			request.TagIds = &desired.TagIds
+		case FieldFeatureId:
+			request.FeatureId = &desired.FeatureId
+		case FieldTitle:
+			content.Title = &desired.Title
+		case FieldText:
+			content.Text = &desired.Text
+		case FieldUrl:
+			content.Url = &desired.Url
+		case FieldIsActive:
+			request.IsActive = &desired.IsActive
+		}
+	}
+	if content.Title != nil || content.Text != nil || content.Url != nil {
+		request.Content = content
+	}
+	return BatchOperation{Op: "patch", BannerId: bannerId, Banner: request}
+}
+
+func deleteOperation(bannerId models.BannerId) BatchOperation {
+	return BatchOperation{Op: "delete", BannerId: bannerId}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, request, response interface{}) error {
+	resp, err := c.send(ctx, method, path, request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if response == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// send любой ответ не 2xx превращается в ошибку вместе с телом, в нем error_place и error_value сервиса
+func (c *Client) send(ctx context.Context, method, path string, request interface{}) (*http.Response, error) {
+	var body io.Reader
+	if request != nil {
+		requestBytes, err := json.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(requestBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("token", c.token)
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return resp, nil
+}
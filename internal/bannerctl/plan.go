@@ -0,0 +1,321 @@
+package bannerctl
+
+import (
+	"avito/assignment/internal/models"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type ActionType string
+
+const (
+	ActionCreate ActionType = "create"
+	ActionUpdate ActionType = "update"
+	ActionDelete ActionType = "delete"
+)
+
+const (
+	FieldTagIds    = "tag_ids"
+	FieldFeatureId = "feature_id"
+	FieldTitle     = "content.title"
+	FieldText      = "content.text"
+	FieldUrl       = "content.url"
+	FieldIsActive  = "is_active"
+)
+
+type FieldDiff struct {
+	Field string
+	From  string
+	To    string
+}
+
+type Action struct {
+	Type     ActionType
+	BannerId models.BannerId
+	Desired  *Desired
+	Diffs    []FieldDiff
+}
+
+type Conflict struct {
+	Source  string
+	Message string
+}
+
+// Plan что нужно сделать с живыми баннерами, чтобы они совпали со спеками.
+// Порядок действий - удаления, изменения, создания, так освобожденные пары тэг-фича успевают освободиться до создания
+type Plan struct {
+	Actions   []Action
+	Conflicts []Conflict
+	Unchanged int
+	// Unmanaged живые баннеры без спеки, которые остаются как есть без prune
+	Unmanaged int
+}
+
+type pairKey struct {
+	tagId     models.TagId
+	featureId models.FeatureId
+}
+
+// planEntry спека и найденный для нее живой баннер, спека с problems уходит в конфликты
+type planEntry struct {
+	source   string
+	desired  *Desired
+	matched  *Live
+	problems []string
+}
+
+// BuildPlan сопоставляет спеки с живыми баннерами: по banner_id, если он указан в спеке, иначе по общей паре тэг-фича.
+// Пара живого баннера не считается занятой, если план ее освобождает: баннер удаляется при prune или переезжает по своей спеке,
+// так баннеры с banner_id могут обменяться парами. Спеки с конфликтами в план не попадают, а найденные для них баннеры
+// не удаляются при prune и своих пар не освобождают
+func BuildPlan(specs []Spec, registry *Registry, live []Live, prune bool) *Plan {
+	plan := &Plan{}
+
+	liveById := make(map[models.BannerId]*Live, len(live))
+	pairOwners := make(map[pairKey]models.BannerId)
+	for i := range live {
+		banner := &live[i]
+		liveById[banner.BannerId] = banner
+		for _, tagId := range banner.TagIds {
+			pairOwners[pairKey{tagId, banner.FeatureId}] = banner.BannerId
+		}
+	}
+
+	entries := make([]*planEntry, len(specs))
+	// баннеры, которые спеки назвали по banner_id, по паре они другим спекам не сопоставляются
+	named := make(map[models.BannerId]struct{})
+	for i := range specs {
+		desired, err := specs[i].Resolve(registry)
+		if err != nil {
+			entries[i] = &planEntry{source: specs[i].Source, problems: []string{err.Error()}}
+			continue
+		}
+		entries[i] = &planEntry{source: desired.Source, desired: desired}
+		if desired.BannerId != nil {
+			named[*desired.BannerId] = struct{}{}
+		}
+	}
+
+	specPairs := make(map[pairKey]string)
+	claimed := make(map[models.BannerId]string)
+	for _, entry := range entries {
+		desired := entry.desired
+		if desired == nil {
+			continue
+		}
+
+		for _, tagId := range desired.TagIds {
+			pair := pairKey{tagId, desired.FeatureId}
+			if source, ok := specPairs[pair]; ok {
+				entry.problems = append(entry.problems, fmt.Sprintf("tag %d and feature %d are also used by %s", tagId, desired.FeatureId, source))
+				continue
+			}
+			specPairs[pair] = desired.Source
+		}
+
+		if desired.BannerId != nil {
+			banner, ok := liveById[*desired.BannerId]
+			if !ok {
+				entry.problems = append(entry.problems, fmt.Sprintf("banner %d not found", *desired.BannerId))
+			}
+			entry.matched = banner
+		} else {
+			candidates := matchCandidates(desired, pairOwners, named)
+			if len(candidates) > 1 {
+				entry.problems = append(entry.problems, fmt.Sprintf("matches several banners %v, set banner_id", candidates))
+				// ни один из них не удаляется при prune, пока конфликт не разрешен
+				for _, candidate := range candidates {
+					if _, ok := claimed[candidate]; !ok {
+						claimed[candidate] = desired.Source
+					}
+				}
+			} else if len(candidates) == 1 {
+				entry.matched = liveById[candidates[0]]
+			}
+		}
+
+		if entry.matched != nil {
+			if source, ok := claimed[entry.matched.BannerId]; ok {
+				entry.problems = append(entry.problems, fmt.Sprintf("banner %d is also claimed by %s", entry.matched.BannerId, source))
+			} else {
+				claimed[entry.matched.BannerId] = desired.Source
+			}
+		}
+	}
+
+	// каждый новый конфликт оставляет пары его баннера занятыми, поэтому проверяем до тех пор, пока конфликты появляются
+	for checkPairOwners(entries, live, pairOwners, claimed, prune) {
+	}
+
+	var updates, creates []Action
+	for _, entry := range entries {
+		if len(entry.problems) != 0 {
+			plan.conflict(entry.source, strings.Join(entry.problems, "; "))
+			continue
+		}
+		if entry.matched == nil {
+			creates = append(creates, Action{Type: ActionCreate, Desired: entry.desired, Diffs: diffBanner(nil, entry.desired)})
+			continue
+		}
+		diffs := diffBanner(entry.matched, entry.desired)
+		if len(diffs) == 0 {
+			plan.Unchanged++
+			continue
+		}
+		updates = append(updates, Action{Type: ActionUpdate, BannerId: entry.matched.BannerId, Desired: entry.desired, Diffs: diffs})
+	}
+
+	for i := range live {
+		if _, ok := claimed[live[i].BannerId]; ok {
+			continue
+		}
+		if !prune {
+			plan.Unmanaged++
+			continue
+		}
+		plan.Actions = append(plan.Actions, Action{Type: ActionDelete, BannerId: live[i].BannerId})
+	}
+	plan.Actions = append(plan.Actions, updates...)
+	plan.Actions = append(plan.Actions, creates...)
+
+	return plan
+}
+
+// checkPairOwners отмечает конфликтом спеки, которым нужна пара другого живого баннера, не освобожденная планом.
+// Возвращает true, если появился хотя бы один новый конфликт
+func checkPairOwners(entries []*planEntry, live []Live, pairOwners map[pairKey]models.BannerId, claimed map[models.BannerId]string, prune bool) bool {
+	released := make(map[pairKey]struct{})
+	if prune {
+		for i := range live {
+			if _, ok := claimed[live[i].BannerId]; ok {
+				continue
+			}
+			for _, tagId := range live[i].TagIds {
+				released[pairKey{tagId, live[i].FeatureId}] = struct{}{}
+			}
+		}
+	}
+	for _, entry := range entries {
+		if entry.matched == nil || len(entry.problems) != 0 {
+			continue
+		}
+		kept := make(map[pairKey]struct{}, len(entry.desired.TagIds))
+		for _, tagId := range entry.desired.TagIds {
+			kept[pairKey{tagId, entry.desired.FeatureId}] = struct{}{}
+		}
+		for _, tagId := range entry.matched.TagIds {
+			pair := pairKey{tagId, entry.matched.FeatureId}
+			if _, ok := kept[pair]; !ok {
+				released[pair] = struct{}{}
+			}
+		}
+	}
+
+	found := false
+	for _, entry := range entries {
+		if entry.desired == nil || len(entry.problems) != 0 {
+			continue
+		}
+		for _, tagId := range entry.desired.TagIds {
+			pair := pairKey{tagId, entry.desired.FeatureId}
+			owner, ok := pairOwners[pair]
+			if !ok || entry.matched != nil && owner == entry.matched.BannerId {
+				continue
+			}
+			if _, ok = released[pair]; !ok {
+				entry.problems = append(entry.problems, fmt.Sprintf("tag %d and feature %d already belong to banner %d", tagId, entry.desired.FeatureId, owner))
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+func (p *Plan) conflict(source, message string) {
+	p.Conflicts = append(p.Conflicts, Conflict{Source: source, Message: message})
+}
+
+func (p *Plan) Count(actionType ActionType) int {
+	count := 0
+	for _, action := range p.Actions {
+		if action.Type == actionType {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Format печатает план в виде, удобном для ревью: + создание, ~ изменение, - удаление, ! конфликт
+func (p *Plan) Format(w io.Writer) {
+	for _, conflict := range p.Conflicts {
+		fmt.Fprintf(w, "! conflict %s: %s\n", conflict.Source, conflict.Message)
+	}
+	for _, action := range p.Actions {
+		switch action.Type {
+		case ActionCreate:
+			fmt.Fprintf(w, "+ create %s\n", action.Desired.Source)
+			for _, diff := range action.Diffs {
+				fmt.Fprintf(w, "    %s: %s\n", diff.Field, diff.To)
+			}
+		case ActionUpdate:
+			fmt.Fprintf(w, "~ update banner %d (%s)\n", action.BannerId, action.Desired.Source)
+			for _, diff := range action.Diffs {
+				fmt.Fprintf(w, "    %s: %s -> %s\n", diff.Field, diff.From, diff.To)
+			}
+		case ActionDelete:
+			fmt.Fprintf(w, "- delete banner %d\n", action.BannerId)
+		}
+	}
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete, %d unchanged, %d unmanaged, %d conflicts.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete), p.Unchanged, p.Unmanaged, len(p.Conflicts))
+}
+
+func matchCandidates(desired *Desired, pairOwners map[pairKey]models.BannerId, named map[models.BannerId]struct{}) []models.BannerId {
+	unique := make(map[models.BannerId]struct{})
+	var candidates []models.BannerId
+	for _, tagId := range desired.TagIds {
+		owner, ok := pairOwners[pairKey{tagId, desired.FeatureId}]
+		if !ok {
+			continue
+		}
+		if _, ok = named[owner]; ok {
+			continue
+		}
+		if _, ok = unique[owner]; !ok {
+			unique[owner] = struct{}{}
+			candidates = append(candidates, owner)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+	return candidates
+}
+
+// diffBanner поля, в которых живой баннер расходится со спекой, для создания live == nil и From пустые
+func diffBanner(live *Live, desired *Desired) []FieldDiff {
+	var current Live
+	if live != nil {
+		current = *live
+		current.TagIds = append([]models.TagId(nil), live.TagIds...)
+		sort.Slice(current.TagIds, func(i, j int) bool { return current.TagIds[i] < current.TagIds[j] })
+	}
+
+	var diffs []FieldDiff
+	add := func(field, from, to string) {
+		if live == nil || from != to {
+			diffs = append(diffs, FieldDiff{Field: field, From: from, To: to})
+		}
+	}
+	add(FieldTagIds, fmt.Sprint(current.TagIds), fmt.Sprint(desired.TagIds))
+	add(FieldFeatureId, fmt.Sprint(current.FeatureId), fmt.Sprint(desired.FeatureId))
+	add(FieldTitle, fmt.Sprintf("%q", current.Content.Title), fmt.Sprintf("%q", desired.Title))
+	add(FieldText, fmt.Sprintf("%q", current.Content.Text), fmt.Sprintf("%q", desired.Text))
+	add(FieldUrl, fmt.Sprintf("%q", current.Content.Url), fmt.Sprintf("%q", desired.Url))
+	add(FieldIsActive, fmt.Sprint(current.IsActive), fmt.Sprint(desired.IsActive))
+	return diffs
+}
@@ -0,0 +1,149 @@
+package bannerctl
+
+import (
+	"avito/assignment/internal/models"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Spec описание одного баннера в yaml, в файле может быть несколько документов через ---.
+// Тэги и фичу можно задавать слагами или айдишниками, banner_id привязывает спеку к конкретному баннеру
+type Spec struct {
+	BannerId  *models.BannerId `yaml:"banner_id"`
+	Feature   string           `yaml:"feature"`
+	FeatureId models.FeatureId `yaml:"feature_id"`
+	Tags      []string         `yaml:"tags"`
+	TagIds    []models.TagId   `yaml:"tag_ids"`
+	Content   struct {
+		Title string `yaml:"title"`
+		Text  string `yaml:"text"`
+		Url   string `yaml:"url"`
+	} `yaml:"content"`
+	IsActive *bool `yaml:"is_active"`
+
+	// Source файл и номер документа в нем, для сообщений
+	Source string `yaml:"-"`
+}
+
+// LoadSpecs читает все *.yaml и *.yml в директории рекурсивно, порядок - по пути файла
+func LoadSpecs(dir string) ([]Spec, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var specs []Spec
+	for _, path := range paths {
+		fileSpecs, err := loadSpecFile(path)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, fileSpecs...)
+	}
+	return specs, nil
+}
+
+func loadSpecFile(path string) ([]Spec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var specs []Spec
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	for document := 1; ; document++ {
+		var spec Spec
+		err = decoder.Decode(&spec)
+		if errors.Is(err, io.EOF) {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s#%d: %w", path, document, err)
+		}
+		spec.Source = fmt.Sprintf("%s#%d", path, document)
+		specs = append(specs, spec)
+	}
+}
+
+// Desired спека после подстановки слагов, в том виде, в котором сравнивается с живыми баннерами
+type Desired struct {
+	Source    string
+	BannerId  *models.BannerId
+	FeatureId models.FeatureId
+	TagIds    []models.TagId
+	Title     string
+	Text      string
+	Url       string
+	IsActive  bool
+}
+
+// Resolve переводит слаги в айди и проверяет обязательные поля, по умолчанию баннер активен
+func (s *Spec) Resolve(registry *Registry) (*Desired, error) {
+	desired := &Desired{
+		Source:    s.Source,
+		BannerId:  s.BannerId,
+		FeatureId: s.FeatureId,
+		Title:     s.Content.Title,
+		Text:      s.Content.Text,
+		Url:       s.Content.Url,
+		IsActive:  s.IsActive == nil || *s.IsActive,
+	}
+
+	if s.Feature != "" {
+		featureId, ok := registry.Features[s.Feature]
+		if !ok {
+			return nil, fmt.Errorf("unknown feature slug %s", s.Feature)
+		}
+		if s.FeatureId != 0 && s.FeatureId != models.FeatureId(featureId) {
+			return nil, fmt.Errorf("feature slug %s points to id %d, but feature_id %d was set", s.Feature, featureId, s.FeatureId)
+		}
+		desired.FeatureId = models.FeatureId(featureId)
+	}
+	if desired.FeatureId == 0 {
+		return nil, errors.New("feature or feature_id is required")
+	}
+
+	tagIds := make(map[models.TagId]struct{})
+	for _, tagId := range s.TagIds {
+		tagIds[tagId] = struct{}{}
+	}
+	for _, slug := range s.Tags {
+		tagId, ok := registry.Tags[slug]
+		if !ok {
+			return nil, fmt.Errorf("unknown tag slug %s", slug)
+		}
+		tagIds[models.TagId(tagId)] = struct{}{}
+	}
+	if len(tagIds) == 0 {
+		return nil, errors.New("tags or tag_ids are required")
+	}
+	for tagId := range tagIds {
+		desired.TagIds = append(desired.TagIds, tagId)
+	}
+	sort.Slice(desired.TagIds, func(i, j int) bool { return desired.TagIds[i] < desired.TagIds[j] })
+
+	if desired.Title == "" || desired.Text == "" || desired.Url == "" {
+		return nil, errors.New("content.title, content.text and content.url are required")
+	}
+	return desired, nil
+}
@@ -0,0 +1,111 @@
+package banners_grpc
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/api/banners_v1"
+	"avito/assignment/pkg/utilities"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func ToGetBanner(req *banners_v1.GetBannerRequest, token string) *banners_usecase.GetBanner {
+	return &banners_usecase.GetBanner{
+		TagId:          models.TagId(req.GetTagId()),
+		FeatureId:      models.FeatureId(req.GetFeatureId()),
+		UseLastVersion: req.GetUseLastVersion(),
+		AuthToken:      token,
+		Attributes:     req.GetAttributes(),
+	}
+}
+
+func ToGetManyBanner(req *banners_v1.GetManyBannerRequest) *banners_usecase.GetManyBanner {
+	getManyBanner := &banners_usecase.GetManyBanner{}
+	if req.FeatureId != nil {
+		featureId := models.FeatureId(req.GetFeatureId())
+		getManyBanner.FeatureId = &featureId
+	}
+	if req.TagId != nil {
+		tagId := models.TagId(req.GetTagId())
+		getManyBanner.TagId = &tagId
+	}
+	if req.Limit != nil {
+		limit := int(req.GetLimit())
+		getManyBanner.Limit = &limit
+	}
+	if req.Offset != nil {
+		offset := int(req.GetOffset())
+		getManyBanner.Offset = &offset
+	}
+	return getManyBanner
+}
+
+func ToAddBanner(req *banners_v1.AddBannerRequest) *banners_usecase.AddBanner {
+	addBanner := &banners_usecase.AddBanner{
+		TagIds:    utilities.RemoveDuplicates[models.TagId](toTagIds(req.GetTagIds())),
+		FeatureId: models.FeatureId(req.GetFeatureId()),
+		IsActive:  req.GetIsActive(),
+	}
+	addBanner.Content.Title = req.GetContent().GetTitle()
+	addBanner.Content.Text = req.GetContent().GetText()
+	addBanner.Content.Url = req.GetContent().GetUrl()
+	return addBanner
+}
+
+func ToPatchBanner(req *banners_v1.PatchBannerRequest) *banners_usecase.PatchBanner {
+	patchBanner := &banners_usecase.PatchBanner{
+		BannerId:        models.BannerId(req.GetBannerId()),
+		IsActive:        req.IsActive,
+		ExpectedVersion: req.ExpectedVersion,
+	}
+	if req.TagIds != nil {
+		ids := utilities.RemoveDuplicates[models.TagId](toTagIds(req.GetTagIds().GetTagIds()))
+		patchBanner.TagIds = &ids
+	}
+	if req.FeatureId != nil {
+		featureId := models.FeatureId(req.GetFeatureId())
+		patchBanner.FeatureId = &featureId
+	}
+	if req.Content != nil {
+		patchBanner.Title = req.Content.Title
+		patchBanner.Text = req.Content.Text
+		patchBanner.Url = req.Content.Url
+	}
+	return patchBanner
+}
+
+func ToContent(b *models.FullBanner) *banners_v1.Content {
+	return &banners_v1.Content{
+		Title: b.Content.Title,
+		Text:  b.Content.Text,
+		Url:   b.Content.Url,
+	}
+}
+
+func ToBanners(b *[]models.FullBanner) []*banners_v1.Banner {
+	banners := make([]*banners_v1.Banner, len(*b))
+	for i, value := range *b {
+		tagIds := make([]int64, len(value.TagIds))
+		for j, tagId := range value.TagIds {
+			tagIds[j] = int64(tagId)
+		}
+		banners[i] = &banners_v1.Banner{
+			BannerId:  int64(value.BannerId),
+			TagIds:    tagIds,
+			FeatureId: int64(value.FeatureId),
+			Content:   ToContent(&value),
+			IsActive:  value.IsActive,
+			CreatedAt: timestamppb.New(value.CreatedAt),
+			UpdatedAt: timestamppb.New(value.UpdatedAt),
+			Version:   value.Version,
+		}
+	}
+	return banners
+}
+
+func toTagIds(ids []int64) []models.TagId {
+	tagIds := make([]models.TagId, len(ids))
+	for i, id := range ids {
+		tagIds[i] = models.TagId(id)
+	}
+	return tagIds
+}
@@ -0,0 +1,151 @@
+package banners_grpc
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/middleware"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/api/banners_v1"
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type BannersHandlers struct {
+	banners_v1.UnimplementedBannersServiceServer
+	bannersUC BannersUseCase
+	cfg       *config.Config
+}
+
+func NewBannersHandlers(bannersUC BannersUseCase, cfg *config.Config) *BannersHandlers {
+	return &BannersHandlers{
+		bannersUC: bannersUC,
+		cfg:       cfg,
+	}
+}
+
+func (b *BannersHandlers) GetBanner(ctx context.Context, req *banners_v1.GetBannerRequest) (*banners_v1.GetBannerResponse, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersGRPC.GetBanner")
+	defer span.End()
+
+	if req.GetTagId() == 0 || req.GetFeatureId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "BannersGRPC.GetBanner.ReadRequest")
+	}
+
+	bannerInfo, err := b.bannersUC.GetBanner(ctx, ToGetBanner(req, middleware.TokenFromContext(ctx)))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &banners_v1.GetBannerResponse{Content: ToContent(bannerInfo)}, nil
+}
+
+func (b *BannersHandlers) GetManyBanner(ctx context.Context, req *banners_v1.GetManyBannerRequest) (*banners_v1.GetManyBannerResponse, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersGRPC.GetManyBanner")
+	defer span.End()
+
+	manyBannerInfo, err := b.bannersUC.GetManyBanner(ctx, ToGetManyBanner(req))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &banners_v1.GetManyBannerResponse{Banners: ToBanners(manyBannerInfo)}, nil
+}
+
+func (b *BannersHandlers) AddBanner(ctx context.Context, req *banners_v1.AddBannerRequest) (*banners_v1.AddBannerResponse, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersGRPC.AddBanner")
+	defer span.End()
+
+	if len(req.GetTagIds()) == 0 || req.GetFeatureId() == 0 || req.GetContent() == nil {
+		return nil, status.Error(codes.InvalidArgument, "BannersGRPC.AddBanner.ReadRequest")
+	}
+
+	bannerId, err := b.bannersUC.AddBanner(ctx, ToAddBanner(req))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &banners_v1.AddBannerResponse{BannerId: int64(bannerId)}, nil
+}
+
+func (b *BannersHandlers) PatchBanner(ctx context.Context, req *banners_v1.PatchBannerRequest) (*banners_v1.PatchBannerResponse, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersGRPC.PatchBanner")
+	defer span.End()
+
+	version, err := b.bannersUC.PatchBanner(ctx, ToPatchBanner(req))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &banners_v1.PatchBannerResponse{Version: version}, nil
+}
+
+func (b *BannersHandlers) DeleteBanner(ctx context.Context, req *banners_v1.DeleteBannerRequest) (*banners_v1.DeleteBannerResponse, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersGRPC.DeleteBanner")
+	defer span.End()
+
+	// по персональному токену в корзину пишется имя админа, по общему - роль
+	actor := middleware.ActorFromContext(ctx)
+	if actor == "" {
+		actor = middleware.TokenFromContext(ctx)
+	}
+	if err := b.bannersUC.DeleteBanner(ctx, models.BannerId(req.GetBannerId()), actor); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &banners_v1.DeleteBannerResponse{}, nil
+}
+
+func (b *BannersHandlers) ViewVersions(ctx context.Context, req *banners_v1.ViewVersionsRequest) (*banners_v1.ViewVersionsResponse, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersGRPC.ViewVersions")
+	defer span.End()
+
+	banners, err := b.bannersUC.ViewVersions(ctx, models.BannerId(req.GetBannerId()))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &banners_v1.ViewVersionsResponse{Banners: ToBanners(banners)}, nil
+}
+
+func (b *BannersHandlers) BannerRollback(ctx context.Context, req *banners_v1.BannerRollbackRequest) (*banners_v1.BannerRollbackResponse, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersGRPC.BannerRollback")
+	defer span.End()
+
+	if err := b.bannersUC.BannerRollback(ctx, models.BannerId(req.GetBannerId()), req.GetVersion(), req.ExpectedVersion); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &banners_v1.BannerRollbackResponse{}, nil
+}
+
+// toStatusError переводит http ошибки юзкейса в коды grpc
+func toStatusError(err error) error {
+	var e *fiber.Error
+	if !errors.As(err, &e) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	switch e.Code {
+	case fiber.StatusBadRequest:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case fiber.StatusUnauthorized:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case fiber.StatusForbidden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case fiber.StatusNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case fiber.StatusConflict:
+		return status.Error(codes.Aborted, err.Error())
+	case fiber.StatusPreconditionFailed:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case fiber.StatusUnprocessableEntity:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case fiber.StatusServiceUnavailable:
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
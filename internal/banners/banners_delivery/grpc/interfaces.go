@@ -0,0 +1,17 @@
+package banners_grpc
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/models"
+	"context"
+)
+
+type BannersUseCase interface {
+	GetBanner(ctx context.Context, getBannerParams *banners_usecase.GetBanner) (*models.FullBanner, error)
+	GetManyBanner(ctx context.Context, getManyBannerParams *banners_usecase.GetManyBanner) (*[]models.FullBanner, error)
+	AddBanner(ctx context.Context, addBannerParams *banners_usecase.AddBanner) (models.BannerId, error)
+	PatchBanner(ctx context.Context, patchBannerParams *banners_usecase.PatchBanner) (int64, error)
+	DeleteBanner(ctx context.Context, bannerId models.BannerId, actor string) error
+	ViewVersions(ctx context.Context, bannerId models.BannerId) (*[]models.FullBanner, error)
+	BannerRollback(ctx context.Context, bannerId models.BannerId, version int64, expectedVersion *int64) error
+}
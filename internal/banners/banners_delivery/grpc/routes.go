@@ -0,0 +1,22 @@
+package banners_grpc
+
+import (
+	"avito/assignment/pkg/api/banners_v1"
+	"avito/assignment/pkg/constant"
+	"google.golang.org/grpc"
+)
+
+// MethodRestrictions роли для каждого метода, аналог MapBannersRoutes
+var MethodRestrictions = map[string][]string{
+	banners_v1.BannersService_GetBanner_FullMethodName:      constant.AllRoles,
+	banners_v1.BannersService_GetManyBanner_FullMethodName:  constant.AdminRoles,
+	banners_v1.BannersService_AddBanner_FullMethodName:      constant.AdminRoles,
+	banners_v1.BannersService_PatchBanner_FullMethodName:    constant.AdminRoles,
+	banners_v1.BannersService_DeleteBanner_FullMethodName:   constant.AdminRoles,
+	banners_v1.BannersService_ViewVersions_FullMethodName:   constant.AdminRoles,
+	banners_v1.BannersService_BannerRollback_FullMethodName: constant.AdminRoles,
+}
+
+func MapBannersService(server *grpc.Server, h banners_v1.BannersServiceServer) {
+	banners_v1.RegisterBannersServiceServer(server, h)
+}
@@ -0,0 +1,133 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+const maxBatchOperations = 100
+
+// Batch упорядоченный список add/patch/delete/rollback в одной транзакции.
+// 200 - результаты по операциям, 4xx - failed_operation с номером операции, на которой все откатилось
+func (b *BannersHandlers) Batch() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.Batch")
+		defer span.End()
+
+		batch := BatchRequest{}
+		if err := c.BodyParser(&batch); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.Batch.ReadRequest")
+		}
+		if len(batch.Operations) == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, errors.New("operations are required"), "BannersHandlers.Batch.EmptyBatch")
+		}
+		if len(batch.Operations) > maxBatchOperations {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("batch has %d operations, at most %d are allowed", len(batch.Operations), maxBatchOperations), "BannersHandlers.Batch.TooManyOperations")
+		}
+
+		operations := make([]banners_usecase.BatchOperation, len(batch.Operations))
+		for i, operation := range batch.Operations {
+			batchOperation, err := b.toBatchOperation(ctx, c, span, operation)
+			if err != nil {
+				return batchError(c, &banners_usecase.BatchOperationError{Index: i, Type: operation.Op, Err: err})
+			}
+			operations[i] = *batchOperation
+		}
+
+		results, err := b.bannersUC.Batch(ctx, operations, requestActor(c))
+		if err != nil {
+			return batchError(c, err)
+		}
+
+		return c.JSON(fiber.Map{
+			"results": ToBatchResponse(results),
+		})
+	}
+}
+
+// toBatchOperation разбирает операцию тем же путем, что и одиночные ручки: слаги, валидация, непустые тэги
+func (b *BannersHandlers) toBatchOperation(ctx context.Context, c *fiber.Ctx, span trace.Span, operation BatchOperationRequest) (*banners_usecase.BatchOperation, error) {
+	batchOperation := &banners_usecase.BatchOperation{Type: operation.Op, BannerId: operation.BannerId, Version: operation.Version}
+
+	switch operation.Op {
+	case banners_usecase.BatchAdd:
+		addBanner := AddBannerRequest{}
+		if err := json.Unmarshal(operation.Banner, &addBanner); err != nil {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.Batch.ReadBanner")
+		}
+		if err := addBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return nil, err
+		}
+		if err := reqvalidator.Validate(c, &addBanner); err != nil {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.Batch.ReadBanner")
+		}
+		if len(addBanner.TagIds) == 0 {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, errors.New("tag_ids is required"), "BannersHandlers.Batch.NilTagIds")
+		}
+		batchOperation.Add = addBanner.ToAddBanner()
+	case banners_usecase.BatchPatch:
+		patchBanner := PatchBannerRequest{}
+		if err := json.Unmarshal(operation.Banner, &patchBanner); err != nil {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.Batch.ReadBanner")
+		}
+		if err := patchBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return nil, err
+		}
+		if err := reqvalidator.Validate(c, &patchBanner); err != nil {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.Batch.ReadBanner")
+		}
+		if patchBanner.TagIds != nil && len(*patchBanner.TagIds) == 0 {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, errors.New("tag_ids must not be empty"), "BannersHandlers.Batch.NilTagIds")
+		}
+		batchOperation.Patch = patchBanner.ToPatchBanner(operation.BannerId)
+		expectedVersion, err := mergeExpectedVersions(operation.ExpectedVersion, patchBanner.ExpectedVersion)
+		if err != nil {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.Batch.WrongExpectedVersion")
+		}
+		batchOperation.Patch.ExpectedVersion = expectedVersion
+	case banners_usecase.BatchRollback:
+		batchOperation.ExpectedVersion = operation.ExpectedVersion
+	case banners_usecase.BatchDelete:
+	default:
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			fmt.Errorf("unknown operation %q, expected add, patch, delete or rollback", operation.Op), "BannersHandlers.Batch.UnknownOperation")
+	}
+
+	if operation.Op != banners_usecase.BatchAdd && operation.BannerId <= 0 {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, errors.New("banner_id is required"), "BannersHandlers.Batch.WrongBannerParams")
+	}
+	if operation.Op == banners_usecase.BatchRollback && operation.Version <= 0 {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, errors.New("version is required"), "BannersHandlers.Batch.WrongVersionParams")
+	}
+	return batchOperation, nil
+}
+
+// batchError клиентские ошибки операции отдаются телом с ее номером, остальные уходят в error_handler как есть
+func batchError(c *fiber.Ctx, err error) error {
+	var operationErr *banners_usecase.BatchOperationError
+	var fiberErr *fiber.Error
+	if !errors.As(err, &operationErr) || !errors.As(err, &fiberErr) || fiberErr.Code >= fiber.StatusInternalServerError {
+		return err
+	}
+
+	errorPlace, _, _ := strings.Cut(operationErr.Err.Error(), "|")
+	c.Status(fiberErr.Code)
+	return c.JSON(fiber.Map{
+		"failed_operation": BatchFailedOperationResponse{
+			Index:      operationErr.Index,
+			Op:         operationErr.Type,
+			ErrorPlace: errorPlace,
+			Error:      errorValue(operationErr.Err),
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+// CloneBanner копия контента баннера на новые тэги и/или фичу.
+// 201 - копия создана, 400 со списком conflicts - часть пар (тэг, фича) уже занята, ничего не создано
+func (b *BannersHandlers) CloneBanner() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.CloneBanner")
+		defer span.End()
+
+		cloneBanner := CloneBannerRequest{}
+		if err := reqvalidator.ReadRequest(c, &cloneBanner); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.CloneBanner.ReadRequest")
+		}
+		if err := cloneBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+		if cloneBanner.TagIds == nil && cloneBanner.FeatureId == nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New("tag_ids or feature_id is required"), "BannersHandlers.CloneBanner.NothingToChange")
+		}
+		if cloneBanner.TagIds != nil && len(*cloneBanner.TagIds) == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.CloneBanner.NilTagIds")
+		}
+		bannerId, err := strconv.Atoi(c.Params("banner_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.CloneBanner.WrongBannerParams")
+		}
+
+		result, err := b.bannersUC.CloneBanner(ctx, cloneBanner.ToCloneBanner(models.BannerId(bannerId)))
+		if err != nil {
+			return err
+		}
+
+		if len(result.Conflicts) != 0 {
+			c.Status(fiber.StatusBadRequest)
+		} else {
+			c.Status(fiber.StatusCreated)
+		}
+		return c.JSON(ToCloneBannerResponse(result))
+	}
+}
@@ -0,0 +1,250 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/middleware"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultDraftsLimit = 100
+	maxDraftsLimit     = 1000
+)
+
+type GetDraftsRequest struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending approved published rejected"`
+	Limit  int    `query:"limit" validate:"gte=0"`
+	Offset int    `query:"offset" validate:"gte=0"`
+}
+
+func (d *GetDraftsRequest) ToGetDrafts() *banners_usecase.GetDrafts {
+	getDrafts := &banners_usecase.GetDrafts{Status: d.Status, Limit: d.Limit, Offset: d.Offset}
+	if getDrafts.Limit == 0 {
+		getDrafts.Limit = defaultDraftsLimit
+	}
+	if getDrafts.Limit > maxDraftsLimit {
+		getDrafts.Limit = maxDraftsLimit
+	}
+	return getDrafts
+}
+
+// RejectDraftRequest тело необязательное, комментарий увидит автор черновика
+type RejectDraftRequest struct {
+	Comment string `json:"comment"`
+}
+
+type DraftResponse struct {
+	DraftId     models.DraftId        `json:"draft_id"`
+	BannerId    models.BannerId       `json:"banner_id"`
+	BaseVersion int64                 `json:"base_version"`
+	Banner      GetManyBannerResponse `json:"banner"`
+	Status      string                `json:"status"`
+	Author      string                `json:"author"`
+	ReviewedBy  *string               `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time            `json:"reviewed_at,omitempty"`
+	Comment     string                `json:"comment,omitempty"`
+	PublishedBy *string               `json:"published_by,omitempty"`
+	PublishedAt *time.Time            `json:"published_at,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+func ToDraftResponse(draft *models.BannerDraft) DraftResponse {
+	return DraftResponse{
+		DraftId:     draft.DraftId,
+		BannerId:    draft.BannerId,
+		BaseVersion: draft.BaseVersion,
+		Banner:      GetManyBannerResponse(draft.Banner),
+		Status:      draft.Status,
+		Author:      draft.Author,
+		ReviewedBy:  draft.ReviewedBy,
+		ReviewedAt:  draft.ReviewedAt,
+		Comment:     draft.Comment,
+		PublishedBy: draft.PublishedBy,
+		PublishedAt: draft.PublishedAt,
+		CreatedAt:   draft.CreatedAt,
+		UpdatedAt:   draft.UpdatedAt,
+	}
+}
+
+func ToDraftsResponse(drafts []models.BannerDraft) []DraftResponse {
+	response := make([]DraftResponse, len(drafts))
+	for i := range drafts {
+		response[i] = ToDraftResponse(&drafts[i])
+	}
+	return response
+}
+
+type DraftChangeResponse struct {
+	Field string      `json:"field"`
+	Live  interface{} `json:"live"`
+	Draft interface{} `json:"draft"`
+}
+
+type DraftDiffResponse struct {
+	Draft   DraftResponse         `json:"draft"`
+	Live    GetManyBannerResponse `json:"live"`
+	Changes []DraftChangeResponse `json:"changes"`
+}
+
+func ToDraftDiffResponse(diff *banners_usecase.DraftDiff) DraftDiffResponse {
+	response := DraftDiffResponse{
+		Draft:   ToDraftResponse(diff.Draft),
+		Live:    GetManyBannerResponse(*diff.Live),
+		Changes: make([]DraftChangeResponse, len(diff.Changes)),
+	}
+	for i, change := range diff.Changes {
+		response.Changes[i] = DraftChangeResponse(change)
+	}
+	return response
+}
+
+// SaveDraft тело как у PATCH /banner/:banner_id, правка копится в черновике и не видна пользователям до публикации
+func (b *BannersHandlers) SaveDraft() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.SaveDraft")
+		defer span.End()
+
+		patchBanner := PatchBannerRequest{}
+		if err := reqvalidator.ReadRequest(c, &patchBanner); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.SaveDraft.ReadRequest")
+		}
+		if err := patchBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+		bannerId, err := strconv.Atoi(c.Params("banner_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.SaveDraft.WrongBannerParams")
+		}
+
+		patchBannerDTO := patchBanner.ToPatchBanner(models.BannerId(bannerId))
+		patchBannerDTO.ExpectedVersion, err = expectedVersion(c, patchBanner.ExpectedVersion)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.SaveDraft.WrongExpectedVersion")
+		}
+
+		draftId, err := b.bannersUC.SaveDraft(ctx, patchBannerDTO, middleware.Actor(c))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"draft_id": draftId,
+		})
+	}
+}
+
+// GetDrafts очередь черновиков на ревью, по умолчанию ожидающие одобрения
+func (b *BannersHandlers) GetDrafts() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetDrafts")
+		defer span.End()
+
+		getDrafts := GetDraftsRequest{}
+		if err := reqvalidator.ReadQuery(c, &getDrafts); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetDrafts.ReadQuery")
+		}
+
+		drafts, err := b.bannersUC.GetDrafts(ctx, getDrafts.ToGetDrafts())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToDraftsResponse(drafts))
+	}
+}
+
+func (b *BannersHandlers) GetDraftDiff() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetDraftDiff")
+		defer span.End()
+
+		draftId, err := strconv.ParseInt(c.Params("draft_id"), 10, 64)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.GetDraftDiff.WrongDraftParams")
+		}
+
+		diff, err := b.bannersUC.GetDraftDiff(ctx, models.DraftId(draftId))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToDraftDiffResponse(diff))
+	}
+}
+
+func (b *BannersHandlers) ApproveDraft() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.ApproveDraft")
+		defer span.End()
+
+		draftId, err := strconv.ParseInt(c.Params("draft_id"), 10, 64)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.ApproveDraft.WrongDraftParams")
+		}
+
+		err = b.bannersUC.ApproveDraft(ctx, models.DraftId(draftId), middleware.Actor(c))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
+
+func (b *BannersHandlers) RejectDraft() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.RejectDraft")
+		defer span.End()
+
+		draftId, err := strconv.ParseInt(c.Params("draft_id"), 10, 64)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.RejectDraft.WrongDraftParams")
+		}
+		rejectDraft := RejectDraftRequest{}
+		if len(c.Body()) != 0 {
+			if err = c.BodyParser(&rejectDraft); err != nil {
+				return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.RejectDraft.ReadRequest")
+			}
+		}
+
+		err = b.bannersUC.RejectDraft(ctx, models.DraftId(draftId), middleware.Actor(c), rejectDraft.Comment)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
+
+// PublishDraft атомарно переносит черновик в живой баннер
+func (b *BannersHandlers) PublishDraft() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.PublishDraft")
+		defer span.End()
+
+		draftId, err := strconv.ParseInt(c.Params("draft_id"), 10, 64)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.PublishDraft.WrongDraftParams")
+		}
+
+		err = b.bannersUC.PublishDraft(ctx, models.DraftId(draftId), middleware.Actor(c))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
@@ -1,45 +1,97 @@
 package banners_http
 
 import (
+	"avito/assignment/internal/banners/banners_stream"
 	"avito/assignment/internal/banners/banners_usecase"
 	"avito/assignment/internal/models"
 	"avito/assignment/pkg/utilities"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// GetBannerRequest тэг и фича передаются айди или слагом из реестра
 type GetBannerRequest struct {
-	TagId          models.TagId     `json:"tag_id" validate:"required"`
-	FeatureId      models.FeatureId `json:"feature_id" validate:"required"`
+	TagId          models.TagId     `json:"tag_id" validate:"required_without=Tag"`
+	FeatureId      models.FeatureId `json:"feature_id" validate:"required_without=Feature"`
+	Tag            string           `json:"tag"`
+	Feature        string           `json:"feature"`
 	UseLastVersion bool             `json:"use_last_version"`
 }
 
+// GetManyBannerRequest created_*/updated_* - полуинтервал [after, before), title и url - поиск подстроки
 type GetManyBannerRequest struct {
-	FeatureId *models.FeatureId `json:"feature_id"`
-	TagId     *models.TagId     `json:"tag_id"`
-	Limit     *int              `json:"limit"`
-	Offset    *int              `json:"offset"`
+	FeatureId     *models.FeatureId  `json:"feature_id"`
+	TagId         *models.TagId      `json:"tag_id"`
+	Feature       *string            `json:"feature"`
+	Tag           *string            `json:"tag"`
+	FeatureIds    []models.FeatureId `json:"feature_ids"`
+	TagIds        []models.TagId     `json:"tag_ids"`
+	Features      []string           `json:"features"`
+	Tags          []string           `json:"tags"`
+	IsActive      *bool              `json:"is_active"`
+	Status        []string           `json:"status" validate:"omitempty,dive,oneof=draft scheduled active paused expired archived"`
+	CreatedAfter  *time.Time         `json:"created_after"`
+	CreatedBefore *time.Time         `json:"created_before"`
+	UpdatedAfter  *time.Time         `json:"updated_after"`
+	UpdatedBefore *time.Time         `json:"updated_before"`
+	Title         *string            `json:"title"`
+	Url           *string            `json:"url"`
+	Limit         *int               `json:"limit"`
+	Offset        *int               `json:"offset"`
+	SortBy        string             `json:"sort_by" validate:"omitempty,oneof=banner_id created_at updated_at version rank"`
+	SortOrder     string             `json:"sort_order" validate:"omitempty,oneof=asc desc"`
+	Cursor        string             `json:"cursor"`
+	WithTotal     bool               `json:"with_total"`
+	// Search полнотекстовый поиск по title и text, search_versions - искать и в прошлых версиях
+	Search         *string `json:"search" validate:"omitempty,min=1"`
+	SearchVersions bool    `json:"search_versions"`
+}
+
+// GetManyBannerQuery то, что можно передать в query, чтобы ходить по ссылкам из Link без тела
+type GetManyBannerQuery struct {
+	Cursor    string `query:"cursor"`
+	Limit     *int   `query:"limit" validate:"omitempty,gte=0"`
+	WithTotal bool   `query:"with_total"`
 }
 
 type AddBannerRequest struct {
-	TagIds    []models.TagId   `json:"tag_ids" validate:"required"`
-	FeatureId models.FeatureId `json:"feature_id" validate:"required"`
+	TagIds    []models.TagId   `json:"tag_ids" validate:"required_without=Tags"`
+	FeatureId models.FeatureId `json:"feature_id" validate:"required_without=Feature"`
+	Tags      []string         `json:"tags"`
+	Feature   string           `json:"feature"`
 	Content   struct {
 		Title string `json:"title" validate:"required"`
 		Text  string `json:"text" validate:"required"`
 		Url   string `json:"url" validate:"required"`
 	} `json:"content"`
 	IsActive bool `json:"is_active"`
+	// Status без него статус берется из is_active: true - active, false - paused
+	Status string `json:"status" validate:"omitempty,oneof=draft scheduled active paused expired archived"`
 }
 
 type PatchBannerRequest struct {
 	TagIds    *[]models.TagId   `json:"tag_ids"`
 	FeatureId *models.FeatureId `json:"feature_id"`
+	Tags      *[]string         `json:"tags"`
+	Feature   *string           `json:"feature"`
 	Content   *struct {
 		Title *string `json:"title"`
 		Text  *string `json:"text"`
 		Url   *string `json:"url"`
 	} `json:"content"`
-	IsActive *bool `json:"is_active"`
+	IsActive *bool   `json:"is_active"`
+	Status   *string `json:"status" validate:"omitempty,oneof=draft scheduled active paused expired archived"`
+	// ExpectedVersion то же, что If-Match: версия баннера, поверх которой делается патч
+	ExpectedVersion *int64 `json:"expected_version"`
+}
+
+// BannerRollbackRequest тело отката необязательное, expected_version можно передать и в If-Match
+type BannerRollbackRequest struct {
+	ExpectedVersion *int64 `json:"expected_version"`
 }
 
 func (b *GetBannerRequest) ToGetBanner() *banners_usecase.GetBanner {
@@ -52,10 +104,25 @@ func (b *GetBannerRequest) ToGetBanner() *banners_usecase.GetBanner {
 
 func (b *GetManyBannerRequest) ToGetManyBanner() *banners_usecase.GetManyBanner {
 	return &banners_usecase.GetManyBanner{
-		FeatureId: b.FeatureId,
-		TagId:     b.TagId,
-		Limit:     b.Limit,
-		Offset:    b.Offset,
+		FeatureId:      b.FeatureId,
+		TagId:          b.TagId,
+		FeatureIds:     b.FeatureIds,
+		TagIds:         b.TagIds,
+		IsActive:       b.IsActive,
+		Status:         b.Status,
+		CreatedAfter:   b.CreatedAfter,
+		CreatedBefore:  b.CreatedBefore,
+		UpdatedAfter:   b.UpdatedAfter,
+		UpdatedBefore:  b.UpdatedBefore,
+		Title:          b.Title,
+		Url:            b.Url,
+		Limit:          b.Limit,
+		Offset:         b.Offset,
+		SortBy:         b.SortBy,
+		SortDesc:       b.SortOrder == "desc",
+		WithTotal:      b.WithTotal,
+		Search:         b.Search,
+		SearchVersions: b.SearchVersions,
 	}
 }
 
@@ -69,6 +136,7 @@ func (b *AddBannerRequest) ToAddBanner() *banners_usecase.AddBanner {
 			Url   string
 		}{Title: b.Content.Title, Text: b.Content.Text, Url: b.Content.Url},
 		IsActive: b.IsActive,
+		Status:   b.Status,
 	}
 }
 
@@ -82,9 +150,11 @@ func (b *PatchBannerRequest) ToPatchBanner(bannerId models.BannerId) *banners_us
 				}
 				return nil
 			}(b.TagIds),
-			FeatureId: b.FeatureId,
-			IsActive:  b.IsActive,
-			BannerId:  bannerId,
+			FeatureId:       b.FeatureId,
+			IsActive:        b.IsActive,
+			Status:          b.Status,
+			BannerId:        bannerId,
+			ExpectedVersion: b.ExpectedVersion,
 		}
 	}
 	return &banners_usecase.PatchBanner{
@@ -95,12 +165,14 @@ func (b *PatchBannerRequest) ToPatchBanner(bannerId models.BannerId) *banners_us
 			}
 			return nil
 		}(b.TagIds),
-		FeatureId: b.FeatureId,
-		Title:     b.Content.Title,
-		Text:      b.Content.Text,
-		Url:       b.Content.Url,
-		IsActive:  b.IsActive,
-		BannerId:  bannerId,
+		FeatureId:       b.FeatureId,
+		Title:           b.Content.Title,
+		Text:            b.Content.Text,
+		Url:             b.Content.Url,
+		IsActive:        b.IsActive,
+		Status:          b.Status,
+		BannerId:        bannerId,
+		ExpectedVersion: b.ExpectedVersion,
 	}
 }
 
@@ -128,11 +200,42 @@ type GetManyBannerResponse struct {
 		Url   string `json:"url"`
 	} `json:"content"`
 	IsActive  bool      `json:"is_active"`
+	Status    string    `json:"status"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Version   int64     `json:"version"`
 }
 
+// SearchBannerResponse баннер из выдачи поиска, совпадения подсвечены <b></b>
+type SearchBannerResponse struct {
+	GetManyBannerResponse
+	Search SearchMatchResponse `json:"search"`
+}
+
+type SearchMatchResponse struct {
+	Rank            float32 `json:"rank"`
+	Title           string  `json:"title"`
+	Text            string  `json:"text"`
+	MatchedVersions []int64 `json:"matched_versions,omitempty"`
+}
+
+func ToSearchBannersResponse(page *banners_usecase.BannersPage) []SearchBannerResponse {
+	response := make([]SearchBannerResponse, len(page.Banners))
+	for i, banner := range page.Banners {
+		match := page.Matches[banner.BannerId]
+		response[i] = SearchBannerResponse{
+			GetManyBannerResponse: GetManyBannerResponse(banner),
+			Search: SearchMatchResponse{
+				Rank:            match.Rank,
+				Title:           match.Title,
+				Text:            match.Text,
+				MatchedVersions: match.MatchedVersions,
+			},
+		}
+	}
+	return response
+}
+
 func ToGetManyBannerResponse(b *[]models.FullBanner) *[]GetManyBannerResponse {
 	getManyBannerResponse := make([]GetManyBannerResponse, len(*b))
 
@@ -142,3 +245,415 @@ func ToGetManyBannerResponse(b *[]models.FullBanner) *[]GetManyBannerResponse {
 
 	return &getManyBannerResponse
 }
+
+// StreamBannersRequest pair в формате "tag_id:feature_id", tag подписывает на все фичи тэга
+type StreamBannersRequest struct {
+	Pairs  []string       `query:"pair"`
+	TagIds []models.TagId `query:"tag"`
+}
+
+func (b *StreamBannersRequest) ToSubscription() (*banners_stream.Subscription, error) {
+	subscription := &banners_stream.Subscription{TagIds: b.TagIds}
+	for _, pair := range b.Pairs {
+		tagPart, featurePart, found := strings.Cut(pair, ":")
+		if !found {
+			return nil, fmt.Errorf("pair %q must look like tag_id:feature_id", pair)
+		}
+		tagId, err := strconv.ParseInt(tagPart, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("pair %q has invalid tag_id", pair)
+		}
+		featureId, err := strconv.ParseInt(featurePart, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("pair %q has invalid feature_id", pair)
+		}
+		subscription.Pairs = append(subscription.Pairs, banners_stream.Pair{TagId: models.TagId(tagId), FeatureId: models.FeatureId(featureId)})
+	}
+	return subscription, nil
+}
+
+// ReadAttributes собирает атрибуты для шаблонов из query параметров вида attr.user.name=...
+func ReadAttributes(c *fiber.Ctx) map[string]string {
+	attributes := make(map[string]string)
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if name, found := strings.CutPrefix(string(key), "attr."); found {
+			attributes[name] = string(value)
+		}
+	})
+	return attributes
+}
+
+type StreamBannerEvent struct {
+	TagId     models.TagId       `json:"tag_id"`
+	FeatureId models.FeatureId   `json:"feature_id"`
+	Deleted   bool               `json:"deleted"`
+	Content   *GetBannerResponse `json:"content,omitempty"`
+}
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+)
+
+// GetChangesRequest since - курсор из next_cursor прошлого ответа, пустой курсор = с начала журнала
+type GetChangesRequest struct {
+	Since string `query:"since"`
+	Limit int    `query:"limit" validate:"gte=0"`
+}
+
+func (b *GetChangesRequest) ToGetChanges() (*banners_usecase.GetChanges, error) {
+	getChanges := &banners_usecase.GetChanges{Limit: b.Limit}
+	if getChanges.Limit == 0 {
+		getChanges.Limit = defaultChangesLimit
+	}
+	if getChanges.Limit > maxChangesLimit {
+		getChanges.Limit = maxChangesLimit
+	}
+
+	if b.Since != "" {
+		since, err := strconv.ParseInt(b.Since, 10, 64)
+		if err != nil || since < 0 {
+			return nil, fmt.Errorf("invalid cursor %q", b.Since)
+		}
+		getChanges.Since = since
+	}
+
+	return getChanges, nil
+}
+
+type ChangeResponse struct {
+	ChangeId  int64           `json:"change_id"`
+	BannerId  models.BannerId `json:"banner_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type GetChangesResponse struct {
+	Changes    []ChangeResponse `json:"changes"`
+	NextCursor string           `json:"next_cursor"`
+}
+
+func ToGetChangesResponse(changes []models.ChangeLogEntry, nextCursor int64) *GetChangesResponse {
+	response := &GetChangesResponse{
+		Changes:    make([]ChangeResponse, len(changes)),
+		NextCursor: strconv.FormatInt(nextCursor, 10),
+	}
+	for i, change := range changes {
+		response.Changes[i] = ChangeResponse(change)
+	}
+	return response
+}
+
+type BulkDeleteRequest struct {
+	FeatureId *models.FeatureId `json:"feature_id"`
+	TagId     *models.TagId     `json:"tag_id"`
+	Feature   *string           `json:"feature"`
+	Tag       *string           `json:"tag"`
+}
+
+func (b *BulkDeleteRequest) ToBulkDelete() *banners_usecase.BulkDelete {
+	return &banners_usecase.BulkDelete{
+		FeatureId: b.FeatureId,
+		TagId:     b.TagId,
+	}
+}
+
+type BulkDeleteJobResponse struct {
+	JobId      models.JobId      `json:"job_id"`
+	FeatureId  *models.FeatureId `json:"feature_id"`
+	TagId      *models.TagId     `json:"tag_id"`
+	Status     string            `json:"status"`
+	Total      *int64            `json:"total"`
+	Deleted    int64             `json:"deleted"`
+	Errors     []string          `json:"errors"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	FinishedAt *time.Time        `json:"finished_at"`
+}
+
+func ToBulkDeleteJobResponse(job *models.BulkDeleteJob) *BulkDeleteJobResponse {
+	return &BulkDeleteJobResponse{
+		JobId:      job.JobId,
+		FeatureId:  job.FeatureId,
+		TagId:      job.TagId,
+		Status:     job.Status,
+		Total:      job.Total,
+		Deleted:    job.Deleted,
+		Errors:     job.Errors,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+// BulkPatchRequest фильтр как у BulkDeleteRequest, патч как у PatchBannerRequest, но без тэгов и фичи
+type BulkPatchRequest struct {
+	FeatureId *models.FeatureId `json:"feature_id"`
+	TagId     *models.TagId     `json:"tag_id"`
+	Feature   *string           `json:"feature"`
+	Tag       *string           `json:"tag"`
+	Content   *struct {
+		Title *string `json:"title"`
+		Text  *string `json:"text"`
+		Url   *string `json:"url"`
+	} `json:"content"`
+	UrlRewrite *struct {
+		From string `json:"from" validate:"required"`
+		To   string `json:"to"`
+	} `json:"url_rewrite"`
+	IsActive *bool   `json:"is_active"`
+	Status   *string `json:"status" validate:"omitempty,oneof=draft scheduled active paused expired archived"`
+	DryRun   bool    `json:"dry_run"`
+}
+
+func (b *BulkPatchRequest) ToBulkPatch() *banners_usecase.BulkPatch {
+	bulkPatch := &banners_usecase.BulkPatch{
+		FeatureId: b.FeatureId,
+		TagId:     b.TagId,
+		IsActive:  b.IsActive,
+		Status:    b.Status,
+		DryRun:    b.DryRun,
+	}
+	if b.Content != nil {
+		bulkPatch.Title, bulkPatch.Text, bulkPatch.Url = b.Content.Title, b.Content.Text, b.Content.Url
+	}
+	if b.UrlRewrite != nil {
+		bulkPatch.UrlRewrite = &banners_usecase.UrlRewrite{From: b.UrlRewrite.From, To: b.UrlRewrite.To}
+	}
+	return bulkPatch
+}
+
+type BulkPatchPreviewResponse struct {
+	BannerId models.BannerId       `json:"banner_id"`
+	Before   GetManyBannerResponse `json:"before"`
+	After    GetManyBannerResponse `json:"after"`
+}
+
+type BulkPatchResponse struct {
+	Matched   int64                      `json:"matched"`
+	Affected  int                        `json:"affected"`
+	DryRun    bool                       `json:"dry_run"`
+	BannerIds []models.BannerId          `json:"banner_ids"`
+	Preview   []BulkPatchPreviewResponse `json:"preview,omitempty"`
+}
+
+func ToBulkPatchResponse(result *banners_usecase.BulkPatchResult) *BulkPatchResponse {
+	response := &BulkPatchResponse{
+		Matched:   result.Matched,
+		Affected:  len(result.BannerIds),
+		DryRun:    result.DryRun,
+		BannerIds: make([]models.BannerId, 0, len(result.BannerIds)),
+	}
+	response.BannerIds = append(response.BannerIds, result.BannerIds...)
+	for _, preview := range result.Previews {
+		response.Preview = append(response.Preview, BulkPatchPreviewResponse{
+			BannerId: preview.Before.BannerId,
+			Before:   GetManyBannerResponse(preview.Before),
+			After:    GetManyBannerResponse(preview.After),
+		})
+	}
+	return response
+}
+
+type BatchRequest struct {
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationRequest banner - тело как у POST /banner для add и как у PATCH /banner/:banner_id для patch
+type BatchOperationRequest struct {
+	Op       string          `json:"op"`
+	BannerId models.BannerId `json:"banner_id"`
+	Version  int64           `json:"version"`
+	Banner   json.RawMessage `json:"banner"`
+	// ExpectedVersion для patch и rollback, у patch ее можно передать и в самом banner
+	ExpectedVersion *int64 `json:"expected_version"`
+}
+
+type BatchOperationResponse struct {
+	Index    int             `json:"index"`
+	Op       string          `json:"op"`
+	BannerId models.BannerId `json:"banner_id"`
+}
+
+func ToBatchResponse(results []banners_usecase.BatchOperationResult) []BatchOperationResponse {
+	response := make([]BatchOperationResponse, len(results))
+	for i, result := range results {
+		response[i] = BatchOperationResponse{Index: i, Op: result.Type, BannerId: result.BannerId}
+	}
+	return response
+}
+
+type BatchFailedOperationResponse struct {
+	Index      int    `json:"index"`
+	Op         string `json:"op"`
+	ErrorPlace string `json:"error_place"`
+	Error      string `json:"error"`
+}
+
+// ImportBannersRequest format можно не передавать, тогда он берется из Content-Type
+type ImportBannersRequest struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv ndjson"`
+	DryRun bool   `query:"dry_run"`
+}
+
+// ImportRowRequest строка файла импорта, Err - ошибка разбора строки
+type ImportRowRequest struct {
+	Line   int
+	Banner AddBannerRequest
+	Err    error
+}
+
+type ImportRowErrorResponse struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportResponse struct {
+	Total     int                      `json:"total"`
+	Valid     int                      `json:"valid"`
+	DryRun    bool                     `json:"dry_run"`
+	Applied   bool                     `json:"applied"`
+	BannerIds []models.BannerId        `json:"banner_ids"`
+	Errors    []ImportRowErrorResponse `json:"errors"`
+}
+
+func ToImportResponse(result *banners_usecase.ImportResult) *ImportResponse {
+	response := &ImportResponse{
+		Total:     result.Total,
+		Valid:     result.Valid,
+		DryRun:    result.DryRun,
+		Applied:   result.Applied,
+		BannerIds: make([]models.BannerId, 0, len(result.Created)),
+		Errors:    make([]ImportRowErrorResponse, len(result.Errors)),
+	}
+	response.BannerIds = append(response.BannerIds, result.Created...)
+	for i, rowError := range result.Errors {
+		response.Errors[i] = ImportRowErrorResponse(rowError)
+	}
+	return response
+}
+
+// ExportBannersRequest фильтры как у GetManyBanner, но в query, format по умолчанию ndjson
+// ExportBannersRequest те же фильтры и сортировка, что у GetManyBannerRequest, только в query: списки повторяющимися ключами
+type ExportBannersRequest struct {
+	Format         string             `query:"format" validate:"omitempty,oneof=csv ndjson"`
+	FeatureId      *models.FeatureId  `query:"feature_id"`
+	TagId          *models.TagId      `query:"tag_id"`
+	Feature        *string            `query:"feature"`
+	Tag            *string            `query:"tag"`
+	FeatureIds     []models.FeatureId `query:"feature_ids"`
+	TagIds         []models.TagId     `query:"tag_ids"`
+	Features       []string           `query:"features"`
+	Tags           []string           `query:"tags"`
+	IsActive       *bool              `query:"is_active"`
+	Status         []string           `query:"status" validate:"omitempty,dive,oneof=draft scheduled active paused expired archived"`
+	CreatedAfter   *time.Time         `query:"created_after"`
+	CreatedBefore  *time.Time         `query:"created_before"`
+	UpdatedAfter   *time.Time         `query:"updated_after"`
+	UpdatedBefore  *time.Time         `query:"updated_before"`
+	Title          *string            `query:"title"`
+	Url            *string            `query:"url"`
+	Limit          *int               `query:"limit" validate:"omitempty,gte=0"`
+	Offset         *int               `query:"offset" validate:"omitempty,gte=0"`
+	SortBy         string             `query:"sort_by" validate:"omitempty,oneof=banner_id created_at updated_at version rank"`
+	SortOrder      string             `query:"sort_order" validate:"omitempty,oneof=asc desc"`
+	Search         *string            `query:"search" validate:"omitempty,min=1"`
+	SearchVersions bool               `query:"search_versions"`
+	WithVersions   bool               `query:"with_versions"`
+}
+
+func (b *ExportBannersRequest) ToExportBanners() *banners_usecase.ExportBanners {
+	return &banners_usecase.ExportBanners{
+		GetManyBanner: banners_usecase.GetManyBanner{
+			FeatureId:      b.FeatureId,
+			TagId:          b.TagId,
+			FeatureIds:     b.FeatureIds,
+			TagIds:         b.TagIds,
+			IsActive:       b.IsActive,
+			Status:         b.Status,
+			CreatedAfter:   b.CreatedAfter,
+			CreatedBefore:  b.CreatedBefore,
+			UpdatedAfter:   b.UpdatedAfter,
+			UpdatedBefore:  b.UpdatedBefore,
+			Title:          b.Title,
+			Url:            b.Url,
+			Limit:          b.Limit,
+			Offset:         b.Offset,
+			SortBy:         b.SortBy,
+			SortDesc:       b.SortOrder == "desc",
+			Search:         b.Search,
+			SearchVersions: b.SearchVersions,
+		},
+		WithVersions: b.WithVersions,
+	}
+}
+
+// ExportTrailerResponse последняя запись ndjson выгрузки, по ней клиент отличает полную выгрузку от оборванной
+type ExportTrailerResponse struct {
+	ExportEnd   bool    `json:"export_end,omitempty"`
+	ExportError *string `json:"export_error,omitempty"`
+	Rows        int64   `json:"rows"`
+}
+
+type ExportBannerResponse struct {
+	GetManyBannerResponse
+	Versions []GetManyBannerResponse `json:"versions,omitempty"`
+}
+
+func ToExportBannerResponse(exported *banners_usecase.ExportedBanner) *ExportBannerResponse {
+	response := &ExportBannerResponse{GetManyBannerResponse: GetManyBannerResponse(exported.Banner)}
+	for _, version := range exported.Versions {
+		response.Versions = append(response.Versions, GetManyBannerResponse(version))
+	}
+	return response
+}
+
+type SnapshotResponse struct {
+	SnapshotId    string            `json:"snapshot_id"`
+	FormatVersion int               `json:"format_version"`
+	SchemaVersion int64             `json:"schema_version"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Counts        map[string]int    `json:"counts"`
+	Checksums     map[string]string `json:"checksums"`
+}
+
+func ToSnapshotResponse(manifest *models.SnapshotManifest) *SnapshotResponse {
+	return (*SnapshotResponse)(manifest)
+}
+
+// CloneBannerRequest нужно передать новые тэги, новую фичу или и то, и другое
+type CloneBannerRequest struct {
+	TagIds    *[]models.TagId   `json:"tag_ids"`
+	FeatureId *models.FeatureId `json:"feature_id"`
+	Tags      *[]string         `json:"tags"`
+	Feature   *string           `json:"feature"`
+	IsActive  *bool             `json:"is_active"`
+}
+
+func (b *CloneBannerRequest) ToCloneBanner(bannerId models.BannerId) *banners_usecase.CloneBanner {
+	return &banners_usecase.CloneBanner{
+		BannerId:  bannerId,
+		TagIds:    b.TagIds,
+		FeatureId: b.FeatureId,
+		IsActive:  b.IsActive,
+	}
+}
+
+type CloneConflictResponse struct {
+	TagId     models.TagId     `json:"tag_id"`
+	FeatureId models.FeatureId `json:"feature_id"`
+}
+
+type CloneBannerResponse struct {
+	BannerId  models.BannerId         `json:"banner_id,omitempty"`
+	Conflicts []CloneConflictResponse `json:"conflicts,omitempty"`
+}
+
+func ToCloneBannerResponse(result *banners_usecase.CloneResult) *CloneBannerResponse {
+	response := &CloneBannerResponse{BannerId: result.BannerId}
+	for _, conflict := range result.Conflicts {
+		response.Conflicts = append(response.Conflicts, CloneConflictResponse(conflict))
+	}
+	return response
+}
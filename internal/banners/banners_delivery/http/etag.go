@@ -0,0 +1,51 @@
+package banners_http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+)
+
+var (
+	errInvalidIfMatch      = errors.New("If-Match must be a single strong ETag with the banner version or *")
+	errConflictingVersions = errors.New("If-Match and expected_version point to different versions")
+)
+
+// bannerETag сильный ETag баннера - его версия, ее же принимает If-Match
+func bannerETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// expectedVersion версия из If-Match и expected_version тела, nil - проверять нечего, * тоже ничего не проверяет
+func expectedVersion(c *fiber.Ctx, bodyVersion *int64) (*int64, error) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "" || ifMatch == "*" {
+		return bodyVersion, nil
+	}
+
+	unquoted, ok := strings.CutPrefix(ifMatch, `"`)
+	if !ok {
+		return nil, errInvalidIfMatch
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return nil, errInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+	return mergeExpectedVersions(&version, bodyVersion)
+}
+
+func mergeExpectedVersions(first, second *int64) (*int64, error) {
+	if first == nil {
+		return second, nil
+	}
+	if second != nil && *first != *second {
+		return nil, errConflictingVersions
+	}
+	return first, nil
+}
@@ -0,0 +1,174 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var exportCSVHeader = []string{"banner_id", "tag_ids", "feature_id", "title", "text", "url", "is_active", "status", "created_at", "updated_at", "version"}
+
+// ExportBanners потоковая выгрузка баннеров в ndjson или csv
+// 1. Фильтры и слаги те же, что у GetManyBanner, ошибки в них отдаем обычным ответом до начала выгрузки
+// 2. Дальше пишем строку за строкой из курсора постгреса, ответ не собирается в памяти целиком
+// 3. Ошибка посреди выгрузки уже не может поменять статус, поэтому логируем ее и дописываем в конец служебную запись об ошибке
+// 4. Успешная выгрузка заканчивается записью export_end с числом баннеров: нет ее - выгрузка оборвалась.
+// В ndjson это отдельная json строка, в csv - строка-комментарий с #, импорт пропускает и то, и другое
+// 5. Выгрузка ограничена Export.TimeoutSeconds, если клиент отвалился и запись не прошла, курсор закрываем сразу
+func (b *BannersHandlers) ExportBanners() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.ExportBanners")
+		defer span.End()
+
+		exportRequest := ExportBannersRequest{}
+		if err := reqvalidator.ReadQuery(c, &exportRequest); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.ExportBanners.ReadQuery")
+		}
+		// ответ уже стримится, когда до него дойдет usecase, поэтому ошибки запроса ловим до потока
+		if exportRequest.SortBy == "rank" && exportRequest.Search == nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, errors.New("sort by rank requires search"), "BannersHandlers.ExportBanners.RankWithoutSearch")
+		}
+		if err := exportRequest.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+		exportParams := exportRequest.ToExportBanners()
+
+		var writeRow func(w *bufio.Writer) func(*banners_usecase.ExportedBanner) error
+		var writeTrailer func(w *bufio.Writer, trailer *ExportTrailerResponse)
+		switch exportRequest.Format {
+		case "", importFormatNDJSON:
+			c.Set(fiber.HeaderContentType, "application/x-ndjson")
+			c.Set(fiber.HeaderContentDisposition, `attachment; filename="banners.ndjson"`)
+			writeRow = writeExportNDJSON
+			writeTrailer = writeExportNDJSONTrailer
+		case importFormatCSV:
+			c.Set(fiber.HeaderContentType, "text/csv")
+			c.Set(fiber.HeaderContentDisposition, `attachment; filename="banners.csv"`)
+			writeRow = func(w *bufio.Writer) func(*banners_usecase.ExportedBanner) error {
+				return writeExportCSV(w, exportParams.WithVersions)
+			}
+			writeTrailer = writeExportCSVTrailer
+		}
+
+		// запрос fiber заканчивается раньше выгрузки, поэтому дальше живем на отдельном контексте с тем же трейсом
+		exportCtx, cancel := context.WithTimeout(trace.ContextWithSpanContext(context.Background(), span.SpanContext()),
+			time.Duration(b.cfg.Export.TimeoutSeconds)*time.Second)
+		c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+
+			var rows int64
+			var writeErr error
+			write := writeRow(w)
+			err := b.bannersUC.ExportBanners(exportCtx, exportParams, func(exported *banners_usecase.ExportedBanner) error {
+				if writeErr = write(exported); writeErr != nil {
+					cancel()
+					return writeErr
+				}
+				rows++
+				return nil
+			})
+			switch {
+			case writeErr != nil:
+				// клиент уже не читает ответ, служебную запись писать некуда
+				log.Errorf("BannersHandlers.ExportBanners.Write: %s", writeErr.Error())
+				return
+			case err != nil:
+				log.Errorf("BannersHandlers.ExportBanners: %s", err.Error())
+				message := "export is interrupted"
+				writeTrailer(w, &ExportTrailerResponse{Rows: rows, ExportError: &message})
+			default:
+				writeTrailer(w, &ExportTrailerResponse{Rows: rows, ExportEnd: true})
+			}
+			_ = w.Flush()
+		}))
+
+		return nil
+	}
+}
+
+func writeExportNDJSON(w *bufio.Writer) func(*banners_usecase.ExportedBanner) error {
+	encoder := json.NewEncoder(w)
+	return func(exported *banners_usecase.ExportedBanner) error {
+		return encoder.Encode(ToExportBannerResponse(exported))
+	}
+}
+
+func writeExportNDJSONTrailer(w *bufio.Writer, trailer *ExportTrailerResponse) {
+	_ = json.NewEncoder(w).Encode(trailer)
+}
+
+// writeExportCSVTrailer строка-комментарий, csv ридер с Comment = '#' ее пропустит
+func writeExportCSVTrailer(w *bufio.Writer, trailer *ExportTrailerResponse) {
+	if trailer.ExportError != nil {
+		_, _ = fmt.Fprintf(w, "#export_error,rows=%d,%s\n", trailer.Rows, *trailer.ExportError)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "#export_end,rows=%d\n", trailer.Rows)
+}
+
+// writeExportCSV колонки совместимы с импортом, с версиями добавляется колонка record: banner или version,
+// версии идут сразу за своим баннером
+func writeExportCSV(w *bufio.Writer, withVersions bool) func(*banners_usecase.ExportedBanner) error {
+	writer := csv.NewWriter(w)
+	header := exportCSVHeader
+	if withVersions {
+		header = append(header[:len(header):len(header)], "record")
+	}
+	headerErr := writer.Write(header)
+	writer.Flush()
+	return func(exported *banners_usecase.ExportedBanner) error {
+		if headerErr != nil {
+			return headerErr
+		}
+		if err := writer.Write(toExportCSVRecord(&exported.Banner, "banner", withVersions)); err != nil {
+			return err
+		}
+		for i := range exported.Versions {
+			if err := writer.Write(toExportCSVRecord(&exported.Versions[i], "version", withVersions)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+}
+
+func toExportCSVRecord(banner *models.FullBanner, record string, withVersions bool) []string {
+	tagIds := make([]string, len(banner.TagIds))
+	for i, tagId := range banner.TagIds {
+		tagIds[i] = strconv.FormatInt(int64(tagId), 10)
+	}
+
+	row := []string{
+		strconv.FormatInt(int64(banner.BannerId), 10),
+		strings.Join(tagIds, ";"),
+		strconv.FormatInt(int64(banner.FeatureId), 10),
+		banner.Content.Title,
+		banner.Content.Text,
+		banner.Content.Url,
+		strconv.FormatBool(banner.IsActive),
+		banner.Status,
+		banner.CreatedAt.Format(time.RFC3339),
+		banner.UpdatedAt.Format(time.RFC3339),
+		strconv.FormatInt(banner.Version, 10),
+	}
+	if withVersions {
+		row = append(row, record)
+	}
+	return row
+}
@@ -2,6 +2,7 @@ package banners_http
 
 import (
 	"avito/assignment/config"
+	"avito/assignment/internal/banners/banners_usecase"
 	"avito/assignment/internal/models"
 	"avito/assignment/pkg/errlst"
 	"avito/assignment/pkg/traces"
@@ -11,14 +12,18 @@ import (
 )
 
 type BannersHandlers struct {
-	bannersUC BannersUseCase
-	cfg       *config.Config
+	bannersUC     BannersUseCase
+	bannersStream BannersStream
+	slugResolver  SlugResolver
+	cfg           *config.Config
 }
 
-func NewUserHandler(bannersUC BannersUseCase, cfg *config.Config) *BannersHandlers {
+func NewUserHandler(bannersUC BannersUseCase, bannersStream BannersStream, slugResolver SlugResolver, cfg *config.Config) *BannersHandlers {
 	return &BannersHandlers{
-		bannersUC: bannersUC,
-		cfg:       cfg,
+		bannersUC:     bannersUC,
+		bannersStream: bannersStream,
+		slugResolver:  slugResolver,
+		cfg:           cfg,
 	}
 }
 
@@ -30,40 +35,91 @@ func (b *BannersHandlers) GetBanner() fiber.Handler {
 		token := c.Locals("token").(string)
 
 		getBanner := GetBannerRequest{}
-		if err := reqvalidator.ReadRequest(c, &getBanner); err != nil {
+		if err := c.BodyParser(&getBanner); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetBanner.ReadRequest")
+		}
+		if err := getBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+		if err := reqvalidator.Validate(c, &getBanner); err != nil {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetBanner.ReadRequest")
 		}
 
 		getBannerDTO := getBanner.ToGetBanner()
 		getBannerDTO.AuthToken = token
+		getBannerDTO.Attributes = ReadAttributes(c)
 
 		bannerInfo, err := b.bannersUC.GetBanner(ctx, getBannerDTO)
 		if err != nil {
 			return err
 		}
 
+		c.Set(fiber.HeaderETag, bannerETag(bannerInfo.Version))
 		return c.JSON(ToGetBannerResponse(bannerInfo))
 	}
 }
 
+// GetManyBanner
+// 1. Фильтры берем из тела, а если передан курсор - из него, тогда тело не нужно и offset не применяется
+// 2. limit и with_total из query перекрывают тело и курсор
+// 3. Тело ответа - массив баннеров, курсоры соседних страниц и общее число уходят в заголовки
+// 4. С search к каждому баннеру добавляется ранг и подсвеченные совпадения
 func (b *BannersHandlers) GetManyBanner() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetManyBanner")
 		defer span.End()
 
+		query := GetManyBannerQuery{}
+		if err := reqvalidator.ReadQuery(c, &query); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetManyBanner.ReadQuery")
+		}
+
 		getManyBanner := GetManyBannerRequest{}
-		if err := reqvalidator.ReadRequest(c, &getManyBanner); err != nil {
+		if len(c.Body()) != 0 {
+			if err := c.BodyParser(&getManyBanner); err != nil {
+				return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetManyBanner.ReadRequest")
+			}
+		}
+		if query.Cursor != "" {
+			getManyBanner.Cursor = query.Cursor
+		}
+
+		var pageKey *banners_usecase.PageKey
+		if getManyBanner.Cursor != "" {
+			cursor, err := decodeBannersCursor(b.cfg.Pagination.CursorSecret, getManyBanner.Cursor)
+			if err != nil {
+				return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetManyBanner.InvalidCursor")
+			}
+			withTotal := getManyBanner.WithTotal
+			getManyBanner = cursor.Request
+			getManyBanner.WithTotal = withTotal
+			pageKey = &cursor.Key
+		} else if err := getManyBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+		if query.Limit != nil {
+			getManyBanner.Limit = query.Limit
+		}
+		getManyBanner.WithTotal = getManyBanner.WithTotal || query.WithTotal
+		if err := reqvalidator.Validate(c, &getManyBanner); err != nil {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetManyBanner.ReadRequest")
 		}
 
 		getManyBannerDTO := getManyBanner.ToGetManyBanner()
+		getManyBannerDTO.After = pageKey
 
-		manyBannerInfo, err := b.bannersUC.GetManyBanner(ctx, getManyBannerDTO)
+		page, err := b.bannersUC.GetBannersPage(ctx, getManyBannerDTO)
 		if err != nil {
 			return err
 		}
 
-		return c.JSON(ToGetManyBannerResponse(manyBannerInfo))
+		if err = setPageHeaders(c, b.cfg.Pagination.CursorSecret, getManyBanner, page); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersHandlers.GetManyBanner.SetPageHeaders")
+		}
+		if getManyBanner.Search != nil {
+			return c.JSON(ToSearchBannersResponse(page))
+		}
+		return c.JSON(ToGetManyBannerResponse(&page.Banners))
 	}
 }
 
@@ -73,7 +129,13 @@ func (b *BannersHandlers) AddBanner() fiber.Handler {
 		defer span.End()
 
 		addBanner := AddBannerRequest{}
-		if err := reqvalidator.ReadRequest(c, &addBanner); err != nil {
+		if err := c.BodyParser(&addBanner); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.AddBanner.ReadRequest")
+		}
+		if err := addBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+		if err := reqvalidator.Validate(c, &addBanner); err != nil {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.AddBanner.ReadRequest")
 		}
 		if len(addBanner.TagIds) == 0 {
@@ -94,8 +156,13 @@ func (b *BannersHandlers) AddBanner() fiber.Handler {
 	}
 }
 
+// PatchBanner обычный json с указательными полями, application/json-patch+json или application/merge-patch+json
 func (b *BannersHandlers) PatchBanner() fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if mediaType := patchMediaType(c); mediaType == jsonPatchMediaType || mediaType == mergePatchMediaType {
+			return b.patchBannerDocument(c, mediaType)
+		}
+
 		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.PatchBanner")
 		defer span.End()
 
@@ -103,18 +170,30 @@ func (b *BannersHandlers) PatchBanner() fiber.Handler {
 		if err := reqvalidator.ReadRequest(c, &patchBanner); err != nil {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.PatchBanner.ReadRequest")
 		}
+		if err := patchBanner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+		// тэги можно заменить, но не убрать совсем: баннер без тэгов не найти через /user_banner
+		if patchBanner.TagIds != nil && len(*patchBanner.TagIds) == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.PatchBanner.NilTagIds")
+		}
 		bannerId, err := strconv.Atoi(c.Params("banner_id"))
 		if err != nil {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.PatchBanner.WrongBannerParams")
 		}
 
 		patchBannerDTO := patchBanner.ToPatchBanner(models.BannerId(bannerId))
+		patchBannerDTO.ExpectedVersion, err = expectedVersion(c, patchBanner.ExpectedVersion)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.PatchBanner.WrongExpectedVersion")
+		}
 
-		err = b.bannersUC.PatchBanner(ctx, patchBannerDTO)
+		version, err := b.bannersUC.PatchBanner(ctx, patchBannerDTO)
 		if err != nil {
 			return err
 		}
 
+		c.Set(fiber.HeaderETag, bannerETag(version))
 		return c.JSON(fiber.Map{
 			"message": "Success",
 		})
@@ -131,7 +210,7 @@ func (b *BannersHandlers) DeleteBanner() fiber.Handler {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.DeleteBanner.WrongBannerParams")
 		}
 
-		err = b.bannersUC.DeleteBanner(ctx, models.BannerId(bannerId))
+		err = b.bannersUC.DeleteBanner(ctx, models.BannerId(bannerId), requestActor(c))
 		if err != nil {
 			return err
 		}
@@ -157,6 +236,8 @@ func (b *BannersHandlers) ViewVersions() fiber.Handler {
 		if err != nil {
 			return err
 		}
+		// первой идет текущая версия баннера
+		c.Set(fiber.HeaderETag, bannerETag((*banners)[0].Version))
 
 		return c.JSON(banners)
 	}
@@ -175,8 +256,18 @@ func (b *BannersHandlers) BannerRollback() fiber.Handler {
 		if err != nil {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.ViewVersions.WrongVersionParams")
 		}
+		bannerRollback := BannerRollbackRequest{}
+		if len(c.Body()) != 0 {
+			if err = c.BodyParser(&bannerRollback); err != nil {
+				return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.BannerRollback.ReadRequest")
+			}
+		}
+		bannerRollbackVersion, err := expectedVersion(c, bannerRollback.ExpectedVersion)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.BannerRollback.WrongExpectedVersion")
+		}
 
-		err = b.bannersUC.BannerRollback(ctx, models.BannerId(bannerId), int64(version))
+		err = b.bannersUC.BannerRollback(ctx, models.BannerId(bannerId), int64(version), bannerRollbackVersion)
 		if err != nil {
 			return err
 		}
@@ -186,3 +277,96 @@ func (b *BannersHandlers) BannerRollback() fiber.Handler {
 		})
 	}
 }
+
+func (b *BannersHandlers) GetChanges() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetChanges")
+		defer span.End()
+
+		getChanges := GetChangesRequest{}
+		if err := reqvalidator.ReadQuery(c, &getChanges); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetChanges.ReadQuery")
+		}
+
+		getChangesDTO, err := getChanges.ToGetChanges()
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetChanges.WrongCursor")
+		}
+
+		changes, nextCursor, err := b.bannersUC.GetChanges(ctx, getChangesDTO)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToGetChangesResponse(changes, nextCursor))
+	}
+}
+
+func (b *BannersHandlers) BulkDelete() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.BulkDelete")
+		defer span.End()
+
+		bulkDelete := BulkDeleteRequest{}
+		if err := reqvalidator.ReadRequest(c, &bulkDelete); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.BulkDelete.ReadRequest")
+		}
+		if err := bulkDelete.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+
+		bulkDeleteDTO := bulkDelete.ToBulkDelete()
+		bulkDeleteDTO.Actor = requestActor(c)
+		jobId, err := b.bannersUC.BulkDelete(ctx, bulkDeleteDTO)
+		if err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusAccepted)
+		return c.JSON(fiber.Map{
+			"job_id": jobId,
+		})
+	}
+}
+
+// BulkPatch патч всех баннеров под фильтром в одной транзакции, с dry_run только превью
+func (b *BannersHandlers) BulkPatch() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.BulkPatch")
+		defer span.End()
+
+		bulkPatch := BulkPatchRequest{}
+		if err := reqvalidator.ReadRequest(c, &bulkPatch); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.BulkPatch.ReadRequest")
+		}
+		if err := bulkPatch.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+			return err
+		}
+
+		result, err := b.bannersUC.BulkPatch(ctx, bulkPatch.ToBulkPatch())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToBulkPatchResponse(result))
+	}
+}
+
+func (b *BannersHandlers) GetBulkDeleteJob() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetBulkDeleteJob")
+		defer span.End()
+
+		jobId, err := strconv.Atoi(c.Params("job_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.GetBulkDeleteJob.WrongJobParams")
+		}
+
+		job, err := b.bannersUC.GetBulkDeleteJob(ctx, models.JobId(jobId))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToBulkDeleteJobResponse(job))
+	}
+}
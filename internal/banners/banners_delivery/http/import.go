@@ -0,0 +1,278 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	importFormatCSV    = "csv"
+	importFormatNDJSON = "ndjson"
+	maxImportRows      = 1000
+)
+
+// ImportBanners импорт баннеров из csv или ndjson, все или ничего
+// 1. Разбираем файл построчно, ошибки разбора, слагов и валидации копим по номеру строки
+// 2. Остальные проверки как в AddBanner делает юзкейс, включая конфликты внутри файла
+// 3. 200 - dry run без ошибок, 201 - все записано, 400 - есть ошибки, ничего не записано
+func (b *BannersHandlers) ImportBanners() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.ImportBanners")
+		defer span.End()
+
+		importRequest := ImportBannersRequest{}
+		if err := reqvalidator.ReadQuery(c, &importRequest); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.ImportBanners.ReadQuery")
+		}
+		format := importRequest.Format
+		if format == "" {
+			format = importFormatFromContentType(string(c.Request().Header.ContentType()))
+		}
+
+		var rows []ImportRowRequest
+		var err error
+		switch format {
+		case importFormatCSV:
+			rows, err = ParseImportCSV(bytes.NewReader(c.Body()))
+		case importFormatNDJSON:
+			rows, err = ParseImportNDJSON(bytes.NewReader(c.Body()))
+		default:
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("unknown import format %q, expected csv or ndjson", format), "BannersHandlers.ImportBanners.WrongFormat")
+		}
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.ImportBanners.ReadBody")
+		}
+		if len(rows) == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, errors.New("file has no rows"), "BannersHandlers.ImportBanners.EmptyFile")
+		}
+		if len(rows) > maxImportRows {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("file has %d rows, at most %d are allowed", len(rows), maxImportRows), "BannersHandlers.ImportBanners.TooManyRows")
+		}
+
+		importBanners := &banners_usecase.ImportBanners{DryRun: importRequest.DryRun}
+		for _, row := range rows {
+			importRow, err := b.toImportRow(ctx, c, span, row)
+			if err != nil {
+				return err
+			}
+			importBanners.Rows = append(importBanners.Rows, importRow)
+		}
+
+		result, err := b.bannersUC.ImportBanners(ctx, importBanners)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(result.Errors) != 0:
+			c.Status(fiber.StatusBadRequest)
+		case result.Applied:
+			c.Status(fiber.StatusCreated)
+		}
+		return c.JSON(ToImportResponse(result))
+	}
+}
+
+// toImportRow доводит строку до AddBanner тем же путем, что и AddBanner: слаги, валидация, непустые тэги.
+// Клиентские ошибки становятся ошибкой строки, остальные прерывают импорт
+func (b *BannersHandlers) toImportRow(ctx context.Context, c *fiber.Ctx, span trace.Span, row ImportRowRequest) (banners_usecase.ImportRow, error) {
+	importRow := banners_usecase.ImportRow{Line: row.Line, Err: row.Err}
+	if row.Err != nil {
+		return importRow, nil
+	}
+
+	if err := row.Banner.ResolveSlugs(ctx, span, b.slugResolver); err != nil {
+		var fiberErr *fiber.Error
+		if !errors.As(err, &fiberErr) || fiberErr.Code != fiber.StatusBadRequest {
+			return importRow, err
+		}
+		importRow.Err = errors.New(errorValue(err))
+		return importRow, nil
+	}
+	if err := reqvalidator.Validate(c, &row.Banner); err != nil {
+		importRow.Err = err
+		return importRow, nil
+	}
+	if len(row.Banner.TagIds) == 0 {
+		importRow.Err = errors.New("tag_ids is required")
+		return importRow, nil
+	}
+
+	importRow.Banner = row.Banner.ToAddBanner()
+	return importRow, nil
+}
+
+// errorValue достает текст ошибки из обертки SpanSetErrWrap, как это делает error_handler
+func errorValue(err error) string {
+	errorInfo := strings.Split(err.Error(), "|")
+	if len(errorInfo) == 1 {
+		return err.Error()
+	}
+	return errorInfo[1]
+}
+
+func importFormatFromContentType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(strings.ToLower(mediaType)) {
+	case "text/csv":
+		return importFormatCSV
+	case "application/x-ndjson", "application/jsonl":
+		return importFormatNDJSON
+	}
+	return ""
+}
+
+// ParseImportNDJSON одна строка - один объект в формате тела POST /banner, пустые строки и служебная запись выгрузки пропускаются
+func ParseImportNDJSON(r io.Reader) ([]ImportRowRequest, error) {
+	var rows []ImportRowRequest
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || isExportTrailer(text) {
+			continue
+		}
+		row := ImportRowRequest{Line: line}
+		if err := json.Unmarshal(text, &row.Banner); err != nil {
+			row.Err = fmt.Errorf("invalid json: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// isExportTrailer последняя запись ndjson выгрузки, так выгрузку можно импортировать как есть
+func isExportTrailer(text []byte) bool {
+	trailer := ExportTrailerResponse{}
+	return json.Unmarshal(text, &trailer) == nil && (trailer.ExportEnd || trailer.ExportError != nil)
+}
+
+// ParseImportCSV первая строка - заголовок, колонки tag_ids, feature_id, tags, feature, title, text, url, is_active, status
+// в любом порядке, списки тэгов разделяются ';'. Обязательны title, text, url и хотя бы одна колонка тэгов и фичи
+func ParseImportCSV(r io.Reader) ([]ImportRowRequest, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	// служебные строки выгрузки, см. ExportBanners
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"title", "text", "url"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv header has no %s column", name)
+		}
+	}
+	if !hasAnyColumn(columns, "tag_ids", "tags") || !hasAnyColumn(columns, "feature_id", "feature") {
+		return nil, errors.New("csv header must have tag_ids or tags and feature_id or feature columns")
+	}
+
+	var rows []ImportRowRequest
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, ImportRowRequest{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := ImportRowRequest{Line: line}
+		row.Banner, row.Err = parseCSVRecord(columns, record)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseCSVRecord(columns map[string]int, record []string) (AddBannerRequest, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	banner := AddBannerRequest{}
+	for _, part := range splitList(field("tag_ids")) {
+		tagId, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return banner, fmt.Errorf("invalid tag id %q", part)
+		}
+		banner.TagIds = append(banner.TagIds, models.TagId(tagId))
+	}
+	banner.Tags = splitList(field("tags"))
+	if value := field("feature_id"); value != "" {
+		featureId, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return banner, fmt.Errorf("invalid feature id %q", value)
+		}
+		banner.FeatureId = models.FeatureId(featureId)
+	}
+	banner.Feature = field("feature")
+	banner.Content.Title = field("title")
+	banner.Content.Text = field("text")
+	banner.Content.Url = field("url")
+	if value := field("is_active"); value != "" {
+		isActive, err := strconv.ParseBool(value)
+		if err != nil {
+			return banner, fmt.Errorf("invalid is_active %q", value)
+		}
+		banner.IsActive = isActive
+	}
+	banner.Status = field("status")
+	return banner, nil
+}
+
+func splitList(value string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func hasAnyColumn(columns map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := columns[name]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package banners_http
 
 import (
+	"avito/assignment/internal/banners/banners_stream"
 	"avito/assignment/internal/banners/banners_usecase"
 	"avito/assignment/internal/models"
 	"context"
@@ -12,17 +13,72 @@ type Handlers interface {
 	GetManyBanner() fiber.Handler
 	AddBanner() fiber.Handler
 	PatchBanner() fiber.Handler
+	CloneBanner() fiber.Handler
 	DeleteBanner() fiber.Handler
+	GetTrash() fiber.Handler
+	RestoreBanner() fiber.Handler
+	SaveDraft() fiber.Handler
+	GetDrafts() fiber.Handler
+	GetDraftDiff() fiber.Handler
+	ApproveDraft() fiber.Handler
+	RejectDraft() fiber.Handler
+	PublishDraft() fiber.Handler
 	ViewVersions() fiber.Handler
 	BannerRollback() fiber.Handler
+	StreamBanners() fiber.Handler
+	GetChanges() fiber.Handler
+	BulkDelete() fiber.Handler
+	GetBulkDeleteJob() fiber.Handler
+	BulkPatch() fiber.Handler
+	Batch() fiber.Handler
+	ImportBanners() fiber.Handler
+	ExportBanners() fiber.Handler
+	CreateSnapshot() fiber.Handler
+	GetSnapshots() fiber.Handler
+	GetSnapshot() fiber.Handler
+	DeleteSnapshot() fiber.Handler
+	RestoreSnapshot() fiber.Handler
 }
 
 type BannersUseCase interface {
 	GetBanner(ctx context.Context, getBannerParams *banners_usecase.GetBanner) (*models.FullBanner, error)
-	GetManyBanner(ctx context.Context, getManyBannerParams *banners_usecase.GetManyBanner) (*[]models.FullBanner, error)
+	GetBannersPage(ctx context.Context, getManyBannerParams *banners_usecase.GetManyBanner) (*banners_usecase.BannersPage, error)
 	AddBanner(ctx context.Context, addBannerParams *banners_usecase.AddBanner) (models.BannerId, error)
-	PatchBanner(ctx context.Context, patchBannerParams *banners_usecase.PatchBanner) error
-	DeleteBanner(ctx context.Context, bannerId models.BannerId) error
+	PatchBanner(ctx context.Context, patchBannerParams *banners_usecase.PatchBanner) (int64, error)
+	CloneBanner(ctx context.Context, cloneParams *banners_usecase.CloneBanner) (*banners_usecase.CloneResult, error)
+	DeleteBanner(ctx context.Context, bannerId models.BannerId, actor string) error
+	GetTrash(ctx context.Context, getTrashParams *banners_usecase.GetTrash) ([]models.TrashedBanner, error)
+	RestoreBanner(ctx context.Context, bannerId models.BannerId) error
+	SaveDraft(ctx context.Context, patchBannerParams *banners_usecase.PatchBanner, actor string) (models.DraftId, error)
+	GetDrafts(ctx context.Context, getDraftsParams *banners_usecase.GetDrafts) ([]models.BannerDraft, error)
+	GetDraftDiff(ctx context.Context, draftId models.DraftId) (*banners_usecase.DraftDiff, error)
+	ApproveDraft(ctx context.Context, draftId models.DraftId, actor string) error
+	RejectDraft(ctx context.Context, draftId models.DraftId, actor, comment string) error
+	PublishDraft(ctx context.Context, draftId models.DraftId, actor string) error
 	ViewVersions(ctx context.Context, bannerId models.BannerId) (*[]models.FullBanner, error)
-	BannerRollback(ctx context.Context, bannerId models.BannerId, version int64) error
+	BannerRollback(ctx context.Context, bannerId models.BannerId, version int64, expectedVersion *int64) error
+	PatchBannerDocument(ctx context.Context, bannerId models.BannerId, expectedVersion *int64, apply func(current *models.FullBanner) (*models.FullBanner, error)) (int64, error)
+	GetChanges(ctx context.Context, getChangesParams *banners_usecase.GetChanges) ([]models.ChangeLogEntry, int64, error)
+	PresentBanner(ctx context.Context, fullBanner *models.FullBanner, authToken string, attributes map[string]string) (*models.FullBanner, error)
+	BulkDelete(ctx context.Context, bulkDeleteParams *banners_usecase.BulkDelete) (models.JobId, error)
+	GetBulkDeleteJob(ctx context.Context, jobId models.JobId) (*models.BulkDeleteJob, error)
+	BulkPatch(ctx context.Context, bulkPatchParams *banners_usecase.BulkPatch) (*banners_usecase.BulkPatchResult, error)
+	Batch(ctx context.Context, operations []banners_usecase.BatchOperation, actor string) ([]banners_usecase.BatchOperationResult, error)
+	ImportBanners(ctx context.Context, importParams *banners_usecase.ImportBanners) (*banners_usecase.ImportResult, error)
+	ExportBanners(ctx context.Context, exportParams *banners_usecase.ExportBanners, write func(*banners_usecase.ExportedBanner) error) error
+	CreateSnapshot(ctx context.Context) (*models.SnapshotManifest, error)
+	GetSnapshots(ctx context.Context) ([]models.SnapshotManifest, error)
+	GetSnapshot(ctx context.Context, snapshotId string) (*models.SnapshotManifest, error)
+	DeleteSnapshot(ctx context.Context, snapshotId string) error
+	RestoreSnapshot(ctx context.Context, snapshotId string) (*models.SnapshotManifest, error)
+}
+
+type BannersStream interface {
+	Subscribe() *banners_stream.Subscriber
+	Unsubscribe(subscriber *banners_stream.Subscriber)
+	Replay(ctx context.Context, afterEventId string) ([]models.BannerChange, error)
+}
+
+type SlugResolver interface {
+	ResolveSlug(ctx context.Context, kind models.RegistryKind, slug string) (int64, error)
 }
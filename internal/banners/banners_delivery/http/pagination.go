@@ -0,0 +1,111 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerTotalCount = "X-Total-Count"
+	headerNextCursor = "X-Next-Cursor"
+	headerPrevCursor = "X-Prev-Cursor"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// bannersCursor содержимое непрозрачного курсора: фильтры, сортировка и лимит запроса вместе с позицией,
+// поэтому по курсору можно продолжить выдачу без тела запроса. Курсор подписан HMAC, клиент не может подменить в нем фильтры или ключ
+type bannersCursor struct {
+	Request GetManyBannerRequest    `json:"r"`
+	Key     banners_usecase.PageKey `json:"k"`
+}
+
+// encodeBannersCursor курсор - base64 json и через точку его подпись.
+// Слаги к этому моменту уже переведены в айди, поэтому в курсор не попадают
+func encodeBannersCursor(secret string, request GetManyBannerRequest, key *banners_usecase.PageKey) (string, error) {
+	request.Feature, request.Tag, request.Features, request.Tags = nil, nil, nil, nil
+	request.Offset, request.Cursor, request.WithTotal = nil, "", false
+
+	cursorBytes, err := json.Marshal(bannersCursor{Request: request, Key: *key})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(cursorBytes)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signCursor(secret, payload)), nil
+}
+
+// decodeBannersCursor сначала проверяет подпись, потом строго разбирает json: лишние поля и мусор после него - ошибка
+func decodeBannersCursor(secret, cursor string) (*bannersCursor, error) {
+	payload, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	signatureBytes, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(signatureBytes, signCursor(secret, payload)) {
+		return nil, errInvalidCursor
+	}
+	cursorBytes, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	decoded := &bannersCursor{}
+	decoder := json.NewDecoder(bytes.NewReader(cursorBytes))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(decoded); err != nil || decoder.More() {
+		return nil, errInvalidCursor
+	}
+	return decoded, nil
+}
+
+func signCursor(secret, payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// setPageHeaders X-Total-Count, курсоры соседних страниц и Link на них, тело ответа остается массивом баннеров
+func setPageHeaders(c *fiber.Ctx, secret string, request GetManyBannerRequest, page *banners_usecase.BannersPage) error {
+	if page.Total != nil {
+		c.Set(headerTotalCount, strconv.FormatInt(*page.Total, 10))
+	}
+
+	var links []string
+	for _, neighbour := range []struct {
+		rel    string
+		header string
+		key    *banners_usecase.PageKey
+	}{
+		{"next", headerNextCursor, page.Next},
+		{"prev", headerPrevCursor, page.Prev},
+	} {
+		if neighbour.key == nil {
+			continue
+		}
+		cursor, err := encodeBannersCursor(secret, request, neighbour.key)
+		if err != nil {
+			return err
+		}
+		c.Set(neighbour.header, cursor)
+
+		query := url.Values{"cursor": {cursor}}
+		if request.WithTotal {
+			query.Set("with_total", "true")
+		}
+		links = append(links, fmt.Sprintf(`<%s%s?%s>; rel="%s"`, c.BaseURL(), c.Path(), query.Encode(), neighbour.rel))
+	}
+	if len(links) != 0 {
+		c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+	}
+	return nil
+}
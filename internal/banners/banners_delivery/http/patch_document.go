@@ -0,0 +1,156 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/jsonpatch"
+	"avito/assignment/pkg/traces"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/trace"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	jsonPatchMediaType  = "application/json-patch+json"
+	mergePatchMediaType = "application/merge-patch+json"
+)
+
+// BannerDocument баннер в том виде, к которому применяются json patch и merge patch.
+// version только для чтения, ее удобно проверять операцией test
+type BannerDocument struct {
+	TagIds    []models.TagId   `json:"tag_ids"`
+	FeatureId models.FeatureId `json:"feature_id"`
+	Content   struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+		Url   string `json:"url"`
+	} `json:"content"`
+	IsActive bool   `json:"is_active"`
+	Status   string `json:"status"`
+	Version  int64  `json:"version"`
+}
+
+func ToBannerDocument(banner *models.FullBanner) *BannerDocument {
+	document := &BannerDocument{
+		TagIds:    banner.TagIds,
+		FeatureId: banner.FeatureId,
+		IsActive:  banner.IsActive,
+		Status:    banner.Status,
+		Version:   banner.Version,
+	}
+	if document.TagIds == nil {
+		document.TagIds = []models.TagId{}
+	}
+	document.Content.Title = banner.Content.Title
+	document.Content.Text = banner.Content.Text
+	document.Content.Url = banner.Content.Url
+	return document
+}
+
+// ToFullBanner проверяет документ после патча так же строго, как AddBannerRequest
+func (d *BannerDocument) ToFullBanner(current *models.FullBanner) (*models.FullBanner, error) {
+	if d.Version != current.Version {
+		return nil, errors.New("version is read-only")
+	}
+	if len(d.TagIds) == 0 {
+		return nil, errors.New("tag_ids must not be empty")
+	}
+	if d.FeatureId == 0 {
+		return nil, errors.New("feature_id is required")
+	}
+	if d.Content.Title == "" || d.Content.Text == "" || d.Content.Url == "" {
+		return nil, errors.New("content.title, content.text and content.url are required")
+	}
+
+	banner := *current
+	banner.TagIds = d.TagIds
+	banner.FeatureId = d.FeatureId
+	banner.Content.Title = d.Content.Title
+	banner.Content.Text = d.Content.Text
+	banner.Content.Url = d.Content.Url
+	// старые клиенты меняют только is_active, статус тогда выводится из него
+	switch {
+	case d.Status != current.Status:
+		if !slices.Contains(models.BannerStatuses, d.Status) {
+			return nil, fmt.Errorf("unknown status %q", d.Status)
+		}
+		if d.IsActive != current.IsActive && d.IsActive != (d.Status == models.BannerStatusActive) {
+			return nil, fmt.Errorf("is_active %t contradicts status %s", d.IsActive, d.Status)
+		}
+		banner.Status = d.Status
+	case d.IsActive != current.IsActive:
+		banner.Status = models.StatusFromIsActive(current.Status, d.IsActive)
+	}
+	banner.IsActive = banner.Status == models.BannerStatusActive
+	return &banner, nil
+}
+
+func patchMediaType(c *fiber.Ctx) string {
+	mediaType, _, _ := strings.Cut(c.Get(fiber.HeaderContentType), ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// patchBannerDocument PATCH /banner/:banner_id с json patch или merge patch
+// 1. Патч накладывается на текущий баннер внутри транзакции юзкейса, поэтому add/remove по тэгам не требуют присылать весь список
+// 2. Результат разбирается строго: неизвестные поля и пустые обязательные - 400
+// 3. Дальше тот же путь, что у обычного патча, включая If-Match и проверку конфликтов
+func (b *BannersHandlers) patchBannerDocument(c *fiber.Ctx, mediaType string) error {
+	ctx, span := traces.StartFiberTrace(c, "BannersHandlers.PatchBannerDocument")
+	defer span.End()
+
+	bannerId, err := strconv.Atoi(c.Params("banner_id"))
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.PatchBanner.WrongBannerParams")
+	}
+	expectedBannerVersion, err := expectedVersion(c, nil)
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.PatchBanner.WrongExpectedVersion")
+	}
+
+	patch := c.Body()
+	version, err := b.bannersUC.PatchBannerDocument(ctx, models.BannerId(bannerId), expectedBannerVersion, func(current *models.FullBanner) (*models.FullBanner, error) {
+		return applyBannerPatch(span, mediaType, current, patch)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderETag, bannerETag(version))
+	return c.JSON(fiber.Map{
+		"message": "Success",
+	})
+}
+
+func applyBannerPatch(span trace.Span, mediaType string, current *models.FullBanner, patch []byte) (*models.FullBanner, error) {
+	document, err := json.Marshal(ToBannerDocument(current))
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersHandlers.PatchBanner.Marshal")
+	}
+
+	if mediaType == jsonPatchMediaType {
+		document, err = jsonpatch.Apply(document, patch)
+	} else {
+		document, err = jsonpatch.MergePatch(document, patch)
+	}
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.PatchBanner.InvalidPatch")
+	}
+
+	patched := &BannerDocument{}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(patched); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.PatchBanner.InvalidPatch")
+	}
+	banner, err := patched.ToFullBanner(current)
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.PatchBanner.InvalidPatch")
+	}
+	return banner, nil
+}
@@ -9,9 +9,31 @@ import (
 func MapBannersRoutes(group fiber.Router, h Handlers, mw *middleware.MDWManager) {
 	group.Get("/user_banner", mw.CheckAuthToken(constant.AllRoles), h.GetBanner())
 	group.Get("/banner", mw.CheckAuthToken(constant.AdminRoles), h.GetManyBanner())
-	group.Post("/banner", mw.CheckAuthToken(constant.AdminRoles), h.AddBanner())
-	group.Patch("/banner/:banner_id", mw.CheckAuthToken(constant.AdminRoles), h.PatchBanner())
-	group.Delete("/banner/:banner_id", mw.CheckAuthToken(constant.AdminRoles), h.DeleteBanner())
+	group.Post("/banner", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.AddBanner())
+	group.Get("/banner/export", mw.CheckAuthToken(constant.AdminRoles), h.ExportBanners())
+	group.Post("/banner/import", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.ImportBanners())
+	group.Post("/banner/bulk_delete", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.BulkDelete())
+	group.Get("/banner/bulk_delete/:job_id", mw.CheckAuthToken(constant.AdminRoles), h.GetBulkDeleteJob())
+	group.Post("/banner/bulk_patch", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.BulkPatch())
+	group.Post("/banner/batch", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.Batch())
+	group.Get("/banner/trash", mw.CheckAuthToken(constant.AdminRoles), h.GetTrash())
+	group.Get("/banner/drafts", mw.CheckAuthToken(constant.AdminRoles), h.GetDrafts())
+	group.Get("/banner/drafts/:draft_id/diff", mw.CheckAuthToken(constant.AdminRoles), h.GetDraftDiff())
+	group.Post("/banner/drafts/:draft_id/approve", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.ApproveDraft())
+	group.Post("/banner/drafts/:draft_id/reject", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.RejectDraft())
+	group.Post("/banner/drafts/:draft_id/publish", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.PublishDraft())
+	group.Patch("/banner/:banner_id", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.PatchBanner())
+	group.Delete("/banner/:banner_id", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.DeleteBanner())
+	group.Post("/banner/:banner_id/clone", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.CloneBanner())
+	group.Post("/banner/:banner_id/restore", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.RestoreBanner())
+	group.Put("/banner/:banner_id/draft", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.SaveDraft())
 	group.Get("/banner_versions/:banner_id", mw.CheckAuthToken(constant.AdminRoles), h.ViewVersions())
-	group.Put("/banner_rollback/:banner_id/:version", mw.CheckAuthToken(constant.AdminRoles), h.BannerRollback())
+	group.Put("/banner_rollback/:banner_id/:version", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.BannerRollback())
+	group.Post("/snapshots", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.CreateSnapshot())
+	group.Get("/snapshots", mw.CheckAuthToken(constant.AdminRoles), h.GetSnapshots())
+	group.Get("/snapshots/:snapshot_id", mw.CheckAuthToken(constant.AdminRoles), h.GetSnapshot())
+	group.Delete("/snapshots/:snapshot_id", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.DeleteSnapshot())
+	group.Post("/snapshots/:snapshot_id/restore", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.RestoreSnapshot())
+	group.Get("/changes", mw.CheckAuthToken(constant.AdminRoles), h.GetChanges())
+	group.Get("/user_banner/stream", mw.CheckAuthToken(constant.AllRoles), h.StreamBanners())
 }
@@ -0,0 +1,149 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resolveSlug подставляет айди по слагу, если передан и айди, и слаг, они должны указывать на одно и то же
+func resolveSlug(ctx context.Context, span trace.Span, resolver SlugResolver, kind models.RegistryKind, slug string, id *int64) error {
+	if slug == "" {
+		return nil
+	}
+
+	resolvedId, err := resolver.ResolveSlug(ctx, kind, slug)
+	if err != nil {
+		return err
+	}
+	if *id != 0 && *id != resolvedId {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			fmt.Errorf("%s slug %s points to id %d, but id %d was passed", kind.Singular(), slug, resolvedId, *id), "BannersHandlers.ResolveSlug.Mismatch")
+	}
+
+	*id = resolvedId
+	return nil
+}
+
+func resolveTagSlugs(ctx context.Context, resolver SlugResolver, slugs []string) ([]models.TagId, error) {
+	tagIds := make([]models.TagId, 0, len(slugs))
+	for _, slug := range slugs {
+		tagId, err := resolver.ResolveSlug(ctx, models.TagsRegistry, slug)
+		if err != nil {
+			return nil, err
+		}
+		tagIds = append(tagIds, models.TagId(tagId))
+	}
+	return tagIds, nil
+}
+
+func (b *GetBannerRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	if err := resolveSlug(ctx, span, resolver, models.TagsRegistry, b.Tag, (*int64)(&b.TagId)); err != nil {
+		return err
+	}
+	return resolveSlug(ctx, span, resolver, models.FeaturesRegistry, b.Feature, (*int64)(&b.FeatureId))
+}
+
+func (b *GetManyBannerRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	tagIds, err := resolveTagSlugs(ctx, resolver, b.Tags)
+	if err != nil {
+		return err
+	}
+	b.TagIds = append(b.TagIds, tagIds...)
+	for _, slug := range b.Features {
+		featureId, err := resolver.ResolveSlug(ctx, models.FeaturesRegistry, slug)
+		if err != nil {
+			return err
+		}
+		b.FeatureIds = append(b.FeatureIds, models.FeatureId(featureId))
+	}
+
+	if b.Tag != nil {
+		if b.TagId == nil {
+			b.TagId = new(models.TagId)
+		}
+		if err := resolveSlug(ctx, span, resolver, models.TagsRegistry, *b.Tag, (*int64)(b.TagId)); err != nil {
+			return err
+		}
+	}
+	if b.Feature != nil {
+		if b.FeatureId == nil {
+			b.FeatureId = new(models.FeatureId)
+		}
+		if err := resolveSlug(ctx, span, resolver, models.FeaturesRegistry, *b.Feature, (*int64)(b.FeatureId)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveSlugs тэги по слагам добавляются к переданным айдишникам
+func (b *AddBannerRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	tagIds, err := resolveTagSlugs(ctx, resolver, b.Tags)
+	if err != nil {
+		return err
+	}
+	b.TagIds = append(b.TagIds, tagIds...)
+
+	return resolveSlug(ctx, span, resolver, models.FeaturesRegistry, b.Feature, (*int64)(&b.FeatureId))
+}
+
+func (b *PatchBannerRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	if b.Tags != nil {
+		tagIds, err := resolveTagSlugs(ctx, resolver, *b.Tags)
+		if err != nil {
+			return err
+		}
+		if b.TagIds == nil {
+			b.TagIds = &[]models.TagId{}
+		}
+		*b.TagIds = append(*b.TagIds, tagIds...)
+	}
+	if b.Feature != nil {
+		if b.FeatureId == nil {
+			b.FeatureId = new(models.FeatureId)
+		}
+		return resolveSlug(ctx, span, resolver, models.FeaturesRegistry, *b.Feature, (*int64)(b.FeatureId))
+	}
+	return nil
+}
+
+func (b *BulkDeleteRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	filter := GetManyBannerRequest{FeatureId: b.FeatureId, TagId: b.TagId, Feature: b.Feature, Tag: b.Tag}
+	if err := filter.ResolveSlugs(ctx, span, resolver); err != nil {
+		return err
+	}
+	b.FeatureId, b.TagId = filter.FeatureId, filter.TagId
+	return nil
+}
+
+func (b *BulkPatchRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	filter := GetManyBannerRequest{FeatureId: b.FeatureId, TagId: b.TagId, Feature: b.Feature, Tag: b.Tag}
+	if err := filter.ResolveSlugs(ctx, span, resolver); err != nil {
+		return err
+	}
+	b.FeatureId, b.TagId = filter.FeatureId, filter.TagId
+	return nil
+}
+
+func (b *ExportBannersRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	filter := GetManyBannerRequest{FeatureId: b.FeatureId, TagId: b.TagId, Feature: b.Feature, Tag: b.Tag,
+		FeatureIds: b.FeatureIds, TagIds: b.TagIds, Features: b.Features, Tags: b.Tags}
+	if err := filter.ResolveSlugs(ctx, span, resolver); err != nil {
+		return err
+	}
+	b.FeatureId, b.TagId, b.FeatureIds, b.TagIds = filter.FeatureId, filter.TagId, filter.FeatureIds, filter.TagIds
+	return nil
+}
+
+func (b *CloneBannerRequest) ResolveSlugs(ctx context.Context, span trace.Span, resolver SlugResolver) error {
+	patch := PatchBannerRequest{TagIds: b.TagIds, FeatureId: b.FeatureId, Tags: b.Tags, Feature: b.Feature}
+	if err := patch.ResolveSlugs(ctx, span, resolver); err != nil {
+		return err
+	}
+	b.TagIds, b.FeatureId = patch.TagIds, patch.FeatureId
+	return nil
+}
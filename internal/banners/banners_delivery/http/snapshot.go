@@ -0,0 +1,80 @@
+package banners_http
+
+import (
+	"avito/assignment/pkg/traces"
+	"github.com/gofiber/fiber/v2"
+)
+
+func (b *BannersHandlers) CreateSnapshot() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.CreateSnapshot")
+		defer span.End()
+
+		manifest, err := b.bannersUC.CreateSnapshot(ctx)
+		if err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusCreated)
+		return c.JSON(ToSnapshotResponse(manifest))
+	}
+}
+
+func (b *BannersHandlers) GetSnapshots() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetSnapshots")
+		defer span.End()
+
+		manifests, err := b.bannersUC.GetSnapshots(ctx)
+		if err != nil {
+			return err
+		}
+
+		response := make([]*SnapshotResponse, len(manifests))
+		for i := range manifests {
+			response[i] = ToSnapshotResponse(&manifests[i])
+		}
+		return c.JSON(response)
+	}
+}
+
+func (b *BannersHandlers) GetSnapshot() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetSnapshot")
+		defer span.End()
+
+		manifest, err := b.bannersUC.GetSnapshot(ctx, c.Params("snapshot_id"))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToSnapshotResponse(manifest))
+	}
+}
+
+func (b *BannersHandlers) DeleteSnapshot() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.DeleteSnapshot")
+		defer span.End()
+
+		if err := b.bannersUC.DeleteSnapshot(ctx, c.Params("snapshot_id")); err != nil {
+			return err
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+func (b *BannersHandlers) RestoreSnapshot() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.RestoreSnapshot")
+		defer span.End()
+
+		manifest, err := b.bannersUC.RestoreSnapshot(ctx, c.Params("snapshot_id"))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToSnapshotResponse(manifest))
+	}
+}
@@ -0,0 +1,148 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/banners/banners_stream"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// StreamBanners sse стрим изменений баннеров по подписанным парам (tag_id, feature_id) или целым тэгам
+// 1. Подписываемся на хаб реплики до чтения истории, чтобы не потерять события между ними
+// 2. Если пришел Last-Event-ID, проверяем его формат и досылаем пропущенное из redis stream
+// 3. Дальше шлем живые события и heartbeat, пока клиент не отвалится
+func (b *BannersHandlers) StreamBanners() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.StreamBanners")
+		defer span.End()
+
+		token := c.Locals("token").(string)
+
+		streamBanners := StreamBannersRequest{}
+		if err := reqvalidator.ReadQuery(c, &streamBanners); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.StreamBanners.ReadQuery")
+		}
+		subscription, err := streamBanners.ToSubscription()
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.StreamBanners.WrongSubscription")
+		}
+		if subscription.Empty() {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.StreamBanners.EmptySubscription")
+		}
+		attributes := ReadAttributes(c)
+
+		lastEventId := c.Get("Last-Event-ID")
+		if lastEventId != "" && !banners_stream.ValidEventId(lastEventId) {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("Last-Event-ID %q is not a stream id", lastEventId), "BannersHandlers.StreamBanners.WrongLastEventId")
+		}
+
+		subscriber := b.bannersStream.Subscribe()
+		if subscriber == nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrServiceUnavailable, nil, "BannersHandlers.StreamBanners.HubClosed")
+		}
+
+		var backlog []models.BannerChange
+		if lastEventId != "" {
+			backlog, err = b.bannersStream.Replay(ctx, lastEventId)
+			if err != nil {
+				b.bannersStream.Unsubscribe(subscriber)
+				return err
+			}
+		}
+
+		// запрос fiber заканчивается раньше стрима, поэтому дальше живем на отдельном контексте с тем же трейсом
+		streamCtx := trace.ContextWithSpanContext(context.Background(), span.SpanContext())
+		heartbeat := time.Duration(b.cfg.Stream.HeartbeatSeconds) * time.Second
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+			defer b.bannersStream.Unsubscribe(subscriber)
+
+			ticker := time.NewTicker(heartbeat)
+			defer ticker.Stop()
+
+			send := func(change *models.BannerChange) error {
+				if !banners_stream.IsAfter(change.EventId, lastEventId) {
+					return nil
+				}
+				for _, update := range subscription.Updates(change) {
+					event, err := b.toStreamEvent(streamCtx, &update, token, attributes)
+					if err != nil {
+						return err
+					}
+					if _, err = fmt.Fprintf(w, "id: %s\nevent: banner\ndata: %s\n\n", change.EventId, event); err != nil {
+						return err
+					}
+				}
+				lastEventId = change.EventId
+				return w.Flush()
+			}
+
+			if _, err := fmt.Fprintf(w, "retry: %d\n\n", heartbeat.Milliseconds()); err != nil {
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+			for i := range backlog {
+				if err := send(&backlog[i]); err != nil {
+					return
+				}
+			}
+
+			for {
+				select {
+				case change, ok := <-subscriber.Changes:
+					if !ok {
+						return
+					}
+					if err := send(&change); err != nil {
+						return
+					}
+				case <-ticker.C:
+					if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+						return
+					}
+					if err := w.Flush(); err != nil {
+						return
+					}
+				}
+			}
+		}))
+
+		return nil
+	}
+}
+
+func (b *BannersHandlers) toStreamEvent(ctx context.Context, update *banners_stream.Update, token string, attributes map[string]string) ([]byte, error) {
+	event := StreamBannerEvent{
+		TagId:     update.TagId,
+		FeatureId: update.FeatureId,
+		Deleted:   true,
+	}
+
+	if update.Banner != nil {
+		banner, err := b.bannersUC.PresentBanner(ctx, update.Banner, token, attributes)
+		if err != nil {
+			return nil, err
+		}
+		if banner != nil {
+			event.Deleted = false
+			event.Content = ToGetBannerResponse(banner)
+		}
+	}
+
+	return json.Marshal(event)
+}
@@ -0,0 +1,103 @@
+package banners_http
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/middleware"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTrashLimit = 100
+	maxTrashLimit     = 1000
+)
+
+// requestActor кто удаляет баннеры: имя админа по его персональному токену, по общему токену - роль
+func requestActor(c *fiber.Ctx) string {
+	if actor := middleware.Actor(c); actor != "" {
+		return actor
+	}
+	token, _ := c.Locals("token").(string)
+	return token
+}
+
+type GetTrashRequest struct {
+	Limit  int `query:"limit" validate:"gte=0"`
+	Offset int `query:"offset" validate:"gte=0"`
+}
+
+func (t *GetTrashRequest) ToGetTrash() *banners_usecase.GetTrash {
+	getTrash := &banners_usecase.GetTrash{Limit: t.Limit, Offset: t.Offset}
+	if getTrash.Limit == 0 {
+		getTrash.Limit = defaultTrashLimit
+	}
+	if getTrash.Limit > maxTrashLimit {
+		getTrash.Limit = maxTrashLimit
+	}
+	return getTrash
+}
+
+type TrashedBannerResponse struct {
+	GetManyBannerResponse
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+}
+
+func ToTrashResponse(banners []models.TrashedBanner) []TrashedBannerResponse {
+	response := make([]TrashedBannerResponse, len(banners))
+	for i, banner := range banners {
+		response[i] = TrashedBannerResponse{
+			GetManyBannerResponse: GetManyBannerResponse(banner.FullBanner),
+			DeletedAt:             banner.DeletedAt,
+			DeletedBy:             banner.DeletedBy,
+		}
+	}
+	return response
+}
+
+// GetTrash баннеры в корзине, сначала недавно удаленные
+func (b *BannersHandlers) GetTrash() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.GetTrash")
+		defer span.End()
+
+		getTrash := GetTrashRequest{}
+		if err := reqvalidator.ReadQuery(c, &getTrash); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersHandlers.GetTrash.ReadQuery")
+		}
+
+		banners, err := b.bannersUC.GetTrash(ctx, getTrash.ToGetTrash())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToTrashResponse(banners))
+	}
+}
+
+// RestoreBanner возвращает баннер из корзины, если его пары тэг-фича никто не занял
+func (b *BannersHandlers) RestoreBanner() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "BannersHandlers.RestoreBanner")
+		defer span.End()
+
+		bannerId, err := strconv.Atoi(c.Params("banner_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "BannersHandlers.RestoreBanner.WrongBannerParams")
+		}
+
+		err = b.bannersUC.RestoreBanner(ctx, models.BannerId(bannerId))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package banners_repository
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel"
+	"time"
+)
+
+// AddDraft заводит открытый черновик баннера. Если параллельный запрос успел завести свой, вернет 0
+func (b *BannersRepo) AddDraft(ctx context.Context, draft *models.BannerDraft) (models.DraftId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.AddDraft")
+	defer span.End()
+
+	query, args, err := sq.Insert(sql_queries.BannerDraftsTableName).
+		Columns(sql_queries.InsertDraftColumns...).
+		Values(
+			draft.BannerId,
+			draft.BaseVersion,
+			draft.Banner.FeatureId,
+			toTagIdsArray(draft.Banner.TagIds),
+			draft.Banner.Content.Title,
+			draft.Banner.Content.Text,
+			draft.Banner.Content.Url,
+			draft.Banner.IsActive,
+			draft.Banner.Status,
+			draft.Status,
+			draft.Author,
+			time.Now(),
+			time.Now(),
+		).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s) WHERE %s IN ('%s', '%s') DO NOTHING RETURNING draft_id",
+			sql_queries.BannerIdColumnName, sql_queries.StatusColumnName, models.DraftPending, models.DraftApproved)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.AddDraft.Insert")
+	}
+
+	var draftId models.DraftId
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.QueryRowContext(ctx, query, args...).Scan(&draftId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.AddDraft.QueryRowContext")
+	}
+
+	return draftId, nil
+}
+
+// UpdateDraftContent новое содержимое открытого черновика поверх текущей версии живого баннера,
+// прошлое одобрение после правки не действует
+func (b *BannersRepo) UpdateDraftContent(ctx context.Context, draft *models.BannerDraft) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.UpdateDraftContent")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.BannerDraftsTableName).
+		Set(sql_queries.BaseVersionColumnName, draft.BaseVersion).
+		Set(sql_queries.FeatureIdColumnName, draft.Banner.FeatureId).
+		Set(sql_queries.TagIdsColumnName, toTagIdsArray(draft.Banner.TagIds)).
+		Set(sql_queries.TitleColumnName, draft.Banner.Content.Title).
+		Set(sql_queries.TextColumnName, draft.Banner.Content.Text).
+		Set(sql_queries.UrlColumnName, draft.Banner.Content.Url).
+		Set(sql_queries.IsActiveColumnName, draft.Banner.IsActive).
+		Set(sql_queries.BannerStatusColumnName, draft.Banner.Status).
+		Set(sql_queries.StatusColumnName, models.DraftPending).
+		Set(sql_queries.AuthorColumnName, draft.Author).
+		Set(sql_queries.ReviewedByColumnName, nil).
+		Set(sql_queries.ReviewedAtColumnName, nil).
+		Set(sql_queries.CommentColumnName, "").
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(sq.Eq{sql_queries.DraftIdColumnName: draft.DraftId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.UpdateDraftContent.Update")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.UpdateDraftContent.ExecContext")
+	}
+
+	return nil
+}
+
+// ReviewDraft одобрение или отклонение черновика
+func (b *BannersRepo) ReviewDraft(ctx context.Context, draftId models.DraftId, status, reviewer, comment string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.ReviewDraft")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.BannerDraftsTableName).
+		Set(sql_queries.StatusColumnName, status).
+		Set(sql_queries.ReviewedByColumnName, reviewer).
+		Set(sql_queries.ReviewedAtColumnName, time.Now()).
+		Set(sql_queries.CommentColumnName, comment).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(sq.Eq{sql_queries.DraftIdColumnName: draftId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReviewDraft.Update")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReviewDraft.ExecContext")
+	}
+
+	return nil
+}
+
+func (b *BannersRepo) MarkDraftPublished(ctx context.Context, draftId models.DraftId, publisher string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.MarkDraftPublished")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.BannerDraftsTableName).
+		Set(sql_queries.StatusColumnName, models.DraftPublished).
+		Set(sql_queries.PublishedByColumnName, publisher).
+		Set(sql_queries.PublishedAtColumnName, time.Now()).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(sq.Eq{sql_queries.DraftIdColumnName: draftId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.MarkDraftPublished.Update")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.MarkDraftPublished.ExecContext")
+	}
+
+	return nil
+}
+
+// GetDraft черновик под блокировкой до конца транзакции, nil - черновика нет
+func (b *BannersRepo) GetDraft(ctx context.Context, draftId models.DraftId) (*models.BannerDraft, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetDraft")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SelectDraftColumns...).
+		From(fmt.Sprintf("%s d", sql_queries.BannerDraftsTableName)).
+		Where(sq.Eq{"d." + sql_queries.DraftIdColumnName: draftId}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetDraft.Select")
+	}
+
+	var drafts []Draft
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &drafts, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetDraft.SelectContext")
+	}
+	if len(drafts) == 0 {
+		return nil, nil
+	}
+
+	draft := drafts[0].ToBannerDraft()
+	return &draft, nil
+}
+
+// GetOpenDraft открытый черновик баннера под блокировкой до конца транзакции, nil - черновика нет
+func (b *BannersRepo) GetOpenDraft(ctx context.Context, bannerId models.BannerId) (*models.BannerDraft, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetOpenDraft")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SelectDraftColumns...).
+		From(fmt.Sprintf("%s d", sql_queries.BannerDraftsTableName)).
+		Where(sq.Eq{"d." + sql_queries.BannerIdColumnName: bannerId}).
+		Where(sq.Eq{"d." + sql_queries.StatusColumnName: []string{models.DraftPending, models.DraftApproved}}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetOpenDraft.Select")
+	}
+
+	var drafts []Draft
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &drafts, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetOpenDraft.SelectContext")
+	}
+	if len(drafts) == 0 {
+		return nil, nil
+	}
+
+	draft := drafts[0].ToBannerDraft()
+	return &draft, nil
+}
+
+// GetDrafts черновики живых баннеров в статусе Status, сначала старые - их раньше начали ждать
+func (b *BannersRepo) GetDrafts(ctx context.Context, getDraftsParams *GetDrafts) ([]models.BannerDraft, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetDrafts")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SelectDraftColumns...).
+		From(fmt.Sprintf("%s d", sql_queries.BannerDraftsTableName)).
+		InnerJoin(fmt.Sprintf("%s b ON b.banner_id = d.banner_id", sql_queries.BannersTableName)).
+		Where(sq.Eq{"d." + sql_queries.StatusColumnName: getDraftsParams.Status}).
+		Where(notTrashed).
+		OrderBy("d.draft_id").
+		Limit(uint64(getDraftsParams.Limit)).
+		Offset(uint64(getDraftsParams.Offset)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetDrafts.Select")
+	}
+
+	var rows []Draft
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetDrafts.SelectContext")
+	}
+
+	drafts := make([]models.BannerDraft, len(rows))
+	for i := range rows {
+		drafts[i] = rows[i].ToBannerDraft()
+	}
+	return drafts, nil
+}
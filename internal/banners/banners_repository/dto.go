@@ -2,16 +2,29 @@ package banners_repository
 
 import (
 	"avito/assignment/internal/models"
+	"github.com/lib/pq"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type GetManyPostgresBanner struct {
-	TagId     *models.TagId
-	FeatureId *models.FeatureId
-	Limit     *int
-	Offset    *int
+	BannersFilter
+	Limit  *int
+	Offset *int
+	// SortBy одна из колонок SortColumns, по умолчанию banner_id, при равенстве порядок добирается по banner_id
+	SortBy   string
+	SortDesc bool
+	// After если задан, выдача начинается строго после этой позиции, Offset при этом не применяется
+	After *Keyset
+}
+
+// Keyset позиция в выдаче: значение ключа сортировки в виде SortValue и banner_id.
+// Backward - идти от позиции назад, строки тогда приходят в обратном порядке
+type Keyset struct {
+	SortValue string
+	BannerId  models.BannerId
+	Backward  bool
 }
 
 type AddPostgresBanner struct {
@@ -20,6 +33,7 @@ type AddPostgresBanner struct {
 	Text      string
 	Url       string
 	IsActive  bool
+	Status    string
 }
 
 type GetInsertParams struct {
@@ -33,6 +47,13 @@ type ExistBanner struct {
 	FeatureId models.FeatureId `db:"feature_id"`
 }
 
+// PairConflict пара (тэг, фича), которую делят несколько баннеров
+type PairConflict struct {
+	TagId     models.TagId     `db:"tag_id"`
+	FeatureId models.FeatureId `db:"feature_id"`
+	BannerIds pq.Int64Array    `db:"banner_ids"`
+}
+
 type PutRedisBanner struct {
 	BannerId  models.BannerId
 	TagIds    []models.TagId
@@ -43,8 +64,10 @@ type PutRedisBanner struct {
 		Url   string
 	}
 	IsActive  bool
+	Status    string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int64
 }
 
 type GetRedisBanner struct {
@@ -58,6 +81,7 @@ type UpdateBannerById struct {
 	Text      *string
 	Url       *string
 	IsActive  *bool
+	Status    *string
 	BannerId  models.BannerId
 	Version   int64
 }
@@ -70,6 +94,7 @@ type FullBanner struct {
 	Text      string           `db:"text"`
 	Url       string           `db:"url"`
 	IsActive  bool             `db:"is_active"`
+	Status    string           `db:"status"`
 	CreatedAt time.Time        `db:"created_at"`
 	UpdatedAt time.Time        `db:"updated_at"`
 	Version   int64            `db:"version"`
@@ -93,8 +118,131 @@ func (b *FullBanner) ToFullBanners() models.FullBanner {
 			Url   string
 		}{Title: b.Title, Text: b.Text, Url: b.Url},
 		IsActive:  b.IsActive,
+		Status:    b.Status,
 		CreatedAt: b.CreatedAt,
 		UpdatedAt: b.UpdatedAt,
 		Version:   b.Version,
 	}
 }
+
+type GetChanges struct {
+	Since int64
+	Limit int
+}
+
+// BannersFilter условия объединяются через AND, списки внутри одного условия - через OR.
+// TagId и TagIds, FeatureId и FeatureIds складываются в один список, Title и Url - поиск подстроки без учета регистра
+type BannersFilter struct {
+	FeatureId     *models.FeatureId
+	TagId         *models.TagId
+	FeatureIds    []models.FeatureId
+	TagIds        []models.TagId
+	IsActive      *bool
+	Status        []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Title         *string
+	Url           *string
+	// Search полнотекстовый поиск по title и text в синтаксисе websearch_to_tsquery
+	Search *string
+	// SearchVersions баннер подходит и тогда, когда поиск находит одну из его прошлых версий
+	SearchVersions bool
+}
+
+// SearchMatch релевантность баннера и подсвеченные совпадения в текущем контенте,
+// MatchedVersions - прошлые версии, в которых нашелся поиск
+type SearchMatch struct {
+	BannerId        models.BannerId `db:"banner_id"`
+	Rank            float32         `db:"rank"`
+	Title           string          `db:"title"`
+	Text            string          `db:"text"`
+	MatchedVersions pq.Int64Array   `db:"matched_versions"`
+}
+
+type GetTrash struct {
+	Limit  int
+	Offset int
+}
+
+// TrashedBanner строка корзины, тэги собраны в массив так же, как в FullBanner
+type TrashedBanner struct {
+	FullBanner
+	DeletedAt time.Time `db:"deleted_at"`
+	DeletedBy string    `db:"deleted_by"`
+}
+
+func (b *TrashedBanner) ToTrashedBanner() models.TrashedBanner {
+	return models.TrashedBanner{
+		FullBanner: b.ToFullBanners(),
+		DeletedAt:  b.DeletedAt,
+		DeletedBy:  b.DeletedBy,
+	}
+}
+
+type GetDrafts struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// Draft строка banner_drafts
+type Draft struct {
+	DraftId      models.DraftId   `db:"draft_id"`
+	BannerId     models.BannerId  `db:"banner_id"`
+	BaseVersion  int64            `db:"base_version"`
+	FeatureId    models.FeatureId `db:"feature_id"`
+	TagIds       pq.Int64Array    `db:"tag_ids"`
+	Title        string           `db:"title"`
+	Text         string           `db:"text"`
+	Url          string           `db:"url"`
+	IsActive     bool             `db:"is_active"`
+	BannerStatus string           `db:"banner_status"`
+	Status       string           `db:"status"`
+	Author       string           `db:"author"`
+	ReviewedBy   *string          `db:"reviewed_by"`
+	ReviewedAt   *time.Time       `db:"reviewed_at"`
+	Comment      string           `db:"comment"`
+	PublishedBy  *string          `db:"published_by"`
+	PublishedAt  *time.Time       `db:"published_at"`
+	CreatedAt    time.Time        `db:"created_at"`
+	UpdatedAt    time.Time        `db:"updated_at"`
+}
+
+func (d *Draft) ToBannerDraft() models.BannerDraft {
+	draft := models.BannerDraft{
+		DraftId:     d.DraftId,
+		BannerId:    d.BannerId,
+		BaseVersion: d.BaseVersion,
+		Status:      d.Status,
+		Author:      d.Author,
+		ReviewedBy:  d.ReviewedBy,
+		ReviewedAt:  d.ReviewedAt,
+		Comment:     d.Comment,
+		PublishedBy: d.PublishedBy,
+		PublishedAt: d.PublishedAt,
+		CreatedAt:   d.CreatedAt,
+		UpdatedAt:   d.UpdatedAt,
+	}
+	draft.Banner = models.FullBanner{
+		BannerId:  d.BannerId,
+		FeatureId: d.FeatureId,
+		IsActive:  d.IsActive,
+		Status:    d.BannerStatus,
+		Version:   d.BaseVersion,
+	}
+	for _, tagId := range d.TagIds {
+		draft.Banner.TagIds = append(draft.Banner.TagIds, models.TagId(tagId))
+	}
+	draft.Banner.Content.Title, draft.Banner.Content.Text, draft.Banner.Content.Url = d.Title, d.Text, d.Url
+	return draft
+}
+
+func toTagIdsArray(tagIds []models.TagId) pq.Int64Array {
+	array := make(pq.Int64Array, len(tagIds))
+	for i, tagId := range tagIds {
+		array[i] = int64(tagId)
+	}
+	return array
+}
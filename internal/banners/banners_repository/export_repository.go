@@ -0,0 +1,93 @@
+package banners_repository
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel"
+)
+
+// exportCursorName курсор живет до конца транзакции, в одной транзакции открывается один экспорт
+const exportCursorName = "banners_export"
+
+// OpenExportCursor объявляет серверный курсор по баннерам под фильтром GetManyBanner, тэги собираются в массив.
+// Должен вызываться внутри транзакции, читать из него - FetchExportCursor
+func (b *BannersRepo) OpenExportCursor(ctx context.Context, filter *GetManyPostgresBanner) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.OpenExportCursor")
+	defer span.End()
+
+	tagIds := fmt.Sprintf("ARRAY(SELECT t.tag_id FROM %s t WHERE t.banner_id = b.banner_id ORDER BY t.tag_id) AS %s",
+		sql_queries.BannersXTagsTableName, sql_queries.TagIdsColumnName)
+	queryBuilder := filterBanners("b.banner_id", &filter.BannersFilter).
+		Columns("b.feature_id", "b.title", "b.text", "b.url", tagIds,
+			"b.is_active", "b.status", "b.created_at", "b.updated_at", "b.version")
+
+	query, args, err := pageBanners(queryBuilder, filter).
+		Prefix(fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR", exportCursorName)).
+		ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.OpenExportCursor.Select")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.OpenExportCursor.ExecContext")
+	}
+
+	return nil
+}
+
+// FetchExportCursor следующая пачка из курсора, пустой ответ - курсор дочитан
+func (b *BannersRepo) FetchExportCursor(ctx context.Context, limit int) ([]models.FullBanner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.FetchExportCursor")
+	defer span.End()
+
+	var banners []FullBanner
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	err := tr.SelectContext(ctx, &banners, fmt.Sprintf("FETCH FORWARD %d FROM %s", limit, exportCursorName))
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.FetchExportCursor.SelectContext")
+	}
+
+	fullBanners := make([]models.FullBanner, len(banners))
+	for i := range banners {
+		fullBanners[i] = banners[i].ToFullBanners()
+	}
+
+	return fullBanners, nil
+}
+
+// GetVersionsByBannerIds версии пачки баннеров, по баннеру от новых к старым
+func (b *BannersRepo) GetVersionsByBannerIds(ctx context.Context, bannerIds []models.BannerId) ([]models.FullBanner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetVersionsByBannerIds")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SelectVersionColumns...).
+		From(sql_queries.BannersVersionsTableName).
+		Where(sq.Eq{sql_queries.BannerIdColumnName: bannerIds}).
+		OrderBy(sql_queries.BannerIdColumnName, fmt.Sprintf("%s DESC", sql_queries.VersionColumnName)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetVersionsByBannerIds.Select")
+	}
+
+	var banners []FullBanner
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &banners, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetVersionsByBannerIds.SelectContext")
+	}
+
+	fullBanners := make([]models.FullBanner, len(banners))
+	for i := range banners {
+		fullBanners[i] = banners[i].ToFullBanners()
+	}
+
+	return fullBanners, nil
+}
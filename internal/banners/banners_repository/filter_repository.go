@@ -0,0 +1,183 @@
+package banners_repository
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel"
+	"strconv"
+	"strings"
+)
+
+// SortColumns колонки, по которым можно сортировать GetManyBanner и выгрузку
+var SortColumns = map[string]string{
+	sql_queries.BannerIdColumnName:  "b.banner_id",
+	sql_queries.CreatedAtColumnName: "b.created_at",
+	sql_queries.UpdatedAtColumnName: "b.updated_at",
+	sql_queries.VersionColumnName:   "b.version",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func filterBanners(columns string, filter *BannersFilter) sq.SelectBuilder {
+	sqlBuilder := sq.Select(columns).
+		From(fmt.Sprintf("%s b", sql_queries.BannersTableName))
+
+	conditions := sq.And{notTrashed}
+	tagIds := filter.TagIds
+	if filter.TagId != nil {
+		tagIds = append([]models.TagId{*filter.TagId}, tagIds...)
+	}
+	if len(tagIds) != 0 {
+		tags, args, _ := sq.Select("1").
+			From(fmt.Sprintf("%s bxt", sql_queries.BannersXTagsTableName)).
+			Where("bxt.banner_id = b.banner_id").
+			Where(sq.Eq{"bxt.tag_id": tagIds}).
+			ToSql()
+		conditions = append(conditions, sq.Expr(fmt.Sprintf("EXISTS (%s)", tags), args...))
+	}
+	featureIds := filter.FeatureIds
+	if filter.FeatureId != nil {
+		featureIds = append([]models.FeatureId{*filter.FeatureId}, featureIds...)
+	}
+	if len(featureIds) != 0 {
+		conditions = append(conditions, sq.Eq{"b.feature_id": featureIds})
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, sq.Eq{"b.is_active": *filter.IsActive})
+	}
+	if len(filter.Status) != 0 {
+		conditions = append(conditions, sq.Eq{"b.status": filter.Status})
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, sq.GtOrEq{"b.created_at": *filter.CreatedAfter})
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, sq.Lt{"b.created_at": *filter.CreatedBefore})
+	}
+	if filter.UpdatedAfter != nil {
+		conditions = append(conditions, sq.GtOrEq{"b.updated_at": *filter.UpdatedAfter})
+	}
+	if filter.UpdatedBefore != nil {
+		conditions = append(conditions, sq.Lt{"b.updated_at": *filter.UpdatedBefore})
+	}
+	if filter.Title != nil {
+		conditions = append(conditions, sq.ILike{"b.title": "%" + likeEscaper.Replace(*filter.Title) + "%"})
+	}
+	if filter.Url != nil {
+		conditions = append(conditions, sq.ILike{"b.url": "%" + likeEscaper.Replace(*filter.Url) + "%"})
+	}
+	if filter.Search != nil {
+		conditions = append(conditions, searchCondition(filter))
+	}
+
+	return sqlBuilder.Where(conditions).PlaceholderFormat(sq.Dollar)
+}
+
+// keysetTimeLayout timestamp без зоны, как он лежит в базе
+const keysetTimeLayout = "2006-01-02 15:04:05.999999"
+
+// SortValue значение ключа сортировки баннера для Keyset, постгрес сам приводит его к типу колонки
+func SortValue(banner *models.FullBanner, sortBy string) string {
+	switch sortBy {
+	case sql_queries.CreatedAtColumnName:
+		return banner.CreatedAt.Format(keysetTimeLayout)
+	case sql_queries.UpdatedAtColumnName:
+		return banner.UpdatedAt.Format(keysetTimeLayout)
+	case sql_queries.VersionColumnName:
+		return strconv.FormatInt(banner.Version, 10)
+	}
+	return strconv.FormatInt(int64(banner.BannerId), 10)
+}
+
+// pageBanners сортировка и пагинация GetManyBanner поверх filterBanners, по Keyset или по offset
+func pageBanners(sqlBuilder sq.SelectBuilder, params *GetManyPostgresBanner) sq.SelectBuilder {
+	column, ok := SortColumns[params.SortBy]
+	if !ok {
+		column = "b.banner_id"
+	}
+	var columnArgs []interface{}
+	valueCast := ""
+	if params.SortBy == SearchRank && params.Search != nil {
+		column, columnArgs = rankExpression(&params.BannersFilter)
+		valueCast = "::REAL"
+	}
+	desc := params.SortDesc
+	if params.After != nil && params.After.Backward {
+		desc = !desc
+	}
+	direction, operator := "ASC", ">"
+	if desc {
+		direction, operator = "DESC", "<"
+	}
+
+	if params.After != nil {
+		if column == "b.banner_id" {
+			sqlBuilder = sqlBuilder.Where(sq.Expr(fmt.Sprintf("b.banner_id %s ?", operator), params.After.BannerId))
+		} else {
+			keysetArgs := append(append([]interface{}{}, columnArgs...), params.After.SortValue, params.After.BannerId)
+			sqlBuilder = sqlBuilder.Where(sq.Expr(fmt.Sprintf("(%s, b.banner_id) %s (?%s, ?)", column, operator, valueCast), keysetArgs...))
+		}
+	}
+
+	if column == "b.banner_id" {
+		sqlBuilder = sqlBuilder.OrderBy(fmt.Sprintf("b.banner_id %s", direction))
+	} else {
+		sqlBuilder = sqlBuilder.OrderByClause(fmt.Sprintf("%s %s, b.banner_id %s", column, direction, direction), columnArgs...)
+	}
+
+	if params.Offset != nil && params.After == nil {
+		sqlBuilder = sqlBuilder.Offset(uint64(*params.Offset))
+	}
+	if params.Limit != nil && *params.Limit != 0 {
+		sqlBuilder = sqlBuilder.Limit(uint64(*params.Limit))
+	}
+	return sqlBuilder
+}
+
+// GetBannerIdsByFilter айди баннеров под фильтром строго после after по возрастанию
+func (b *BannersRepo) GetBannerIdsByFilter(ctx context.Context, filter *BannersFilter, after models.BannerId, limit int) ([]models.BannerId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetBannerIdsByFilter")
+	defer span.End()
+
+	query, args, err := filterBanners("b.banner_id", filter).
+		Where(sq.Gt{"b.banner_id": after}).
+		OrderBy("b.banner_id").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetBannerIdsByFilter.Select")
+	}
+
+	var bannerIds []models.BannerId
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &bannerIds, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetBannerIdsByFilter.SelectContext")
+	}
+
+	return bannerIds, nil
+}
+
+func (b *BannersRepo) CountBannersByFilter(ctx context.Context, filter *BannersFilter) (int64, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.CountBannersByFilter")
+	defer span.End()
+
+	query, args, err := filterBanners("COUNT(*)", filter).ToSql()
+	if err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.CountBannersByFilter.Select")
+	}
+
+	var count int64
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.GetContext(ctx, &count, query, args...); err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.CountBannersByFilter.GetContext")
+	}
+
+	return count, nil
+}
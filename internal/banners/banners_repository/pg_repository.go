@@ -8,13 +8,13 @@ import (
 	"avito/assignment/pkg/traces"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	sq "github.com/Masterminds/squirrel"
 	trmsqlx "github.com/avito-tech/go-transaction-manager/sqlx"
 	"github.com/jmoiron/sqlx"
 	"go.opentelemetry.io/otel"
-	"sort"
 	"strings"
 	"time"
 )
@@ -68,6 +68,7 @@ func (b *BannersRepo) GetBanner(ctx context.Context, featureId models.FeatureId,
 			sq.And{
 				sq.Eq{sql_queries.TagIdColumnName: tagId},
 				sq.Eq{sql_queries.FeatureIdColumnName: featureId},
+				notTrashed,
 			},
 		).
 		PlaceholderFormat(sq.Dollar).
@@ -94,32 +95,9 @@ func (b *BannersRepo) GetManyBanner(ctx context.Context, getManyPostgresBannerPa
 	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetManyBanner")
 	defer span.End()
 
-	var conditions sq.And
-	var sqlBuilder sq.SelectBuilder
-	if getManyPostgresBannerParams.TagId != nil {
-		conditions = append(conditions, sq.Eq{sql_queries.TagIdColumnName: getManyPostgresBannerParams.TagId})
-		sqlBuilder = sq.Select(sql_queries.GetBannerColumnsWithInnerJoin...).
-			From(fmt.Sprintf("%s b", sql_queries.BannersTableName)).
-			InnerJoin(fmt.Sprintf("%s bxt ON bxt.banner_id = b.banner_id", sql_queries.BannersXTagsTableName))
-	} else {
-		sqlBuilder = sq.Select(sql_queries.SelectBannerColumns...).
-			From(sql_queries.BannersTableName)
-	}
-	if getManyPostgresBannerParams.FeatureId != nil {
-		conditions = append(conditions, sq.Eq{sql_queries.FeatureIdColumnName: getManyPostgresBannerParams.FeatureId})
-	}
-
-	queryBuilder := sqlBuilder.Where(conditions).
-		PlaceholderFormat(sq.Dollar).
-		OrderBy(sql_queries.BannerIdColumnName)
-
-	if getManyPostgresBannerParams.Offset != nil {
-		queryBuilder = queryBuilder.Offset(uint64(*getManyPostgresBannerParams.Offset))
-	}
-
-	if getManyPostgresBannerParams.Limit != nil && *getManyPostgresBannerParams.Limit != 0 {
-		queryBuilder = queryBuilder.Limit(uint64(*getManyPostgresBannerParams.Limit))
-	}
+	queryBuilder := filterBanners("b.banner_id", &getManyPostgresBannerParams.BannersFilter).
+		Columns("b.title", "b.text", "b.url", "b.feature_id", "b.created_at", "b.updated_at", "b.is_active", "b.status", "b.version")
+	queryBuilder = pageBanners(queryBuilder, getManyPostgresBannerParams)
 
 	query, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -141,18 +119,11 @@ func (b *BannersRepo) GetManyPossibleTagIds(ctx context.Context, bannerIds []mod
 	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetManyPossibleTagIds")
 	defer span.End()
 
-	manyFullBanner := make([]models.FullBanner, len(*manyBanner))
-	manyFullBanner[0] = *(*manyBanner)[0].ToFullBannerWithoutTagIds()
-
-	sort.SliceStable(bannerIds, func(i, j int) bool {
-		return bannerIds[i] < bannerIds[j]
-	})
-
 	query, args, err := sq.Select(sql_queries.BannerIdColumnName, sql_queries.TagIdColumnName).
 		From(sql_queries.BannersXTagsTableName).
 		Where(sq.Eq{sql_queries.BannerIdColumnName: bannerIds}).
 		PlaceholderFormat(sq.Dollar).
-		OrderBy(sql_queries.BannerIdColumnName).
+		OrderBy(sql_queries.BannerIdColumnName, sql_queries.TagIdColumnName).
 		ToSql()
 	if err != nil {
 		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetManyPossibleTagIds.Select")
@@ -165,26 +136,27 @@ func (b *BannersRepo) GetManyPossibleTagIds(ctx context.Context, bannerIds []mod
 	}
 	defer rows.Close()
 
-	var currIndex int = 0
-
+	// баннеры могут прийти в любой сортировке, поэтому тэги раскладываем по айди, а не по позиции
+	tagIds := make(map[models.BannerId][]models.TagId, len(bannerIds))
 	for rows.Next() {
 		var bannerId models.BannerId
 		var tagId models.TagId
 		if err := rows.Scan(&bannerId, &tagId); err != nil {
 			return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetManyPossibleTagIds.Scan")
 		}
-
-		if bannerId != bannerIds[currIndex] {
-			currIndex++
-			manyFullBanner[currIndex] = *(*manyBanner)[currIndex].ToFullBannerWithoutTagIds()
-		}
-		manyFullBanner[currIndex].TagIds = append(manyFullBanner[currIndex].TagIds, tagId)
+		tagIds[bannerId] = append(tagIds[bannerId], tagId)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetManyPossibleTagIds.rows.Err")
 	}
 
+	manyFullBanner := make([]models.FullBanner, len(*manyBanner))
+	for i := range *manyBanner {
+		manyFullBanner[i] = *(*manyBanner)[i].ToFullBannerWithoutTagIds()
+		manyFullBanner[i].TagIds = tagIds[manyFullBanner[i].BannerId]
+	}
+
 	return &manyFullBanner, nil
 }
 
@@ -202,6 +174,7 @@ func (b *BannersRepo) AddBanner(ctx context.Context, addPostgresBannerParams *Ad
 			time.Now(),
 			time.Now(),
 			addPostgresBannerParams.IsActive,
+			addPostgresBannerParams.Status,
 			1,
 		).
 		Suffix("RETURNING banner_id,created_at,updated_at").
@@ -238,6 +211,7 @@ func (b *BannersRepo) CheckExist(ctx context.Context, tagIds []models.TagId, fea
 			sq.And{
 				sq.Eq{sql_queries.TagIdColumnName: tagIds},
 				sq.Eq{sql_queries.FeatureIdColumnName: featureId},
+				notTrashed,
 			},
 		).
 		PlaceholderFormat(sq.Dollar).ToSql()
@@ -254,6 +228,86 @@ func (b *BannersRepo) CheckExist(ctx context.Context, tagIds []models.TagId, fea
 	return &existParams, nil
 }
 
+// GetPairConflicts пары (тэг, фича) переданных баннеров, которые заняты больше чем одним баннером
+func (b *BannersRepo) GetPairConflicts(ctx context.Context, bannerIds []models.BannerId) ([]PairConflict, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetPairConflicts")
+	defer span.End()
+
+	pairs, pairsArgs, err := sq.Select("pbxt.tag_id", "pb.feature_id").
+		From(fmt.Sprintf("%s pb", sql_queries.BannersTableName)).
+		InnerJoin(fmt.Sprintf("%s pbxt ON pbxt.banner_id = pb.banner_id", sql_queries.BannersXTagsTableName)).
+		Where(sq.Eq{"pb.banner_id": bannerIds}).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetPairConflicts.SelectPairs")
+	}
+
+	query, args, err := sq.Select("bxt.tag_id", "b.feature_id", "array_agg(b.banner_id ORDER BY b.banner_id) AS banner_ids").
+		From(fmt.Sprintf("%s b", sql_queries.BannersTableName)).
+		InnerJoin(fmt.Sprintf("%s bxt ON bxt.banner_id = b.banner_id", sql_queries.BannersXTagsTableName)).
+		Where(sq.Expr(fmt.Sprintf("(bxt.tag_id, b.feature_id) IN (%s)", pairs), pairsArgs...)).
+		Where(notTrashed).
+		GroupBy("bxt.tag_id", "b.feature_id").
+		Having("COUNT(*) > 1").
+		OrderBy("bxt.tag_id", "b.feature_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetPairConflicts.Select")
+	}
+
+	var conflicts []PairConflict
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &conflicts, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetPairConflicts.SelectContext")
+	}
+	return conflicts, nil
+}
+
+func (b *BannersRepo) GetRegisteredTagIds(ctx context.Context, tagIds []models.TagId) ([]models.TagId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetRegisteredTagIds")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.TagIdColumnName).
+		From(sql_queries.TagsTableName).
+		Where(sq.Eq{sql_queries.TagIdColumnName: tagIds}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetRegisteredTagIds.Select")
+	}
+
+	var registeredTagIds []models.TagId
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &registeredTagIds, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetRegisteredTagIds.SelectContext")
+	}
+	return registeredTagIds, nil
+}
+
+func (b *BannersRepo) IsFeatureRegistered(ctx context.Context, featureId models.FeatureId) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.IsFeatureRegistered")
+	defer span.End()
+
+	query, args, err := sq.Select("1").
+		From(sql_queries.FeaturesTableName).
+		Where(sq.Eq{sql_queries.FeatureIdColumnName: featureId}).
+		Prefix("SELECT EXISTS (").Suffix(")").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.IsFeatureRegistered.Select")
+	}
+
+	var registered bool
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.GetContext(ctx, &registered, query, args...); err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.IsFeatureRegistered.GetContext")
+	}
+	return registered, nil
+}
+
 func (b *BannersRepo) AddTags(ctx context.Context, tagIds []models.TagId, bannerId models.BannerId) error {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.AddTags")
 	defer span.End()
@@ -276,6 +330,7 @@ func (b *BannersRepo) AddTags(ctx context.Context, tagIds []models.TagId, banner
 	return nil
 }
 
+// GetBannerById баннер, с которого сняли все тэги, тоже находится, TagIds у него пустой
 func (b *BannersRepo) GetBannerById(ctx context.Context, bannerId models.BannerId) (*models.FullBanner, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetBannerById")
 	defer span.End()
@@ -284,10 +339,11 @@ func (b *BannersRepo) GetBannerById(ctx context.Context, bannerId models.BannerI
 
 	query, args, err := sq.Select(sql_queries.GetFullBannerColumns...).
 		From(fmt.Sprintf("%s b", sql_queries.BannersTableName)).
-		InnerJoin(fmt.Sprintf("%s bxt ON bxt.banner_id = b.banner_id", sql_queries.BannersXTagsTableName)).
+		LeftJoin(fmt.Sprintf("%s bxt ON bxt.banner_id = b.banner_id", sql_queries.BannersXTagsTableName)).
 		Where(
 			sq.And{
 				sq.Eq{"b." + sql_queries.BannerIdColumnName: bannerId},
+				notTrashed,
 			},
 		).
 		PlaceholderFormat(sq.Dollar).
@@ -311,9 +367,10 @@ func (b *BannersRepo) GetBannerById(ctx context.Context, bannerId models.BannerI
 			return nil, err
 		}
 		if firstRow {
-			fullBanner = banner.ToFullBanner([]models.TagId{banner.TagId})
+			fullBanner = banner.ToFullBanner([]models.TagId{})
 			firstRow = false
-		} else {
+		}
+		if banner.TagId != 0 {
 			fullBanner.TagIds = append(fullBanner.TagIds, banner.TagId)
 		}
 	}
@@ -325,7 +382,9 @@ func (b *BannersRepo) GetBannerById(ctx context.Context, bannerId models.BannerI
 	return fullBanner, nil
 }
 
-func (b *BannersRepo) UpdateBannerById(ctx context.Context, updateBannerByIdParams *UpdateBannerById) error {
+// UpdateBannerById обновляет баннер, только если его версия все еще Version, иначе возвращает false.
+// Так параллельный патч, успевший закоммититься между чтением и записью, не затирается молча
+func (b *BannersRepo) UpdateBannerById(ctx context.Context, updateBannerByIdParams *UpdateBannerById) (bool, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.UpdateBannerById")
 	defer span.End()
 
@@ -334,19 +393,24 @@ func (b *BannersRepo) UpdateBannerById(ctx context.Context, updateBannerByIdPara
 
 	query, args, err := sqlBuilder.
 		Where(sq.Eq{sql_queries.BannerIdColumnName: updateBannerByIdParams.BannerId}).
+		Where(sq.Eq{sql_queries.VersionColumnName: updateBannerByIdParams.Version}).
 		PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
 
-	_, err = tr.ExecContext(ctx, query, args...)
+	result, err := tr.ExecContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return updated != 0, nil
 }
 
 func filter(sqlBuilder sq.UpdateBuilder, updateBannerByIdParams *UpdateBannerById) sq.UpdateBuilder {
@@ -365,6 +429,9 @@ func filter(sqlBuilder sq.UpdateBuilder, updateBannerByIdParams *UpdateBannerByI
 	if updateBannerByIdParams.IsActive != nil {
 		sqlBuilder = sqlBuilder.Set(sql_queries.IsActiveColumnName, updateBannerByIdParams.IsActive)
 	}
+	if updateBannerByIdParams.Status != nil {
+		sqlBuilder = sqlBuilder.Set(sql_queries.StatusColumnName, updateBannerByIdParams.Status)
+	}
 	sqlBuilder = sqlBuilder.Set(sql_queries.UpdatedAtColumnName, time.Now())
 	sqlBuilder = sqlBuilder.Set(sql_queries.VersionColumnName, updateBannerByIdParams.Version+1)
 
@@ -393,6 +460,7 @@ func (b *BannersRepo) AddVersion(ctx context.Context, prevBanner *models.FullBan
 			prevBanner.CreatedAt,
 			prevBanner.UpdatedAt,
 			prevBanner.IsActive,
+			prevBanner.Status,
 			prevBanner.Version,
 		).
 		PlaceholderFormat(sq.Dollar).ToSql()
@@ -513,3 +581,62 @@ func (b *BannersRepo) GetBannerVersions(ctx context.Context, bannerId models.Ban
 
 	return &fullBanners, nil
 }
+
+// AddChange пишет событие в журнал в текущей транзакции
+// advisory lock держится до коммита, поэтому change_id становятся видны читателям строго по возрастанию
+func (b *BannersRepo) AddChange(ctx context.Context, eventType string, banner *models.FullBanner) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.AddChange")
+	defer span.End()
+
+	payload, err := json.Marshal(banner.ToPayload())
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.AddChange.Marshal")
+	}
+
+	query, args, err := sq.Insert(sql_queries.BannerChangesTableName).
+		Columns(sql_queries.InsertChangeColumns...).
+		Values(
+			banner.BannerId,
+			eventType,
+			string(payload),
+			time.Now(),
+		).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.AddChange.Insert")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err = tr.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", sql_queries.BannerChangesLockId); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.AddChange.AdvisoryLock")
+	}
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.AddChange.ExecContext")
+	}
+
+	return nil
+}
+
+func (b *BannersRepo) GetChanges(ctx context.Context, getChangesParams *GetChanges) ([]models.ChangeLogEntry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetChanges")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SelectChangeColumns...).
+		From(sql_queries.BannerChangesTableName).
+		Where(sq.Gt{sql_queries.ChangeIdColumnName: getChangesParams.Since}).
+		OrderBy(sql_queries.ChangeIdColumnName).
+		Limit(uint64(getChangesParams.Limit)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetChanges.Select")
+	}
+
+	var changes []models.ChangeLogEntry
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &changes, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetChanges.SelectContext")
+	}
+
+	return changes, nil
+}
@@ -15,6 +15,8 @@ import (
 	"time"
 )
 
+const bannerChangesKey = "banners:changes"
+
 type ClientRedisRepo struct {
 	db  *redis.Client
 	cfg *config.Config
@@ -93,6 +95,131 @@ func (r *ClientRedisRepo) DelBannerRedis(ctx context.Context, featureId models.F
 	return nil
 }
 
+// FlushBannersRedis удаляет из кэша все баннеры, ключи вида tag_id:feature_id
+func (r *ClientRedisRepo) FlushBannersRedis(ctx context.Context) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ClientRedisRepo.FlushBannersRedis")
+	defer span.End()
+
+	iter := r.db.Scan(ctx, 0, "[0-9]*:[0-9]*", 1000).Iterator()
+	keys := make([]string, 0, 1000)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == cap(keys) {
+			if err := r.db.Del(ctx, keys...).Err(); err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.FlushBannersRedis.Del; err = %s", err.Error()))
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.FlushBannersRedis.Scan; err = %s", err.Error()))
+	}
+
+	if len(keys) != 0 {
+		if err := r.db.Del(ctx, keys...).Err(); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.FlushBannersRedis.Del; err = %s", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+func (r *ClientRedisRepo) PublishBannerChange(ctx context.Context, change *models.BannerChange) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ClientRedisRepo.PublishBannerChange")
+	defer span.End()
+
+	changeBytes, err := json.Marshal(change)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.PublishBannerChange.Marshal; err = %s", err.Error()))
+	}
+
+	eventId, err := r.db.XAdd(ctx, &redis.XAddArgs{
+		Stream: bannerChangesKey,
+		MaxLen: r.cfg.Stream.ReplayLimit,
+		Approx: true,
+		Values: map[string]interface{}{"change": changeBytes},
+	}).Result()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.PublishBannerChange.XAdd; err = %s", err.Error()))
+	}
+	change.EventId = eventId
+
+	changeBytes, err = json.Marshal(change)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.PublishBannerChange.Marshal; err = %s", err.Error()))
+	}
+
+	if err = r.db.Publish(ctx, bannerChangesKey, changeBytes).Err(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.PublishBannerChange.Publish; err = %s", err.Error()))
+	}
+
+	return nil
+}
+
+// GetBannerChanges отдает события из redis stream строго после afterEventId, нужно для Last-Event-ID
+func (r *ClientRedisRepo) GetBannerChanges(ctx context.Context, afterEventId string) ([]models.BannerChange, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ClientRedisRepo.GetBannerChanges")
+	defer span.End()
+
+	messages, err := r.db.XRangeN(ctx, bannerChangesKey, "("+afterEventId, "+", r.cfg.Stream.ReplayLimit).Result()
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.GetBannerChanges.XRange; err = %s", err.Error()))
+	}
+
+	changes := make([]models.BannerChange, 0, len(messages))
+	for _, message := range messages {
+		value, ok := message.Values["change"].(string)
+		if !ok {
+			continue
+		}
+
+		var change models.BannerChange
+		if err = json.Unmarshal([]byte(value), &change); err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("ClientRedisRepo.GetBannerChanges.Unmarshal; err = %s", err.Error()))
+		}
+		change.EventId = message.ID
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// SubscribeBannerChanges подписывается на pub/sub канал, канал закрывается вместе с ctx
+func (r *ClientRedisRepo) SubscribeBannerChanges(ctx context.Context) <-chan models.BannerChange {
+	pubSub := r.db.Subscribe(ctx, bannerChangesKey)
+	changes := make(chan models.BannerChange)
+
+	go func() {
+		defer close(changes)
+		defer pubSub.Close()
+
+		messages := pubSub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var change models.BannerChange
+				if err := json.Unmarshal([]byte(message.Payload), &change); err != nil {
+					continue
+				}
+
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes
+}
+
 func (r *ClientRedisRepo) createDbKey(tagId models.TagId, featureId models.FeatureId) string {
 	return strings.Join([]string{strconv.Itoa(int(tagId)), strconv.Itoa(int(featureId))}, ":")
 }
@@ -0,0 +1,170 @@
+package banners_repository
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel"
+	"strconv"
+)
+
+// SearchRank сортировка по релевантности, доступна только вместе с поиском
+const SearchRank = "rank"
+
+// таблицы, которые обходит ReindexSearchVectors
+const (
+	SearchTableBanners  = sql_queries.BannersTableName
+	SearchTableVersions = sql_queries.BannersVersionsTableName
+)
+
+// tsQuery язык поиска берется из search_settings, тем же языком построены search_vector
+const tsQuery = "websearch_to_tsquery(banner_schema.search_language(), ?)"
+
+// searchCondition баннер подходит под поиск сам или, с SearchVersions, любой из его прошлых версий
+func searchCondition(filter *BannersFilter) sq.Sqlizer {
+	condition := sq.Expr(fmt.Sprintf("b.search_vector @@ %s", tsQuery), *filter.Search)
+	if !filter.SearchVersions {
+		return condition
+	}
+	versions := sq.Expr(fmt.Sprintf("EXISTS (SELECT 1 FROM %s v WHERE v.banner_id = b.banner_id AND v.search_vector @@ %s)",
+		sql_queries.BannersVersionsTableName, tsQuery), *filter.Search)
+	return sq.Or{condition, versions}
+}
+
+// rankExpression ts_rank баннера, с SearchVersions - лучший из рангов баннера и его версий
+func rankExpression(filter *BannersFilter) (string, []interface{}) {
+	rank := fmt.Sprintf("ts_rank(b.search_vector, %s)", tsQuery)
+	if !filter.SearchVersions {
+		return rank, []interface{}{*filter.Search}
+	}
+	return fmt.Sprintf("GREATEST(%s, COALESCE((SELECT MAX(ts_rank(v.search_vector, %s)) FROM %s v WHERE v.banner_id = b.banner_id), 0))",
+		rank, tsQuery, sql_queries.BannersVersionsTableName), []interface{}{*filter.Search, *filter.Search}
+}
+
+// FormatRank ранг для Keyset, real из постгреса переживает круговое преобразование без потерь
+func FormatRank(rank float32) string {
+	return strconv.FormatFloat(float64(rank), 'g', -1, 32)
+}
+
+// GetSearchMatches ранг и подсветка только для баннеров страницы, ts_headline слишком дорогой, чтобы считать его под фильтром
+func (b *BannersRepo) GetSearchMatches(ctx context.Context, bannerIds []models.BannerId, filter *BannersFilter) (map[models.BannerId]SearchMatch, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetSearchMatches")
+	defer span.End()
+
+	rank, rankArgs := rankExpression(filter)
+	matchedVersions := sq.Expr("ARRAY[]::BIGINT[]")
+	if filter.SearchVersions {
+		matchedVersions = sq.Expr(fmt.Sprintf("ARRAY(SELECT v.version FROM %s v WHERE v.banner_id = b.banner_id AND v.search_vector @@ %s ORDER BY v.version)",
+			sql_queries.BannersVersionsTableName, tsQuery), *filter.Search)
+	}
+
+	query, args, err := sq.Select("b.banner_id").
+		Column(sq.Alias(sq.Expr(rank, rankArgs...), "rank")).
+		Column(sq.Alias(sq.Expr(fmt.Sprintf("ts_headline(banner_schema.search_language(), coalesce(b.title, ''), %s, 'HighlightAll=true')",
+			tsQuery), *filter.Search), "title")).
+		Column(sq.Alias(sq.Expr(fmt.Sprintf("ts_headline(banner_schema.search_language(), coalesce(b.text, ''), %s, 'MaxFragments=2, MaxWords=20, MinWords=5')",
+			tsQuery), *filter.Search), "text")).
+		Column(sq.Alias(matchedVersions, "matched_versions")).
+		From(fmt.Sprintf("%s b", sql_queries.BannersTableName)).
+		Where(sq.Eq{"b.banner_id": bannerIds}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSearchMatches.Select")
+	}
+
+	var matches []SearchMatch
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &matches, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSearchMatches.SelectContext")
+	}
+
+	matchesById := make(map[models.BannerId]SearchMatch, len(matches))
+	for _, match := range matches {
+		matchesById[match.BannerId] = match
+	}
+	return matchesById, nil
+}
+
+// GetSearchLanguage язык, которым построены search_vector и который берут триггеры
+func (b *BannersRepo) GetSearchLanguage(ctx context.Context) (string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetSearchLanguage")
+	defer span.End()
+
+	query, args, err := sq.Select(fmt.Sprintf("%s::TEXT", sql_queries.LanguageColumnName)).
+		From(sql_queries.SearchSettingsTableName).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSearchLanguage.Select")
+	}
+
+	var language string
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.GetContext(ctx, &language, query, args...); err != nil {
+		return "", traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSearchLanguage.GetContext")
+	}
+	return language, nil
+}
+
+// SetSearchLanguage запоминает язык поиска, false - он и так был таким
+func (b *BannersRepo) SetSearchLanguage(ctx context.Context, language string) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.SetSearchLanguage")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.SearchSettingsTableName).
+		Set(sql_queries.LanguageColumnName, sq.Expr("?::REGCONFIG", language)).
+		Where(sq.Expr(fmt.Sprintf("%s <> ?::REGCONFIG", sql_queries.LanguageColumnName), language)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.SetSearchLanguage.Update")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	result, err := tr.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.SetSearchLanguage.ExecContext")
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.SetSearchLanguage.RowsAffected")
+	}
+	return changed != 0, nil
+}
+
+// searchTables таблицы с search_vector и их ключи для обхода пачками
+var searchTables = map[string]string{
+	SearchTableBanners:  sql_queries.BannerIdColumnName,
+	SearchTableVersions: sql_queries.IdColumnName,
+}
+
+// ReindexSearchVectors пересобирает search_vector следующей пачки строк table с ключом больше after.
+// Возвращает ключ последней строки пачки и ее размер, 0 строк - таблица пройдена
+func (b *BannersRepo) ReindexSearchVectors(ctx context.Context, table, language string, after int64, limit int) (int64, int, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.ReindexSearchVectors")
+	defer span.End()
+
+	key, ok := searchTables[table]
+	if !ok {
+		return 0, 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, fmt.Errorf("unknown search table %s", table), "BannersRepo.ReindexSearchVectors.Table")
+	}
+	query := fmt.Sprintf(`UPDATE %[1]s t SET %[3]s = banner_schema.banner_search_vector($1::REGCONFIG, t.title, t.text)
+		FROM (SELECT %[2]s FROM %[1]s WHERE %[2]s > $2 ORDER BY %[2]s LIMIT $3) batch
+		WHERE t.%[2]s = batch.%[2]s
+		RETURNING t.%[2]s`, table, key, sql_queries.SearchVectorColumnName)
+
+	var keys []int64
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err := tr.SelectContext(ctx, &keys, query, language, after, limit); err != nil {
+		return 0, 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReindexSearchVectors.SelectContext")
+	}
+
+	last := after
+	for _, key := range keys {
+		last = max(last, key)
+	}
+	return last, len(keys), nil
+}
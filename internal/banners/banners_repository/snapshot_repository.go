@@ -0,0 +1,162 @@
+package banners_repository
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel"
+	"strings"
+)
+
+// snapshotInsertBatch строк на один insert, чтобы не упереться в лимит параметров постгреса
+const snapshotInsertBatch = 500
+
+// GetSchemaVersion последняя примененная миграция goose, по ней проверяется совместимость снапшотов
+func (b *BannersRepo) GetSchemaVersion(ctx context.Context) (int64, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetSchemaVersion")
+	defer span.End()
+
+	var version int64
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	err := tr.GetContext(ctx, &version, fmt.Sprintf("SELECT COALESCE(MAX(version_id), 0) FROM %s WHERE is_applied", sql_queries.GooseVersionTableName))
+	if err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSchemaVersion.GetContext")
+	}
+
+	return version, nil
+}
+
+// GetSnapshotData все баннеры, их тэги и версии, для согласованного среза вызывать в repeatable read транзакции
+func (b *BannersRepo) GetSnapshotData(ctx context.Context) (*models.SnapshotData, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetSnapshotData")
+	defer span.End()
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	data := &models.SnapshotData{}
+
+	query, args, err := sq.Select(sql_queries.SelectBannerColumns...).
+		From(sql_queries.BannersTableName).
+		OrderBy(sql_queries.BannerIdColumnName).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSnapshotData.SelectBanners")
+	}
+	if err = tr.SelectContext(ctx, &data.Banners, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSnapshotData.SelectContextBanners")
+	}
+
+	query, args, err = sq.Select(sql_queries.BannerIdColumnName, sql_queries.TagIdColumnName).
+		From(sql_queries.BannersXTagsTableName).
+		OrderBy(sql_queries.BannerIdColumnName, sql_queries.TagIdColumnName).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSnapshotData.SelectTagLinks")
+	}
+	if err = tr.SelectContext(ctx, &data.TagLinks, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSnapshotData.SelectContextTagLinks")
+	}
+
+	query, args, err = sq.Select(sql_queries.SelectVersionColumns...).
+		From(sql_queries.BannersVersionsTableName).
+		OrderBy(sql_queries.BannerIdColumnName, sql_queries.VersionColumnName).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSnapshotData.SelectVersions")
+	}
+	if err = tr.SelectContext(ctx, &data.Versions, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetSnapshotData.SelectContextVersions")
+	}
+
+	return data, nil
+}
+
+// LockBannersTables блокирует запись в баннеры, тэги и версии до конца транзакции, чтение остается доступным
+func (b *BannersRepo) LockBannersTables(ctx context.Context) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.LockBannersTables")
+	defer span.End()
+
+	query := fmt.Sprintf("LOCK TABLE %s, %s, %s IN EXCLUSIVE MODE",
+		sql_queries.BannersTableName, sql_queries.BannersXTagsTableName, sql_queries.BannersVersionsTableName)
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err := tr.ExecContext(ctx, query); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.LockBannersTables.ExecContext")
+	}
+
+	return nil
+}
+
+// ReplaceSnapshotData заменяет содержимое баннеров, тэгов и версий данными снапшота, вызывать внутри транзакции.
+// banner_id сохраняются, счетчик айди не откатывается назад, чтобы не выдать заново айди удаленных баннеров
+func (b *BannersRepo) ReplaceSnapshotData(ctx context.Context, data *models.SnapshotData) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.ReplaceSnapshotData")
+	defer span.End()
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+
+	for _, table := range []string{sql_queries.BannersVersionsTableName, sql_queries.BannersXTagsTableName, sql_queries.BannersTableName} {
+		if _, err := tr.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", table)); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.Delete")
+		}
+	}
+
+	for start := 0; start < len(data.Banners); start += snapshotInsertBatch {
+		sqlBuilder := sq.Insert(sql_queries.BannersTableName).Columns(sql_queries.SelectBannerColumns...)
+		for _, banner := range data.Banners[start:min(start+snapshotInsertBatch, len(data.Banners))] {
+			sqlBuilder = sqlBuilder.Values(banner.BannerId, banner.FeatureId, banner.Title, banner.Text, banner.Url,
+				banner.IsActive, banner.Status, banner.CreatedAt, banner.UpdatedAt, banner.Version, banner.DeletedAt, banner.DeletedBy)
+		}
+		query, args, err := sqlBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.InsertBanners")
+		}
+		// banner_id GENERATED ALWAYS, а squirrel не умеет ставить OVERRIDING между колонками и VALUES
+		query = strings.Replace(query, ") VALUES ", ") OVERRIDING SYSTEM VALUE VALUES ", 1)
+		if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.ExecContextBanners")
+		}
+	}
+
+	for start := 0; start < len(data.TagLinks); start += snapshotInsertBatch {
+		sqlBuilder := sq.Insert(sql_queries.BannersXTagsTableName).Columns(sql_queries.BannerIdColumnName, sql_queries.TagIdColumnName)
+		for _, tagLink := range data.TagLinks[start:min(start+snapshotInsertBatch, len(data.TagLinks))] {
+			sqlBuilder = sqlBuilder.Values(tagLink.BannerId, tagLink.TagId)
+		}
+		query, args, err := sqlBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.InsertTagLinks")
+		}
+		if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.ExecContextTagLinks")
+		}
+	}
+
+	for start := 0; start < len(data.Versions); start += snapshotInsertBatch {
+		sqlBuilder := sq.Insert(sql_queries.BannersVersionsTableName).Columns(sql_queries.SelectVersionColumns...)
+		for _, version := range data.Versions[start:min(start+snapshotInsertBatch, len(data.Versions))] {
+			sqlBuilder = sqlBuilder.Values(version.BannerId, version.FeatureId, version.Title, version.Text, version.Url,
+				version.TagIds, version.IsActive, version.Status, version.CreatedAt, version.UpdatedAt, version.Version)
+		}
+		query, args, err := sqlBuilder.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.InsertVersions")
+		}
+		if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.ExecContextVersions")
+		}
+	}
+
+	sequence := fmt.Sprintf("pg_get_serial_sequence('%s', '%s')", sql_queries.BannersTableName, sql_queries.BannerIdColumnName)
+	query := fmt.Sprintf("SELECT setval(%s, GREATEST(MAX(%s), nextval(%s))) FROM %s",
+		sequence, sql_queries.BannerIdColumnName, sequence, sql_queries.BannersTableName)
+	if _, err := tr.ExecContext(ctx, query); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.ReplaceSnapshotData.SetVal")
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package banners_repository
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel"
+	"time"
+)
+
+// notTrashed условие на живые баннеры, таблица баннеров во всех запросах идет под алиасом b
+var notTrashed = sq.Eq{"b." + sql_queries.DeletedAtColumnName: nil}
+
+func trashedBanners() sq.SelectBuilder {
+	tagIds := fmt.Sprintf("ARRAY(SELECT t.tag_id FROM %s t WHERE t.banner_id = b.banner_id ORDER BY t.tag_id) AS %s",
+		sql_queries.BannersXTagsTableName, sql_queries.TagIdsColumnName)
+	return sq.Select("b.banner_id", "b.feature_id", "b.title", "b.text", "b.url", tagIds,
+		"b.is_active", "b.status", "b.created_at", "b.updated_at", "b.version", "b.deleted_at", "b.deleted_by").
+		From(fmt.Sprintf("%s b", sql_queries.BannersTableName)).
+		Where(sq.NotEq{"b." + sql_queries.DeletedAtColumnName: nil}).
+		PlaceholderFormat(sq.Dollar)
+}
+
+// TrashBanner переносит живой баннер в корзину, тэги и версии остаются на месте до очистки
+func (b *BannersRepo) TrashBanner(ctx context.Context, bannerId models.BannerId, actor string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.TrashBanner")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.BannersTableName).
+		Set(sql_queries.DeletedAtColumnName, time.Now()).
+		Set(sql_queries.DeletedByColumnName, actor).
+		Where(sq.Eq{sql_queries.BannerIdColumnName: bannerId}).
+		Where(sq.Eq{sql_queries.DeletedAtColumnName: nil}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.TrashBanner.Update")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.TrashBanner.ExecContext")
+	}
+
+	return nil
+}
+
+// GetTrash корзина, сначала недавно удаленные
+func (b *BannersRepo) GetTrash(ctx context.Context, getTrashParams *GetTrash) ([]models.TrashedBanner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetTrash")
+	defer span.End()
+
+	query, args, err := trashedBanners().
+		OrderBy("b.deleted_at DESC", "b.banner_id DESC").
+		Limit(uint64(getTrashParams.Limit)).
+		Offset(uint64(getTrashParams.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetTrash.Select")
+	}
+
+	var banners []TrashedBanner
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &banners, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetTrash.SelectContext")
+	}
+
+	trashed := make([]models.TrashedBanner, len(banners))
+	for i := range banners {
+		trashed[i] = banners[i].ToTrashedBanner()
+	}
+	return trashed, nil
+}
+
+// GetTrashedBannerById баннер из корзины под блокировкой до конца транзакции, nil - в корзине его нет
+func (b *BannersRepo) GetTrashedBannerById(ctx context.Context, bannerId models.BannerId) (*models.TrashedBanner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.GetTrashedBannerById")
+	defer span.End()
+
+	query, args, err := trashedBanners().
+		Where(sq.Eq{"b." + sql_queries.BannerIdColumnName: bannerId}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetTrashedBannerById.Select")
+	}
+
+	var banners []TrashedBanner
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &banners, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.GetTrashedBannerById.SelectContext")
+	}
+	if len(banners) == 0 {
+		return nil, nil
+	}
+
+	trashed := banners[0].ToTrashedBanner()
+	return &trashed, nil
+}
+
+// RestoreBanner достает баннер из корзины, проверки пар тэг-фича на вызывающем
+func (b *BannersRepo) RestoreBanner(ctx context.Context, bannerId models.BannerId) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.RestoreBanner")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.BannersTableName).
+		Set(sql_queries.DeletedAtColumnName, nil).
+		Set(sql_queries.DeletedByColumnName, nil).
+		Where(sq.Eq{sql_queries.BannerIdColumnName: bannerId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.RestoreBanner.Update")
+	}
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.RestoreBanner.ExecContext")
+	}
+
+	return nil
+}
+
+// LockExpiredTrash айди баннеров, лежащих в корзине с before и раньше.
+// SKIP LOCKED, чтобы очистка на нескольких репликах и восстановление не ждали друг друга
+func (b *BannersRepo) LockExpiredTrash(ctx context.Context, before time.Time, limit int) ([]models.BannerId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersRepo.LockExpiredTrash")
+	defer span.End()
+
+	query, args, err := sq.Select("b.banner_id").
+		From(fmt.Sprintf("%s b", sql_queries.BannersTableName)).
+		Where(sq.LtOrEq{"b." + sql_queries.DeletedAtColumnName: before}).
+		OrderBy("b.deleted_at", "b.banner_id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.LockExpiredTrash.Select")
+	}
+
+	var bannerIds []models.BannerId
+
+	tr := b.txGetter.DefaultTrOrDB(ctx, b.db)
+	if err = tr.SelectContext(ctx, &bannerIds, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersRepo.LockExpiredTrash.SelectContext")
+	}
+
+	return bannerIds, nil
+}
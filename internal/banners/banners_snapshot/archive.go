@@ -0,0 +1,210 @@
+package banners_snapshot
+
+import (
+	"archive/tar"
+	"avito/assignment/internal/models"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// FormatVersion версия раскладки архива, меняется только вместе с кодом чтения
+const FormatVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	bannersFile  = "banners.ndjson"
+	tagLinksFile = "banners_x_tags.ndjson"
+	versionsFile = "banners_versions.ndjson"
+)
+
+// WriteArchive пишет tar.gz: сначала manifest.json, потом по ndjson файлу на таблицу.
+// Количества строк и sha256 файлов проставляются в manifest
+func WriteArchive(w io.Writer, manifest *models.SnapshotManifest, data *models.SnapshotData) error {
+	manifest.FormatVersion = FormatVersion
+	manifest.Counts = map[string]int{
+		bannersFile:  len(data.Banners),
+		tagLinksFile: len(data.TagLinks),
+		versionsFile: len(data.Versions),
+	}
+
+	files := []string{bannersFile, tagLinksFile, versionsFile}
+	contents := make(map[string][]byte, len(files))
+	var err error
+	if contents[bannersFile], err = encodeNDJSON(data.Banners); err != nil {
+		return err
+	}
+	if contents[tagLinksFile], err = encodeNDJSON(data.TagLinks); err != nil {
+		return err
+	}
+	if contents[versionsFile], err = encodeNDJSON(data.Versions); err != nil {
+		return err
+	}
+	manifest.Checksums = make(map[string]string, len(files))
+	for _, name := range files {
+		manifest.Checksums[name] = checksum(contents[name])
+	}
+
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	if err = writeFile(tarWriter, manifestFile, manifestContent, manifest.CreatedAt); err != nil {
+		return err
+	}
+	for _, name := range files {
+		if err = writeFile(tarWriter, name, contents[name], manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// ReadManifest читает только manifest, он всегда первый файл архива
+func ReadManifest(r io.Reader) (*models.SnapshotManifest, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("archive is not gzip: %w", err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	header, err := tarReader.Next()
+	if err != nil {
+		return nil, fmt.Errorf("archive is not tar: %w", err)
+	}
+	if header.Name != manifestFile {
+		return nil, fmt.Errorf("archive must start with %s, got %s", manifestFile, header.Name)
+	}
+	return decodeManifest(tarReader)
+}
+
+// ReadArchive читает архив целиком и сверяет формат, количества строк и контрольные суммы с manifest
+func ReadArchive(r io.Reader) (*models.SnapshotManifest, *models.SnapshotData, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("archive is not gzip: %w", err)
+	}
+	defer gzipReader.Close()
+
+	var manifest *models.SnapshotManifest
+	contents := make(map[string][]byte)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("archive is not tar: %w", err)
+		}
+		if header.Name == manifestFile {
+			if manifest, err = decodeManifest(tarReader); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if contents[header.Name], err = io.ReadAll(tarReader); err != nil {
+			return nil, nil, err
+		}
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("archive has no %s", manifestFile)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, nil, fmt.Errorf("archive format version %d is not supported, expected %d", manifest.FormatVersion, FormatVersion)
+	}
+
+	data := &models.SnapshotData{}
+	if err = readFile(contents, manifest, bannersFile, &data.Banners); err != nil {
+		return nil, nil, err
+	}
+	if err = readFile(contents, manifest, tagLinksFile, &data.TagLinks); err != nil {
+		return nil, nil, err
+	}
+	if err = readFile(contents, manifest, versionsFile, &data.Versions); err != nil {
+		return nil, nil, err
+	}
+
+	return manifest, data, nil
+}
+
+func decodeManifest(r io.Reader) (*models.SnapshotManifest, error) {
+	manifest := &models.SnapshotManifest{}
+	if err := json.NewDecoder(r).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+	}
+	return manifest, nil
+}
+
+func writeFile(tarWriter *tar.Writer, name string, content []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tarWriter.Write(content)
+	return err
+}
+
+func encodeNDJSON[T any](rows []T) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for i := range rows {
+		if err := encoder.Encode(rows[i]); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+// readFile сверяет файл с manifest и разбирает его построчно в target
+func readFile[T any](contents map[string][]byte, manifest *models.SnapshotManifest, name string, target *[]T) error {
+	content, ok := contents[name]
+	if !ok {
+		return fmt.Errorf("archive has no %s", name)
+	}
+	if checksum(content) != manifest.Checksums[name] {
+		return fmt.Errorf("checksum mismatch for %s", name)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var row T
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			return fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		*target = append(*target, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	if len(*target) != manifest.Counts[name] {
+		return fmt.Errorf("%s has %d rows, manifest says %d", name, len(*target), manifest.Counts[name])
+	}
+	return nil
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,54 @@
+package banners_snapshot
+
+import (
+	"avito/assignment/internal/models"
+	"fmt"
+)
+
+// MinSchemaVersion схема, на которой появились снапшоты, более ранних снапшотов не бывает
+const MinSchemaVersion = 20241019095000
+
+// schemaUpgrade миграция, которая поменяла колонки баннеров, тэгов или версий. upgrade доводит данные снапшота
+// до этой миграции так же, как она довела данные в базе
+type schemaUpgrade struct {
+	version int64
+	upgrade func(data *models.SnapshotData)
+}
+
+// schemaUpgrades по возрастанию версии. Миграции, которые только добавили nullable колонки или не трогали
+// эти таблицы, сюда не попадают: снапшот до них восстанавливается как есть
+var schemaUpgrades = []schemaUpgrade{
+	{version: 20241019104000, upgrade: fillBannerStatus},
+}
+
+// UpgradeData доводит данные снапшота схемы snapshotVersion до схемы currentVersion. Снапшот схемы новее текущей
+// может нести колонки, о которых код ничего не знает, поэтому его не восстанавливаем
+func UpgradeData(snapshotVersion, currentVersion int64, data *models.SnapshotData) error {
+	if snapshotVersion < MinSchemaVersion || snapshotVersion > currentVersion {
+		return fmt.Errorf("snapshot schema version %d is incompatible with current schema version %d, supported versions are %d-%d",
+			snapshotVersion, currentVersion, MinSchemaVersion, currentVersion)
+	}
+	for _, schemaUpgrade := range schemaUpgrades {
+		if snapshotVersion < schemaUpgrade.version && schemaUpgrade.version <= currentVersion {
+			schemaUpgrade.upgrade(data)
+		}
+	}
+	return nil
+}
+
+// fillBannerStatus статус появился вместо is_active, см. миграцию 20241019104000_banner_status
+func fillBannerStatus(data *models.SnapshotData) {
+	for i := range data.Banners {
+		data.Banners[i].Status = statusFromIsActive(data.Banners[i].IsActive)
+	}
+	for i := range data.Versions {
+		data.Versions[i].Status = statusFromIsActive(data.Versions[i].IsActive)
+	}
+}
+
+func statusFromIsActive(isActive bool) string {
+	if isActive {
+		return models.BannerStatusActive
+	}
+	return models.BannerStatusPaused
+}
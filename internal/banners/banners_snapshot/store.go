@@ -0,0 +1,121 @@
+package banners_snapshot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const archiveExt = ".tar.gz"
+
+// snapshotIdRegexp айди снапшота - это имя файла, поэтому снаружи принимаем только то, что выдали сами.
+// Айди без случайного суффикса выдавались раньше, их архивы остаются доступными
+var snapshotIdRegexp = regexp.MustCompile(`^snapshot_[0-9]{8}T[0-9]{9}(_[0-9a-f]{8})?$`)
+
+var ErrNotFound = errors.New("snapshot not found")
+
+// Store архивы снапшотов в локальной директории, файл сначала пишется во временный и потом переименовывается
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// NewSnapshotId время с точностью до миллисекунды и случайный суффикс: снапшоты, снятые в одну миллисекунду
+// на разных репликах или при повторе, не перезаписывают друг друга
+func NewSnapshotId(now time.Time) string {
+	now = now.UTC()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("snapshot_%s%03d_%s", now.Format("20060102T150405"), now.Nanosecond()/int(time.Millisecond), hex.EncodeToString(suffix))
+}
+
+func ValidSnapshotId(snapshotId string) bool {
+	return snapshotIdRegexp.MatchString(snapshotId)
+}
+
+func (s *Store) Save(snapshotId string, write func(w io.Writer) error) (err error) {
+	if err = os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, snapshotId+"_*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(snapshotId))
+}
+
+func (s *Store) Open(snapshotId string) (io.ReadCloser, error) {
+	if !ValidSnapshotId(snapshotId) {
+		return nil, ErrNotFound
+	}
+	file, err := os.Open(s.path(snapshotId))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *Store) Delete(snapshotId string) error {
+	if !ValidSnapshotId(snapshotId) {
+		return ErrNotFound
+	}
+	err := os.Remove(s.path(snapshotId))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// List айди снапшотов от новых к старым, айди сортируются так же, как время создания
+func (s *Store) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshotIds []string
+	for _, entry := range entries {
+		snapshotId, ok := strings.CutSuffix(entry.Name(), archiveExt)
+		if ok && !entry.IsDir() && ValidSnapshotId(snapshotId) {
+			snapshotIds = append(snapshotIds, snapshotId)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(snapshotIds)))
+	return snapshotIds, nil
+}
+
+func (s *Store) path(snapshotId string) string {
+	return filepath.Join(s.dir, snapshotId+archiveExt)
+}
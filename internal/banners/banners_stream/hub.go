@@ -0,0 +1,91 @@
+package banners_stream
+
+import (
+	"avito/assignment/internal/models"
+	"context"
+	"sync"
+)
+
+type ChangesRepository interface {
+	SubscribeBannerChanges(ctx context.Context) <-chan models.BannerChange
+	GetBannerChanges(ctx context.Context, afterEventId string) ([]models.BannerChange, error)
+}
+
+// Hub держит одну подписку на redis на реплику и раздает события всем локальным sse клиентам
+type Hub struct {
+	changesRepo ChangesRepository
+
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+}
+
+type Subscriber struct {
+	Changes chan models.BannerChange
+}
+
+const subscriberBufferSize = 64
+
+func NewHub(changesRepo ChangesRepository) *Hub {
+	return &Hub{
+		changesRepo: changesRepo,
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+// Run раздает события, пока не закроется ctx, после этого закрывает каналы всех подписчиков
+func (h *Hub) Run(ctx context.Context) {
+	changes := h.changesRepo.SubscribeBannerChanges(ctx)
+	for change := range changes {
+		h.broadcast(change)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for subscriber := range h.subscribers {
+		close(subscriber.Changes)
+		delete(h.subscribers, subscriber)
+	}
+}
+
+func (h *Hub) broadcast(change models.BannerChange) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for subscriber := range h.subscribers {
+		select {
+		case subscriber.Changes <- change:
+		default:
+			// медленный клиент не должен тормозить остальных, он догонит пропущенное через Last-Event-ID
+		}
+	}
+}
+
+// Subscribe возвращает nil, если хаб уже остановлен
+func (h *Hub) Subscribe() *Subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+
+	subscriber := &Subscriber{Changes: make(chan models.BannerChange, subscriberBufferSize)}
+	h.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[subscriber]; ok {
+		close(subscriber.Changes)
+		delete(h.subscribers, subscriber)
+	}
+}
+
+func (h *Hub) Replay(ctx context.Context, afterEventId string) ([]models.BannerChange, error) {
+	return h.changesRepo.GetBannerChanges(ctx, afterEventId)
+}
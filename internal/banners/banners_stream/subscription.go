@@ -0,0 +1,101 @@
+package banners_stream
+
+import (
+	"avito/assignment/internal/models"
+	"strconv"
+	"strings"
+)
+
+type Pair struct {
+	TagId     models.TagId
+	FeatureId models.FeatureId
+}
+
+// Subscription набор пар (tag_id, feature_id) и целых тэгов, за которыми следит клиент
+type Subscription struct {
+	Pairs  []Pair
+	TagIds []models.TagId
+}
+
+// Update изменение по одной паре, Banner = nil если на паре больше нет баннера
+type Update struct {
+	TagId     models.TagId
+	FeatureId models.FeatureId
+	Banner    *models.FullBanner
+}
+
+func (s *Subscription) Empty() bool {
+	return len(s.Pairs) == 0 && len(s.TagIds) == 0
+}
+
+func (s *Subscription) Match(tagId models.TagId, featureId models.FeatureId) bool {
+	for _, id := range s.TagIds {
+		if id == tagId {
+			return true
+		}
+	}
+	for _, pair := range s.Pairs {
+		if pair.TagId == tagId && pair.FeatureId == featureId {
+			return true
+		}
+	}
+	return false
+}
+
+// Updates раскладывает событие на изменения по парам, на которые подписан клиент
+func (s *Subscription) Updates(change *models.BannerChange) []Update {
+	var updates []Update
+	current := make(map[Pair]bool)
+
+	if change.Banner != nil {
+		for _, tagId := range change.Banner.TagIds {
+			current[Pair{TagId: tagId, FeatureId: change.Banner.FeatureId}] = true
+			if s.Match(tagId, change.Banner.FeatureId) {
+				updates = append(updates, Update{TagId: tagId, FeatureId: change.Banner.FeatureId, Banner: change.Banner})
+			}
+		}
+	}
+
+	for _, tagId := range change.PrevTagIds {
+		pair := Pair{TagId: tagId, FeatureId: change.PrevFeatureId}
+		if !current[pair] && s.Match(tagId, change.PrevFeatureId) {
+			updates = append(updates, Update{TagId: tagId, FeatureId: change.PrevFeatureId})
+		}
+	}
+
+	return updates
+}
+
+// IsAfter сравнивает id событий redis stream вида "<ms>-<seq>"
+func IsAfter(eventId, lastEventId string) bool {
+	if lastEventId == "" {
+		return true
+	}
+
+	ms, seq := splitEventId(eventId)
+	lastMs, lastSeq := splitEventId(lastEventId)
+	if ms != lastMs {
+		return ms > lastMs
+	}
+	return seq > lastSeq
+}
+
+// ValidEventId id события redis stream: "<ms>-<seq>" или "<ms>"
+func ValidEventId(eventId string) bool {
+	msPart, seqPart, found := strings.Cut(eventId, "-")
+	if _, err := strconv.ParseUint(msPart, 10, 64); err != nil {
+		return false
+	}
+	if !found {
+		return true
+	}
+	_, err := strconv.ParseUint(seqPart, 10, 64)
+	return err == nil
+}
+
+func splitEventId(eventId string) (uint64, uint64) {
+	msPart, seqPart, _ := strings.Cut(eventId, "-")
+	ms, _ := strconv.ParseUint(msPart, 10, 64)
+	seq, _ := strconv.ParseUint(seqPart, 10, 64)
+	return ms, seq
+}
@@ -0,0 +1,141 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/banners/banners_repository"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+// Batch
+// 1. Выполняем операции по порядку в одной транзакции так же, как одиночные ручки, но без CheckExist,
+// промежуточные состояния вроде "переношу баннер на пару, которую освобождает следующая операция" допустимы
+// 2. Уникальность пар (тэг, фича) проверяем один раз по итоговому состоянию добавленных и измененных баннеров
+// 3. Любая ошибка откатывает весь батч и возвращается как BatchOperationError с номером операции,
+// конфликт итогового состояния приписывается последней операции, которая тронула один из конфликтующих баннеров
+// 4. После коммита сбрасываем ключи в редисе и рассылаем изменения в порядке операций
+func (b *BannersUC) Batch(ctx context.Context, operations []BatchOperation, actor string) ([]BatchOperationResult, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.Batch")
+	defer span.End()
+
+	var results []BatchOperationResult
+	var changes []*models.BannerChange
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		results = make([]BatchOperationResult, 0, len(operations))
+		changes = make([]*models.BannerChange, 0, len(operations))
+		// последняя операция, которая добавила или изменила баннер, удаленные баннеры отсюда убираются
+		touchedBy := make(map[models.BannerId]int)
+
+		for i := range operations {
+			change, err := b.batchOperation(ctx, span, &operations[i], actor)
+			if err != nil {
+				return &BatchOperationError{Index: i, Type: operations[i].Type, Err: err}
+			}
+			changes = append(changes, change)
+			results = append(results, BatchOperationResult{Type: operations[i].Type, BannerId: change.BannerId})
+			if change.Type == models.BannerChangeDeleted {
+				delete(touchedBy, change.BannerId)
+			} else {
+				touchedBy[change.BannerId] = i
+			}
+		}
+		if len(touchedBy) == 0 {
+			return nil
+		}
+
+		bannerIds := make([]models.BannerId, 0, len(touchedBy))
+		for bannerId := range touchedBy {
+			bannerIds = append(bannerIds, bannerId)
+		}
+		conflicts, err := b.bannersPGRepo.GetPairConflicts(ctx, bannerIds)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) == 0 {
+			return nil
+		}
+
+		index := 0
+		for _, conflict := range conflicts {
+			for _, bannerId := range conflict.BannerIds {
+				if touched, ok := touchedBy[models.BannerId(bannerId)]; ok && touched > index {
+					index = touched
+				}
+			}
+		}
+		return &BatchOperationError{
+			Index: index,
+			Type:  operations[index].Type,
+			Err: traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New(formatPairConflicts(conflicts)), "BannersUC.Batch.AlreadyExists"),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		b.dropCachedBanner(ctx, change)
+		b.publishChange(ctx, change)
+	}
+	return results, nil
+}
+
+// batchOperation одна операция батча, должна вызываться внутри транзакции
+func (b *BannersUC) batchOperation(ctx context.Context, span trace.Span, operation *BatchOperation, actor string) (*models.BannerChange, error) {
+	switch operation.Type {
+	case BatchAdd:
+		addBanner := operation.Add
+		err := b.validateTemplates(span, "BannersUC.Batch.InvalidTemplate",
+			&addBanner.Content.Title, &addBanner.Content.Text, &addBanner.Content.Url)
+		if err != nil {
+			return nil, err
+		}
+		if err = addBanner.resolveStatus(); err != nil {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.Batch.WrongStatus")
+		}
+		err = b.validateReferences(ctx, span, "BannersUC.Batch.UnknownReference", addBanner.TagIds, &addBanner.FeatureId)
+		if err != nil {
+			return nil, err
+		}
+		return b.insertBanner(ctx, addBanner)
+	case BatchPatch:
+		patchBanner := operation.Patch
+		err := b.validateTemplates(span, "BannersUC.Batch.InvalidTemplate", patchBanner.Title, patchBanner.Text, patchBanner.Url)
+		if err != nil {
+			return nil, err
+		}
+		return b.patchBanner(ctx, span, patchBanner, false)
+	case BatchDelete:
+		return b.deleteBanner(ctx, span, operation.BannerId, actor)
+	case BatchRollback:
+		banner, err := b.bannersPGRepo.GetBannerVersions(ctx, operation.BannerId, []int64{operation.Version})
+		if err != nil {
+			return nil, err
+		}
+		if len(*banner) == 0 {
+			return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				fmt.Errorf("impossible to rollback, banner with id %d and version %d doesnt exist", operation.BannerId, operation.Version), "BannersUC.Batch.DoNotExist")
+		}
+		patchBannerParams := ToPatchBanner((*banner)[0])
+		patchBannerParams.ExpectedVersion = operation.ExpectedVersion
+		return b.patchBanner(ctx, span, patchBannerParams, false)
+	}
+
+	return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+		fmt.Errorf("unknown operation %q", operation.Type), "BannersUC.Batch.UnknownOperation")
+}
+
+func formatPairConflicts(conflicts []banners_repository.PairConflict) string {
+	descriptions := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		descriptions[i] = fmt.Sprintf("tag %d and feature %d are used by banners %v", conflict.TagId, conflict.FeatureId, []int64(conflict.BannerIds))
+	}
+	return strings.Join(descriptions, "; ")
+}
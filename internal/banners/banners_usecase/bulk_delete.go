@@ -0,0 +1,167 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/banners/banners_repository"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// BulkDeleteJobType тип задачи раннера, под ним регистрируется HandleBulkDelete
+const BulkDeleteJobType = "banners.bulk_delete"
+
+// BulkDelete ставит в очередь удаление всех баннеров фичи и/или тэга, само удаление делает HandleBulkDelete
+func (b *BannersUC) BulkDelete(ctx context.Context, bulkDeleteParams *BulkDelete) (models.JobId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.BulkDelete")
+	defer span.End()
+
+	if bulkDeleteParams.FeatureId == nil && bulkDeleteParams.TagId == nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New("feature_id or tag_id is required"), "BannersUC.BulkDelete.EmptyFilter")
+	}
+
+	return b.jobs.Enqueue(ctx, BulkDeleteJobType, bulkDeleteParams.ToBulkDeletePayload())
+}
+
+func (b *BannersUC) GetBulkDeleteJob(ctx context.Context, jobId models.JobId) (*models.BulkDeleteJob, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetBulkDeleteJob")
+	defer span.End()
+
+	job, err := b.jobs.GetJob(ctx, jobId)
+	if err != nil {
+		return nil, err
+	}
+	if job.Type != BulkDeleteJobType {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("bulk delete job with id %d doesnt exist", jobId), "BannersUC.GetBulkDeleteJob.DoNotExist")
+	}
+
+	bulkDeleteJob, err := toBulkDeleteJob(job)
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersUC.GetBulkDeleteJob.Unmarshal")
+	}
+
+	return bulkDeleteJob, nil
+}
+
+// HandleBulkDelete обработчик задачи массового удаления
+// 1. Считаем, сколько баннеров под фильтром, при повторе попытки счет начинается заново с оставшихся
+// 2. Пачками переносим баннеры в корзину так же, как DeleteBanner: ключи в редисе, журнал изменений
+// 3. Каждая пачка - своя транзакция, после коммита пишем прогресс и рассылаем изменения
+// 4. Пачку, упавшую на сбое базы или редиса, повторяем, прежде чем отдать ошибку раннеру
+// 5. Между пачками проверяем отмену, уже удаленное остается удаленным
+// 6. Пачки идут по возрастанию banner_id, поэтому пропущенный баннер не выбирается снова и задача всегда заканчивается
+func (b *BannersUC) HandleBulkDelete(ctx context.Context, payload BulkDeletePayload, progress JobProgress) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.HandleBulkDelete")
+	defer span.End()
+
+	filter := payload.ToBannersFilter()
+	total, err := b.bannersPGRepo.CountBannersByFilter(ctx, filter)
+	if err != nil {
+		return err
+	}
+	state := BulkDeleteProgress{Total: total}
+	if err = progress.Report(ctx, state); err != nil {
+		return err
+	}
+
+	var after models.BannerId
+	for {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		batch, err := b.deleteBulkBatchWithRetry(ctx, span, filter, after, payload.Actor)
+		if err != nil {
+			return err
+		}
+		if batch.selected == 0 {
+			return nil
+		}
+
+		for _, change := range batch.changes {
+			b.publishChange(ctx, change)
+		}
+		after = batch.last
+		state.Deleted += int64(len(batch.changes))
+		if err = progress.Report(ctx, state); err != nil {
+			return err
+		}
+	}
+}
+
+type bulkDeleteBatch struct {
+	// selected сколько баннеров попало в пачку, включая удаленные мимо задачи
+	selected int64
+	// last последний айди пачки, следующая пачка начинается после него
+	last    models.BannerId
+	changes []*models.BannerChange
+}
+
+func (b *BannersUC) deleteBulkBatchWithRetry(ctx context.Context, span trace.Span, filter *banners_repository.BannersFilter, after models.BannerId, actor string) (*bulkDeleteBatch, error) {
+	backoff := time.Duration(b.cfg.BulkDelete.RetryBackoffMs) * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		batch, err := b.deleteBulkBatch(ctx, span, filter, after, actor)
+		if err == nil || !isTransient(err) || attempt >= b.cfg.BulkDelete.BatchAttempts {
+			return batch, err
+		}
+		log.Warnf("BannersUC.HandleBulkDelete: batch attempt %d failed: %s", attempt, err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff * time.Duration(attempt)):
+		}
+	}
+}
+
+// deleteBulkBatch пачка в своей транзакции. Баннер, который успели удалить мимо задачи, пропускаем:
+// цель задачи - чтобы его не было, но в Deleted попадают только баннеры, перенесенные в корзину этой задачей
+func (b *BannersUC) deleteBulkBatch(ctx context.Context, span trace.Span, filter *banners_repository.BannersFilter, after models.BannerId, actor string) (*bulkDeleteBatch, error) {
+	batch := &bulkDeleteBatch{}
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		bannerIds, err := b.bannersPGRepo.GetBannerIdsByFilter(ctx, filter, after, b.cfg.BulkDelete.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		batch.selected = int64(len(bannerIds))
+		if len(bannerIds) != 0 {
+			batch.last = bannerIds[len(bannerIds)-1]
+		}
+		batch.changes = make([]*models.BannerChange, 0, len(bannerIds))
+		for _, bannerId := range bannerIds {
+			change, err := b.deleteBanner(ctx, span, bannerId, actor)
+			if errors.Is(err, errlst.HttpErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			batch.changes = append(batch.changes, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// isTransient сбой базы или редиса, а не ошибка в самих данных: ее имеет смысл повторить
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var fiberErr *fiber.Error
+	return !errors.As(err, &fiberErr) || fiberErr.Code >= fiber.StatusInternalServerError
+}
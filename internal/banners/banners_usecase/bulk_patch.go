@@ -0,0 +1,108 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+)
+
+// errDryRun откатывает транзакцию dry run, наружу не уходит
+var errDryRun = errors.New("bulk patch dry run")
+
+// BulkPatch
+// 1. Проверяем фильтр, патч и шаблоны в нем
+// 2. В одной транзакции берем все баннеры под фильтром, больше cfg.BulkPatch.MaxBanners - отказ
+// 3. Баннеры, которые уже в нужном состоянии, пропускаем, остальные патчим как PatchBanner, с версией и журналом
+// 4. Dry run проходит тот же путь с проверками и записью, собирает превью и всегда откатывает транзакцию
+// 5. После коммита сбрасываем ключи затронутых баннеров в редисе и рассылаем изменения
+func (b *BannersUC) BulkPatch(ctx context.Context, bulkPatchParams *BulkPatch) (*BulkPatchResult, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.BulkPatch")
+	defer span.End()
+
+	if bulkPatchParams.FeatureId == nil && bulkPatchParams.TagId == nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New("feature_id or tag_id is required"), "BannersUC.BulkPatch.EmptyFilter")
+	}
+	if bulkPatchParams.Url != nil && bulkPatchParams.UrlRewrite != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New("url and url_rewrite cant be used together"), "BannersUC.BulkPatch.UrlConflict")
+	}
+	if bulkPatchParams.Title == nil && bulkPatchParams.Text == nil && bulkPatchParams.Url == nil &&
+		bulkPatchParams.UrlRewrite == nil && bulkPatchParams.IsActive == nil && bulkPatchParams.Status == nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New("nothing to update"), "BannersUC.BulkPatch.NothingToUpdate")
+	}
+	err := b.validateTemplates(span, "BannersUC.BulkPatch.InvalidTemplate",
+		bulkPatchParams.Title, bulkPatchParams.Text, bulkPatchParams.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkPatchResult{DryRun: bulkPatchParams.DryRun}
+	var changes []*models.BannerChange
+	err = b.trManager.Do(ctx, func(ctx context.Context) error {
+		filter := bulkPatchParams.ToBannersFilter()
+		matched, err := b.bannersPGRepo.CountBannersByFilter(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if matched > int64(b.cfg.BulkPatch.MaxBanners) {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("filter matches %d banners, at most %d can be patched at once", matched, b.cfg.BulkPatch.MaxBanners), "BannersUC.BulkPatch.TooManyBanners")
+		}
+		result.Matched = matched
+
+		bannerIds, err := b.bannersPGRepo.GetBannerIdsByFilter(ctx, filter, 0, b.cfg.BulkPatch.MaxBanners)
+		if err != nil {
+			return err
+		}
+
+		result.BannerIds = make([]models.BannerId, 0, len(bannerIds))
+		changes = make([]*models.BannerChange, 0, len(bannerIds))
+		for _, bannerId := range bannerIds {
+			prevBanner, err := b.bannersPGRepo.GetBannerById(ctx, bannerId)
+			if err != nil {
+				return err
+			}
+			patch := bulkPatchParams.ToPatchBanner(prevBanner)
+			if patch == nil {
+				continue
+			}
+			if bulkPatchParams.UrlRewrite != nil {
+				if err = b.checkTemplates(nil, nil, patch.Url); err != nil {
+					return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+						fmt.Errorf("banner %d: %w", bannerId, err), "BannersUC.BulkPatch.InvalidTemplate")
+				}
+			}
+			result.BannerIds = append(result.BannerIds, bannerId)
+
+			change, err := b.patchBanner(ctx, span, patch, true)
+			if err != nil {
+				return err
+			}
+			if bulkPatchParams.DryRun {
+				result.Previews = append(result.Previews, BulkPatchPreview{Before: *prevBanner, After: *change.Banner})
+				continue
+			}
+			changes = append(changes, change)
+		}
+		if bulkPatchParams.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		b.dropCachedBanner(ctx, change)
+		b.publishChange(ctx, change)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+)
+
+// CloneBanner
+// 1. Берем исходный баннер, не переданные тэги, фича и активность берутся у него. Копия без тэгов не создается
+// 2. Проверяем, что тэги и фича заведены в реестрах
+// 3. CheckExist по всем парам (тэг, фича) копии, при конфликтах ничего не пишем и отдаем их списком
+// 4. Добавляем копию так же, как AddBanner, изменение рассылаем после коммита
+func (b *BannersUC) CloneBanner(ctx context.Context, cloneParams *CloneBanner) (*CloneResult, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.CloneBanner")
+	defer span.End()
+
+	result := &CloneResult{}
+	var change *models.BannerChange
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		source, err := b.bannersPGRepo.GetBannerById(ctx, cloneParams.BannerId)
+		if err != nil {
+			return err
+		}
+		if source.BannerId == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				errors.New(fmt.Sprintf("impossible to clone, banner with id %d doesnt exist", cloneParams.BannerId)), "BannersUC.CloneBanner.DoNotExist")
+		}
+
+		addBannerParams := cloneParams.ToAddBanner(source)
+		if len(addBannerParams.TagIds) == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New("source banner has no tags, tag_ids is required"), "BannersUC.CloneBanner.NilTagIds")
+		}
+		if err = addBannerParams.resolveStatus(); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.CloneBanner.WrongStatus")
+		}
+		err = b.validateReferences(ctx, span, "BannersUC.CloneBanner.UnknownReference", addBannerParams.TagIds, &addBannerParams.FeatureId)
+		if err != nil {
+			return err
+		}
+
+		existBanners, err := b.bannersPGRepo.CheckExist(ctx, addBannerParams.TagIds, addBannerParams.FeatureId)
+		if err != nil {
+			return err
+		}
+		if len(*existBanners) != 0 {
+			result.Conflicts = *existBanners
+			return nil
+		}
+
+		change, err = b.insertBanner(ctx, addBannerParams)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if change == nil {
+		return result, nil
+	}
+
+	b.publishChange(ctx, change)
+	result.BannerId = change.BannerId
+	return result, nil
+}
@@ -0,0 +1,283 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"avito/assignment/pkg/utilities"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"slices"
+)
+
+// requiresApproval баннеры фичи меняются только через одобренный черновик
+func (b *BannersUC) requiresApproval(featureId models.FeatureId) bool {
+	return slices.Contains(b.cfg.Drafts.ApprovalFeatureIds, int64(featureId))
+}
+
+// checkDraftActor черновики ведут только админы с персональными токенами, по общему токену роли автора от проверяющего не отличить
+func checkDraftActor(span trace.Span, actor, errorPlace string) error {
+	if actor == "" {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrForbidden,
+			errors.New("drafts can be managed only with a personal admin token"), errorPlace)
+	}
+	return nil
+}
+
+// SaveDraft
+// 1. Патч накладывается на открытый черновик баннера, если его нет - на живой баннер
+// 2. Проверяем, что тэги и фича заведены в реестрах, уникальность пар проверяется при публикации
+// 3. Черновик всегда делается поверх текущей версии живого баннера, повторное сохранение переносит его на свежую версию
+// 4. Правка черновика сбрасывает одобрение, автором становится тот, кто сохранил
+// 5. Если первый черновик баннера параллельно завел кто-то еще, отдаем 409, сохранение можно повторить поверх него
+func (b *BannersUC) SaveDraft(ctx context.Context, patchBannerParams *PatchBanner, actor string) (models.DraftId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.SaveDraft")
+	defer span.End()
+
+	if err := checkDraftActor(span, actor, "BannersUC.SaveDraft.NoActor"); err != nil {
+		return 0, err
+	}
+
+	err := b.validateTemplates(span, "BannersUC.SaveDraft.InvalidTemplate",
+		patchBannerParams.Title, patchBannerParams.Text, patchBannerParams.Url)
+	if err != nil {
+		return 0, err
+	}
+
+	var draftId models.DraftId
+	err = b.trManager.Do(ctx, func(ctx context.Context) error {
+		live, err := b.bannersPGRepo.GetBannerById(ctx, patchBannerParams.BannerId)
+		if err != nil {
+			return err
+		}
+		if live.BannerId == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				errors.New(fmt.Sprintf("impossible to draft, banner with id %d doesnt exist", patchBannerParams.BannerId)), "BannersUC.SaveDraft.ErrNotFound")
+		}
+		if err = checkExpectedVersion(span, live, patchBannerParams.ExpectedVersion); err != nil {
+			return err
+		}
+
+		draft, err := b.bannersPGRepo.GetOpenDraft(ctx, live.BannerId)
+		if err != nil {
+			return err
+		}
+		base := *live
+		if draft != nil {
+			base = draft.Banner
+		}
+		if err = patchBannerParams.resolveStatus(base.Status); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.SaveDraft.WrongStatus")
+		}
+		next := patchBannerParams.Apply(base)
+		next.TagIds = utilities.RemoveDuplicates(next.TagIds)
+
+		err = b.validateReferences(ctx, span, "BannersUC.SaveDraft.UnknownReference", next.TagIds, &next.FeatureId)
+		if err != nil {
+			return err
+		}
+		if ToDiffPatchBanner(live, &next).Check(live) {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New(fmt.Sprintf("draft of banner %d doesnt differ from the live banner", live.BannerId)), "BannersUC.SaveDraft.NothingToUpdate")
+		}
+
+		if draft == nil {
+			draftId, err = b.bannersPGRepo.AddDraft(ctx, &models.BannerDraft{
+				BannerId:    live.BannerId,
+				BaseVersion: live.Version,
+				Banner:      next,
+				Status:      models.DraftPending,
+				Author:      actor,
+			})
+			if err != nil {
+				return err
+			}
+			if draftId == 0 {
+				return traces.SpanSetErrWrap(span, errlst.HttpErrConflict,
+					errors.New(fmt.Sprintf("banner %d got another draft concurrently, save again", live.BannerId)), "BannersUC.SaveDraft.Conflict")
+			}
+			return nil
+		}
+
+		draft.BaseVersion = live.Version
+		draft.Banner = next
+		draft.Author = actor
+		draftId = draft.DraftId
+		return b.bannersPGRepo.UpdateDraftContent(ctx, draft)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return draftId, nil
+}
+
+func (b *BannersUC) GetDrafts(ctx context.Context, getDraftsParams *GetDrafts) ([]models.BannerDraft, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetDrafts")
+	defer span.End()
+
+	if getDraftsParams.Status == "" {
+		getDraftsParams.Status = models.DraftPending
+	}
+
+	return b.bannersPGRepo.GetDrafts(ctx, getDraftsParams.ToGetDraftsPostgres())
+}
+
+// GetDraftDiff черновик, живой баннер и поля, которые поменяются при публикации
+func (b *BannersUC) GetDraftDiff(ctx context.Context, draftId models.DraftId) (*DraftDiff, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetDraftDiff")
+	defer span.End()
+
+	diff := &DraftDiff{}
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		draft, err := b.getDraft(ctx, span, draftId, "BannersUC.GetDraftDiff.DoNotExist")
+		if err != nil {
+			return err
+		}
+		live, err := b.bannersPGRepo.GetBannerById(ctx, draft.BannerId)
+		if err != nil {
+			return err
+		}
+		if live.BannerId == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				errors.New(fmt.Sprintf("banner with id %d of draft %d doesnt exist", draft.BannerId, draftId)), "BannersUC.GetDraftDiff.ErrNotFound")
+		}
+
+		diff.Draft = draft
+		diff.Live = live
+		diff.Changes = toDraftChanges(live, &draft.Banner)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// ApproveDraft одобрить можно только ожидающий черновик и только не его автору, оба известны по персональным токенам
+func (b *BannersUC) ApproveDraft(ctx context.Context, draftId models.DraftId, actor string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.ApproveDraft")
+	defer span.End()
+
+	if err := checkDraftActor(span, actor, "BannersUC.ApproveDraft.NoActor"); err != nil {
+		return err
+	}
+
+	return b.trManager.Do(ctx, func(ctx context.Context) error {
+		draft, err := b.getDraft(ctx, span, draftId, "BannersUC.ApproveDraft.DoNotExist")
+		if err != nil {
+			return err
+		}
+		if draft.Status != models.DraftPending {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New(fmt.Sprintf("draft %d is %s, only pending drafts can be approved", draftId, draft.Status)), "BannersUC.ApproveDraft.WrongStatus")
+		}
+		if draft.Author == actor {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrForbidden,
+				errors.New(fmt.Sprintf("draft %d must be approved by someone other than its author %s", draftId, actor)), "BannersUC.ApproveDraft.SelfApproval")
+		}
+
+		return b.bannersPGRepo.ReviewDraft(ctx, draftId, models.DraftApproved, actor, "")
+	})
+}
+
+// RejectDraft закрывает открытый черновик, автор может так отозвать свой черновик
+func (b *BannersUC) RejectDraft(ctx context.Context, draftId models.DraftId, actor, comment string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.RejectDraft")
+	defer span.End()
+
+	if err := checkDraftActor(span, actor, "BannersUC.RejectDraft.NoActor"); err != nil {
+		return err
+	}
+
+	return b.trManager.Do(ctx, func(ctx context.Context) error {
+		draft, err := b.getDraft(ctx, span, draftId, "BannersUC.RejectDraft.DoNotExist")
+		if err != nil {
+			return err
+		}
+		if !draft.IsOpen() {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New(fmt.Sprintf("draft %d is already %s", draftId, draft.Status)), "BannersUC.RejectDraft.WrongStatus")
+		}
+
+		return b.bannersPGRepo.ReviewDraft(ctx, draftId, models.DraftRejected, actor, comment)
+	})
+}
+
+// PublishDraft
+// 1. Лочим черновик, публиковать можно только открытый
+// 2. Для фич с обязательным одобрением черновик должен быть одобрен
+// 3. Если живой баннер изменился после сохранения черновика, отдаем 412, черновик нужно пересохранить
+// 4. Применяем разницу черновика с живым баннером так же, как PatchBanner, и закрываем черновик в той же транзакции
+// 5. После коммита сбрасываем кэш и рассылаем изменение
+func (b *BannersUC) PublishDraft(ctx context.Context, draftId models.DraftId, actor string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.PublishDraft")
+	defer span.End()
+
+	if err := checkDraftActor(span, actor, "BannersUC.PublishDraft.NoActor"); err != nil {
+		return err
+	}
+
+	var change *models.BannerChange
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		draft, err := b.getDraft(ctx, span, draftId, "BannersUC.PublishDraft.DoNotExist")
+		if err != nil {
+			return err
+		}
+		if !draft.IsOpen() {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New(fmt.Sprintf("draft %d is already %s", draftId, draft.Status)), "BannersUC.PublishDraft.WrongStatus")
+		}
+
+		live, err := b.bannersPGRepo.GetBannerById(ctx, draft.BannerId)
+		if err != nil {
+			return err
+		}
+		if live.BannerId == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				errors.New(fmt.Sprintf("impossible to publish, banner with id %d doesnt exist", draft.BannerId)), "BannersUC.PublishDraft.ErrNotFound")
+		}
+		if draft.Status != models.DraftApproved && (b.requiresApproval(live.FeatureId) || b.requiresApproval(draft.Banner.FeatureId)) {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrForbidden,
+				errors.New(fmt.Sprintf("draft %d must be approved before publishing", draftId)), "BannersUC.PublishDraft.ApprovalRequired")
+		}
+		if live.Version != draft.BaseVersion {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrPreconditionFailed,
+				errors.New(fmt.Sprintf("banner %d has version %d, draft %d was made over version %d, save the draft again", live.BannerId, live.Version, draftId, draft.BaseVersion)),
+				"BannersUC.PublishDraft.Stale")
+		}
+
+		patchBannerParams := ToDiffPatchBanner(live, &draft.Banner)
+		patchBannerParams.viaDraft = true
+		change, err = b.patchBanner(ctx, span, patchBannerParams, true)
+		if err != nil {
+			return err
+		}
+
+		return b.bannersPGRepo.MarkDraftPublished(ctx, draftId, actor)
+	})
+	if err != nil {
+		return err
+	}
+
+	b.dropCachedBanner(ctx, change)
+	b.publishChange(ctx, change)
+	return nil
+}
+
+// getDraft черновик под блокировкой, до конца транзакции его не поменяет параллельный запрос
+func (b *BannersUC) getDraft(ctx context.Context, span trace.Span, draftId models.DraftId, errorPlace string) (*models.BannerDraft, error) {
+	draft, err := b.bannersPGRepo.GetDraft(ctx, draftId)
+	if err != nil {
+		return nil, err
+	}
+	if draft == nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			errors.New(fmt.Sprintf("draft with id %d doesnt exist", draftId)), errorPlace)
+	}
+	return draft, nil
+}
@@ -4,6 +4,10 @@ import (
 	"avito/assignment/internal/banners/banners_repository"
 	"avito/assignment/internal/models"
 	"avito/assignment/pkg/utilities"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 )
 
 type GetBanner struct {
@@ -11,22 +15,91 @@ type GetBanner struct {
 	FeatureId      models.FeatureId
 	UseLastVersion bool
 	AuthToken      string
+	Attributes     map[string]string
 }
 
 type GetManyBanner struct {
-	FeatureId *models.FeatureId
-	TagId     *models.TagId
-	Limit     *int
-	Offset    *int
+	FeatureId     *models.FeatureId
+	TagId         *models.TagId
+	FeatureIds    []models.FeatureId
+	TagIds        []models.TagId
+	IsActive      *bool
+	Status        []string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Title         *string
+	Url           *string
+	Limit         *int
+	Offset        *int
+	SortBy        string
+	SortDesc      bool
+	// After позиция, с которой продолжается выдача, при ней Offset игнорируется
+	After *PageKey
+	// WithTotal посчитать общее число баннеров под фильтром
+	WithTotal bool
+	// Search полнотекстовый поиск, без SortBy выдача идет по убыванию релевантности
+	Search         *string
+	SearchVersions bool
+}
+
+// PageKey позиция в выдаче GetManyBanner: ключ сортировки и banner_id последнего отданного баннера.
+// Backward - страница перед этой позицией
+type PageKey struct {
+	SortValue string          `json:"v,omitempty"`
+	BannerId  models.BannerId `json:"id"`
+	Backward  bool            `json:"b,omitempty"`
+}
+
+// BannersPage страница GetManyBanner, Next и Prev nil, если в эту сторону баннеров нет, Total только по WithTotal.
+// Matches есть только у поиска
+type BannersPage struct {
+	Banners []models.FullBanner
+	Next    *PageKey
+	Prev    *PageKey
+	Total   *int64
+	Matches map[models.BannerId]banners_repository.SearchMatch
 }
 
 func (b *GetManyBanner) ToGetManyPostgresBanner() *banners_repository.GetManyPostgresBanner {
 	return &banners_repository.GetManyPostgresBanner{
-		TagId:     b.TagId,
-		FeatureId: b.FeatureId,
-		Limit:     b.Limit,
-		Offset:    b.Offset,
+		BannersFilter: banners_repository.BannersFilter{
+			FeatureId:      b.FeatureId,
+			TagId:          b.TagId,
+			FeatureIds:     b.FeatureIds,
+			TagIds:         b.TagIds,
+			IsActive:       b.IsActive,
+			Status:         b.Status,
+			CreatedAfter:   b.CreatedAfter,
+			CreatedBefore:  b.CreatedBefore,
+			UpdatedAfter:   b.UpdatedAfter,
+			UpdatedBefore:  b.UpdatedBefore,
+			Title:          b.Title,
+			Url:            b.Url,
+			Search:         b.Search,
+			SearchVersions: b.SearchVersions,
+		},
+		Limit:    b.Limit,
+		Offset:   b.Offset,
+		SortBy:   b.SortBy,
+		SortDesc: b.SortDesc,
+		After:    b.After.ToKeyset(),
+	}
+}
+
+func (k *PageKey) ToKeyset() *banners_repository.Keyset {
+	if k == nil {
+		return nil
+	}
+	return &banners_repository.Keyset{SortValue: k.SortValue, BannerId: k.BannerId, Backward: k.Backward}
+}
+
+func (p *BannersPage) pageKey(banner *models.FullBanner, sortBy string, backward bool) *PageKey {
+	if sortBy == banners_repository.SearchRank {
+		return &PageKey{SortValue: banners_repository.FormatRank(p.Matches[banner.BannerId].Rank), BannerId: banner.BannerId, Backward: backward}
 	}
+	return &PageKey{SortValue: banners_repository.SortValue(banner, sortBy), BannerId: banner.BannerId, Backward: backward}
 }
 
 type AddBanner struct {
@@ -38,6 +111,8 @@ type AddBanner struct {
 		Url   string
 	}
 	IsActive bool
+	// Status пустой берется из IsActive
+	Status string
 }
 
 func (b *AddBanner) ToAddBannerPostgres() *banners_repository.AddPostgresBanner {
@@ -47,9 +122,26 @@ func (b *AddBanner) ToAddBannerPostgres() *banners_repository.AddPostgresBanner
 		Text:      b.Content.Text,
 		Url:       b.Content.Url,
 		IsActive:  b.IsActive,
+		Status:    b.Status,
 	}
 }
 
+// resolveStatus сводит Status и IsActive нового баннера к одному статусу.
+// is_active по умолчанию false, поэтому противоречием считается только is_active true при неактивном статусе
+func (b *AddBanner) resolveStatus() error {
+	if b.Status == "" {
+		b.Status = models.StatusFromIsActive("", b.IsActive)
+	}
+	if !models.IsInitialStatus(b.Status) {
+		return fmt.Errorf("banner cant be created with status %s", b.Status)
+	}
+	if b.IsActive && b.Status != models.BannerStatusActive {
+		return fmt.Errorf("is_active true contradicts status %s", b.Status)
+	}
+	b.IsActive = b.Status == models.BannerStatusActive
+	return nil
+}
+
 func ToPutRedisBanner(fullBanner *models.FullBanner) *banners_repository.PutRedisBanner {
 	return &banners_repository.PutRedisBanner{
 		BannerId:  fullBanner.BannerId,
@@ -61,8 +153,10 @@ func ToPutRedisBanner(fullBanner *models.FullBanner) *banners_repository.PutRedi
 			Url   string
 		}{Title: fullBanner.Content.Title, Text: fullBanner.Content.Text, Url: fullBanner.Content.Url},
 		IsActive:  fullBanner.IsActive,
+		Status:    fullBanner.Status,
 		CreatedAt: fullBanner.CreatedAt,
 		UpdatedAt: fullBanner.UpdatedAt,
+		Version:   fullBanner.Version,
 	}
 }
 
@@ -73,7 +167,16 @@ type PatchBanner struct {
 	Text      *string
 	Url       *string
 	IsActive  *bool
-	BannerId  models.BannerId
+	// Status новый статус жизненного цикла, при одном IsActive выводится из него
+	Status   *string
+	BannerId models.BannerId
+	// ExpectedVersion версия, которую видел админ, при расхождении патч отклоняется с 412
+	ExpectedVersion *int64
+
+	// viaDraft патч - публикация черновика, для фич с обязательным одобрением других путей нет
+	viaDraft bool
+	// viaRollback патч - откат к версии, статус версии возвращается как есть, без проверки перехода
+	viaRollback bool
 }
 
 func (b *PatchBanner) ToPatchBanner(version int64) *banners_repository.UpdateBannerById {
@@ -83,22 +186,26 @@ func (b *PatchBanner) ToPatchBanner(version int64) *banners_repository.UpdateBan
 		Text:      b.Text,
 		Url:       b.Url,
 		IsActive:  b.IsActive,
+		Status:    b.Status,
 		BannerId:  b.BannerId,
 		Version:   version,
 	}
 }
 
 func (b *PatchBanner) Check(banner *models.FullBanner) bool {
-	if b.FeatureId == nil && b.IsActive == nil && b.TagIds == nil && b.Url == nil && b.Text == nil && b.Title == nil {
+	if b.FeatureId == nil && b.IsActive == nil && b.Status == nil && b.TagIds == nil && b.Url == nil && b.Text == nil && b.Title == nil {
 		return true
 	}
-	var maxCoincidence, currCoincidence int = 6, 0
+	var maxCoincidence, currCoincidence int = 7, 0
 	if b.FeatureId == nil || b.FeatureId != nil && *b.FeatureId == banner.FeatureId {
 		currCoincidence++
 	}
 	if b.IsActive == nil || b.IsActive != nil && *b.IsActive == banner.IsActive {
 		currCoincidence++
 	}
+	if b.Status == nil || b.Status != nil && *b.Status == banner.Status {
+		currCoincidence++
+	}
 	if b.Url == nil || b.Url != nil && *b.Url == banner.Content.Url {
 		currCoincidence++
 	}
@@ -118,14 +225,465 @@ func (b *PatchBanner) Check(banner *models.FullBanner) bool {
 	return currCoincidence == maxCoincidence
 }
 
+// Apply баннер после патча, без записи в базу
+func (b *PatchBanner) Apply(banner models.FullBanner) models.FullBanner {
+	if b.TagIds != nil {
+		banner.TagIds = *b.TagIds
+	}
+	if b.FeatureId != nil {
+		banner.FeatureId = *b.FeatureId
+	}
+	if b.Title != nil {
+		banner.Content.Title = *b.Title
+	}
+	if b.Text != nil {
+		banner.Content.Text = *b.Text
+	}
+	if b.Url != nil {
+		banner.Content.Url = *b.Url
+	}
+	if b.Status != nil {
+		banner.Status = *b.Status
+		banner.IsActive = *b.Status == models.BannerStatusActive
+	} else if b.IsActive != nil {
+		banner.Status = models.StatusFromIsActive(banner.Status, *b.IsActive)
+		banner.IsActive = *b.IsActive
+	}
+	return banner
+}
+
+// resolveStatus сводит Status и IsActive патча к одному статусу поверх текущего и проверяет переход, кроме отката
+func (b *PatchBanner) resolveStatus(current string) error {
+	if b.Status == nil && b.IsActive == nil {
+		return nil
+	}
+	var status string
+	if b.Status != nil {
+		status = *b.Status
+		if b.IsActive != nil && *b.IsActive != (status == models.BannerStatusActive) {
+			return fmt.Errorf("is_active %t contradicts status %s", *b.IsActive, status)
+		}
+	} else {
+		status = models.StatusFromIsActive(current, *b.IsActive)
+	}
+	if !b.viaRollback && !models.CanTransition(current, status) {
+		return fmt.Errorf("banner cant go from status %s to %s", current, status)
+	}
+	isActive := status == models.BannerStatusActive
+	b.Status, b.IsActive = &status, &isActive
+	return nil
+}
+
+// ToPatchBanner патч отката к версии banner
 func ToPatchBanner(banner models.FullBanner) *PatchBanner {
 	return &PatchBanner{
-		TagIds:    &banner.TagIds,
-		FeatureId: &banner.FeatureId,
-		Title:     &banner.Content.Title,
-		Text:      &banner.Content.Text,
-		Url:       &banner.Content.Url,
-		IsActive:  &banner.IsActive,
-		BannerId:  banner.BannerId,
+		TagIds:      &banner.TagIds,
+		FeatureId:   &banner.FeatureId,
+		Title:       &banner.Content.Title,
+		Text:        &banner.Content.Text,
+		Url:         &banner.Content.Url,
+		IsActive:    &banner.IsActive,
+		Status:      &banner.Status,
+		BannerId:    banner.BannerId,
+		viaRollback: true,
+	}
+}
+
+// ToDiffPatchBanner патч только из тех полей, которыми next отличается от prev, тэги сравниваются как множество
+func ToDiffPatchBanner(prev, next *models.FullBanner) *PatchBanner {
+	patchBanner := &PatchBanner{BannerId: prev.BannerId}
+	tagIds := utilities.RemoveDuplicates[models.TagId](next.TagIds)
+	if !utilities.AreSlicesEqual(tagIds, prev.TagIds) {
+		patchBanner.TagIds = &tagIds
+	}
+	if next.FeatureId != prev.FeatureId {
+		patchBanner.FeatureId = &next.FeatureId
+	}
+	if next.Content.Title != prev.Content.Title {
+		patchBanner.Title = &next.Content.Title
+	}
+	if next.Content.Text != prev.Content.Text {
+		patchBanner.Text = &next.Content.Text
+	}
+	if next.Content.Url != prev.Content.Url {
+		patchBanner.Url = &next.Content.Url
+	}
+	if next.IsActive != prev.IsActive {
+		patchBanner.IsActive = &next.IsActive
+	}
+	if next.Status != prev.Status {
+		patchBanner.Status = &next.Status
+	}
+	return patchBanner
+}
+
+type GetChanges struct {
+	Since int64
+	Limit int
+}
+
+func (b *GetChanges) ToGetChangesPostgres() *banners_repository.GetChanges {
+	return &banners_repository.GetChanges{
+		Since: b.Since,
+		Limit: b.Limit,
+	}
+}
+
+// toWebhookEvents переводит изменение баннера в события вебхуков, смена is_active дает отдельное событие
+func toWebhookEvents(change *models.BannerChange, prevBanner, snapshot *models.FullBanner) []*models.WebhookEvent {
+	featureIds := []models.FeatureId{snapshot.FeatureId}
+	if prevBanner != nil && prevBanner.FeatureId != snapshot.FeatureId {
+		featureIds = append(featureIds, prevBanner.FeatureId)
+	}
+
+	eventTypes := []string{change.Type}
+	if change.Type == models.BannerChangeUpdated && prevBanner != nil && prevBanner.IsActive != snapshot.IsActive {
+		if snapshot.IsActive {
+			eventTypes = append(eventTypes, models.WebhookEventActivated)
+		} else {
+			eventTypes = append(eventTypes, models.WebhookEventDeactivated)
+		}
+	}
+
+	occurredAt := time.Now()
+	events := make([]*models.WebhookEvent, len(eventTypes))
+	for i, eventType := range eventTypes {
+		events[i] = &models.WebhookEvent{
+			EventType:  eventType,
+			BannerId:   change.BannerId,
+			FeatureIds: featureIds,
+			Banner:     snapshot.ToPayload(),
+			OccurredAt: occurredAt,
+		}
+	}
+	return events
+}
+
+type BulkDelete struct {
+	FeatureId *models.FeatureId
+	TagId     *models.TagId
+	// Actor кто запустил удаление, записывается в корзину у каждого баннера
+	Actor string
+}
+
+func (d *BulkDelete) ToBulkDeletePayload() *BulkDeletePayload {
+	return &BulkDeletePayload{
+		FeatureId: d.FeatureId,
+		TagId:     d.TagId,
+		Actor:     d.Actor,
+	}
+}
+
+// BulkDeletePayload payload задачи массового удаления в очереди раннера
+type BulkDeletePayload struct {
+	FeatureId *models.FeatureId `json:"feature_id,omitempty"`
+	TagId     *models.TagId     `json:"tag_id,omitempty"`
+	Actor     string            `json:"actor,omitempty"`
+}
+
+func (p *BulkDeletePayload) ToBannersFilter() *banners_repository.BannersFilter {
+	return &banners_repository.BannersFilter{
+		FeatureId: p.FeatureId,
+		TagId:     p.TagId,
+	}
+}
+
+// BulkPatch фильтр как у массового удаления и патч, который применяется к каждому баннеру под фильтром.
+// Url и UrlRewrite взаимоисключающие, UrlRewrite заменяет все вхождения From на To
+type BulkPatch struct {
+	FeatureId  *models.FeatureId
+	TagId      *models.TagId
+	Title      *string
+	Text       *string
+	Url        *string
+	UrlRewrite *UrlRewrite
+	IsActive   *bool
+	Status     *string
+	DryRun     bool
+}
+
+type UrlRewrite struct {
+	From string
+	To   string
+}
+
+func (p *BulkPatch) ToBannersFilter() *banners_repository.BannersFilter {
+	return &banners_repository.BannersFilter{
+		FeatureId: p.FeatureId,
+		TagId:     p.TagId,
+	}
+}
+
+// ToPatchBanner патч для конкретного баннера, nil - баннер уже в нужном состоянии
+func (p *BulkPatch) ToPatchBanner(banner *models.FullBanner) *PatchBanner {
+	patch := &PatchBanner{
+		Title:    p.Title,
+		Text:     p.Text,
+		Url:      p.Url,
+		IsActive: p.IsActive,
+		Status:   p.Status,
+		BannerId: banner.BannerId,
+	}
+	if p.UrlRewrite != nil {
+		url := strings.ReplaceAll(banner.Content.Url, p.UrlRewrite.From, p.UrlRewrite.To)
+		patch.Url = &url
+	}
+	if patch.Check(banner) {
+		return nil
+	}
+	return patch
+}
+
+// BulkPatchPreview баннер до и после патча
+type BulkPatchPreview struct {
+	Before models.FullBanner
+	After  models.FullBanner
+}
+
+type BulkPatchResult struct {
+	Matched   int64
+	BannerIds []models.BannerId
+	Previews  []BulkPatchPreview
+	DryRun    bool
+}
+
+const (
+	BatchAdd      = "add"
+	BatchPatch    = "patch"
+	BatchDelete   = "delete"
+	BatchRollback = "rollback"
+)
+
+// BatchOperation одна операция батча, Add заполнен для add, Patch - для patch, BannerId и Version - для delete и rollback
+type BatchOperation struct {
+	Type     string
+	BannerId models.BannerId
+	Version  int64
+	Add      *AddBanner
+	Patch    *PatchBanner
+	// ExpectedVersion для отката, у патча она лежит в Patch
+	ExpectedVersion *int64
+}
+
+type BatchOperationResult struct {
+	Type     string
+	BannerId models.BannerId
+}
+
+// BatchOperationError операция, из-за которой откатился весь батч, Err - ошибка в обычном формате SpanSetErrWrap
+type BatchOperationError struct {
+	Index int
+	Type  string
+	Err   error
+}
+
+func (e *BatchOperationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BatchOperationError) Unwrap() error {
+	return e.Err
+}
+
+type BulkDeleteProgress struct {
+	Total   int64 `json:"total"`
+	Deleted int64 `json:"deleted"`
+}
+
+func toBulkDeleteJob(job *models.Job) (*models.BulkDeleteJob, error) {
+	var payload BulkDeletePayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, err
+	}
+
+	bulkDeleteJob := &models.BulkDeleteJob{
+		JobId:      job.JobId,
+		FeatureId:  payload.FeatureId,
+		TagId:      payload.TagId,
+		Status:     job.Status,
+		Errors:     []string{},
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	switch job.Status {
+	case models.JobQueued:
+		bulkDeleteJob.Status = models.BulkDeletePending
+	case models.JobSucceeded:
+		bulkDeleteJob.Status = models.BulkDeleteDone
+	}
+	if job.LastError != nil {
+		bulkDeleteJob.Errors = append(bulkDeleteJob.Errors, *job.LastError)
+	}
+	if len(job.Progress) != 0 {
+		var progress BulkDeleteProgress
+		if err := json.Unmarshal(job.Progress, &progress); err != nil {
+			return nil, err
+		}
+		bulkDeleteJob.Total = &progress.Total
+		bulkDeleteJob.Deleted = progress.Deleted
+	}
+
+	return bulkDeleteJob, nil
+}
+
+// ImportRow строка файла импорта, Err - ошибка разбора, такая строка дальше не проверяется
+type ImportRow struct {
+	Line   int
+	Banner *AddBanner
+	Err    error
+}
+
+type ImportBanners struct {
+	Rows   []ImportRow
+	DryRun bool
+}
+
+type ImportRowError struct {
+	Line  int
+	Error string
+}
+
+// ImportResult отчет импорта, Applied = true только если все строки прошли проверку и записаны
+type ImportResult struct {
+	Total   int
+	Valid   int
+	Created []models.BannerId
+	Errors  []ImportRowError
+	DryRun  bool
+	Applied bool
+}
+
+type ExportBanners struct {
+	GetManyBanner
+	WithVersions bool
+}
+
+// ExportedBanner Versions заполняются только при WithVersions, от новых к старым
+type ExportedBanner struct {
+	Banner   models.FullBanner
+	Versions []models.FullBanner
+}
+
+// CloneBanner nil поля берутся у исходного баннера
+type CloneBanner struct {
+	BannerId  models.BannerId
+	TagIds    *[]models.TagId
+	FeatureId *models.FeatureId
+	IsActive  *bool
+}
+
+// ToAddBanner копия завершенного или архивного баннера начинается с черновика
+func (b *CloneBanner) ToAddBanner(source *models.FullBanner) *AddBanner {
+	addBanner := &AddBanner{
+		TagIds:    source.TagIds,
+		FeatureId: source.FeatureId,
+		Content:   source.Content,
+		IsActive:  source.IsActive,
+		Status:    source.Status,
+	}
+	if !models.IsInitialStatus(addBanner.Status) {
+		addBanner.Status = models.BannerStatusDraft
+	}
+	if b.TagIds != nil {
+		addBanner.TagIds = utilities.RemoveDuplicates(*b.TagIds)
+	}
+	if b.FeatureId != nil {
+		addBanner.FeatureId = *b.FeatureId
+	}
+	if b.IsActive != nil {
+		addBanner.IsActive = *b.IsActive
+		addBanner.Status = models.StatusFromIsActive(addBanner.Status, *b.IsActive)
+	}
+	return addBanner
+}
+
+// CloneResult либо айди копии, либо пары (тэг, фича), которые уже заняты
+type CloneResult struct {
+	BannerId  models.BannerId
+	Conflicts []banners_repository.ExistBanner
+}
+
+type GetTrash struct {
+	Limit  int
+	Offset int
+}
+
+func (t *GetTrash) ToGetTrashPostgres() *banners_repository.GetTrash {
+	return &banners_repository.GetTrash{
+		Limit:  t.Limit,
+		Offset: t.Offset,
+	}
+}
+
+// PurgeTrashPayload payload задачи очистки корзины, удаляются баннеры, попавшие в корзину не позже Before
+type PurgeTrashPayload struct {
+	Before time.Time `json:"before"`
+}
+
+type PurgeTrashProgress struct {
+	Purged int64 `json:"purged"`
+}
+
+// ReindexSearchPayload payload задачи переиндексации поиска, Language - новый язык из конфига
+type ReindexSearchPayload struct {
+	Language string `json:"language"`
+}
+
+type ReindexSearchProgress struct {
+	Reindexed int64 `json:"reindexed"`
+}
+
+type GetDrafts struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+func (d *GetDrafts) ToGetDraftsPostgres() *banners_repository.GetDrafts {
+	return &banners_repository.GetDrafts{
+		Status: d.Status,
+		Limit:  d.Limit,
+		Offset: d.Offset,
+	}
+}
+
+// DraftDiff черновик рядом с живым баннером и поля, которые изменятся при публикации
+type DraftDiff struct {
+	Draft   *models.BannerDraft
+	Live    *models.FullBanner
+	Changes []DraftChange
+}
+
+type DraftChange struct {
+	Field string
+	Live  interface{}
+	Draft interface{}
+}
+
+func toDraftChanges(live, draft *models.FullBanner) []DraftChange {
+	patch := ToDiffPatchBanner(live, draft)
+	var changes []DraftChange
+	if patch.TagIds != nil {
+		changes = append(changes, DraftChange{Field: "tag_ids", Live: live.TagIds, Draft: *patch.TagIds})
+	}
+	if patch.FeatureId != nil {
+		changes = append(changes, DraftChange{Field: "feature_id", Live: live.FeatureId, Draft: *patch.FeatureId})
+	}
+	if patch.Title != nil {
+		changes = append(changes, DraftChange{Field: "content.title", Live: live.Content.Title, Draft: *patch.Title})
+	}
+	if patch.Text != nil {
+		changes = append(changes, DraftChange{Field: "content.text", Live: live.Content.Text, Draft: *patch.Text})
+	}
+	if patch.Url != nil {
+		changes = append(changes, DraftChange{Field: "content.url", Live: live.Content.Url, Draft: *patch.Url})
+	}
+	if patch.IsActive != nil {
+		changes = append(changes, DraftChange{Field: "is_active", Live: live.IsActive, Draft: *patch.IsActive})
+	}
+	if patch.Status != nil {
+		changes = append(changes, DraftChange{Field: "status", Live: live.Status, Draft: *patch.Status})
 	}
+	return changes
 }
@@ -0,0 +1,68 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/banners/banners_repository"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel"
+)
+
+// ExportBanners
+// 1. В транзакции открываем курсор по баннерам под фильтром GetManyBanner, тэги приходят сразу массивом
+// 2. Читаем курсор пачками по Export.BatchSize, если нужны версии - догружаем их на всю пачку одним запросом
+// 3. Каждый баннер сразу отдаем в write, в памяти держится не больше одной пачки
+// 4. Сортировка как в GetBannersPage: поиск без явной сортировки идет по убыванию ранга, ранг без поиска - ошибка
+func (b *BannersUC) ExportBanners(ctx context.Context, exportParams *ExportBanners, write func(*ExportedBanner) error) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.ExportBanners")
+	defer span.End()
+
+	pgParams := exportParams.ToGetManyPostgresBanner()
+	if pgParams.Search != nil && pgParams.SortBy == "" {
+		pgParams.SortBy, pgParams.SortDesc = banners_repository.SearchRank, true
+	}
+	if pgParams.Search == nil && pgParams.SortBy == banners_repository.SearchRank {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New("sort by rank requires search"), "BannersUC.ExportBanners.RankWithoutSearch")
+	}
+
+	return b.trManager.Do(ctx, func(ctx context.Context) error {
+		if err := b.bannersPGRepo.OpenExportCursor(ctx, pgParams); err != nil {
+			return err
+		}
+
+		for {
+			banners, err := b.bannersPGRepo.FetchExportCursor(ctx, b.cfg.Export.BatchSize)
+			if err != nil {
+				return err
+			}
+			if len(banners) == 0 {
+				return nil
+			}
+
+			versions := make(map[models.BannerId][]models.FullBanner)
+			if exportParams.WithVersions {
+				bannerIds := make([]models.BannerId, len(banners))
+				for i := range banners {
+					bannerIds[i] = banners[i].BannerId
+				}
+				bannerVersions, err := b.bannersPGRepo.GetVersionsByBannerIds(ctx, bannerIds)
+				if err != nil {
+					return err
+				}
+				for _, version := range bannerVersions {
+					versions[version.BannerId] = append(versions[version.BannerId], version)
+				}
+			}
+
+			for i := range banners {
+				exported := &ExportedBanner{Banner: banners[i], Versions: versions[banners[i].BannerId]}
+				if err = write(exported); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
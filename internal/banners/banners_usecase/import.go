@@ -0,0 +1,111 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"sort"
+)
+
+// ImportBanners
+// 1. Строки, которые не удалось разобрать, сразу попадают в отчет с ошибкой разбора
+// 2. Проверяем шаблоны и конфликты по (тэг, фича) внутри самого файла
+// 3. В транзакции проверяем реестры и CheckExist против базы, как в AddBanner
+// 4. Если есть хоть одна ошибка или это dry run - ничего не пишем и отдаем отчет
+// 5. Иначе добавляем все баннеры в этой же транзакции, изменения рассылаем после коммита
+func (b *BannersUC) ImportBanners(ctx context.Context, importParams *ImportBanners) (*ImportResult, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.ImportBanners")
+	defer span.End()
+
+	result := &ImportResult{Total: len(importParams.Rows), DryRun: importParams.DryRun}
+	rowErrors := make(map[int]string)
+
+	takenBy := make(map[existKey]int)
+	for _, row := range importParams.Rows {
+		if row.Err != nil {
+			rowErrors[row.Line] = row.Err.Error()
+			continue
+		}
+		banner := row.Banner
+		if err := banner.resolveStatus(); err != nil {
+			rowErrors[row.Line] = err.Error()
+			continue
+		}
+		if err := b.checkTemplates(&banner.Content.Title, &banner.Content.Text, &banner.Content.Url); err != nil {
+			rowErrors[row.Line] = err.Error()
+			continue
+		}
+		for _, tagId := range banner.TagIds {
+			key := existKey{tagId: tagId, featureId: banner.FeatureId}
+			if line, ok := takenBy[key]; ok {
+				rowErrors[row.Line] = fmt.Sprintf("tag id %d and feature id %d are already used on line %d", tagId, banner.FeatureId, line)
+				break
+			}
+		}
+		if _, failed := rowErrors[row.Line]; failed {
+			continue
+		}
+		for _, tagId := range banner.TagIds {
+			takenBy[existKey{tagId: tagId, featureId: banner.FeatureId}] = row.Line
+		}
+	}
+
+	var changes []*models.BannerChange
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		for _, row := range importParams.Rows {
+			if _, failed := rowErrors[row.Line]; failed {
+				continue
+			}
+			problem, err := b.checkReferences(ctx, row.Banner.TagIds, &row.Banner.FeatureId)
+			if err != nil {
+				return err
+			}
+			if problem != nil {
+				rowErrors[row.Line] = problem.Error()
+				continue
+			}
+			existBanners, err := b.bannersPGRepo.CheckExist(ctx, row.Banner.TagIds, row.Banner.FeatureId)
+			if err != nil {
+				return err
+			}
+			if len(*existBanners) != 0 {
+				rowErrors[row.Line] = fmt.Sprintf("these banners already exists %v", *existBanners)
+			}
+		}
+
+		if len(rowErrors) != 0 || importParams.DryRun {
+			return nil
+		}
+
+		for _, row := range importParams.Rows {
+			change, err := b.insertBanner(ctx, row.Banner)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		b.publishChange(ctx, change)
+		result.Created = append(result.Created, change.BannerId)
+	}
+	result.Applied = len(changes) != 0
+	result.Valid = result.Total - len(rowErrors)
+	for line, message := range rowErrors {
+		result.Errors = append(result.Errors, ImportRowError{Line: line, Error: message})
+	}
+	sort.Slice(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+
+	return result, nil
+}
+
+type existKey struct {
+	tagId     models.TagId
+	featureId models.FeatureId
+}
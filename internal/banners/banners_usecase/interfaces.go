@@ -4,12 +4,17 @@ import (
 	"avito/assignment/internal/banners/banners_repository"
 	"avito/assignment/internal/models"
 	"context"
+	"io"
+	"time"
 )
 
 type PostgresRepository interface {
 	GetPossibleTagIds(ctx context.Context, bannerId models.BannerId) ([]models.TagId, error)
 	GetManyPossibleTagIds(ctx context.Context, bannerIds []models.BannerId, manyBanner *[]models.Banner) (*[]models.FullBanner, error)
 	CheckExist(ctx context.Context, tagIds []models.TagId, featureId models.FeatureId) (*[]banners_repository.ExistBanner, error)
+	GetPairConflicts(ctx context.Context, bannerIds []models.BannerId) ([]banners_repository.PairConflict, error)
+	GetRegisteredTagIds(ctx context.Context, tagIds []models.TagId) ([]models.TagId, error)
+	IsFeatureRegistered(ctx context.Context, featureId models.FeatureId) (bool, error)
 
 	GetBanner(ctx context.Context, featureId models.FeatureId, tagId models.TagId) (*models.Banner, error)
 	GetBannerById(ctx context.Context, bannerId models.BannerId) (*models.FullBanner, error)
@@ -20,15 +25,71 @@ type PostgresRepository interface {
 	AddTags(ctx context.Context, tagIds []models.TagId, bannerId models.BannerId) error
 	AddVersion(ctx context.Context, prevBanner *models.FullBanner) error
 
-	UpdateBannerById(ctx context.Context, bannerId *banners_repository.UpdateBannerById) error
+	UpdateBannerById(ctx context.Context, bannerId *banners_repository.UpdateBannerById) (bool, error)
 
 	DeleteBannerById(ctx context.Context, bannerId models.BannerId) error
 	DeleteTags(ctx context.Context, tagIds []models.TagId, bannerId models.BannerId) error
 	DeleteVersion(ctx context.Context, versions []int64, bannerId models.BannerId) error
+
+	TrashBanner(ctx context.Context, bannerId models.BannerId, actor string) error
+	GetTrash(ctx context.Context, getTrashParams *banners_repository.GetTrash) ([]models.TrashedBanner, error)
+	GetTrashedBannerById(ctx context.Context, bannerId models.BannerId) (*models.TrashedBanner, error)
+	RestoreBanner(ctx context.Context, bannerId models.BannerId) error
+	LockExpiredTrash(ctx context.Context, before time.Time, limit int) ([]models.BannerId, error)
+
+	AddDraft(ctx context.Context, draft *models.BannerDraft) (models.DraftId, error)
+	UpdateDraftContent(ctx context.Context, draft *models.BannerDraft) error
+	ReviewDraft(ctx context.Context, draftId models.DraftId, status, reviewer, comment string) error
+	MarkDraftPublished(ctx context.Context, draftId models.DraftId, publisher string) error
+	GetDraft(ctx context.Context, draftId models.DraftId) (*models.BannerDraft, error)
+	GetOpenDraft(ctx context.Context, bannerId models.BannerId) (*models.BannerDraft, error)
+	GetDrafts(ctx context.Context, getDraftsParams *banners_repository.GetDrafts) ([]models.BannerDraft, error)
+
+	AddChange(ctx context.Context, eventType string, banner *models.FullBanner) error
+	GetChanges(ctx context.Context, getChangesParams *banners_repository.GetChanges) ([]models.ChangeLogEntry, error)
+
+	GetBannerIdsByFilter(ctx context.Context, filter *banners_repository.BannersFilter, after models.BannerId, limit int) ([]models.BannerId, error)
+	CountBannersByFilter(ctx context.Context, filter *banners_repository.BannersFilter) (int64, error)
+	GetSearchMatches(ctx context.Context, bannerIds []models.BannerId, filter *banners_repository.BannersFilter) (map[models.BannerId]banners_repository.SearchMatch, error)
+	GetSearchLanguage(ctx context.Context) (string, error)
+	SetSearchLanguage(ctx context.Context, language string) (bool, error)
+	ReindexSearchVectors(ctx context.Context, table, language string, after int64, limit int) (int64, int, error)
+
+	OpenExportCursor(ctx context.Context, filter *banners_repository.GetManyPostgresBanner) error
+	FetchExportCursor(ctx context.Context, limit int) ([]models.FullBanner, error)
+	GetVersionsByBannerIds(ctx context.Context, bannerIds []models.BannerId) ([]models.FullBanner, error)
+
+	GetSchemaVersion(ctx context.Context) (int64, error)
+	GetSnapshotData(ctx context.Context) (*models.SnapshotData, error)
+	LockBannersTables(ctx context.Context) error
+	ReplaceSnapshotData(ctx context.Context, data *models.SnapshotData) error
 }
 
 type RedisRepository interface {
 	PutBannerRedis(ctx context.Context, putRedisBannerParams *banners_repository.PutRedisBanner) error
 	GetBannerRedis(ctx context.Context, featureId models.FeatureId, tagId models.TagId) (*models.FullBanner, error)
 	DelBannerRedis(ctx context.Context, featureId models.FeatureId, tagId models.TagId) error
+	FlushBannersRedis(ctx context.Context) error
+	PublishBannerChange(ctx context.Context, change *models.BannerChange) error
+}
+
+type WebhooksOutbox interface {
+	Enqueue(ctx context.Context, event *models.WebhookEvent) error
+}
+
+type Jobs interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (models.JobId, error)
+	EnqueueUnique(ctx context.Context, jobType, uniqueKey string, payload interface{}) (models.JobId, error)
+	GetJob(ctx context.Context, jobId models.JobId) (*models.Job, error)
+}
+
+type SnapshotStore interface {
+	Save(snapshotId string, write func(w io.Writer) error) error
+	Open(snapshotId string) (io.ReadCloser, error)
+	Delete(snapshotId string) error
+	List() ([]string, error)
+}
+
+type JobProgress interface {
+	Report(ctx context.Context, progress interface{}) error
 }
@@ -0,0 +1,58 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+)
+
+// PatchBannerDocument
+// 1. В транзакции читаем баннер и проверяем ожидаемую версию до того, как накладывать на него патч
+// 2. apply накладывает на баннер json patch или merge patch и возвращает то, каким баннер должен стать
+// 3. Из результата берем только изменившиеся поля, дальше все как в PatchBanner: шаблоны, реестры, конфликты, журнал.
+// Возвращает новую версию баннера
+func (b *BannersUC) PatchBannerDocument(ctx context.Context, bannerId models.BannerId, expectedVersion *int64,
+	apply func(current *models.FullBanner) (*models.FullBanner, error)) (int64, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.PatchBannerDocument")
+	defer span.End()
+
+	var change *models.BannerChange
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		prevBanner, err := b.bannersPGRepo.GetBannerById(ctx, bannerId)
+		if err != nil {
+			return err
+		}
+		if prevBanner.BannerId == 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				errors.New(fmt.Sprintf("impossible to update, banner with id %d doesnt exist", bannerId)), "BannersUC.PatchBanner.ErrNotFound")
+		}
+		if err = checkExpectedVersion(span, prevBanner, expectedVersion); err != nil {
+			return err
+		}
+
+		nextBanner, err := apply(prevBanner)
+		if err != nil {
+			return err
+		}
+		patchBannerParams := ToDiffPatchBanner(prevBanner, nextBanner)
+
+		err = b.validateTemplates(span, "BannersUC.PatchBanner.InvalidTemplate",
+			patchBannerParams.Title, patchBannerParams.Text, patchBannerParams.Url)
+		if err != nil {
+			return err
+		}
+
+		change, err = b.patchBanner(ctx, span, patchBannerParams, true)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b.publishChange(ctx, change)
+	return change.Banner.Version, nil
+}
@@ -0,0 +1,48 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"avito/assignment/pkg/utilities"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// validateReferences проверяет, что тэги и фича заведены в реестрах, nil фича не проверяется
+func (b *BannersUC) validateReferences(ctx context.Context, span trace.Span, errorPlace string, tagIds []models.TagId, featureId *models.FeatureId) error {
+	problem, err := b.checkReferences(ctx, tagIds, featureId)
+	if err != nil {
+		return err
+	}
+	if problem != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, problem, errorPlace)
+	}
+	return nil
+}
+
+// checkReferences problem - что не так со ссылками, err - ошибка похода в базу
+func (b *BannersUC) checkReferences(ctx context.Context, tagIds []models.TagId, featureId *models.FeatureId) (problem error, err error) {
+	if len(tagIds) != 0 {
+		registeredTagIds, err := b.bannersPGRepo.GetRegisteredTagIds(ctx, tagIds)
+		if err != nil {
+			return nil, err
+		}
+		if unknownTagIds := utilities.FindUniqueElements(tagIds, registeredTagIds); len(unknownTagIds) != 0 {
+			return fmt.Errorf("unknown tag ids %v", unknownTagIds), nil
+		}
+	}
+
+	if featureId != nil {
+		registered, err := b.bannersPGRepo.IsFeatureRegistered(ctx, *featureId)
+		if err != nil {
+			return nil, err
+		}
+		if !registered {
+			return fmt.Errorf("unknown feature id %d", *featureId), nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,85 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/banners/banners_repository"
+	"context"
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel"
+)
+
+// ReindexSearchJobType тип задачи раннера, под ним регистрируется HandleReindexSearch
+const ReindexSearchJobType = "banners.reindex_search"
+
+// EnqueueSearchReindex
+// 1. Сравниваем язык поиска из конфига с тем, которым построены search_vector в базе
+// 2. Если он сменился, ставим задачу переиндексации. Реплики стартуют одновременно, но с unique_key задача будет одна.
+// Язык входит в unique_key: задача на новый язык встает, даже если еще не закончилась задача на прошлый
+func (b *BannersUC) EnqueueSearchReindex(ctx context.Context) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.EnqueueSearchReindex")
+	defer span.End()
+
+	language, err := b.bannersPGRepo.GetSearchLanguage(ctx)
+	if err != nil {
+		return err
+	}
+	if language == b.cfg.Search.Language {
+		return nil
+	}
+
+	uniqueKey := ReindexSearchJobType + ":" + b.cfg.Search.Language
+	jobId, err := b.jobs.EnqueueUnique(ctx, ReindexSearchJobType, uniqueKey, &ReindexSearchPayload{Language: b.cfg.Search.Language})
+	if err != nil {
+		return err
+	}
+	log.Infof("search language changed from %s to %s, reindex job %d", language, b.cfg.Search.Language, jobId)
+	return nil
+}
+
+// HandleReindexSearch обработчик задачи переиндексации поиска
+// 1. Запоминаем новый язык, с этого момента на нем строят вектора триггеры и ищут запросы
+// 2. Пересобираем search_vector баннеров, затем версий, пачками по ключу, каждая пачка - своя короткая транзакция
+// 3. Пока обход не дошел до баннера, поиск находит его хуже. Обход идемпотентный, ретрай просто начинает заново
+// 4. Перед каждой пачкой сверяем язык в базе: если его уже сменила задача на другой язык, эта задача устарела и выходит
+func (b *BannersUC) HandleReindexSearch(ctx context.Context, payload ReindexSearchPayload, progress JobProgress) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.HandleReindexSearch")
+	defer span.End()
+
+	if _, err := b.bannersPGRepo.SetSearchLanguage(ctx, payload.Language); err != nil {
+		return err
+	}
+
+	state := ReindexSearchProgress{}
+	for _, table := range []string{banners_repository.SearchTableBanners, banners_repository.SearchTableVersions} {
+		var after int64
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			language, err := b.bannersPGRepo.GetSearchLanguage(ctx)
+			if err != nil {
+				return err
+			}
+			if language != payload.Language {
+				log.Infof("search language changed to %s, reindex for %s stopped", language, payload.Language)
+				return nil
+			}
+
+			last, reindexed, err := b.bannersPGRepo.ReindexSearchVectors(ctx, table, payload.Language, after, b.cfg.Search.ReindexBatchSize)
+			if err != nil {
+				return err
+			}
+			if reindexed == 0 {
+				break
+			}
+			after = last
+
+			state.Reindexed += int64(reindexed)
+			if err = progress.Report(ctx, state); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Infof("search vectors rebuilt for language %s", payload.Language)
+	return nil
+}
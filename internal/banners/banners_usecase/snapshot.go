@@ -0,0 +1,364 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/banners/banners_snapshot"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"avito/assignment/pkg/utilities"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	trmsql "github.com/avito-tech/go-transaction-manager/sql"
+	"github.com/avito-tech/go-transaction-manager/trm/settings"
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"sort"
+	"time"
+)
+
+// snapshotTxSettings снапшот читает три таблицы, repeatable read дает один согласованный срез
+var snapshotTxSettings = trmsql.MustSettings(settings.Must(),
+	trmsql.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}))
+
+// CreateSnapshot
+// 1. В repeatable read транзакции читаем версию схемы и все баннеры, тэги и версии
+// 2. Пишем tar.gz с manifest и контрольными суммами в директорию снапшотов
+func (b *BannersUC) CreateSnapshot(ctx context.Context) (*models.SnapshotManifest, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.CreateSnapshot")
+	defer span.End()
+
+	manifest := &models.SnapshotManifest{}
+	data := &models.SnapshotData{}
+	err := b.trManager.DoWithSettings(ctx, snapshotTxSettings, func(ctx context.Context) error {
+		schemaVersion, err := b.bannersPGRepo.GetSchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		manifest.SchemaVersion = schemaVersion
+
+		data, err = b.bannersPGRepo.GetSnapshotData(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.CreatedAt = time.Now().UTC()
+	manifest.SnapshotId = banners_snapshot.NewSnapshotId(manifest.CreatedAt)
+	err = b.snapshots.Save(manifest.SnapshotId, func(w io.Writer) error {
+		return banners_snapshot.WriteArchive(w, manifest, data)
+	})
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersUC.CreateSnapshot.Save")
+	}
+
+	return manifest, nil
+}
+
+func (b *BannersUC) GetSnapshots(ctx context.Context) ([]models.SnapshotManifest, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetSnapshots")
+	defer span.End()
+
+	snapshotIds, err := b.snapshots.List()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersUC.GetSnapshots.List")
+	}
+
+	manifests := make([]models.SnapshotManifest, 0, len(snapshotIds))
+	for _, snapshotId := range snapshotIds {
+		manifest, err := b.readSnapshotManifest(span, snapshotId)
+		if err != nil {
+			log.Errorf("BannersUC.GetSnapshots: %s", err.Error())
+			continue
+		}
+		manifests = append(manifests, *manifest)
+	}
+
+	return manifests, nil
+}
+
+func (b *BannersUC) GetSnapshot(ctx context.Context, snapshotId string) (*models.SnapshotManifest, error) {
+	_, span := otel.Tracer("").Start(ctx, "BannersUC.GetSnapshot")
+	defer span.End()
+
+	return b.readSnapshotManifest(span, snapshotId)
+}
+
+func (b *BannersUC) DeleteSnapshot(ctx context.Context, snapshotId string) error {
+	_, span := otel.Tracer("").Start(ctx, "BannersUC.DeleteSnapshot")
+	defer span.End()
+
+	err := b.snapshots.Delete(snapshotId)
+	if errors.Is(err, banners_snapshot.ErrNotFound) {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("snapshot %s doesnt exist", snapshotId), "BannersUC.DeleteSnapshot.DoNotExist")
+	}
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersUC.DeleteSnapshot.Delete")
+	}
+	return nil
+}
+
+// RestoreSnapshot
+// 1. Читаем архив, сверяем формат, количества строк и контрольные суммы
+// 2. Снапшот более ранней схемы доводим до текущей, снапшот более новой схемы не восстанавливаем
+// 3. Проверяем сами данные: ссылки на баннеры, тэги у каждого баннера, уникальность пар (тэг, фича)
+// 4. В транзакции лочим таблицы на запись, проверяем реестры и заменяем баннеры, тэги и версии целиком
+// 5. Разницу со старым состоянием пишем в журнал изменений и вебхуки, как при обычных изменениях
+// 6. После коммита перестраиваем кэш в редисе и рассылаем изменения
+func (b *BannersUC) RestoreSnapshot(ctx context.Context, snapshotId string) (*models.SnapshotManifest, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.RestoreSnapshot")
+	defer span.End()
+
+	archive, err := b.snapshots.Open(snapshotId)
+	if errors.Is(err, banners_snapshot.ErrNotFound) {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("snapshot %s doesnt exist", snapshotId), "BannersUC.RestoreSnapshot.DoNotExist")
+	}
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersUC.RestoreSnapshot.Open")
+	}
+	defer archive.Close()
+
+	manifest, data, err := banners_snapshot.ReadArchive(archive)
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.RestoreSnapshot.InvalidArchive")
+	}
+
+	var changes []snapshotChange
+	var restored map[models.BannerId]*models.FullBanner
+	err = b.trManager.Do(ctx, func(ctx context.Context) error {
+		schemaVersion, err := b.bannersPGRepo.GetSchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if err = banners_snapshot.UpgradeData(manifest.SchemaVersion, schemaVersion, data); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.RestoreSnapshot.IncompatibleSchema")
+		}
+		if err = validateSnapshotData(data); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.RestoreSnapshot.InvalidData")
+		}
+		restored = snapshotBanners(data, false)
+
+		if err = b.bannersPGRepo.LockBannersTables(ctx); err != nil {
+			return err
+		}
+		if err = b.validateSnapshotReferences(ctx, span, snapshotBanners(data, true)); err != nil {
+			return err
+		}
+
+		current, err := b.bannersPGRepo.GetSnapshotData(ctx)
+		if err != nil {
+			return err
+		}
+		if err = b.bannersPGRepo.ReplaceSnapshotData(ctx, data); err != nil {
+			return err
+		}
+
+		changes = diffSnapshotBanners(snapshotBanners(current, false), restored)
+		for _, change := range changes {
+			if err = b.recordChange(ctx, change.BannerChange, change.prevBanner); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b.rebuildCache(ctx, restored)
+	for _, change := range changes {
+		b.publishChange(ctx, change.BannerChange)
+	}
+
+	return manifest, nil
+}
+
+func (b *BannersUC) readSnapshotManifest(span trace.Span, snapshotId string) (*models.SnapshotManifest, error) {
+	archive, err := b.snapshots.Open(snapshotId)
+	if errors.Is(err, banners_snapshot.ErrNotFound) {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("snapshot %s doesnt exist", snapshotId), "BannersUC.GetSnapshot.DoNotExist")
+	}
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersUC.GetSnapshot.Open")
+	}
+	defer archive.Close()
+
+	manifest, err := banners_snapshot.ReadManifest(archive)
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "BannersUC.GetSnapshot.ReadManifest")
+	}
+	return manifest, nil
+}
+
+// validateSnapshotReferences все тэги и фичи снапшота должны быть заведены в реестрах, иначе упадут внешние ключи
+func (b *BannersUC) validateSnapshotReferences(ctx context.Context, span trace.Span, banners map[models.BannerId]*models.FullBanner) error {
+	tagIds := make(map[models.TagId]struct{})
+	featureIds := make(map[models.FeatureId]struct{})
+	for _, banner := range banners {
+		for _, tagId := range banner.TagIds {
+			tagIds[tagId] = struct{}{}
+		}
+		featureIds[banner.FeatureId] = struct{}{}
+	}
+
+	uniqueTagIds := make([]models.TagId, 0, len(tagIds))
+	for tagId := range tagIds {
+		uniqueTagIds = append(uniqueTagIds, tagId)
+	}
+	sort.Slice(uniqueTagIds, func(i, j int) bool { return uniqueTagIds[i] < uniqueTagIds[j] })
+	if err := b.validateReferences(ctx, span, "BannersUC.RestoreSnapshot.UnknownReference", uniqueTagIds, nil); err != nil {
+		return err
+	}
+	for featureId := range featureIds {
+		featureId := featureId
+		if err := b.validateReferences(ctx, span, "BannersUC.RestoreSnapshot.UnknownReference", nil, &featureId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildCache после восстановления старые ключи могут указывать на чужие баннеры, поэтому кэш чистится целиком и прогревается заново
+func (b *BannersUC) rebuildCache(ctx context.Context, banners map[models.BannerId]*models.FullBanner) {
+	if err := b.bannersRedisRepo.FlushBannersRedis(ctx); err != nil {
+		log.Errorf("BannersUC.rebuildCache: %s", err.Error())
+		return
+	}
+	for _, banner := range banners {
+		if err := b.bannersRedisRepo.PutBannerRedis(ctx, ToPutRedisBanner(banner)); err != nil {
+			log.Errorf("BannersUC.rebuildCache: %s", err.Error())
+			return
+		}
+	}
+}
+
+// validateSnapshotData архив мог быть собран руками, поэтому проверяем то, что в базе держит код, а не ограничения
+func validateSnapshotData(data *models.SnapshotData) error {
+	bannerIds := make(map[models.BannerId]struct{}, len(data.Banners))
+	for _, banner := range data.Banners {
+		if _, ok := bannerIds[banner.BannerId]; ok {
+			return fmt.Errorf("banner id %d is duplicated", banner.BannerId)
+		}
+		bannerIds[banner.BannerId] = struct{}{}
+	}
+
+	type tagLink struct {
+		bannerId models.BannerId
+		tagId    models.TagId
+	}
+	tagLinks := make(map[tagLink]struct{}, len(data.TagLinks))
+	for _, link := range data.TagLinks {
+		if _, ok := bannerIds[link.BannerId]; !ok {
+			return fmt.Errorf("tag %d is linked to unknown banner id %d", link.TagId, link.BannerId)
+		}
+		if _, ok := tagLinks[tagLink{link.BannerId, link.TagId}]; ok {
+			return fmt.Errorf("tag %d is linked to banner id %d twice", link.TagId, link.BannerId)
+		}
+		tagLinks[tagLink{link.BannerId, link.TagId}] = struct{}{}
+	}
+	for _, version := range data.Versions {
+		if _, ok := bannerIds[version.BannerId]; !ok {
+			return fmt.Errorf("version %d belongs to unknown banner id %d", version.Version, version.BannerId)
+		}
+	}
+
+	live := snapshotBanners(data, false)
+	takenBy := make(map[existKey]models.BannerId)
+	for _, banner := range snapshotBanners(data, true) {
+		if len(banner.TagIds) == 0 {
+			return fmt.Errorf("banner id %d has no tags", banner.BannerId)
+		}
+		// баннеры из корзины пары не занимают
+		if _, ok := live[banner.BannerId]; !ok {
+			continue
+		}
+		for _, tagId := range banner.TagIds {
+			key := existKey{tagId: tagId, featureId: banner.FeatureId}
+			if other, ok := takenBy[key]; ok {
+				return fmt.Errorf("banners %d and %d share tag id %d and feature id %d", other, banner.BannerId, tagId, banner.FeatureId)
+			}
+			takenBy[key] = banner.BannerId
+		}
+	}
+	return nil
+}
+
+// snapshotBanners баннеры снапшота с тэгами, баннеры из корзины попадают только с withTrashed
+func snapshotBanners(data *models.SnapshotData, withTrashed bool) map[models.BannerId]*models.FullBanner {
+	banners := make(map[models.BannerId]*models.FullBanner, len(data.Banners))
+	for _, row := range data.Banners {
+		if row.DeletedAt != nil && !withTrashed {
+			continue
+		}
+		banner := &models.FullBanner{
+			BannerId:  row.BannerId,
+			FeatureId: row.FeatureId,
+			IsActive:  row.IsActive,
+			Status:    row.Status,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Version:   row.Version,
+		}
+		banner.Content.Title, banner.Content.Text, banner.Content.Url = row.Title, row.Text, row.Url
+		banners[row.BannerId] = banner
+	}
+	for _, link := range data.TagLinks {
+		if banner, ok := banners[link.BannerId]; ok {
+			banner.TagIds = append(banner.TagIds, link.TagId)
+		}
+	}
+	return banners
+}
+
+type snapshotChange struct {
+	*models.BannerChange
+	prevBanner *models.FullBanner
+}
+
+// diffSnapshotBanners изменения, которые увидят подписчики после восстановления, по возрастанию banner_id
+func diffSnapshotBanners(current, restored map[models.BannerId]*models.FullBanner) []snapshotChange {
+	var changes []snapshotChange
+	for bannerId, prevBanner := range current {
+		banner, ok := restored[bannerId]
+		switch {
+		case !ok:
+			changes = append(changes, snapshotChange{prevBanner: prevBanner, BannerChange: &models.BannerChange{
+				Type:          models.BannerChangeDeleted,
+				BannerId:      bannerId,
+				PrevFeatureId: prevBanner.FeatureId,
+				PrevTagIds:    prevBanner.TagIds,
+			}})
+		case !sameBanner(prevBanner, banner):
+			changes = append(changes, snapshotChange{prevBanner: prevBanner, BannerChange: &models.BannerChange{
+				Type:          models.BannerChangeUpdated,
+				BannerId:      bannerId,
+				Banner:        banner,
+				PrevFeatureId: prevBanner.FeatureId,
+				PrevTagIds:    prevBanner.TagIds,
+			}})
+		}
+	}
+	for bannerId, banner := range restored {
+		if _, ok := current[bannerId]; !ok {
+			changes = append(changes, snapshotChange{BannerChange: &models.BannerChange{
+				Type:     models.BannerChangeCreated,
+				BannerId: bannerId,
+				Banner:   banner,
+			}})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].BannerId < changes[j].BannerId })
+	return changes
+}
+
+func sameBanner(a, b *models.FullBanner) bool {
+	return a.FeatureId == b.FeatureId && a.Content == b.Content && a.IsActive == b.IsActive && a.Status == b.Status &&
+		a.Version == b.Version && a.UpdatedAt.Equal(b.UpdatedAt) && utilities.AreSlicesEqual(a.TagIds, b.TagIds)
+}
@@ -0,0 +1,53 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/templater"
+	"avito/assignment/pkg/traces"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// validateTemplates проверяет шаблоны в контенте при записи, в строгом режиме неизвестные переменные запрещены
+func (b *BannersUC) validateTemplates(span trace.Span, errorPlace string, title, text, url *string) error {
+	if err := b.checkTemplates(title, text, url); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, errorPlace)
+	}
+	return nil
+}
+
+// checkTemplates то же без обертки, нужна там, где ошибки собираются построчно
+func (b *BannersUC) checkTemplates(title, text, url *string) error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{name: "title", value: title},
+		{name: "text", value: text},
+		{name: "url", value: url},
+	}
+
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		err := templater.Validate(*field.value, b.cfg.BannerSettings.Templates.Variables, b.cfg.BannerSettings.Templates.StrictMode)
+		if err != nil {
+			return fmt.Errorf("invalid template in %s: %w", field.name, err)
+		}
+	}
+
+	return nil
+}
+
+// renderBanner подставляет атрибуты запроса в шаблоны, в кэше при этом лежит исходный шаблон.
+// Шаблоны проверяются только при записи, контент, который не разбирается как шаблон, отдается как есть.
+// Ответ - json, title и text клиент экранирует сам под место вывода, поэтому экранируется только url
+func renderBanner(fullBanner *models.FullBanner, attributes map[string]string) *models.FullBanner {
+	rendered := *fullBanner
+	rendered.Content.Title = templater.RenderOrRaw(fullBanner.Content.Title, attributes, nil)
+	rendered.Content.Text = templater.RenderOrRaw(fullBanner.Content.Text, attributes, nil)
+	rendered.Content.Url = templater.RenderOrRaw(fullBanner.Content.Url, attributes, templater.URLEscaper)
+	return &rendered
+}
@@ -0,0 +1,134 @@
+package banners_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel"
+	"time"
+)
+
+// PurgeTrashJobType тип задачи раннера, под ним регистрируется HandlePurgeTrash
+const PurgeTrashJobType = "banners.purge_trash"
+
+func (b *BannersUC) GetTrash(ctx context.Context, getTrashParams *GetTrash) ([]models.TrashedBanner, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetTrash")
+	defer span.End()
+
+	return b.bannersPGRepo.GetTrash(ctx, getTrashParams.ToGetTrashPostgres())
+}
+
+// RestoreBanner
+// 1. Берем баннер из корзины под блокировкой, чтобы его не удалила очистка
+// 2. Пока он лежал в корзине, его пары (тэг, фича) могли занять, поэтому заново делаем CheckExist
+// 3. Возвращаем баннер, для подписчиков он появляется заново, событие рассылаем после коммита
+func (b *BannersUC) RestoreBanner(ctx context.Context, bannerId models.BannerId) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.RestoreBanner")
+	defer span.End()
+
+	var change *models.BannerChange
+	err := b.trManager.Do(ctx, func(ctx context.Context) error {
+		trashed, err := b.bannersPGRepo.GetTrashedBannerById(ctx, bannerId)
+		if err != nil {
+			return err
+		}
+		if trashed == nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				errors.New(fmt.Sprintf("impossible to restore, banner with id %d is not in trash", bannerId)), "BannersUC.RestoreBanner.DoNotExist")
+		}
+
+		existBanners, err := b.bannersPGRepo.CheckExist(ctx, trashed.TagIds, trashed.FeatureId)
+		if err != nil {
+			return err
+		}
+		if len(*existBanners) != 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New(fmt.Sprintf("these banners already exists {tag_id feature_id} %v", *existBanners)), "BannersUC.RestoreBanner.AlreadyExists")
+		}
+
+		if err = b.bannersPGRepo.RestoreBanner(ctx, bannerId); err != nil {
+			return err
+		}
+
+		change = &models.BannerChange{Type: models.BannerChangeCreated, BannerId: bannerId, Banner: &trashed.FullBanner}
+		return b.recordChange(ctx, change, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	b.publishChange(ctx, change)
+	return nil
+}
+
+// HandlePurgeTrash обработчик задачи очистки корзины
+// 1. Пачками лочим баннеры, пролежавшие в корзине дольше срока хранения
+// 2. Удаляем их насовсем вместе с тэгами и версиями, событий нет - подписчики узнали об удалении при переносе в корзину
+// 3. Каждая пачка - своя транзакция, после коммита пишем прогресс
+func (b *BannersUC) HandlePurgeTrash(ctx context.Context, payload PurgeTrashPayload, progress JobProgress) error {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.HandlePurgeTrash")
+	defer span.End()
+
+	state := PurgeTrashProgress{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var purged int
+		err := b.trManager.Do(ctx, func(ctx context.Context) error {
+			bannerIds, err := b.bannersPGRepo.LockExpiredTrash(ctx, payload.Before, b.cfg.Trash.PurgeBatchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, bannerId := range bannerIds {
+				if err = b.bannersPGRepo.DeleteVersion(ctx, []int64{}, bannerId); err != nil {
+					return err
+				}
+				if err = b.bannersPGRepo.DeleteTags(ctx, []models.TagId{}, bannerId); err != nil {
+					return err
+				}
+				if err = b.bannersPGRepo.DeleteBannerById(ctx, bannerId); err != nil {
+					return err
+				}
+			}
+			purged = len(bannerIds)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if purged == 0 {
+			return nil
+		}
+
+		state.Purged += int64(purged)
+		if err = progress.Report(ctx, state); err != nil {
+			return err
+		}
+	}
+}
+
+// RunTrashRetention раз в PurgeIntervalSeconds ставит в очередь очистку корзины.
+// Пока одна очистка в очереди или в работе, другие реплики новую не ставят
+func (b *BannersUC) RunTrashRetention(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(b.cfg.Trash.PurgeIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			before := time.Now().Add(-time.Duration(b.cfg.Trash.RetentionHours) * time.Hour)
+			if _, err := b.jobs.EnqueueUnique(ctx, PurgeTrashJobType, PurgeTrashJobType, &PurgeTrashPayload{Before: before}); err != nil {
+				log.Errorf("BannersUC.RunTrashRetention: %s", err.Error())
+			}
+		}
+	}
+}
@@ -13,7 +13,10 @@ import (
 	"fmt"
 	"github.com/avito-tech/go-transaction-manager/trm/manager"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"slices"
 )
 
 type BannersUC struct {
@@ -21,20 +24,27 @@ type BannersUC struct {
 	trManager        *manager.Manager
 	bannersPGRepo    PostgresRepository
 	bannersRedisRepo RedisRepository
+	webhooksOutbox   WebhooksOutbox
+	jobs             Jobs
+	snapshots        SnapshotStore
 }
 
-func NewBannersUC(cfg *config.Config, trManager *manager.Manager, bannersRepo PostgresRepository, redisClient RedisRepository) *BannersUC {
+func NewBannersUC(cfg *config.Config, trManager *manager.Manager, bannersRepo PostgresRepository, redisClient RedisRepository, webhooksOutbox WebhooksOutbox, jobs Jobs, snapshots SnapshotStore) *BannersUC {
 	return &BannersUC{
 		cfg:              cfg,
 		trManager:        trManager,
 		bannersPGRepo:    bannersRepo,
 		bannersRedisRepo: redisClient,
+		webhooksOutbox:   webhooksOutbox,
+		jobs:             jobs,
+		snapshots:        snapshots,
 	}
 }
 
 // GetBanner (берем случай с use_last_version = false, так как он сложнее)
 // 1. Запрашиваем редис отдать запись, если удается - то сразу ее возвращаем
 // 2. Если нам не удалось ее получить, то берем ее из постгреса и кладем в редис
+// 3. В редисе хранится шаблон, атрибуты запроса подставляются при каждой выдаче
 func (b *BannersUC) GetBanner(ctx context.Context, getBannerParams *GetBanner) (*models.FullBanner, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetBanner")
 	defer span.End()
@@ -44,11 +54,12 @@ func (b *BannersUC) GetBanner(ctx context.Context, getBannerParams *GetBanner) (
 		if err != nil && !errors.Is(err, fiber.ErrNotFound) {
 			return nil, err
 		}
-		if fullBanner != nil {
-			if fullBanner.IsActive == false && getBannerParams.AuthToken == constant.UserToken {
+		// записи кэша без версии остались от старых реплик, их ETag был бы неверным, поэтому идем в постгрес
+		if fullBanner != nil && fullBanner.Version != 0 {
+			if !isVisible(fullBanner, getBannerParams.AuthToken) {
 				return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound, nil, "BannersUC.GetBanner.NotAdmin")
 			}
-			return fullBanner, nil
+			return renderBanner(fullBanner, getBannerParams.Attributes), nil
 		}
 	}
 
@@ -75,39 +86,95 @@ func (b *BannersUC) GetBanner(ctx context.Context, getBannerParams *GetBanner) (
 		}
 	}
 
-	if fullBanner.IsActive == false && getBannerParams.AuthToken == constant.UserToken {
+	if !isVisible(fullBanner, getBannerParams.AuthToken) {
 		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound, nil, "BannersUC.GetBanner.NotAdmin")
 	}
-	return fullBanner, nil
+	return renderBanner(fullBanner, getBannerParams.Attributes), nil
 }
 
 // GetManyBanner
 // 1. Просим из постгреса все записи из бд с баннерами соответствующие требованиям
 // 2. Добавляем к каждой записи тэг айдишники из бд с тэгами
 func (b *BannersUC) GetManyBanner(ctx context.Context, getManyBannerParams *GetManyBanner) (*[]models.FullBanner, error) {
-	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetManyBanner")
+	page, err := b.GetBannersPage(ctx, getManyBannerParams)
+	if err != nil {
+		return nil, err
+	}
+	return &page.Banners, nil
+}
+
+// GetBannersPage
+// 1. Просим у постгреса на одну запись больше лимита, так узнаем, есть ли баннеры дальше
+// 2. Страница назад приходит в обратном порядке, разворачиваем
+// 3. Добавляем к каждой записи тэг айдишники из бд с тэгами
+// 4. Next - от последнего баннера, Prev - от первого, если в эту сторону еще есть баннеры
+// 5. По WithTotal считаем всех под фильтром в той же транзакции
+// 6. Для поиска добираем ранг и подсветку баннеров страницы, без явной сортировки идем по убыванию ранга
+func (b *BannersUC) GetBannersPage(ctx context.Context, getManyBannerParams *GetManyBanner) (*BannersPage, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetBannersPage")
 	defer span.End()
 
-	manyBannerInfo := &[]models.FullBanner{}
+	pgParams := getManyBannerParams.ToGetManyPostgresBanner()
+	if pgParams.Search != nil && pgParams.SortBy == "" {
+		pgParams.SortBy, pgParams.SortDesc = banners_repository.SearchRank, true
+	}
+	if pgParams.Search == nil && pgParams.SortBy == banners_repository.SearchRank {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New("sort by rank requires search"), "BannersUC.GetBannersPage.RankWithoutSearch")
+	}
+	limit := 0
+	if getManyBannerParams.Limit != nil && *getManyBannerParams.Limit > 0 {
+		limit = *getManyBannerParams.Limit
+		limitWithNext := limit + 1
+		pgParams.Limit = &limitWithNext
+	}
+	backward := getManyBannerParams.After != nil && getManyBannerParams.After.Backward
+
+	page := &BannersPage{Banners: []models.FullBanner{}}
+	hasMore := false
 	err := b.trManager.Do(ctx, func(ctx context.Context) error {
-		manyBanner, err := b.bannersPGRepo.GetManyBanner(ctx, getManyBannerParams.ToGetManyPostgresBanner())
+		if getManyBannerParams.WithTotal {
+			total, err := b.bannersPGRepo.CountBannersByFilter(ctx, &pgParams.BannersFilter)
+			if err != nil {
+				return err
+			}
+			page.Total = &total
+		}
+
+		manyBanner, err := b.bannersPGRepo.GetManyBanner(ctx, pgParams)
 		if err != nil {
 			return err
 		}
 
-		if *manyBanner == nil {
+		if len(*manyBanner) == 0 {
 			return nil
 		}
 
+		if limit != 0 && len(*manyBanner) > limit {
+			hasMore = true
+			*manyBanner = (*manyBanner)[:limit]
+		}
+		if backward {
+			slices.Reverse(*manyBanner)
+		}
+
 		var bannerIds []models.BannerId
 		for _, banner := range *manyBanner {
 			bannerIds = append(bannerIds, banner.BannerId)
 		}
 
-		manyBannerInfo, err = b.bannersPGRepo.GetManyPossibleTagIds(ctx, bannerIds, manyBanner)
+		manyBannerInfo, err := b.bannersPGRepo.GetManyPossibleTagIds(ctx, bannerIds, manyBanner)
 		if err != nil {
 			return err
 		}
+		page.Banners = *manyBannerInfo
+
+		if pgParams.Search != nil {
+			page.Matches, err = b.bannersPGRepo.GetSearchMatches(ctx, bannerIds, &pgParams.BannersFilter)
+			if err != nil {
+				return err
+			}
+		}
 
 		return nil
 	})
@@ -115,143 +182,271 @@ func (b *BannersUC) GetManyBanner(ctx context.Context, getManyBannerParams *GetM
 		return nil, err
 	}
 
-	return manyBannerInfo, nil
+	if limit == 0 || len(page.Banners) == 0 {
+		return page, nil
+	}
+	first, last := &page.Banners[0], &page.Banners[len(page.Banners)-1]
+	sortBy := pgParams.SortBy
+	if backward {
+		// пришли назад от позиции, значит за последним баннером она и лежит
+		page.Next = page.pageKey(last, sortBy, false)
+		if hasMore {
+			page.Prev = page.pageKey(first, sortBy, true)
+		}
+		return page, nil
+	}
+	if hasMore {
+		page.Next = page.pageKey(last, sortBy, false)
+	}
+	if getManyBannerParams.After != nil || (getManyBannerParams.Offset != nil && *getManyBannerParams.Offset > 0) {
+		page.Prev = page.pageKey(first, sortBy, true)
+	}
+	return page, nil
 }
 
 // AddBanner
-// 1. Проверяем существует ли уже запись в бд с соответствующими фич тэг айдишниками
-// 2. Добавляем запись в бд с баннерами
-// 3. Добавляем записи в бд с тэгами
+// 1. Проверяем, что тэги и фича заведены в реестрах
+// 2. Проверяем существует ли уже запись в бд с соответствующими фич тэг айдишниками
+// 3. Добавляем запись в бд с баннерами
+// 4. Добавляем записи в бд с тэгами
 func (b *BannersUC) AddBanner(ctx context.Context, addBannerParams *AddBanner) (models.BannerId, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetManyBanner")
 	defer span.End()
 
-	var bannerId models.BannerId
-	err := b.trManager.Do(ctx, func(ctx context.Context) error {
-		existBanners, err := b.bannersPGRepo.CheckExist(ctx, addBannerParams.TagIds, addBannerParams.FeatureId)
+	err := b.validateTemplates(span, "BannersUC.AddBanner.InvalidTemplate",
+		&addBannerParams.Content.Title, &addBannerParams.Content.Text, &addBannerParams.Content.Url)
+	if err != nil {
+		return -1, err
+	}
+	if err = addBannerParams.resolveStatus(); err != nil {
+		return -1, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.AddBanner.WrongStatus")
+	}
+
+	var change *models.BannerChange
+	err = b.trManager.Do(ctx, func(ctx context.Context) error {
+		err := b.validateReferences(ctx, span, "BannersUC.AddBanner.UnknownReference", addBannerParams.TagIds, &addBannerParams.FeatureId)
 		if err != nil {
 			return err
 		}
 
-		if len(*existBanners) != 0 {
-			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
-				errors.New(fmt.Sprintf("these banners already exists %v", *existBanners)), "BannersUC.AddBanner.AlreadyExists")
-		}
-
-		insertParams, err := b.bannersPGRepo.AddBanner(ctx, addBannerParams.ToAddBannerPostgres())
+		existBanners, err := b.bannersPGRepo.CheckExist(ctx, addBannerParams.TagIds, addBannerParams.FeatureId)
 		if err != nil {
 			return err
 		}
 
-		err = b.bannersPGRepo.AddTags(ctx, addBannerParams.TagIds, insertParams.BannerId)
-		if err != nil {
-			return err
+		if len(*existBanners) != 0 {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				errors.New(fmt.Sprintf("these banners already exists %v", *existBanners)), "BannersUC.AddBanner.AlreadyExists")
 		}
 
-		bannerId = insertParams.BannerId
-		return nil
+		change, err = b.insertBanner(ctx, addBannerParams)
+		return err
 	})
 	if err != nil {
 		return -1, err
 	}
 
-	return bannerId, nil
+	b.publishChange(ctx, change)
+	return change.BannerId, nil
+}
+
+// insertBanner запись уже проверенного баннера вместе с тэгами и журналом изменений, должна вызываться внутри транзакции
+func (b *BannersUC) insertBanner(ctx context.Context, addBannerParams *AddBanner) (*models.BannerChange, error) {
+	insertParams, err := b.bannersPGRepo.AddBanner(ctx, addBannerParams.ToAddBannerPostgres())
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.bannersPGRepo.AddTags(ctx, addBannerParams.TagIds, insertParams.BannerId)
+	if err != nil {
+		return nil, err
+	}
+
+	banner, err := b.bannersPGRepo.GetBannerById(ctx, insertParams.BannerId)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &models.BannerChange{Type: models.BannerChangeCreated, BannerId: insertParams.BannerId, Banner: banner}
+	if err = b.recordChange(ctx, change, nil); err != nil {
+		return nil, err
+	}
+
+	return change, nil
 }
 
 // PatchBanner Нечитабельный мусор, в readme добавлю че произошло
 // 1. Проверяем существует ли баннер, который надо обновить
-// 2. Проверяем способны ли мы добавить в бд запись
-// 3. Проверяем обновляем ли мы хоть что то в существующей записи
-// 4. Обновляю баннер
-// 5. Удаляю + добавляю тэги, чтобы они соответствовали запросу
-// 6. Добавляю версию в бд с версиями
-// 7. Удаляю пятую версию баннера в бд (костыльно, но лучше не придумать наверное)
-func (b *BannersUC) PatchBanner(ctx context.Context, patchBannerParams *PatchBanner) error {
+// 2. Проверяем, что новые тэги и фича заведены в реестрах
+// 3. Проверяем способны ли мы добавить в бд запись
+// 4. Проверяем обновляем ли мы хоть что то в существующей записи
+// 5. Обновляю баннер
+// 6. Удаляю + добавляю тэги, чтобы они соответствовали запросу
+// 7. Добавляю версию в бд с версиями
+// 8. Удаляю пятую версию баннера в бд (костыльно, но лучше не придумать наверное)
+// Если передана ExpectedVersion, а баннер уже другой версии - 412, обновление идет только поверх прочитанной версии
+func (b *BannersUC) PatchBanner(ctx context.Context, patchBannerParams *PatchBanner) (int64, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.PatchBanner")
 	defer span.End()
 
-	err := b.trManager.Do(ctx, func(ctx context.Context) error {
-		prevBanner, err := b.bannersPGRepo.GetBannerById(ctx, patchBannerParams.BannerId)
-		if err != nil {
-			return err
-		}
-		if prevBanner.BannerId == 0 {
-			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
-				errors.New(fmt.Sprintf("impossible to update, banner with id %d doesnt exist", patchBannerParams.BannerId)), "BannersUC.PatchBanner.ErrNotFound")
+	err := b.validateTemplates(span, "BannersUC.PatchBanner.InvalidTemplate",
+		patchBannerParams.Title, patchBannerParams.Text, patchBannerParams.Url)
+	if err != nil {
+		return 0, err
+	}
+
+	var change *models.BannerChange
+	err = b.trManager.Do(ctx, func(ctx context.Context) error {
+		change, err = b.patchBanner(ctx, span, patchBannerParams, true)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b.publishChange(ctx, change)
+	return change.Banner.Version, nil
+}
+
+// patchBanner общая часть PatchBanner и BannerRollback, должна вызываться внутри транзакции.
+// checkExist = false только в батче, там уникальность проверяется по итоговому состоянию
+func (b *BannersUC) patchBanner(ctx context.Context, span trace.Span, patchBannerParams *PatchBanner, checkExist bool) (*models.BannerChange, error) {
+	prevBanner, err := b.bannersPGRepo.GetBannerById(ctx, patchBannerParams.BannerId)
+	if err != nil {
+		return nil, err
+	}
+	if prevBanner.BannerId == 0 {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			errors.New(fmt.Sprintf("impossible to update, banner with id %d doesnt exist", patchBannerParams.BannerId)), "BannersUC.PatchBanner.ErrNotFound")
+	}
+	if err = checkExpectedVersion(span, prevBanner, patchBannerParams.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	if err = patchBannerParams.resolveStatus(prevBanner.Status); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "BannersUC.PatchBanner.WrongStatus")
+	}
+	if !patchBannerParams.viaDraft && (b.requiresApproval(prevBanner.FeatureId) ||
+		patchBannerParams.FeatureId != nil && b.requiresApproval(*patchBannerParams.FeatureId)) {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrForbidden,
+			fmt.Errorf("banner with id %d can be changed only through an approved draft", prevBanner.BannerId), "BannersUC.PatchBanner.ApprovalRequired")
+	}
+
+	var addTags []models.TagId
+	if patchBannerParams.TagIds != nil {
+		addTags = utilities.FindUniqueElements(*patchBannerParams.TagIds, prevBanner.TagIds)
+	}
+	var newFeatureId *models.FeatureId
+	if patchBannerParams.FeatureId != nil && *patchBannerParams.FeatureId != prevBanner.FeatureId {
+		newFeatureId = patchBannerParams.FeatureId
+	}
+	if err = b.validateReferences(ctx, span, "BannersUC.PatchBanner.UnknownReference", addTags, newFeatureId); err != nil {
+		return nil, err
+	}
+	if checkExist {
+		if err = b.checkPatchExist(ctx, span, patchBannerParams, prevBanner, addTags); err != nil {
+			return nil, err
 		}
+	}
 
-		var addTags []models.TagId
-		var existBanners *[]banners_repository.ExistBanner
-		if patchBannerParams.TagIds != nil {
-			addTags = utilities.FindUniqueElements(*patchBannerParams.TagIds, prevBanner.TagIds)
-		}
-		if patchBannerParams.FeatureId != nil && *patchBannerParams.FeatureId != prevBanner.FeatureId {
-			if patchBannerParams.TagIds != nil {
-				existBanners, err = b.bannersPGRepo.CheckExist(ctx, *patchBannerParams.TagIds, *patchBannerParams.FeatureId)
-				if err != nil {
-					return err
-				}
-			} else {
-				existBanners, err = b.bannersPGRepo.CheckExist(ctx, prevBanner.TagIds, *patchBannerParams.FeatureId)
-				if err != nil {
-					return err
-				}
+	if patchBannerParams.Check(prevBanner) {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New(fmt.Sprintf("nothing to update")), "BannersUC.PatchBanner.NothingToUpdate")
+	}
+
+	updated, err := b.bannersPGRepo.UpdateBannerById(ctx, patchBannerParams.ToPatchBanner(prevBanner.Version))
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrPreconditionFailed,
+			errors.New(fmt.Sprintf("banner with id %d was changed concurrently, version %d is stale", prevBanner.BannerId, prevBanner.Version)),
+			"BannersUC.PatchBanner.ConcurrentUpdate")
+	}
+	if patchBannerParams.TagIds != nil {
+		if len(addTags) != 0 {
+			err = b.bannersPGRepo.AddTags(ctx, addTags, prevBanner.BannerId)
+			if err != nil {
+				return nil, err
 			}
-		} else {
-			if patchBannerParams.TagIds != nil {
-				existBanners, err = b.bannersPGRepo.CheckExist(ctx, addTags, prevBanner.FeatureId)
-				if err != nil {
-					return err
-				}
-			} else {
-				existBanners = &[]banners_repository.ExistBanner{}
+		}
+		deleteTags := utilities.FindUniqueElements(prevBanner.TagIds, *patchBannerParams.TagIds)
+		if len(deleteTags) != 0 {
+			err = b.bannersPGRepo.DeleteTags(ctx, deleteTags, prevBanner.BannerId)
+			if err != nil {
+				return nil, err
 			}
 		}
+	}
 
-		if len(*existBanners) != 0 {
-			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
-				errors.New(fmt.Sprintf("these banners already exists {tag_id feature_id} %v", *existBanners)), "BannersUC.PatchBanner.AlreadyExists")
-		}
+	err = b.bannersPGRepo.AddVersion(ctx, prevBanner)
+	if err != nil {
+		return nil, err
+	}
 
-		if patchBannerParams.Check(prevBanner) {
-			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
-				errors.New(fmt.Sprintf("nothing to update")), "BannersUC.PatchBanner.NothingToUpdate")
-		}
+	err = b.bannersPGRepo.DeleteVersion(ctx, []int64{prevBanner.Version - 3}, patchBannerParams.BannerId)
+	if err != nil {
+		return nil, err
+	}
 
-		err = b.bannersPGRepo.UpdateBannerById(ctx, patchBannerParams.ToPatchBanner(prevBanner.Version))
-		if err != nil {
-			return err
-		}
+	banner, err := b.bannersPGRepo.GetBannerById(ctx, patchBannerParams.BannerId)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &models.BannerChange{
+		Type:          models.BannerChangeUpdated,
+		BannerId:      patchBannerParams.BannerId,
+		Banner:        banner,
+		PrevFeatureId: prevBanner.FeatureId,
+		PrevTagIds:    prevBanner.TagIds,
+	}
+	if err = b.recordChange(ctx, change, prevBanner); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// checkExpectedVersion 412, если админ патчит не ту версию, которую видел
+func checkExpectedVersion(span trace.Span, prevBanner *models.FullBanner, expectedVersion *int64) error {
+	if expectedVersion == nil || *expectedVersion == prevBanner.Version {
+		return nil
+	}
+	return traces.SpanSetErrWrap(span, errlst.HttpErrPreconditionFailed,
+		errors.New(fmt.Sprintf("banner with id %d has version %d, expected %d", prevBanner.BannerId, prevBanner.Version, *expectedVersion)),
+		"BannersUC.PatchBanner.VersionMismatch")
+}
+
+// checkPatchExist проверяет, что пары (тэг, фича) баннера после патча не заняты другими баннерами
+func (b *BannersUC) checkPatchExist(ctx context.Context, span trace.Span, patchBannerParams *PatchBanner, prevBanner *models.FullBanner, addTags []models.TagId) error {
+	var err error
+	var existBanners *[]banners_repository.ExistBanner
+	if patchBannerParams.FeatureId != nil && *patchBannerParams.FeatureId != prevBanner.FeatureId {
 		if patchBannerParams.TagIds != nil {
-			if len(addTags) != 0 {
-				err = b.bannersPGRepo.AddTags(ctx, addTags, prevBanner.BannerId)
-				if err != nil {
-					return err
-				}
+			existBanners, err = b.bannersPGRepo.CheckExist(ctx, *patchBannerParams.TagIds, *patchBannerParams.FeatureId)
+			if err != nil {
+				return err
 			}
-			deleteTags := utilities.FindUniqueElements(prevBanner.TagIds, *patchBannerParams.TagIds)
-			if len(deleteTags) != 0 {
-				err = b.bannersPGRepo.DeleteTags(ctx, deleteTags, prevBanner.BannerId)
-				if err != nil {
-					return err
-				}
+		} else {
+			existBanners, err = b.bannersPGRepo.CheckExist(ctx, prevBanner.TagIds, *patchBannerParams.FeatureId)
+			if err != nil {
+				return err
 			}
 		}
-
-		err = b.bannersPGRepo.AddVersion(ctx, prevBanner)
-		if err != nil {
-			return err
-		}
-
-		err = b.bannersPGRepo.DeleteVersion(ctx, []int64{prevBanner.Version - 3}, patchBannerParams.BannerId)
-		if err != nil {
-			return err
+	} else {
+		if patchBannerParams.TagIds != nil {
+			existBanners, err = b.bannersPGRepo.CheckExist(ctx, addTags, prevBanner.FeatureId)
+			if err != nil {
+				return err
+			}
+		} else {
+			existBanners = &[]banners_repository.ExistBanner{}
 		}
+	}
 
-		return nil
-	})
-	if err != nil {
-		return err
+	if len(*existBanners) != 0 {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			errors.New(fmt.Sprintf("these banners already exists {tag_id feature_id} %v", *existBanners)), "BannersUC.PatchBanner.AlreadyExists")
 	}
 
 	return nil
@@ -259,48 +454,60 @@ func (b *BannersUC) PatchBanner(ctx context.Context, patchBannerParams *PatchBan
 
 // DeleteBanner
 // 1. Проверяю существует ли запись, которую я хочу удалить (в readme добавлю кое че по этому поводу)
-// 2. Удаляю версии, тэги и сам баннер
-func (b *BannersUC) DeleteBanner(ctx context.Context, bannerId models.BannerId) error {
+// 2. Переношу баннер в корзину, тэги и версии остаются, насовсем его удалит очистка корзины
+func (b *BannersUC) DeleteBanner(ctx context.Context, bannerId models.BannerId, actor string) error {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.DeleteBanner")
 	defer span.End()
 
-	err := b.trManager.Do(ctx, func(ctx context.Context) error {
-		prevBanner, err := b.bannersPGRepo.GetBannerById(ctx, bannerId)
-		if err != nil {
-			return err
-		}
-		if prevBanner.BannerId == 0 {
-			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
-				errors.New(fmt.Sprintf("impossible to delete, banner with id %d doesnt exist", bannerId)), "BannersUC.DeleteBanner.DoNotExist")
-		}
-
-		err = b.bannersPGRepo.DeleteVersion(ctx, []int64{}, bannerId)
-		if err != nil {
-			return err
-		}
-		err = b.bannersPGRepo.DeleteTags(ctx, []models.TagId{}, bannerId)
-		if err != nil {
-			return err
-		}
-		err = b.bannersPGRepo.DeleteBannerById(ctx, bannerId)
-		if err != nil {
-			return err
-		}
-		for _, tagId := range prevBanner.TagIds {
-			err = b.bannersRedisRepo.DelBannerRedis(ctx, prevBanner.FeatureId, tagId)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
+	var change *models.BannerChange
+	err := b.trManager.Do(ctx, func(ctx context.Context) (err error) {
+		change, err = b.deleteBanner(ctx, span, bannerId, actor)
+		return err
 	})
 	if err != nil {
 		return err
 	}
 
+	b.publishChange(ctx, change)
 	return nil
 }
 
+// deleteBanner общая часть DeleteBanner и массового удаления, должна вызываться внутри транзакции.
+// Для подписчиков баннер из корзины удален, поэтому событие то же, что и раньше
+func (b *BannersUC) deleteBanner(ctx context.Context, span trace.Span, bannerId models.BannerId, actor string) (*models.BannerChange, error) {
+	prevBanner, err := b.bannersPGRepo.GetBannerById(ctx, bannerId)
+	if err != nil {
+		return nil, err
+	}
+	if prevBanner.BannerId == 0 {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			errors.New(fmt.Sprintf("impossible to delete, banner with id %d doesnt exist", bannerId)), "BannersUC.DeleteBanner.DoNotExist")
+	}
+
+	err = b.bannersPGRepo.TrashBanner(ctx, bannerId, actor)
+	if err != nil {
+		return nil, err
+	}
+	for _, tagId := range prevBanner.TagIds {
+		err = b.bannersRedisRepo.DelBannerRedis(ctx, prevBanner.FeatureId, tagId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	change := &models.BannerChange{
+		Type:          models.BannerChangeDeleted,
+		BannerId:      bannerId,
+		PrevFeatureId: prevBanner.FeatureId,
+		PrevTagIds:    prevBanner.TagIds,
+	}
+	if err = b.recordChange(ctx, change, prevBanner); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
 // ViewVersions
 // 1. Проверяем существует ли баннер, чьи версии мы хотим просмотреть
 // 2. Добавляем в слайс версий текущую версию и остальные
@@ -338,11 +545,12 @@ func (b *BannersUC) ViewVersions(ctx context.Context, bannerId models.BannerId)
 
 // BannerRollback
 // 1. Проверяем существут ли баннер под таким айди и с такой версией
-// 2. Закидываем баннер в ручку patch
-func (b *BannersUC) BannerRollback(ctx context.Context, bannerId models.BannerId, version int64) error {
+// 2. Закидываем баннер в ручку patch, expectedVersion, если передана, проверяется так же, как в PatchBanner
+func (b *BannersUC) BannerRollback(ctx context.Context, bannerId models.BannerId, version int64, expectedVersion *int64) error {
 	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.BannerRollback")
 	defer span.End()
 
+	var change *models.BannerChange
 	err := b.trManager.Do(ctx, func(ctx context.Context) error {
 		banner, err := b.bannersPGRepo.GetBannerVersions(ctx, bannerId, []int64{version})
 		if err != nil {
@@ -354,7 +562,9 @@ func (b *BannersUC) BannerRollback(ctx context.Context, bannerId models.BannerId
 				errors.New(fmt.Sprintf("impossible to rollback, banner with id %d and version %d doesnt exist", bannerId, version)), "BannersUC.BannerRollback.DoNotExist")
 		}
 
-		err = b.PatchBanner(ctx, ToPatchBanner((*banner)[0]))
+		patchBannerParams := ToPatchBanner((*banner)[0])
+		patchBannerParams.ExpectedVersion = expectedVersion
+		change, err = b.patchBanner(ctx, span, patchBannerParams, true)
 		if err != nil {
 			return err
 		}
@@ -372,5 +582,92 @@ func (b *BannersUC) BannerRollback(ctx context.Context, bannerId models.BannerId
 		return err
 	}
 
+	b.publishChange(ctx, change)
+	return nil
+}
+
+// GetChanges
+// 1. Отдаем события журнала строго после курсора since в порядке change_id
+// 2. Следующий курсор = change_id последнего события, если событий нет - курсор не двигается
+func (b *BannersUC) GetChanges(ctx context.Context, getChangesParams *GetChanges) ([]models.ChangeLogEntry, int64, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "BannersUC.GetChanges")
+	defer span.End()
+
+	changes, err := b.bannersPGRepo.GetChanges(ctx, getChangesParams.ToGetChangesPostgres())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	nextCursor := getChangesParams.Since
+	if len(changes) != 0 {
+		nextCursor = changes[len(changes)-1].ChangeId
+	}
+
+	return changes, nextCursor, nil
+}
+
+// PresentBanner применяет к баннеру правила выдачи, nil - баннер клиенту не виден
+func (b *BannersUC) PresentBanner(ctx context.Context, fullBanner *models.FullBanner, authToken string, attributes map[string]string) (*models.FullBanner, error) {
+	_, span := otel.Tracer("").Start(ctx, "BannersUC.PresentBanner")
+	defer span.End()
+
+	if !isVisible(fullBanner, authToken) {
+		return nil, nil
+	}
+	return renderBanner(fullBanner, attributes), nil
+}
+
+// publishChange рассылает событие всем репликам, запись уже закоммичена, поэтому ошибку только логируем
+func (b *BannersUC) publishChange(ctx context.Context, change *models.BannerChange) {
+	if err := b.bannersRedisRepo.PublishBannerChange(ctx, change); err != nil {
+		log.Errorf("BannersUC.publishChange: %s", err.Error())
+	}
+}
+
+// dropCachedBanner сбрасывает ключи редиса для пар баннера до и после изменения, запись уже закоммичена
+func (b *BannersUC) dropCachedBanner(ctx context.Context, change *models.BannerChange) {
+	drop := func(featureId models.FeatureId, tagIds []models.TagId) {
+		for _, tagId := range tagIds {
+			if err := b.bannersRedisRepo.DelBannerRedis(ctx, featureId, tagId); err != nil {
+				log.Errorf("BannersUC.dropCachedBanner: %s", err.Error())
+			}
+		}
+	}
+	if change.Banner != nil {
+		drop(change.Banner.FeatureId, change.Banner.TagIds)
+	}
+	drop(change.PrevFeatureId, change.PrevTagIds)
+}
+
+// recordChange пишет событие в журнал изменений и в outbox вебхуков в той же транзакции, что и сама запись
+func (b *BannersUC) recordChange(ctx context.Context, change *models.BannerChange, prevBanner *models.FullBanner) error {
+	snapshot := change.Banner
+	if snapshot == nil {
+		snapshot = prevBanner
+	}
+	if err := b.bannersPGRepo.AddChange(ctx, change.Type, snapshot); err != nil {
+		return err
+	}
+
+	for _, event := range toWebhookEvents(change, prevBanner, snapshot) {
+		if err := b.webhooksOutbox.Enqueue(ctx, event); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// isVisible правила выдачи по статусу баннера. У записей кэша от старых реплик статуса нет, он берется из is_active
+func isVisible(fullBanner *models.FullBanner, authToken string) bool {
+	status := fullBanner.Status
+	if status == "" {
+		status = models.StatusFromIsActive("", fullBanner.IsActive)
+	}
+	switch models.DeliveryOf(status) {
+	case models.DeliverAll:
+		return true
+	case models.DeliverAdmins:
+		return authToken != constant.UserToken
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package idempotency_repository
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"time"
+)
+
+// reserveAttempts ключ может истечь между SETNX и GET, тогда занимаем его заново
+const reserveAttempts = 3
+
+// extendScript продлевает бронь, только если ключ все еще держит эта попытка и ответа под ним нет
+var extendScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return 0
+end
+local record = cjson.decode(value)
+if record.owner ~= ARGV[1] or record.done then
+	return 0
+end
+return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+`)
+
+// releaseScript снимает бронь, только если ее держит эта попытка. После истечения брони ключ мог занять повтор
+var releaseScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return 0
+end
+local record = cjson.decode(value)
+if record.owner ~= ARGV[1] or record.done then
+	return 0
+end
+return redis.call('DEL', KEYS[1])
+`)
+
+type IdempotencyRedisRepo struct {
+	db  *redis.Client
+	cfg *config.Config
+}
+
+func NewIdempotencyRedisRepository(db *redis.Client, cfg *config.Config) *IdempotencyRedisRepo {
+	return &IdempotencyRedisRepo{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// Reserve занимает ключ под первую попытку на lockTTL. Если ключ уже занят, возвращает то, что под ним лежит, иначе nil
+func (r *IdempotencyRedisRepo) Reserve(ctx context.Context, key string, record *models.IdempotencyRecord, lockTTL time.Duration) (*models.IdempotencyRecord, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "IdempotencyRedisRepo.Reserve")
+	defer span.End()
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Reserve.Marshal; err = %s", err.Error()))
+	}
+
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		reserved, err := r.db.SetNX(ctx, r.createDbKey(key), recordBytes, lockTTL).Result()
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Reserve.SetNX; err = %s", err.Error()))
+		}
+		if reserved {
+			return nil, nil
+		}
+
+		valueString, err := r.db.Get(ctx, r.createDbKey(key)).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Reserve.Get; err = %s", err.Error()))
+		}
+
+		stored := &models.IdempotencyRecord{}
+		if err = json.Unmarshal([]byte(valueString), stored); err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Reserve.Unmarshal; err = %s", err.Error()))
+		}
+		return stored, nil
+	}
+
+	return nil, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Reserve; key %s keeps expiring", key))
+}
+
+// Save кладет ответ первой попытки на ttl поверх брони
+func (r *IdempotencyRedisRepo) Save(ctx context.Context, key string, record *models.IdempotencyRecord, ttl time.Duration) error {
+	ctx, span := otel.Tracer("").Start(ctx, "IdempotencyRedisRepo.Save")
+	defer span.End()
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Save.Marshal; err = %s", err.Error()))
+	}
+
+	if err = r.db.Set(ctx, r.createDbKey(key), recordBytes, ttl).Err(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Save.Set; err = %s", err.Error()))
+	}
+
+	return nil
+}
+
+// Extend продлевает бронь попытки owner на lockTTL, false - бронь уже истекла или перешла к другой попытке
+func (r *IdempotencyRedisRepo) Extend(ctx context.Context, key, owner string, lockTTL time.Duration) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "IdempotencyRedisRepo.Extend")
+	defer span.End()
+
+	extended, err := extendScript.Run(ctx, r.db, []string{r.createDbKey(key)}, owner, lockTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Extend.Run; err = %s", err.Error()))
+	}
+
+	return extended == 1, nil
+}
+
+// Release снимает бронь попытки owner, если она не удалась, так повтор выполнится заново
+func (r *IdempotencyRedisRepo) Release(ctx context.Context, key, owner string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "IdempotencyRedisRepo.Release")
+	defer span.End()
+
+	if err := releaseScript.Run(ctx, r.db, []string{r.createDbKey(key)}, owner).Err(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("IdempotencyRedisRepo.Release.Run; err = %s", err.Error()))
+	}
+
+	return nil
+}
+
+func (r *IdempotencyRedisRepo) createDbKey(key string) string {
+	return "idempotency:" + key
+}
@@ -0,0 +1,78 @@
+package jobs_http
+
+import (
+	"avito/assignment/internal/jobs/jobs_usecase"
+	"avito/assignment/internal/models"
+	"encoding/json"
+	"time"
+)
+
+const (
+	defaultJobsLimit = 50
+	maxJobsLimit     = 500
+)
+
+type GetJobsRequest struct {
+	Type   *string `query:"type"`
+	Status *string `query:"status" validate:"omitempty,oneof=queued running succeeded failed canceled"`
+	Limit  int     `query:"limit" validate:"gte=0"`
+	Offset int     `query:"offset" validate:"gte=0"`
+}
+
+func (j *GetJobsRequest) ToGetJobs() *jobs_usecase.GetJobs {
+	getJobs := &jobs_usecase.GetJobs{
+		Type:   j.Type,
+		Status: j.Status,
+		Limit:  j.Limit,
+		Offset: j.Offset,
+	}
+	if getJobs.Limit == 0 {
+		getJobs.Limit = defaultJobsLimit
+	}
+	if getJobs.Limit > maxJobsLimit {
+		getJobs.Limit = maxJobsLimit
+	}
+	return getJobs
+}
+
+type JobResponse struct {
+	JobId           models.JobId    `json:"job_id"`
+	Type            string          `json:"type"`
+	Payload         json.RawMessage `json:"payload"`
+	Status          string          `json:"status"`
+	Progress        json.RawMessage `json:"progress"`
+	Attempts        int             `json:"attempts"`
+	MaxAttempts     int             `json:"max_attempts"`
+	RunAt           time.Time       `json:"run_at"`
+	CancelRequested bool            `json:"cancel_requested"`
+	LastError       *string         `json:"last_error"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	FinishedAt      *time.Time      `json:"finished_at"`
+}
+
+func ToJobResponse(job *models.Job) *JobResponse {
+	return &JobResponse{
+		JobId:           job.JobId,
+		Type:            job.Type,
+		Payload:         job.Payload,
+		Status:          job.Status,
+		Progress:        job.Progress,
+		Attempts:        job.Attempts,
+		MaxAttempts:     job.MaxAttempts,
+		RunAt:           job.RunAt,
+		CancelRequested: job.CancelRequested,
+		LastError:       job.LastError,
+		CreatedAt:       job.CreatedAt,
+		UpdatedAt:       job.UpdatedAt,
+		FinishedAt:      job.FinishedAt,
+	}
+}
+
+func ToJobsResponse(jobs []models.Job) []JobResponse {
+	response := make([]JobResponse, len(jobs))
+	for i := range jobs {
+		response[i] = *ToJobResponse(&jobs[i])
+	}
+	return response
+}
@@ -0,0 +1,82 @@
+package jobs_http
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+type JobsHandlers struct {
+	jobsUC JobsUseCase
+	cfg    *config.Config
+}
+
+func NewJobsHandlers(jobsUC JobsUseCase, cfg *config.Config) *JobsHandlers {
+	return &JobsHandlers{
+		jobsUC: jobsUC,
+		cfg:    cfg,
+	}
+}
+
+func (j *JobsHandlers) GetJobs() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "JobsHandlers.GetJobs")
+		defer span.End()
+
+		getJobs := GetJobsRequest{}
+		if err := reqvalidator.ReadQuery(c, &getJobs); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "JobsHandlers.GetJobs.ReadQuery")
+		}
+
+		jobs, err := j.jobsUC.GetJobs(ctx, getJobs.ToGetJobs())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToJobsResponse(jobs))
+	}
+}
+
+func (j *JobsHandlers) GetJob() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "JobsHandlers.GetJob")
+		defer span.End()
+
+		jobId, err := strconv.Atoi(c.Params("job_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "JobsHandlers.GetJob.WrongJobParams")
+		}
+
+		job, err := j.jobsUC.GetJob(ctx, models.JobId(jobId))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToJobResponse(job))
+	}
+}
+
+func (j *JobsHandlers) CancelJob() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "JobsHandlers.CancelJob")
+		defer span.End()
+
+		jobId, err := strconv.Atoi(c.Params("job_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "JobsHandlers.CancelJob.WrongJobParams")
+		}
+
+		if err = j.jobsUC.CancelJob(ctx, models.JobId(jobId)); err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusAccepted)
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package jobs_http
+
+import (
+	"avito/assignment/internal/jobs/jobs_usecase"
+	"avito/assignment/internal/models"
+	"context"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handlers interface {
+	GetJobs() fiber.Handler
+	GetJob() fiber.Handler
+	CancelJob() fiber.Handler
+}
+
+type JobsUseCase interface {
+	GetJobs(ctx context.Context, getJobsParams *jobs_usecase.GetJobs) ([]models.Job, error)
+	GetJob(ctx context.Context, jobId models.JobId) (*models.Job, error)
+	CancelJob(ctx context.Context, jobId models.JobId) error
+}
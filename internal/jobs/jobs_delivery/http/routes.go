@@ -0,0 +1,13 @@
+package jobs_http
+
+import (
+	"avito/assignment/internal/middleware"
+	"avito/assignment/pkg/constant"
+	"github.com/gofiber/fiber/v2"
+)
+
+func MapJobsRoutes(group fiber.Router, h Handlers, mw *middleware.MDWManager) {
+	group.Get("/jobs", mw.CheckAuthToken(constant.AdminRoles), h.GetJobs())
+	group.Get("/jobs/:job_id", mw.CheckAuthToken(constant.AdminRoles), h.GetJob())
+	group.Post("/jobs/:job_id/cancel", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.CancelJob())
+}
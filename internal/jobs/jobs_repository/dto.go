@@ -0,0 +1,40 @@
+package jobs_repository
+
+import (
+	"avito/assignment/internal/models"
+	"time"
+)
+
+// JobLease задача в работе у воркера Owner, записи воркера проходят, только пока аренда его
+type JobLease struct {
+	JobId models.JobId
+	Owner string
+}
+
+type EnqueuePostgresJob struct {
+	Type        string
+	Payload     []byte
+	MaxAttempts int
+	RunAt       time.Time
+	// UniqueKey не nil - задача не ставится, пока в очереди или в работе есть задача с тем же ключом
+	UniqueKey *string
+}
+
+type GetPostgresJobs struct {
+	Type   *string
+	Status *string
+	Limit  int
+	Offset int
+}
+
+type FinishPostgresJob struct {
+	Lease     JobLease
+	Status    string
+	LastError *string
+}
+
+type RetryPostgresJob struct {
+	Lease     JobLease
+	RunAt     time.Time
+	LastError string
+}
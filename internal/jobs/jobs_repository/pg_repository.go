@@ -0,0 +1,387 @@
+package jobs_repository
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/sqlx"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
+)
+
+// ErrLeaseLost аренда задачи истекла и ее забрал другой воркер или задача уже завершена, писать в нее нельзя
+var ErrLeaseLost = errors.New("job lease is lost")
+
+type JobsRepo struct {
+	cfg      *config.Config
+	db       *sqlx.DB
+	txGetter *trmsqlx.CtxGetter
+}
+
+func NewJobsRepository(cfg *config.Config, db *sqlx.DB, txGetter *trmsqlx.CtxGetter) *JobsRepo {
+	return &JobsRepo{
+		cfg:      cfg,
+		db:       db,
+		txGetter: txGetter,
+	}
+}
+
+// Enqueue пишет задачу в транзакции вызывающего, если она есть, так задача появится только вместе с его данными.
+// 0 - задача с тем же UniqueKey уже в очереди или в работе
+func (j *JobsRepo) Enqueue(ctx context.Context, enqueuePostgresJobParams *EnqueuePostgresJob) (models.JobId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.Enqueue")
+	defer span.End()
+
+	query, args, err := sq.Insert(sql_queries.JobsTableName).
+		Columns(sql_queries.InsertJobColumns...).
+		Values(
+			enqueuePostgresJobParams.Type,
+			sq.Expr("?::jsonb", string(enqueuePostgresJobParams.Payload)),
+			models.JobQueued,
+			enqueuePostgresJobParams.MaxAttempts,
+			enqueuePostgresJobParams.RunAt,
+			enqueuePostgresJobParams.UniqueKey,
+			time.Now(),
+			time.Now(),
+		).
+		Suffix(fmt.Sprintf("ON CONFLICT (%s) WHERE %s IN ('%s', '%s') DO NOTHING RETURNING job_id",
+			sql_queries.UniqueKeyColumnName, sql_queries.StatusColumnName, models.JobQueued, models.JobRunning)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.Enqueue.Insert")
+	}
+
+	var jobId models.JobId
+
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	if err = tr.QueryRowxContext(ctx, query, args...).Scan(&jobId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.Enqueue.QueryRowxContext")
+	}
+
+	return jobId, nil
+}
+
+// GetJob nil - задачи нет
+func (j *JobsRepo) GetJob(ctx context.Context, jobId models.JobId) (*models.Job, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.GetJob")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SelectJobColumns...).
+		From(sql_queries.JobsTableName).
+		Where(sq.Eq{sql_queries.JobIdColumnName: jobId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.GetJob.Select")
+	}
+
+	var job models.Job
+
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	if err = tr.GetContext(ctx, &job, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.GetJob.GetContext")
+	}
+
+	return &job, nil
+}
+
+func (j *JobsRepo) GetJobs(ctx context.Context, getPostgresJobsParams *GetPostgresJobs) ([]models.Job, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.GetJobs")
+	defer span.End()
+
+	conditions := sq.Eq{}
+	if getPostgresJobsParams.Type != nil {
+		conditions[sql_queries.TypeColumnName] = *getPostgresJobsParams.Type
+	}
+	if getPostgresJobsParams.Status != nil {
+		conditions[sql_queries.StatusColumnName] = *getPostgresJobsParams.Status
+	}
+
+	query, args, err := sq.Select(sql_queries.SelectJobColumns...).
+		From(sql_queries.JobsTableName).
+		Where(conditions).
+		OrderBy(sql_queries.JobIdColumnName + " DESC").
+		Limit(uint64(getPostgresJobsParams.Limit)).
+		Offset(uint64(getPostgresJobsParams.Offset)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.GetJobs.Select")
+	}
+
+	var jobs []models.Job
+
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	if err = tr.SelectContext(ctx, &jobs, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.GetJobs.SelectContext")
+	}
+
+	return jobs, nil
+}
+
+// ClaimJob
+// 1. Ищем готовую к запуску задачу известного типа или задачу, чья аренда истекла (воркер умер)
+// 2. SKIP LOCKED не дает двум воркерам взять одну задачу
+// 3. Переводим в running, выдаем аренду на lease воркеру owner, считаем попытку
+func (j *JobsRepo) ClaimJob(ctx context.Context, types []string, lease time.Duration, owner string) (*models.Job, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.ClaimJob")
+	defer span.End()
+
+	now := time.Now()
+	candidate := sq.Select(sql_queries.JobIdColumnName).
+		From(sql_queries.JobsTableName).
+		Where(sq.And{
+			sq.Eq{sql_queries.TypeColumnName: types},
+			sq.Or{
+				sq.And{
+					sq.Eq{sql_queries.StatusColumnName: models.JobQueued},
+					sq.LtOrEq{sql_queries.RunAtColumnName: now},
+				},
+				sq.And{
+					sq.Eq{sql_queries.StatusColumnName: models.JobRunning},
+					sq.Lt{sql_queries.LockedUntilColumnName: now},
+				},
+			},
+		}).
+		OrderBy(sql_queries.RunAtColumnName).
+		Limit(1).
+		Suffix("FOR UPDATE SKIP LOCKED")
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.StatusColumnName, models.JobRunning).
+		Set(sql_queries.AttemptsColumnName, sq.Expr("attempts + 1")).
+		Set(sql_queries.LockedUntilColumnName, now.Add(lease)).
+		Set(sql_queries.LeaseOwnerColumnName, owner).
+		Set(sql_queries.UpdatedAtColumnName, now).
+		Where(sq.Expr("job_id = (?)", candidate)).
+		Suffix("RETURNING " + strings.Join(sql_queries.SelectJobColumns, ",")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.ClaimJob.Update")
+	}
+
+	var job models.Job
+
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	if err = tr.GetContext(ctx, &job, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.ClaimJob.GetContext")
+	}
+
+	return &job, nil
+}
+
+// ExtendLease продлевает аренду running задачи и сообщает, не попросили ли ее отменить. ErrLeaseLost - аренда уже не наша
+func (j *JobsRepo) ExtendLease(ctx context.Context, jobLease *JobLease, lease time.Duration) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.ExtendLease")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.LockedUntilColumnName, time.Now().Add(lease)).
+		Where(leased(jobLease)).
+		Suffix("RETURNING " + sql_queries.CancelRequestedColumnName).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.ExtendLease.Update")
+	}
+
+	var cancelRequested bool
+
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	if err = tr.GetContext(ctx, &cancelRequested, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, traces.SpanSetErrWrap(span, ErrLeaseLost,
+				fmt.Errorf("job with id %d is not leased by %s", jobLease.JobId, jobLease.Owner), "JobsRepo.ExtendLease.LeaseLost")
+		}
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.ExtendLease.GetContext")
+	}
+
+	return cancelRequested, nil
+}
+
+// UpdateProgress ErrLeaseLost - аренда уже не наша
+func (j *JobsRepo) UpdateProgress(ctx context.Context, jobLease *JobLease, progress []byte) error {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.UpdateProgress")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.ProgressColumnName, sq.Expr("?::jsonb", string(progress))).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(leased(jobLease)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.UpdateProgress.Update")
+	}
+
+	return j.execLeased(ctx, span, jobLease, query, args, "JobsRepo.UpdateProgress")
+}
+
+// FinishJob переводит задачу воркера в конечный статус и снимает аренду. ErrLeaseLost - аренда уже не наша
+func (j *JobsRepo) FinishJob(ctx context.Context, finishPostgresJobParams *FinishPostgresJob) error {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.FinishJob")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.StatusColumnName, finishPostgresJobParams.Status).
+		Set(sql_queries.LastErrorColumnName, finishPostgresJobParams.LastError).
+		Set(sql_queries.LockedUntilColumnName, nil).
+		Set(sql_queries.LeaseOwnerColumnName, nil).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Set(sql_queries.FinishedAtColumnName, time.Now()).
+		Where(leased(&finishPostgresJobParams.Lease)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.FinishJob.Update")
+	}
+
+	return j.execLeased(ctx, span, &finishPostgresJobParams.Lease, query, args, "JobsRepo.FinishJob")
+}
+
+// RetryJob возвращает задачу воркера в очередь после неудачной попытки. ErrLeaseLost - аренда уже не наша
+func (j *JobsRepo) RetryJob(ctx context.Context, retryPostgresJobParams *RetryPostgresJob) error {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.RetryJob")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.StatusColumnName, models.JobQueued).
+		Set(sql_queries.RunAtColumnName, retryPostgresJobParams.RunAt).
+		Set(sql_queries.LastErrorColumnName, retryPostgresJobParams.LastError).
+		Set(sql_queries.LockedUntilColumnName, nil).
+		Set(sql_queries.LeaseOwnerColumnName, nil).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(leased(&retryPostgresJobParams.Lease)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.RetryJob.Update")
+	}
+
+	return j.execLeased(ctx, span, &retryPostgresJobParams.Lease, query, args, "JobsRepo.RetryJob")
+}
+
+// ReleaseJob отдает задачу воркера обратно при остановке сервера, прерванная попытка не считается. ErrLeaseLost - аренда уже не наша
+func (j *JobsRepo) ReleaseJob(ctx context.Context, jobLease *JobLease) error {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.ReleaseJob")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.StatusColumnName, models.JobQueued).
+		Set(sql_queries.AttemptsColumnName, sq.Expr("GREATEST(attempts - 1, 0)")).
+		Set(sql_queries.RunAtColumnName, time.Now()).
+		Set(sql_queries.LockedUntilColumnName, nil).
+		Set(sql_queries.LeaseOwnerColumnName, nil).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(leased(jobLease)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.ReleaseJob.Update")
+	}
+
+	return j.execLeased(ctx, span, jobLease, query, args, "JobsRepo.ReleaseJob")
+}
+
+// CancelQueuedJob отменяет задачу, пока ее не забрал воркер, false - задача уже не в очереди или ее нет
+func (j *JobsRepo) CancelQueuedJob(ctx context.Context, jobId models.JobId, message string) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.CancelQueuedJob")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.StatusColumnName, models.JobCanceled).
+		Set(sql_queries.CancelRequestedColumnName, true).
+		Set(sql_queries.LastErrorColumnName, message).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Set(sql_queries.FinishedAtColumnName, time.Now()).
+		Where(sq.Eq{
+			sql_queries.JobIdColumnName:  jobId,
+			sql_queries.StatusColumnName: models.JobQueued,
+		}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.CancelQueuedJob.Update")
+	}
+
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	result, err := tr.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.CancelQueuedJob.ExecContext")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.CancelQueuedJob.RowsAffected")
+	}
+
+	return affected != 0, nil
+}
+
+// RequestCancel просит раннер остановить задачу, false - задача уже завершена или ее нет
+func (j *JobsRepo) RequestCancel(ctx context.Context, jobId models.JobId) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsRepo.RequestCancel")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.JobsTableName).
+		Set(sql_queries.CancelRequestedColumnName, true).
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(sq.Eq{
+			sql_queries.JobIdColumnName:  jobId,
+			sql_queries.StatusColumnName: []string{models.JobQueued, models.JobRunning},
+		}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.RequestCancel.Update")
+	}
+
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	result, err := tr.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.RequestCancel.ExecContext")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsRepo.RequestCancel.RowsAffected")
+	}
+
+	return affected != 0, nil
+}
+
+// leased задача в работе именно у воркера из аренды
+func leased(jobLease *JobLease) sq.Eq {
+	return sq.Eq{
+		sql_queries.JobIdColumnName:      jobLease.JobId,
+		sql_queries.StatusColumnName:     models.JobRunning,
+		sql_queries.LeaseOwnerColumnName: jobLease.Owner,
+	}
+}
+
+// execLeased выполняет запись в задачу под арендой, ни одной строки - аренда уже не наша
+func (j *JobsRepo) execLeased(ctx context.Context, span trace.Span, jobLease *JobLease, query string, args []interface{}, place string) error {
+	tr := j.txGetter.DefaultTrOrDB(ctx, j.db)
+	result, err := tr.ExecContext(ctx, query, args...)
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, place+".ExecContext")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, place+".RowsAffected")
+	}
+	if affected == 0 {
+		return traces.SpanSetErrWrap(span, ErrLeaseLost,
+			fmt.Errorf("job with id %d is not leased by %s", jobLease.JobId, jobLease.Owner), place+".LeaseLost")
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package jobs_usecase
+
+import "avito/assignment/internal/jobs/jobs_repository"
+
+type GetJobs struct {
+	Type   *string
+	Status *string
+	Limit  int
+	Offset int
+}
+
+func (j *GetJobs) ToGetPostgresJobs() *jobs_repository.GetPostgresJobs {
+	return &jobs_repository.GetPostgresJobs{
+		Type:   j.Type,
+		Status: j.Status,
+		Limit:  j.Limit,
+		Offset: j.Offset,
+	}
+}
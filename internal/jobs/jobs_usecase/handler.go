@@ -0,0 +1,65 @@
+package jobs_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/utilities"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Handler обработчик задач одного типа, должен уважать отмену ctx: так приходят и отмена задачи, и остановка сервера
+type Handler interface {
+	Handle(ctx context.Context, job *models.Job, progress Progress) error
+}
+
+// Progress сохраняет прогресс задачи, он отдается в GET /jobs/:job_id
+type Progress interface {
+	Report(ctx context.Context, progress interface{}) error
+}
+
+// HandlerFunc типизированный обработчик, payload задачи разбирается в P до вызова
+type HandlerFunc[P any] func(ctx context.Context, payload P, progress Progress) error
+
+func (f HandlerFunc[P]) Handle(ctx context.Context, job *models.Job, progress Progress) error {
+	var payload P
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return f(ctx, payload, progress)
+}
+
+// RetryPolicy число попыток и экспоненциальный backoff между ними
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	return utilities.ExponentialBackoff(p.BaseBackoff, p.MaxBackoff, attempts)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent ошибка, после которой повторять задачу бессмысленно
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
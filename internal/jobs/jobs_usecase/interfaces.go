@@ -0,0 +1,29 @@
+package jobs_usecase
+
+import (
+	"avito/assignment/internal/jobs/jobs_repository"
+	"avito/assignment/internal/models"
+	"context"
+	"time"
+)
+
+type PostgresRepository interface {
+	Enqueue(ctx context.Context, enqueuePostgresJobParams *jobs_repository.EnqueuePostgresJob) (models.JobId, error)
+	GetJob(ctx context.Context, jobId models.JobId) (*models.Job, error)
+	GetJobs(ctx context.Context, getPostgresJobsParams *jobs_repository.GetPostgresJobs) ([]models.Job, error)
+	CancelQueuedJob(ctx context.Context, jobId models.JobId, message string) (bool, error)
+	RequestCancel(ctx context.Context, jobId models.JobId) (bool, error)
+}
+
+type RunnerRepository interface {
+	ClaimJob(ctx context.Context, types []string, lease time.Duration, owner string) (*models.Job, error)
+	ExtendLease(ctx context.Context, jobLease *jobs_repository.JobLease, lease time.Duration) (bool, error)
+	UpdateProgress(ctx context.Context, jobLease *jobs_repository.JobLease, progress []byte) error
+	FinishJob(ctx context.Context, finishPostgresJobParams *jobs_repository.FinishPostgresJob) error
+	RetryJob(ctx context.Context, retryPostgresJobParams *jobs_repository.RetryPostgresJob) error
+	ReleaseJob(ctx context.Context, jobLease *jobs_repository.JobLease) error
+}
+
+type Policies interface {
+	Policy(jobType string) RetryPolicy
+}
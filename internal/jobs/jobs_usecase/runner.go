@@ -0,0 +1,228 @@
+package jobs_usecase
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/jobs/jobs_repository"
+	"avito/assignment/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel"
+	"sync"
+	"time"
+)
+
+var errCancelRequested = errors.New("job canceled")
+
+type registration struct {
+	handler Handler
+	policy  RetryPolicy
+}
+
+// Runner воркеры фоновых задач, стартуют и останавливаются вместе с сервером
+type Runner struct {
+	cfg        *config.Config
+	runnerRepo RunnerRepository
+	handlers   map[string]registration
+}
+
+func NewRunner(cfg *config.Config, runnerRepo RunnerRepository) *Runner {
+	return &Runner{
+		cfg:        cfg,
+		runnerRepo: runnerRepo,
+		handlers:   map[string]registration{},
+	}
+}
+
+// Register регистрирует обработчик типа задач, вызывается до Run
+func (r *Runner) Register(jobType string, handler Handler, policy RetryPolicy) {
+	r.handlers[jobType] = registration{handler: handler, policy: policy}
+}
+
+// Policy политика повторов типа, для незарегистрированных типов берется из конфига
+func (r *Runner) Policy(jobType string) RetryPolicy {
+	if registered, ok := r.handlers[jobType]; ok {
+		return registered.policy
+	}
+	return r.DefaultPolicy()
+}
+
+func (r *Runner) DefaultPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: r.cfg.Jobs.MaxAttempts,
+		BaseBackoff: time.Duration(r.cfg.Jobs.BaseBackoffSeconds) * time.Second,
+		MaxBackoff:  time.Duration(r.cfg.Jobs.MaxBackoffSeconds) * time.Second,
+	}
+}
+
+func (r *Runner) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	for i := 0; i < r.cfg.Jobs.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			r.work(ctx)
+		}()
+	}
+	workers.Wait()
+}
+
+func (r *Runner) work(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(r.cfg.Jobs.PollIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				processed, err := r.RunOnce(ctx)
+				if err != nil {
+					log.Errorf("Runner.RunOnce: %s", err.Error())
+				}
+				if !processed {
+					break
+				}
+			}
+		}
+	}
+}
+
+// RunOnce
+// 1. Забираем одну задачу зарегистрированного типа
+// 2. Пока она выполняется, продлеваем аренду и проверяем запрос на отмену
+// 3. Успех - succeeded, отмена - canceled, остановка сервера или сбой продления аренды - задача возвращается в очередь,
+// аренду забрал другой воркер - итог не пишем
+// 4. Ошибка - повтор по backoff политики, после MaxAttempts или Permanent ошибки - failed
+func (r *Runner) RunOnce(ctx context.Context) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "Runner.RunOnce")
+	defer span.End()
+
+	types := make([]string, 0, len(r.handlers))
+	for jobType := range r.handlers {
+		types = append(types, jobType)
+	}
+	if len(types) == 0 {
+		return false, nil
+	}
+
+	owner, err := newLeaseOwner()
+	if err != nil {
+		return false, err
+	}
+
+	lease := time.Duration(r.cfg.Jobs.LeaseSeconds) * time.Second
+	job, err := r.runnerRepo.ClaimJob(ctx, types, lease, owner)
+	if err != nil || job == nil {
+		return false, err
+	}
+	jobLease := jobs_repository.JobLease{JobId: job.JobId, Owner: owner}
+
+	registered := r.handlers[job.Type]
+	if job.CancelRequested {
+		return true, r.finish(ctx, jobLease, models.JobCanceled, errCancelRequested)
+	}
+	if job.Attempts > job.MaxAttempts {
+		return true, r.finish(ctx, jobLease, models.JobFailed, errors.New("max attempts exceeded"))
+	}
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		r.heartbeat(jobCtx, &jobLease, lease, cancel)
+	}()
+
+	handleErr := registered.handler.Handle(jobCtx, job, &progress{runnerRepo: r.runnerRepo, jobLease: &jobLease})
+	cancelCause := context.Cause(jobCtx)
+	cancel(nil)
+	<-heartbeatDone
+
+	// после остановки сервера ctx уже отменен, а записать итог задачи все равно нужно
+	ctx = context.WithoutCancel(ctx)
+	switch {
+	case errors.Is(cancelCause, jobs_repository.ErrLeaseLost):
+		// задачу уже ведет другой воркер, итог этой попытки не пишем
+		return true, cancelCause
+	case handleErr == nil:
+		return true, r.finish(ctx, jobLease, models.JobSucceeded, nil)
+	case errors.Is(cancelCause, errCancelRequested):
+		return true, r.finish(ctx, jobLease, models.JobCanceled, errCancelRequested)
+	case cancelCause != nil:
+		return true, r.runnerRepo.ReleaseJob(ctx, &jobLease)
+	case IsPermanent(handleErr) || job.Attempts >= job.MaxAttempts:
+		return true, r.finish(ctx, jobLease, models.JobFailed, handleErr)
+	default:
+		return true, r.runnerRepo.RetryJob(ctx, &jobs_repository.RetryPostgresJob{
+			Lease:     jobLease,
+			RunAt:     time.Now().Add(registered.policy.Backoff(job.Attempts)),
+			LastError: handleErr.Error(),
+		})
+	}
+}
+
+// heartbeat продлевает аренду, пока работает обработчик. Не удалось продлить - отменяем обработчик,
+// иначе после истечения аренды задачу заберет другой воркер и они будут работать вдвоем
+func (r *Runner) heartbeat(ctx context.Context, jobLease *jobs_repository.JobLease, lease time.Duration, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cancelRequested, err := r.runnerRepo.ExtendLease(ctx, jobLease, lease)
+			if err != nil {
+				log.Errorf("Runner.heartbeat: %s", err.Error())
+				cancel(err)
+				return
+			}
+			if cancelRequested {
+				cancel(errCancelRequested)
+				return
+			}
+		}
+	}
+}
+
+func (r *Runner) finish(ctx context.Context, jobLease jobs_repository.JobLease, status string, jobErr error) error {
+	var lastError *string
+	if jobErr != nil {
+		message := jobErr.Error()
+		lastError = &message
+	}
+	return r.runnerRepo.FinishJob(ctx, &jobs_repository.FinishPostgresJob{
+		Lease:     jobLease,
+		Status:    status,
+		LastError: lastError,
+	})
+}
+
+type progress struct {
+	runnerRepo RunnerRepository
+	jobLease   *jobs_repository.JobLease
+}
+
+func (p *progress) Report(ctx context.Context, value interface{}) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return p.runnerRepo.UpdateProgress(ctx, p.jobLease, payload)
+}
+
+// newLeaseOwner метка попытки, по ней репозиторий отличает записи текущего владельца аренды от запоздавших
+func newLeaseOwner() (string, error) {
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(owner), nil
+}
@@ -0,0 +1,109 @@
+package jobs_usecase
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/jobs/jobs_repository"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+type JobsUC struct {
+	cfg      *config.Config
+	jobsRepo PostgresRepository
+	policies Policies
+}
+
+func NewJobsUC(cfg *config.Config, jobsRepo PostgresRepository, policies Policies) *JobsUC {
+	return &JobsUC{
+		cfg:      cfg,
+		jobsRepo: jobsRepo,
+		policies: policies,
+	}
+}
+
+// Enqueue ставит задачу в очередь, внутри транзакции вызывающего задача появится только после ее коммита
+func (j *JobsUC) Enqueue(ctx context.Context, jobType string, payload interface{}) (models.JobId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsUC.Enqueue")
+	defer span.End()
+
+	return j.enqueue(ctx, span, jobType, nil, payload)
+}
+
+// EnqueueUnique как Enqueue, но задача не ставится, пока задача с тем же ключом в очереди или в работе, тогда вернет 0
+func (j *JobsUC) EnqueueUnique(ctx context.Context, jobType, uniqueKey string, payload interface{}) (models.JobId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsUC.EnqueueUnique")
+	defer span.End()
+
+	return j.enqueue(ctx, span, jobType, &uniqueKey, payload)
+}
+
+func (j *JobsUC) enqueue(ctx context.Context, span trace.Span, jobType string, uniqueKey *string, payload interface{}) (models.JobId, error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "JobsUC.Enqueue.Marshal")
+	}
+
+	return j.jobsRepo.Enqueue(ctx, &jobs_repository.EnqueuePostgresJob{
+		Type:        jobType,
+		Payload:     rawPayload,
+		MaxAttempts: j.policies.Policy(jobType).MaxAttempts,
+		RunAt:       time.Now(),
+		UniqueKey:   uniqueKey,
+	})
+}
+
+func (j *JobsUC) GetJob(ctx context.Context, jobId models.JobId) (*models.Job, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsUC.GetJob")
+	defer span.End()
+
+	job, err := j.jobsRepo.GetJob(ctx, jobId)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("job with id %d doesnt exist", jobId), "JobsUC.GetJob.DoNotExist")
+	}
+
+	return job, nil
+}
+
+func (j *JobsUC) GetJobs(ctx context.Context, getJobsParams *GetJobs) ([]models.Job, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsUC.GetJobs")
+	defer span.End()
+
+	return j.jobsRepo.GetJobs(ctx, getJobsParams.ToGetPostgresJobs())
+}
+
+// CancelJob
+// 1. Задачу из очереди отменяем сразу одним условным UPDATE, воркер не успеет ее забрать
+// 2. Выполняющуюся помечаем, раннер увидит пометку при продлении аренды и отменит обработчик
+// 3. Завершенную отменить нельзя
+func (j *JobsUC) CancelJob(ctx context.Context, jobId models.JobId) error {
+	ctx, span := otel.Tracer("").Start(ctx, "JobsUC.CancelJob")
+	defer span.End()
+
+	canceled, err := j.jobsRepo.CancelQueuedJob(ctx, jobId, "job canceled")
+	if err != nil || canceled {
+		return err
+	}
+
+	requested, err := j.jobsRepo.RequestCancel(ctx, jobId)
+	if err != nil || requested {
+		return err
+	}
+
+	job, err := j.GetJob(ctx, jobId)
+	if err != nil {
+		return err
+	}
+	return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+		fmt.Errorf("impossible to cancel, job with id %d is already %s", jobId, job.Status), "JobsUC.CancelJob.AlreadyFinished")
+}
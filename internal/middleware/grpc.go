@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"avito/assignment/pkg/utilities"
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tokenCtxKey struct{}
+
+type actorCtxKey struct{}
+
+// metadataCarrier позволяет достать контекст трассировки из grpc metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenCtxKey{}).(string)
+	return token
+}
+
+// ActorFromContext имя админа с персональным токеном, аналог Actor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorCtxKey{}).(string)
+	return actor
+}
+
+// GRPCTrace продолжает трассировку клиента и заводит спан на каждый вызов
+func (m *MDWManager) GRPCTrace() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if ok {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		}
+
+		ctx, span := otel.Tracer("").Start(ctx, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return resp, err
+	}
+}
+
+// GRPCCheckAuthToken аналог CheckAuthToken, токен передается через metadata "token"
+func (m *MDWManager) GRPCCheckAuthToken(restrictions map[string][]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		_, span := otel.Tracer("").Start(ctx, "MDWManager.GRPCCheckAuthToken")
+
+		var token, actor string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("token"); len(values) != 0 {
+				token, actor = m.resolveToken(values[0])
+			}
+		}
+
+		if token == "" {
+			span.SetStatus(codes.Error, "MDWManager.GRPCCheckAuthToken.NilToken")
+			span.End()
+			return nil, status.Error(grpccodes.Unauthenticated, "MDWManager.GRPCCheckAuthToken.NilToken")
+		} else if !utilities.InStringSlice(token, restrictions[info.FullMethod]) {
+			span.SetStatus(codes.Error, "MDWManager.GRPCCheckAuthToken.Forbidden")
+			span.End()
+			return nil, status.Error(grpccodes.PermissionDenied, "MDWManager.GRPCCheckAuthToken.Forbidden")
+		}
+		span.End()
+
+		ctx = context.WithValue(ctx, tokenCtxKey{}, token)
+		return handler(context.WithValue(ctx, actorCtxKey{}, actor), req)
+	}
+}
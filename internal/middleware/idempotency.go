@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader повтор админской записи с тем же ключом получает сохраненный ответ первой попытки
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader выставляется на ответах, отданных из сохраненного
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// replayedHeaders заголовки ответа, которые сохраняются вместе с телом и отдаются при повторе
+var replayedHeaders = []string{fiber.HeaderContentType, fiber.HeaderETag, fiber.HeaderLocation}
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key string, record *models.IdempotencyRecord, lockTTL time.Duration) (*models.IdempotencyRecord, error)
+	Extend(ctx context.Context, key, owner string, lockTTL time.Duration) (bool, error)
+	Save(ctx context.Context, key string, record *models.IdempotencyRecord, ttl time.Duration) error
+	Release(ctx context.Context, key, owner string) error
+}
+
+// Idempotency ставится после CheckAuthToken на админские записи
+// 1. Без заголовка Idempotency-Key запрос идет как обычно
+// 2. Ключи у каждого админа свои (по общему токену роли - общие), под ключ запоминается хэш метода, пути и тела
+// 3. Повтор того же запроса отдает сохраненный ответ, другой запрос с тем же ключом - 422, повтор во время первой попытки - 409
+// 4. Пока первая попытка выполняется, ее бронь продлевается, так долгий импорт или восстановление не выполнятся дважды
+// 5. Сохраняются только выполненные запросы, после ошибки ключ освобождается и запрос можно повторить.
+// Если ответ сохранить не удалось, под ключом остается отметка о выполнении, повтор получит 409 и ничего не выполнит
+func (m *MDWManager) Idempotency() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return c.Next()
+		}
+
+		ctx, span := traces.StartFiberTrace(c, "MDWManager.Idempotency")
+		defer span.End()
+
+		if len(key) > maxIdempotencyKeyLength {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("idempotency key is longer than %d characters", maxIdempotencyKeyLength), "MDWManager.Idempotency.WrongKey")
+		}
+		scope := Actor(c)
+		if scope == "" {
+			scope, _ = c.Locals("token").(string)
+		}
+		storeKey := scope + ":" + key
+		fingerprint := requestFingerprint(c)
+		owner, err := newAttemptOwner()
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "MDWManager.Idempotency.Owner")
+		}
+		lockTTL := time.Duration(m.cfg.Idempotency.LockSeconds) * time.Second
+
+		stored, err := m.idempotency.Reserve(ctx, storeKey, &models.IdempotencyRecord{Fingerprint: fingerprint, Owner: owner}, lockTTL)
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrServiceUnavailable, err, "MDWManager.Idempotency.Reserve")
+		}
+		if stored != nil {
+			return replayIdempotent(c, span, key, fingerprint, stored)
+		}
+
+		stopExtending := m.extendReservation(ctx, storeKey, owner, lockTTL)
+		err = c.Next()
+		stopExtending()
+		if err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			if releaseErr := m.idempotency.Release(ctx, storeKey, owner); releaseErr != nil {
+				log.Errorf("MDWManager.Idempotency.Release: %s", releaseErr.Error())
+			}
+			return err
+		}
+
+		record := &models.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Done:        true,
+			Status:      c.Response().StatusCode(),
+			Headers:     map[string]string{},
+			Body:        c.Response().Body(),
+		}
+		for _, header := range replayedHeaders {
+			if value := c.Response().Header.Peek(header); len(value) != 0 {
+				record.Headers[header] = string(value)
+			}
+		}
+		ttl := time.Duration(m.cfg.Idempotency.TTLSeconds) * time.Second
+		if err = m.idempotency.Save(ctx, storeKey, record, ttl); err != nil {
+			log.Errorf("MDWManager.Idempotency.Save: %s", err.Error())
+			lost := &models.IdempotencyRecord{Fingerprint: fingerprint, Done: true, ResponseLost: true}
+			if err = m.idempotency.Save(ctx, storeKey, lost, ttl); err != nil {
+				log.Errorf("MDWManager.Idempotency.SaveLost: %s", err.Error())
+			}
+		}
+		return nil
+	}
+}
+
+func replayIdempotent(c *fiber.Ctx, span trace.Span, key, fingerprint string, stored *models.IdempotencyRecord) error {
+	if stored.Fingerprint != fingerprint {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrUnprocessable,
+			fmt.Errorf("idempotency key %s was already used for a different request", key), "MDWManager.Idempotency.KeyReused")
+	}
+	if !stored.Done {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrConflict,
+			fmt.Errorf("request with idempotency key %s is still in progress", key), "MDWManager.Idempotency.InProgress")
+	}
+	if stored.ResponseLost {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrConflict,
+			fmt.Errorf("request with idempotency key %s was completed, but its response was not saved", key), "MDWManager.Idempotency.ResponseLost")
+	}
+
+	c.Set(IdempotentReplayedHeader, "true")
+	for header, value := range stored.Headers {
+		c.Set(header, value)
+	}
+	return c.Status(stored.Status).Send(stored.Body)
+}
+
+// extendReservation раз в треть lockTTL продлевает бронь, пока не вызвана возвращенная функция остановки
+func (m *MDWManager) extendReservation(ctx context.Context, key, owner string, lockTTL time.Duration) func() {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(lockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				extended, err := m.idempotency.Extend(ctx, key, owner, lockTTL)
+				if err != nil {
+					log.Errorf("MDWManager.Idempotency.Extend: %s", err.Error())
+					continue
+				}
+				if !extended {
+					log.Errorf("MDWManager.Idempotency.Extend: reservation of %s is lost", key)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func newAttemptOwner() (string, error) {
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(owner), nil
+}
+
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.OriginalURL() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
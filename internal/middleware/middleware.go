@@ -2,18 +2,21 @@ package middleware
 
 import (
 	"avito/assignment/config"
+	"avito/assignment/pkg/constant"
 	"avito/assignment/pkg/errlst"
 	"avito/assignment/pkg/traces"
 	"avito/assignment/pkg/utilities"
+	"crypto/subtle"
 	"github.com/gofiber/fiber/v2"
 )
 
 type MDWManager struct {
-	cfg *config.Config
+	cfg         *config.Config
+	idempotency IdempotencyStore
 }
 
-func NewOfficiantMiddleware(cfg *config.Config) *MDWManager {
-	return &MDWManager{cfg: cfg}
+func NewOfficiantMiddleware(cfg *config.Config, idempotency IdempotencyStore) *MDWManager {
+	return &MDWManager{cfg: cfg, idempotency: idempotency}
 }
 
 func (m *MDWManager) CheckAuthToken(restrictions []string) fiber.Handler {
@@ -21,7 +24,7 @@ func (m *MDWManager) CheckAuthToken(restrictions []string) fiber.Handler {
 		_, span := traces.StartFiberTrace(c, "MDWManager.CheckAuthToken")
 		defer span.End()
 
-		token := c.Get("token")
+		token, actor := m.resolveToken(c.Get("token"))
 		if token == "" {
 			return traces.SpanSetErrWrap(span, errlst.HttpErrUnauthorized, nil, "MDWManager.CheckAuthToken.NilToken")
 		} else if !utilities.InStringSlice(token, restrictions) {
@@ -29,7 +32,24 @@ func (m *MDWManager) CheckAuthToken(restrictions []string) fiber.Handler {
 		}
 
 		c.Locals("token", token)
+		c.Locals("actor", actor)
 
 		return c.Next()
 	}
 }
+
+// Actor имя админа, пришедшего со своим персональным токеном, для общего токена роли пусто
+func Actor(c *fiber.Ctx) string {
+	actor, _ := c.Locals("actor").(string)
+	return actor
+}
+
+// resolveToken персональный токен админа дает роль админа и его имя, остальные токены - сами себе роль
+func (m *MDWManager) resolveToken(token string) (role string, actor string) {
+	for _, admin := range m.cfg.Auth.Admins {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(admin.Token)) == 1 {
+			return constant.AdminToken, admin.Name
+		}
+	}
+	return token, ""
+}
@@ -13,6 +13,7 @@ type Banner struct {
 	Text      string    `db:"text"`
 	Url       string    `db:"url"`
 	IsActive  bool      `db:"is_active"`
+	Status    string    `db:"status"`
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 	Version   int64     `db:"version"`
@@ -28,7 +29,9 @@ type FullBanner struct {
 		Text  string
 		Url   string
 	}
-	IsActive  bool
+	IsActive bool
+	// Status статус жизненного цикла, IsActive всегда равен Status == BannerStatusActive
+	Status    string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Version   int64
@@ -45,6 +48,7 @@ func (b *Banner) ToFullBanner(tagIds []TagId) *FullBanner {
 			Url   string
 		}{Title: b.Title, Text: b.Text, Url: b.Url},
 		IsActive:  b.IsActive,
+		Status:    b.Status,
 		CreatedAt: b.CreatedAt,
 		UpdatedAt: b.UpdatedAt,
 		Version:   b.Version,
@@ -61,8 +65,43 @@ func (b *Banner) ToFullBannerWithoutTagIds() *FullBanner {
 			Url   string
 		}{Title: b.Title, Text: b.Text, Url: b.Url},
 		IsActive:  b.IsActive,
+		Status:    b.Status,
 		CreatedAt: b.CreatedAt,
 		UpdatedAt: b.UpdatedAt,
 		Version:   b.Version,
 	}
 }
+
+// BannerPayload баннер в том виде, в котором он уходит во внешние системы (журнал изменений, вебхуки)
+type BannerPayload struct {
+	BannerId  BannerId  `json:"banner_id"`
+	TagIds    []TagId   `json:"tag_ids"`
+	FeatureId FeatureId `json:"feature_id"`
+	Content   struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+		Url   string `json:"url"`
+	} `json:"content"`
+	IsActive  bool      `json:"is_active"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"version"`
+}
+
+func (b *FullBanner) ToPayload() *BannerPayload {
+	payload := &BannerPayload{
+		BannerId:  b.BannerId,
+		TagIds:    b.TagIds,
+		FeatureId: b.FeatureId,
+		IsActive:  b.IsActive,
+		Status:    b.Status,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+		Version:   b.Version,
+	}
+	payload.Content.Title = b.Content.Title
+	payload.Content.Text = b.Content.Text
+	payload.Content.Url = b.Content.Url
+	return payload
+}
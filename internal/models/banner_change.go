@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	BannerChangeCreated = "created"
+	BannerChangeUpdated = "updated"
+	BannerChangeDeleted = "deleted"
+)
+
+// BannerChange событие об изменении баннера админом, Banner = nil если баннер удален
+// PrevFeatureId и PrevTagIds нужны, чтобы подписчики старых пар узнали, что баннер с них ушел
+type BannerChange struct {
+	EventId       string      `json:"event_id"`
+	Type          string      `json:"type"`
+	BannerId      BannerId    `json:"banner_id"`
+	Banner        *FullBanner `json:"banner"`
+	PrevFeatureId FeatureId   `json:"prev_feature_id"`
+	PrevTagIds    []TagId     `json:"prev_tag_ids"`
+}
+
+// ChangeLogEntry запись журнала изменений, payload - полное состояние баннера после события (для удаления - последнее)
+type ChangeLogEntry struct {
+	ChangeId  int64           `db:"change_id"`
+	BannerId  BannerId        `db:"banner_id"`
+	EventType string          `db:"event_type"`
+	Payload   json.RawMessage `db:"payload"`
+	CreatedAt time.Time       `db:"created_at"`
+}
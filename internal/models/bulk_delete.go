@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Статусы массового удаления в ответе GET /banner/bulk_delete/:job_id. Очередь раннера называет их queued и succeeded,
+// а клиенты ждут имена, которые были до переезда на раннер
+const (
+	BulkDeletePending = "pending"
+	BulkDeleteDone    = "done"
+)
+
+// BulkDeleteJob массовое удаление баннеров фичи и/или тэга глазами админа, хранится как задача раннера
+type BulkDeleteJob struct {
+	JobId      JobId
+	FeatureId  *FeatureId
+	TagId      *TagId
+	Status     string
+	Total      *int64
+	Deleted    int64
+	Errors     []string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt *time.Time
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	DraftPending   = "pending"
+	DraftApproved  = "approved"
+	DraftPublished = "published"
+	DraftRejected  = "rejected"
+)
+
+var DraftStatuses = []string{
+	DraftPending,
+	DraftApproved,
+	DraftPublished,
+	DraftRejected,
+}
+
+type DraftId int64
+
+// BannerDraft правка баннера, которая не видна пользователям, пока ее не опубликуют.
+// Banner - баннер целиком в том виде, в котором он станет живым, BaseVersion - версия живого баннера, поверх которой сделан черновик
+type BannerDraft struct {
+	DraftId     DraftId
+	BannerId    BannerId
+	BaseVersion int64
+	Banner      FullBanner
+	Status      string
+	Author      string
+	ReviewedBy  *string
+	ReviewedAt  *time.Time
+	Comment     string
+	PublishedBy *string
+	PublishedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// IsOpen черновик еще можно править, одобрить, отклонить или опубликовать
+func (d *BannerDraft) IsOpen() bool {
+	return d.Status == DraftPending || d.Status == DraftApproved
+}
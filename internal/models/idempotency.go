@@ -0,0 +1,15 @@
+package models
+
+// IdempotencyRecord запрос с Idempotency-Key и ответ на него. Пока первая попытка выполняется, Done = false и ответа нет.
+// Fingerprint - хэш метода, пути и тела, по нему отличается повтор от другого запроса с тем же ключом.
+// Owner - случайный идентификатор попытки, продлить и снять бронь может только она
+type IdempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	Owner       string            `json:"owner,omitempty"`
+	Done        bool              `json:"done"`
+	Status      int               `json:"status,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	// ResponseLost запрос выполнен, но ответ сохранить не удалось. Повтор его не выполнит заново, но и ответа не получит
+	ResponseLost bool `json:"response_lost,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+var JobStatuses = []string{
+	JobQueued,
+	JobRunning,
+	JobSucceeded,
+	JobFailed,
+	JobCanceled,
+}
+
+type JobId int64
+
+// Job фоновая задача, Payload разбирает обработчик своего Type, Progress пишет сам обработчик
+type Job struct {
+	JobId           JobId           `db:"job_id"`
+	Type            string          `db:"type"`
+	Payload         json.RawMessage `db:"payload"`
+	Status          string          `db:"status"`
+	Progress        json.RawMessage `db:"progress"`
+	Attempts        int             `db:"attempts"`
+	MaxAttempts     int             `db:"max_attempts"`
+	RunAt           time.Time       `db:"run_at"`
+	LockedUntil     *time.Time      `db:"locked_until"`
+	LeaseOwner      *string         `db:"lease_owner"`
+	CancelRequested bool            `db:"cancel_requested"`
+	LastError       *string         `db:"last_error"`
+	CreatedAt       time.Time       `db:"created_at"`
+	UpdatedAt       time.Time       `db:"updated_at"`
+	FinishedAt      *time.Time      `db:"finished_at"`
+}
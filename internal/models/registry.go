@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// RegistryKind реестр тэгов или фич, у обоих одинаковая структура
+type RegistryKind string
+
+const (
+	TagsRegistry     RegistryKind = "tags"
+	FeaturesRegistry RegistryKind = "features"
+)
+
+func (k RegistryKind) Singular() string {
+	if k == TagsRegistry {
+		return "tag"
+	}
+	return "feature"
+}
+
+// RegistryEntry запись реестра, Id - tag_id или feature_id в зависимости от реестра
+type RegistryEntry struct {
+	Id          int64     `db:"id"`
+	Slug        string    `db:"slug"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Owner       string    `db:"owner"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+	Aliases     []string  `db:"-"`
+}
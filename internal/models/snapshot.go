@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// SnapshotBanner строка banners в архиве снапшота, айдишники сохраняются как есть
+type SnapshotBanner struct {
+	BannerId  BannerId  `db:"banner_id" json:"banner_id"`
+	FeatureId FeatureId `db:"feature_id" json:"feature_id"`
+	Title     string    `db:"title" json:"title"`
+	Text      string    `db:"text" json:"text"`
+	Url       string    `db:"url" json:"url"`
+	IsActive  bool      `db:"is_active" json:"is_active"`
+	Status    string    `db:"status" json:"status"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Version   int64     `db:"version" json:"version"`
+
+	// DeletedAt и DeletedBy заполнены у баннеров из корзины
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	DeletedBy *string    `db:"deleted_by" json:"deleted_by,omitempty"`
+}
+
+// SnapshotTagLink строка banners_X_tags в архиве снапшота
+type SnapshotTagLink struct {
+	BannerId BannerId `db:"banner_id" json:"banner_id"`
+	TagId    TagId    `db:"tag_id" json:"tag_id"`
+}
+
+// SnapshotVersion строка banners_versions в архиве снапшота
+type SnapshotVersion struct {
+	BannerId  BannerId      `db:"banner_id" json:"banner_id"`
+	FeatureId FeatureId     `db:"feature_id" json:"feature_id"`
+	TagIds    pq.Int64Array `db:"tag_ids" json:"tag_ids"`
+	Title     string        `db:"title" json:"title"`
+	Text      string        `db:"text" json:"text"`
+	Url       string        `db:"url" json:"url"`
+	IsActive  bool          `db:"is_active" json:"is_active"`
+	Status    string        `db:"status" json:"status"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at" json:"updated_at"`
+	Version   int64         `db:"version" json:"version"`
+}
+
+// SnapshotData полное состояние баннеров, которое снимается в снапшот и из него восстанавливается
+type SnapshotData struct {
+	Banners  []SnapshotBanner
+	TagLinks []SnapshotTagLink
+	Versions []SnapshotVersion
+}
+
+// SnapshotManifest описание архива, Checksums - sha256 каждого файла с данными
+type SnapshotManifest struct {
+	SnapshotId    string            `json:"snapshot_id"`
+	FormatVersion int               `json:"format_version"`
+	SchemaVersion int64             `json:"schema_version"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Counts        map[string]int    `json:"counts"`
+	Checksums     map[string]string `json:"checksums"`
+}
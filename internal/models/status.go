@@ -0,0 +1,85 @@
+package models
+
+const (
+	BannerStatusDraft     = "draft"
+	BannerStatusScheduled = "scheduled"
+	BannerStatusActive    = "active"
+	BannerStatusPaused    = "paused"
+	BannerStatusExpired   = "expired"
+	BannerStatusArchived  = "archived"
+)
+
+var BannerStatuses = []string{
+	BannerStatusDraft,
+	BannerStatusScheduled,
+	BannerStatusActive,
+	BannerStatusPaused,
+	BannerStatusExpired,
+	BannerStatusArchived,
+}
+
+// bannerTransitions в какие статусы можно перевести баннер из текущего
+var bannerTransitions = map[string][]string{
+	BannerStatusDraft:     {BannerStatusScheduled, BannerStatusActive, BannerStatusArchived},
+	BannerStatusScheduled: {BannerStatusDraft, BannerStatusActive, BannerStatusPaused, BannerStatusArchived},
+	BannerStatusActive:    {BannerStatusPaused, BannerStatusExpired, BannerStatusArchived},
+	BannerStatusPaused:    {BannerStatusActive, BannerStatusScheduled, BannerStatusExpired, BannerStatusArchived},
+	BannerStatusExpired:   {BannerStatusDraft, BannerStatusArchived},
+	BannerStatusArchived:  {BannerStatusDraft},
+}
+
+type Delivery int
+
+const (
+	// DeliverNobody баннер не отдается никому
+	DeliverNobody Delivery = iota
+	// DeliverAdmins баннер видят только админы, например для превью
+	DeliverAdmins
+	// DeliverAll баннер отдается всем
+	DeliverAll
+)
+
+// bannerDelivery кому отдается баннер в каждом статусе
+var bannerDelivery = map[string]Delivery{
+	BannerStatusDraft:     DeliverAdmins,
+	BannerStatusScheduled: DeliverAdmins,
+	BannerStatusActive:    DeliverAll,
+	BannerStatusPaused:    DeliverAdmins,
+	BannerStatusExpired:   DeliverAdmins,
+	BannerStatusArchived:  DeliverNobody,
+}
+
+// CanTransition можно ли перевести баннер из статуса from в to, оставаться в том же статусе можно всегда
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, status := range bannerTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInitialStatus статус, с которым баннер можно создать
+func IsInitialStatus(status string) bool {
+	return status == BannerStatusDraft || status == BannerStatusScheduled ||
+		status == BannerStatusActive || status == BannerStatusPaused
+}
+
+// StatusFromIsActive статус для клиентов, которые знают только is_active: true - active,
+// false ставит на паузу активный баннер, остальные статусы и так не активны и остаются как есть
+func StatusFromIsActive(current string, isActive bool) string {
+	if isActive {
+		return BannerStatusActive
+	}
+	if current == "" || current == BannerStatusActive {
+		return BannerStatusPaused
+	}
+	return current
+}
+
+func DeliveryOf(status string) Delivery {
+	return bannerDelivery[status]
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// TrashedBanner баннер в корзине: не отдается пользователям и не занимает пары тэг-фича, пока его не восстановят
+type TrashedBanner struct {
+	FullBanner
+	DeletedAt time.Time
+	DeletedBy string
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	WebhookEventCreated     = "created"
+	WebhookEventUpdated     = "updated"
+	WebhookEventActivated   = "activated"
+	WebhookEventDeactivated = "deactivated"
+	WebhookEventDeleted     = "deleted"
+)
+
+var WebhookEventTypes = []string{
+	WebhookEventCreated,
+	WebhookEventUpdated,
+	WebhookEventActivated,
+	WebhookEventDeactivated,
+	WebhookEventDeleted,
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+type SubscriptionId int64
+
+type DeliveryId int64
+
+// WebhookEvent событие жизненного цикла баннера, FeatureIds - все фичи, которых касается событие
+type WebhookEvent struct {
+	EventType  string
+	BannerId   BannerId
+	FeatureIds []FeatureId
+	Banner     *BannerPayload
+	OccurredAt time.Time
+}
+
+type WebhookSubscription struct {
+	SubscriptionId SubscriptionId `db:"subscription_id"`
+	Url            string         `db:"url"`
+	Secret         string         `db:"secret"`
+	FeatureIds     pq.Int64Array  `db:"feature_ids"`
+	EventTypes     pq.StringArray `db:"event_types"`
+	CreatedAt      time.Time      `db:"created_at"`
+}
+
+// WebhookDelivery строка outbox вместе с адресом и секретом подписки
+type WebhookDelivery struct {
+	DeliveryId     DeliveryId      `db:"delivery_id"`
+	SubscriptionId SubscriptionId  `db:"subscription_id"`
+	Url            string          `db:"url"`
+	Secret         string          `db:"secret"`
+	EventType      string          `db:"event_type"`
+	Payload        json.RawMessage `db:"payload"`
+	Status         string          `db:"status"`
+	Attempts       int             `db:"attempts"`
+	NextAttemptAt  time.Time       `db:"next_attempt_at"`
+	LastError      *string         `db:"last_error"`
+	CreatedAt      time.Time       `db:"created_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at"`
+}
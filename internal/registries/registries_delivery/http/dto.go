@@ -0,0 +1,86 @@
+package registries_http
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/registries/registries_usecase"
+	"time"
+)
+
+type AddEntryRequest struct {
+	Id          int64  `json:"id" validate:"required,gt=0"`
+	Slug        string `json:"slug" validate:"required,slug"`
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+}
+
+func (e *AddEntryRequest) ToAddEntry() *registries_usecase.AddEntry {
+	return &registries_usecase.AddEntry{
+		Id:          e.Id,
+		Slug:        e.Slug,
+		Name:        e.Name,
+		Description: e.Description,
+		Owner:       e.Owner,
+	}
+}
+
+type PatchEntryRequest struct {
+	Slug        *string `json:"slug" validate:"omitempty,slug"`
+	Name        *string `json:"name" validate:"omitempty,min=1"`
+	Description *string `json:"description"`
+	Owner       *string `json:"owner"`
+}
+
+func (e *PatchEntryRequest) ToPatchEntry(id int64) *registries_usecase.PatchEntry {
+	return &registries_usecase.PatchEntry{
+		Id:          id,
+		Slug:        e.Slug,
+		Name:        e.Name,
+		Description: e.Description,
+		Owner:       e.Owner,
+	}
+}
+
+type GetEntriesRequest struct {
+	Limit  *int `query:"limit" validate:"omitempty,gte=0"`
+	Offset *int `query:"offset" validate:"omitempty,gte=0"`
+}
+
+func (e *GetEntriesRequest) ToGetEntries() *registries_usecase.GetEntries {
+	return &registries_usecase.GetEntries{
+		Limit:  e.Limit,
+		Offset: e.Offset,
+	}
+}
+
+type EntryResponse struct {
+	Id          int64     `json:"id"`
+	Slug        string    `json:"slug"`
+	Aliases     []string  `json:"aliases,omitempty"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Owner       string    `json:"owner"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func ToEntryResponse(entry *models.RegistryEntry) *EntryResponse {
+	return &EntryResponse{
+		Id:          entry.Id,
+		Slug:        entry.Slug,
+		Aliases:     entry.Aliases,
+		Name:        entry.Name,
+		Description: entry.Description,
+		Owner:       entry.Owner,
+		CreatedAt:   entry.CreatedAt,
+		UpdatedAt:   entry.UpdatedAt,
+	}
+}
+
+func ToEntriesResponse(entries []models.RegistryEntry) []EntryResponse {
+	response := make([]EntryResponse, len(entries))
+	for i := range entries {
+		response[i] = *ToEntryResponse(&entries[i])
+	}
+	return response
+}
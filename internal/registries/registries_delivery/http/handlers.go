@@ -0,0 +1,128 @@
+package registries_http
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+type RegistriesHandlers struct {
+	registriesUC RegistriesUseCase
+	cfg          *config.Config
+}
+
+func NewRegistriesHandlers(registriesUC RegistriesUseCase, cfg *config.Config) *RegistriesHandlers {
+	return &RegistriesHandlers{
+		registriesUC: registriesUC,
+		cfg:          cfg,
+	}
+}
+
+func (r *RegistriesHandlers) AddEntry(kind models.RegistryKind) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "RegistriesHandlers.AddEntry")
+		defer span.End()
+
+		addEntry := AddEntryRequest{}
+		if err := reqvalidator.ReadRequest(c, &addEntry); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "RegistriesHandlers.AddEntry.ReadRequest")
+		}
+
+		if err := r.registriesUC.AddEntry(ctx, kind, addEntry.ToAddEntry()); err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusCreated)
+		return c.JSON(fiber.Map{
+			"id": addEntry.Id,
+		})
+	}
+}
+
+func (r *RegistriesHandlers) GetEntry(kind models.RegistryKind) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "RegistriesHandlers.GetEntry")
+		defer span.End()
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "RegistriesHandlers.GetEntry.WrongIdParams")
+		}
+
+		entry, err := r.registriesUC.GetEntry(ctx, kind, int64(id))
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToEntryResponse(entry))
+	}
+}
+
+func (r *RegistriesHandlers) GetEntries(kind models.RegistryKind) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "RegistriesHandlers.GetEntries")
+		defer span.End()
+
+		getEntries := GetEntriesRequest{}
+		if err := reqvalidator.ReadQuery(c, &getEntries); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "RegistriesHandlers.GetEntries.ReadQuery")
+		}
+
+		entries, err := r.registriesUC.GetEntries(ctx, kind, getEntries.ToGetEntries())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToEntriesResponse(entries))
+	}
+}
+
+func (r *RegistriesHandlers) PatchEntry(kind models.RegistryKind) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "RegistriesHandlers.PatchEntry")
+		defer span.End()
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "RegistriesHandlers.PatchEntry.WrongIdParams")
+		}
+
+		patchEntry := PatchEntryRequest{}
+		if err = reqvalidator.ReadRequest(c, &patchEntry); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "RegistriesHandlers.PatchEntry.ReadRequest")
+		}
+
+		if err = r.registriesUC.PatchEntry(ctx, kind, patchEntry.ToPatchEntry(int64(id))); err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
+
+func (r *RegistriesHandlers) DeleteEntry(kind models.RegistryKind) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "RegistriesHandlers.DeleteEntry")
+		defer span.End()
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "RegistriesHandlers.DeleteEntry.WrongIdParams")
+		}
+
+		if err = r.registriesUC.DeleteEntry(ctx, kind, int64(id)); err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusNoContent)
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package registries_http
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/registries/registries_usecase"
+	"context"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handlers interface {
+	AddEntry(kind models.RegistryKind) fiber.Handler
+	GetEntry(kind models.RegistryKind) fiber.Handler
+	GetEntries(kind models.RegistryKind) fiber.Handler
+	PatchEntry(kind models.RegistryKind) fiber.Handler
+	DeleteEntry(kind models.RegistryKind) fiber.Handler
+}
+
+type RegistriesUseCase interface {
+	AddEntry(ctx context.Context, kind models.RegistryKind, addEntryParams *registries_usecase.AddEntry) error
+	GetEntry(ctx context.Context, kind models.RegistryKind, id int64) (*models.RegistryEntry, error)
+	GetEntries(ctx context.Context, kind models.RegistryKind, getEntriesParams *registries_usecase.GetEntries) ([]models.RegistryEntry, error)
+	PatchEntry(ctx context.Context, kind models.RegistryKind, patchEntryParams *registries_usecase.PatchEntry) error
+	DeleteEntry(ctx context.Context, kind models.RegistryKind, id int64) error
+}
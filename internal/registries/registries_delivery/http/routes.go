@@ -0,0 +1,19 @@
+package registries_http
+
+import (
+	"avito/assignment/internal/middleware"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/constant"
+	"github.com/gofiber/fiber/v2"
+)
+
+func MapRegistriesRoutes(group fiber.Router, h Handlers, mw *middleware.MDWManager) {
+	for _, kind := range []models.RegistryKind{models.TagsRegistry, models.FeaturesRegistry} {
+		path := "/" + string(kind)
+		group.Post(path, mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.AddEntry(kind))
+		group.Get(path, mw.CheckAuthToken(constant.AdminRoles), h.GetEntries(kind))
+		group.Get(path+"/:id", mw.CheckAuthToken(constant.AdminRoles), h.GetEntry(kind))
+		group.Patch(path+"/:id", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.PatchEntry(kind))
+		group.Delete(path+"/:id", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.DeleteEntry(kind))
+	}
+}
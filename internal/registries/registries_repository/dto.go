@@ -0,0 +1,22 @@
+package registries_repository
+
+type AddPostgresEntry struct {
+	Id          int64
+	Slug        string
+	Name        string
+	Description string
+	Owner       string
+}
+
+type UpdatePostgresEntry struct {
+	Id          int64
+	Slug        *string
+	Name        *string
+	Description *string
+	Owner       *string
+}
+
+type GetPostgresEntries struct {
+	Limit  *int
+	Offset *int
+}
@@ -0,0 +1,326 @@
+package registries_repository
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/sqlx"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"time"
+)
+
+type RegistriesRepo struct {
+	cfg      *config.Config
+	db       *sqlx.DB
+	txGetter *trmsqlx.CtxGetter
+}
+
+func NewRegistriesRepository(cfg *config.Config, db *sqlx.DB, txGetter *trmsqlx.CtxGetter) *RegistriesRepo {
+	return &RegistriesRepo{
+		cfg:      cfg,
+		db:       db,
+		txGetter: txGetter,
+	}
+}
+
+func registry(kind models.RegistryKind) sql_queries.Registry {
+	if kind == models.TagsRegistry {
+		return sql_queries.TagsRegistry
+	}
+	return sql_queries.FeaturesRegistry
+}
+
+func selectColumns(kind models.RegistryKind) []string {
+	return append([]string{fmt.Sprintf("%s AS id", registry(kind).IdColumnName)}, sql_queries.SelectRegistryColumns...)
+}
+
+func (r *RegistriesRepo) AddEntry(ctx context.Context, kind models.RegistryKind, addPostgresEntryParams *AddPostgresEntry) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.AddEntry")
+	defer span.End()
+
+	query, args, err := sq.Insert(registry(kind).TableName).
+		Columns(append([]string{registry(kind).IdColumnName}, sql_queries.SelectRegistryColumns...)...).
+		Values(
+			addPostgresEntryParams.Id,
+			addPostgresEntryParams.Slug,
+			addPostgresEntryParams.Name,
+			addPostgresEntryParams.Description,
+			addPostgresEntryParams.Owner,
+			time.Now(),
+			time.Now(),
+		).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.AddEntry.Insert")
+	}
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.AddEntry.ExecContext")
+	}
+
+	return nil
+}
+
+// GetEntry nil - записи нет
+func (r *RegistriesRepo) GetEntry(ctx context.Context, kind models.RegistryKind, id int64) (*models.RegistryEntry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.GetEntry")
+	defer span.End()
+
+	query, args, err := sq.Select(selectColumns(kind)...).
+		From(registry(kind).TableName).
+		Where(sq.Eq{registry(kind).IdColumnName: id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.GetEntry.Select")
+	}
+
+	var entry models.RegistryEntry
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if err = tr.GetContext(ctx, &entry, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.GetEntry.GetContext")
+	}
+
+	return &entry, nil
+}
+
+func (r *RegistriesRepo) GetEntries(ctx context.Context, kind models.RegistryKind, getPostgresEntriesParams *GetPostgresEntries) ([]models.RegistryEntry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.GetEntries")
+	defer span.End()
+
+	queryBuilder := sq.Select(selectColumns(kind)...).
+		From(registry(kind).TableName).
+		OrderBy(registry(kind).IdColumnName).
+		PlaceholderFormat(sq.Dollar)
+
+	if getPostgresEntriesParams.Offset != nil {
+		queryBuilder = queryBuilder.Offset(uint64(*getPostgresEntriesParams.Offset))
+	}
+	if getPostgresEntriesParams.Limit != nil && *getPostgresEntriesParams.Limit != 0 {
+		queryBuilder = queryBuilder.Limit(uint64(*getPostgresEntriesParams.Limit))
+	}
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.GetEntries.Select")
+	}
+
+	var entries []models.RegistryEntry
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if err = tr.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.GetEntries.SelectContext")
+	}
+
+	return entries, nil
+}
+
+func (r *RegistriesRepo) UpdateEntry(ctx context.Context, kind models.RegistryKind, updatePostgresEntryParams *UpdatePostgresEntry) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.UpdateEntry")
+	defer span.End()
+
+	sqlBuilder := sq.Update(registry(kind).TableName)
+	if updatePostgresEntryParams.Slug != nil {
+		sqlBuilder = sqlBuilder.Set(sql_queries.SlugColumnName, *updatePostgresEntryParams.Slug)
+	}
+	if updatePostgresEntryParams.Name != nil {
+		sqlBuilder = sqlBuilder.Set(sql_queries.NameColumnName, *updatePostgresEntryParams.Name)
+	}
+	if updatePostgresEntryParams.Description != nil {
+		sqlBuilder = sqlBuilder.Set(sql_queries.DescriptionColumnName, *updatePostgresEntryParams.Description)
+	}
+	if updatePostgresEntryParams.Owner != nil {
+		sqlBuilder = sqlBuilder.Set(sql_queries.OwnerColumnName, *updatePostgresEntryParams.Owner)
+	}
+
+	query, args, err := sqlBuilder.
+		Set(sql_queries.UpdatedAtColumnName, time.Now()).
+		Where(sq.Eq{registry(kind).IdColumnName: updatePostgresEntryParams.Id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.UpdateEntry.Update")
+	}
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.UpdateEntry.ExecContext")
+	}
+
+	return nil
+}
+
+func (r *RegistriesRepo) DeleteEntry(ctx context.Context, kind models.RegistryKind, id int64) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.DeleteEntry")
+	defer span.End()
+
+	query, args, err := sq.Delete(registry(kind).TableName).
+		Where(sq.Eq{registry(kind).IdColumnName: id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.DeleteEntry.Delete")
+	}
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.DeleteEntry.ExecContext")
+	}
+
+	return nil
+}
+
+// IsUsed проверяет, ссылается ли на запись хоть один баннер
+func (r *RegistriesRepo) IsUsed(ctx context.Context, kind models.RegistryKind, id int64) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.IsUsed")
+	defer span.End()
+
+	query, args, err := sq.Select("1").
+		From(registry(kind).UsageTableName).
+		Where(sq.Eq{registry(kind).IdColumnName: id}).
+		Limit(1).
+		Prefix("SELECT EXISTS (").Suffix(")").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.IsUsed.Select")
+	}
+
+	var used bool
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if err = tr.GetContext(ctx, &used, query, args...); err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.IsUsed.GetContext")
+	}
+
+	return used, nil
+}
+
+// ResolveSlug ищет айди сначала по текущему слагу, потом по истории переименований, false - слаг не найден
+func (r *RegistriesRepo) ResolveSlug(ctx context.Context, kind models.RegistryKind, slug string) (int64, bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.ResolveSlug")
+	defer span.End()
+
+	currentQuery, currentArgs, err := sq.Select(registry(kind).IdColumnName).
+		From(registry(kind).TableName).
+		Where(sq.Eq{sql_queries.SlugColumnName: slug}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.ResolveSlug.SelectCurrent")
+	}
+
+	aliasQuery, aliasArgs, err := sq.Select(sql_queries.IdColumnName).
+		From(sql_queries.SlugAliasesTableName).
+		Where(sq.Eq{
+			sql_queries.KindColumnName: string(kind),
+			sql_queries.SlugColumnName: slug,
+		}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.ResolveSlug.SelectAlias")
+	}
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	for _, q := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{query: currentQuery, args: currentArgs},
+		{query: aliasQuery, args: aliasArgs},
+	} {
+		var id int64
+		if err = tr.GetContext(ctx, &id, q.query, q.args...); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return 0, false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.ResolveSlug.GetContext")
+		}
+		return id, true, nil
+	}
+
+	return 0, false, nil
+}
+
+func (r *RegistriesRepo) GetSlugAliases(ctx context.Context, kind models.RegistryKind, id int64) ([]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.GetSlugAliases")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SlugColumnName).
+		From(sql_queries.SlugAliasesTableName).
+		Where(sq.Eq{
+			sql_queries.KindColumnName: string(kind),
+			sql_queries.IdColumnName:   id,
+		}).
+		OrderBy(sql_queries.CreatedAtColumnName).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.GetSlugAliases.Select")
+	}
+
+	var aliases []string
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if err = tr.SelectContext(ctx, &aliases, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.GetSlugAliases.SelectContext")
+	}
+
+	return aliases, nil
+}
+
+func (r *RegistriesRepo) AddSlugAlias(ctx context.Context, kind models.RegistryKind, slug string, id int64) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.AddSlugAlias")
+	defer span.End()
+
+	query, args, err := sq.Insert(sql_queries.SlugAliasesTableName).
+		Columns(sql_queries.InsertSlugAliasColumns...).
+		Values(string(kind), slug, id, time.Now()).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.AddSlugAlias.Insert")
+	}
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.AddSlugAlias.ExecContext")
+	}
+
+	return nil
+}
+
+// DeleteSlugAliases удаляет историю слагов записи, nil slugs - всю историю
+func (r *RegistriesRepo) DeleteSlugAliases(ctx context.Context, kind models.RegistryKind, id int64, slugs []string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesRepo.DeleteSlugAliases")
+	defer span.End()
+
+	conditions := sq.Eq{
+		sql_queries.KindColumnName: string(kind),
+		sql_queries.IdColumnName:   id,
+	}
+	if slugs != nil {
+		conditions[sql_queries.SlugColumnName] = slugs
+	}
+
+	query, args, err := sq.Delete(sql_queries.SlugAliasesTableName).
+		Where(conditions).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.DeleteSlugAliases.Delete")
+	}
+
+	tr := r.txGetter.DefaultTrOrDB(ctx, r.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "RegistriesRepo.DeleteSlugAliases.ExecContext")
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package registries_usecase
+
+import "avito/assignment/internal/registries/registries_repository"
+
+type AddEntry struct {
+	Id          int64
+	Slug        string
+	Name        string
+	Description string
+	Owner       string
+}
+
+func (e *AddEntry) ToAddPostgresEntry() *registries_repository.AddPostgresEntry {
+	return &registries_repository.AddPostgresEntry{
+		Id:          e.Id,
+		Slug:        e.Slug,
+		Name:        e.Name,
+		Description: e.Description,
+		Owner:       e.Owner,
+	}
+}
+
+type PatchEntry struct {
+	Id          int64
+	Slug        *string
+	Name        *string
+	Description *string
+	Owner       *string
+}
+
+func (e *PatchEntry) ToUpdatePostgresEntry() *registries_repository.UpdatePostgresEntry {
+	return &registries_repository.UpdatePostgresEntry{
+		Id:          e.Id,
+		Slug:        e.Slug,
+		Name:        e.Name,
+		Description: e.Description,
+		Owner:       e.Owner,
+	}
+}
+
+type GetEntries struct {
+	Limit  *int
+	Offset *int
+}
+
+func (e *GetEntries) ToGetPostgresEntries() *registries_repository.GetPostgresEntries {
+	return &registries_repository.GetPostgresEntries{
+		Limit:  e.Limit,
+		Offset: e.Offset,
+	}
+}
@@ -0,0 +1,29 @@
+package registries_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/registries/registries_repository"
+	"context"
+)
+
+type PostgresRepository interface {
+	AddEntry(ctx context.Context, kind models.RegistryKind, addPostgresEntryParams *registries_repository.AddPostgresEntry) error
+	GetEntry(ctx context.Context, kind models.RegistryKind, id int64) (*models.RegistryEntry, error)
+	GetEntries(ctx context.Context, kind models.RegistryKind, getPostgresEntriesParams *registries_repository.GetPostgresEntries) ([]models.RegistryEntry, error)
+	UpdateEntry(ctx context.Context, kind models.RegistryKind, updatePostgresEntryParams *registries_repository.UpdatePostgresEntry) error
+	DeleteEntry(ctx context.Context, kind models.RegistryKind, id int64) error
+	IsUsed(ctx context.Context, kind models.RegistryKind, id int64) (bool, error)
+
+	GetSlugAliases(ctx context.Context, kind models.RegistryKind, id int64) ([]string, error)
+	AddSlugAlias(ctx context.Context, kind models.RegistryKind, slug string, id int64) error
+	DeleteSlugAliases(ctx context.Context, kind models.RegistryKind, id int64, slugs []string) error
+	SlugRepository
+}
+
+type SlugRepository interface {
+	ResolveSlug(ctx context.Context, kind models.RegistryKind, slug string) (int64, bool, error)
+}
+
+type SlugCache interface {
+	Invalidate(kind models.RegistryKind, slugs ...string)
+}
@@ -0,0 +1,80 @@
+package registries_usecase
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"sync"
+	"time"
+)
+
+type slugCacheEntry struct {
+	id        int64
+	expiresAt time.Time
+}
+
+// SlugResolver переводит слаги в айди, результаты держит в памяти на SlugCacheTTLSeconds.
+// Слаг, однажды выданный записи, навсегда остается за ней (текущим или алиасом),
+// поэтому устаревшая запись в кэше другой реплики может указывать только на удаленную запись
+type SlugResolver struct {
+	cfg            *config.Config
+	registriesRepo SlugRepository
+
+	mu    sync.RWMutex
+	cache map[models.RegistryKind]map[string]slugCacheEntry
+}
+
+func NewSlugResolver(cfg *config.Config, registriesRepo SlugRepository) *SlugResolver {
+	return &SlugResolver{
+		cfg:            cfg,
+		registriesRepo: registriesRepo,
+		cache: map[models.RegistryKind]map[string]slugCacheEntry{
+			models.TagsRegistry:     {},
+			models.FeaturesRegistry: {},
+		},
+	}
+}
+
+func (s *SlugResolver) ResolveSlug(ctx context.Context, kind models.RegistryKind, slug string) (int64, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "SlugResolver.ResolveSlug")
+	defer span.End()
+
+	s.mu.RLock()
+	entry, ok := s.cache[kind][slug]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.id, nil
+	}
+
+	id, found, err := s.registriesRepo.ResolveSlug(ctx, kind, slug)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		s.Invalidate(kind, slug)
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			fmt.Errorf("unknown %s slug %s", kind.Singular(), slug), "SlugResolver.ResolveSlug.UnknownSlug")
+	}
+
+	s.mu.Lock()
+	s.cache[kind][slug] = slugCacheEntry{
+		id:        id,
+		expiresAt: time.Now().Add(time.Duration(s.cfg.Registries.SlugCacheTTLSeconds) * time.Second),
+	}
+	s.mu.Unlock()
+
+	return id, nil
+}
+
+func (s *SlugResolver) Invalidate(kind models.RegistryKind, slugs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, slug := range slugs {
+		delete(s.cache[kind], slug)
+	}
+}
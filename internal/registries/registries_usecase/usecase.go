@@ -0,0 +1,190 @@
+package registries_usecase
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"fmt"
+	"github.com/avito-tech/go-transaction-manager/trm/manager"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RegistriesUC struct {
+	cfg            *config.Config
+	trManager      *manager.Manager
+	registriesRepo PostgresRepository
+	slugCache      SlugCache
+}
+
+func NewRegistriesUC(cfg *config.Config, trManager *manager.Manager, registriesRepo PostgresRepository, slugCache SlugCache) *RegistriesUC {
+	return &RegistriesUC{
+		cfg:            cfg,
+		trManager:      trManager,
+		registriesRepo: registriesRepo,
+		slugCache:      slugCache,
+	}
+}
+
+// AddEntry
+// 1. Проверяем, что запись с таким айди еще не заведена
+// 2. Проверяем, что слаг не занят ни текущей записью, ни чьим-то алиасом
+// 3. Сохраняем запись
+func (r *RegistriesUC) AddEntry(ctx context.Context, kind models.RegistryKind, addEntryParams *AddEntry) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesUC.AddEntry")
+	defer span.End()
+
+	return r.trManager.Do(ctx, func(ctx context.Context) error {
+		entry, err := r.registriesRepo.GetEntry(ctx, kind, addEntryParams.Id)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("%s with id %d already exists", kind.Singular(), addEntryParams.Id), "RegistriesUC.AddEntry.AlreadyExists")
+		}
+
+		if err = r.checkSlugFree(ctx, span, "RegistriesUC.AddEntry.SlugTaken", kind, addEntryParams.Slug, addEntryParams.Id); err != nil {
+			return err
+		}
+
+		return r.registriesRepo.AddEntry(ctx, kind, addEntryParams.ToAddPostgresEntry())
+	})
+}
+
+func (r *RegistriesUC) GetEntry(ctx context.Context, kind models.RegistryKind, id int64) (*models.RegistryEntry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesUC.GetEntry")
+	defer span.End()
+
+	entry, err := r.registriesRepo.GetEntry(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("%s with id %d doesnt exist", kind.Singular(), id), "RegistriesUC.GetEntry.DoNotExist")
+	}
+
+	entry.Aliases, err = r.registriesRepo.GetSlugAliases(ctx, kind, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *RegistriesUC) GetEntries(ctx context.Context, kind models.RegistryKind, getEntriesParams *GetEntries) ([]models.RegistryEntry, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesUC.GetEntries")
+	defer span.End()
+
+	return r.registriesRepo.GetEntries(ctx, kind, getEntriesParams.ToGetPostgresEntries())
+}
+
+// PatchEntry
+// 1. Проверяем, что запись существует
+// 2. При смене слага проверяем, что новый слаг свободен, а старый уводим в алиасы
+// 3. Обновляем переданные поля, айди не меняется - на него ссылаются баннеры
+func (r *RegistriesUC) PatchEntry(ctx context.Context, kind models.RegistryKind, patchEntryParams *PatchEntry) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesUC.PatchEntry")
+	defer span.End()
+
+	var renamed []string
+	err := r.trManager.Do(ctx, func(ctx context.Context) error {
+		entry, err := r.registriesRepo.GetEntry(ctx, kind, patchEntryParams.Id)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				fmt.Errorf("impossible to patch, %s with id %d doesnt exist", kind.Singular(), patchEntryParams.Id), "RegistriesUC.PatchEntry.DoNotExist")
+		}
+
+		if patchEntryParams.Slug != nil && *patchEntryParams.Slug != entry.Slug {
+			err = r.checkSlugFree(ctx, span, "RegistriesUC.PatchEntry.SlugTaken", kind, *patchEntryParams.Slug, entry.Id)
+			if err != nil {
+				return err
+			}
+			if err = r.registriesRepo.AddSlugAlias(ctx, kind, entry.Slug, entry.Id); err != nil {
+				return err
+			}
+			// возврат к одному из прошлых слагов: он снова текущий, из алиасов его убираем
+			err = r.registriesRepo.DeleteSlugAliases(ctx, kind, entry.Id, []string{*patchEntryParams.Slug})
+			if err != nil {
+				return err
+			}
+			renamed = []string{entry.Slug, *patchEntryParams.Slug}
+		}
+
+		return r.registriesRepo.UpdateEntry(ctx, kind, patchEntryParams.ToUpdatePostgresEntry())
+	})
+	if err != nil {
+		return err
+	}
+
+	r.slugCache.Invalidate(kind, renamed...)
+	return nil
+}
+
+// DeleteEntry
+// 1. Проверяем, что запись существует
+// 2. Проверяем, что на нее не ссылается ни один баннер, иначе появятся сироты
+// 3. Удаляем запись вместе с историей слагов
+func (r *RegistriesUC) DeleteEntry(ctx context.Context, kind models.RegistryKind, id int64) error {
+	ctx, span := otel.Tracer("").Start(ctx, "RegistriesUC.DeleteEntry")
+	defer span.End()
+
+	var slugs []string
+	err := r.trManager.Do(ctx, func(ctx context.Context) error {
+		entry, err := r.registriesRepo.GetEntry(ctx, kind, id)
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+				fmt.Errorf("impossible to delete, %s with id %d doesnt exist", kind.Singular(), id), "RegistriesUC.DeleteEntry.DoNotExist")
+		}
+
+		used, err := r.registriesRepo.IsUsed(ctx, kind, id)
+		if err != nil {
+			return err
+		}
+		if used {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("impossible to delete, %s with id %d is used by banners", kind.Singular(), id), "RegistriesUC.DeleteEntry.InUse")
+		}
+
+		aliases, err := r.registriesRepo.GetSlugAliases(ctx, kind, id)
+		if err != nil {
+			return err
+		}
+		slugs = append(aliases, entry.Slug)
+
+		if err = r.registriesRepo.DeleteSlugAliases(ctx, kind, id, nil); err != nil {
+			return err
+		}
+
+		return r.registriesRepo.DeleteEntry(ctx, kind, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.slugCache.Invalidate(kind, slugs...)
+	return nil
+}
+
+// checkSlugFree слаг свободен, если его нет ни у кого или он принадлежит той же записи
+func (r *RegistriesUC) checkSlugFree(ctx context.Context, span trace.Span, errorPlace string, kind models.RegistryKind, slug string, id int64) error {
+	ownerId, found, err := r.registriesRepo.ResolveSlug(ctx, kind, slug)
+	if err != nil {
+		return err
+	}
+	if found && ownerId != id {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+			fmt.Errorf("slug %s is already taken by %s with id %d", slug, kind.Singular(), ownerId), errorPlace)
+	}
+
+	return nil
+}
@@ -1,12 +1,27 @@
 package server
 
 import (
+	banners_grpc "avito/assignment/internal/banners/banners_delivery/grpc"
 	banners_http "avito/assignment/internal/banners/banners_delivery/http"
 	banners_postgres "avito/assignment/internal/banners/banners_repository"
+	"avito/assignment/internal/banners/banners_snapshot"
+	"avito/assignment/internal/banners/banners_stream"
 	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/idempotency/idempotency_repository"
+	jobs_http "avito/assignment/internal/jobs/jobs_delivery/http"
+	"avito/assignment/internal/jobs/jobs_repository"
+	"avito/assignment/internal/jobs/jobs_usecase"
 	"avito/assignment/internal/middleware"
+	registries_http "avito/assignment/internal/registries/registries_delivery/http"
+	"avito/assignment/internal/registries/registries_repository"
+	"avito/assignment/internal/registries/registries_usecase"
+	webhooks_http "avito/assignment/internal/webhooks/webhooks_delivery/http"
+	"avito/assignment/internal/webhooks/webhooks_repository"
+	"avito/assignment/internal/webhooks/webhooks_usecase"
+	"context"
 	trmsqlx "github.com/avito-tech/go-transaction-manager/sqlx"
 	"github.com/avito-tech/go-transaction-manager/trm/manager"
+	"google.golang.org/grpc"
 )
 
 func (s *Server) MapHandlers() (err error) {
@@ -14,14 +29,71 @@ func (s *Server) MapHandlers() (err error) {
 	bannersRedisRepo := banners_postgres.NewClientRedisRepository(s.redis, s.cfg)
 	trManager := manager.Must(trmsqlx.NewDefaultFactory(s.pgDB))
 
-	bannersUC := banners_usecase.NewBannersUC(s.cfg, trManager, bannersPGRepo, bannersRedisRepo)
+	webhooksPGRepo := webhooks_repository.NewWebhooksRepository(s.cfg, s.pgDB, trmsqlx.DefaultCtxGetter)
+	registriesPGRepo := registries_repository.NewRegistriesRepository(s.cfg, s.pgDB, trmsqlx.DefaultCtxGetter)
+	jobsPGRepo := jobs_repository.NewJobsRepository(s.cfg, s.pgDB, trmsqlx.DefaultCtxGetter)
 
-	bannersHandlers := banners_http.NewUserHandler(bannersUC, s.cfg)
-	mw := middleware.NewOfficiantMiddleware(s.cfg)
+	jobsRunner := jobs_usecase.NewRunner(s.cfg, jobsPGRepo)
+	jobsUC := jobs_usecase.NewJobsUC(s.cfg, jobsPGRepo, jobsRunner)
+
+	snapshotStore := banners_snapshot.NewStore(s.cfg.Snapshots.Dir)
+	bannersUC := banners_usecase.NewBannersUC(s.cfg, trManager, bannersPGRepo, bannersRedisRepo, webhooksPGRepo, jobsUC, snapshotStore)
+	if err = bannersUC.EnqueueSearchReindex(context.Background()); err != nil {
+		return err
+	}
+	webhooksUC := webhooks_usecase.NewWebhooksUC(s.cfg, webhooksPGRepo)
+	slugResolver := registries_usecase.NewSlugResolver(s.cfg, registriesPGRepo)
+	registriesUC := registries_usecase.NewRegistriesUC(s.cfg, trManager, registriesPGRepo, slugResolver)
+
+	webhooksDispatcher := webhooks_usecase.NewDispatcher(s.cfg, webhooksPGRepo)
+	s.background = append(s.background, webhooksDispatcher.Run)
+
+	jobsRunner.Register(banners_usecase.BulkDeleteJobType,
+		jobs_usecase.HandlerFunc[banners_usecase.BulkDeletePayload](func(ctx context.Context, payload banners_usecase.BulkDeletePayload, progress jobs_usecase.Progress) error {
+			return bannersUC.HandleBulkDelete(ctx, payload, progress)
+		}),
+		jobsRunner.DefaultPolicy(),
+	)
+	jobsRunner.Register(banners_usecase.PurgeTrashJobType,
+		jobs_usecase.HandlerFunc[banners_usecase.PurgeTrashPayload](func(ctx context.Context, payload banners_usecase.PurgeTrashPayload, progress jobs_usecase.Progress) error {
+			return bannersUC.HandlePurgeTrash(ctx, payload, progress)
+		}),
+		jobsRunner.DefaultPolicy(),
+	)
+	jobsRunner.Register(banners_usecase.ReindexSearchJobType,
+		jobs_usecase.HandlerFunc[banners_usecase.ReindexSearchPayload](func(ctx context.Context, payload banners_usecase.ReindexSearchPayload, progress jobs_usecase.Progress) error {
+			return bannersUC.HandleReindexSearch(ctx, payload, progress)
+		}),
+		jobsRunner.DefaultPolicy(),
+	)
+	s.background = append(s.background, jobsRunner.Run, bannersUC.RunTrashRetention)
+
+	bannersHub := banners_stream.NewHub(bannersRedisRepo)
+	s.background = append(s.background, bannersHub.Run)
+
+	bannersHandlers := banners_http.NewUserHandler(bannersUC, bannersHub, slugResolver, s.cfg)
+	idempotencyRedisRepo := idempotency_repository.NewIdempotencyRedisRepository(s.redis, s.cfg)
+	mw := middleware.NewOfficiantMiddleware(s.cfg, idempotencyRedisRepo)
+
+	webhooksHandlers := webhooks_http.NewWebhooksHandlers(webhooksUC, s.cfg)
+	registriesHandlers := registries_http.NewRegistriesHandlers(registriesUC, s.cfg)
+	jobsHandlers := jobs_http.NewJobsHandlers(jobsUC, s.cfg)
 
 	bannersGroup := s.fiber.Group("")
+	webhooksGroup := s.fiber.Group("")
+	registriesGroup := s.fiber.Group("")
+	jobsGroup := s.fiber.Group("")
 
 	banners_http.MapBannersRoutes(bannersGroup, bannersHandlers, mw)
+	webhooks_http.MapWebhooksRoutes(webhooksGroup, webhooksHandlers, mw)
+	registries_http.MapRegistriesRoutes(registriesGroup, registriesHandlers, mw)
+	jobs_http.MapJobsRoutes(jobsGroup, jobsHandlers, mw)
+
+	s.grpc = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		mw.GRPCTrace(),
+		mw.GRPCCheckAuthToken(banners_grpc.MethodRestrictions),
+	))
+	banners_grpc.MapBannersService(s.grpc, banners_grpc.NewBannersHandlers(bannersUC, s.cfg))
 
 	return nil
 }
@@ -11,8 +11,11 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"net"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -21,7 +24,11 @@ type Server struct {
 	cfg   *config.Config
 	pgDB  *sqlx.DB
 	fiber *fiber.App
+	grpc  *grpc.Server
 	redis *redis.Client
+
+	// background фоновые процессы, живут столько же, сколько сервер
+	background []func(ctx context.Context)
 }
 
 func NewServer(
@@ -46,6 +53,18 @@ func (s *Server) Run() error {
 		return err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var background sync.WaitGroup
+	for _, run := range s.background {
+		background.Add(1)
+		go func(run func(ctx context.Context)) {
+			defer background.Done()
+			run(ctx)
+		}(run)
+	}
+
 	go func() {
 		s.fiber.Get("/health_check", func(c *fiber.Ctx) error {
 			return c.SendStatus(fiber.StatusOK)
@@ -57,16 +76,34 @@ func (s *Server) Run() error {
 		}
 	}()
 
+	listener, err := net.Listen("tcp", s.cfg.Grpc.Host)
+	if err != nil {
+		return err
+	}
+	go func() {
+		log.Info("gRPC server is started ", s.cfg.Grpc.Host)
+		if err := s.grpc.Serve(listener); err != nil {
+			log.Fatal(err.Error())
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
 	<-quit
 
+	cancel()
+	background.Wait()
+	log.Info("Background processes stopped properly")
+
 	if err = s.fiber.ShutdownWithContext(context.Background()); err != nil {
 		log.Error(err)
 	} else {
 		log.Info("Fiber closed properly")
 	}
 
+	s.grpc.GracefulStop()
+	log.Info("gRPC closed properly")
+
 	return nil
 }
 
@@ -1,23 +1,77 @@
 package sql_queries
 
 const (
-	BannersTableName         = "banner_schema.banners"
-	BannersXTagsTableName    = "banner_schema.banners_X_tags"
-	BannersVersionsTableName = "banner_schema.banners_versions"
-	BannerIdColumnName       = "banner_id"
-	TitleColumnName          = "title"
-	TextColumnName           = "text"
-	UrlColumnName            = "url"
-	FeatureIdColumnName      = "feature_id"
-	CreatedAtColumnName      = "created_at"
-	UpdatedAtColumnName      = "updated_at"
-	IsActiveColumnName       = "is_active"
-	IdColumnName             = "id"
-	TagIdColumnName          = "tag_id"
-	TagIdsColumnName         = "tag_ids"
-	VersionColumnName        = "version"
+	BannersTableName          = "banner_schema.banners"
+	BannersXTagsTableName     = "banner_schema.banners_X_tags"
+	BannersVersionsTableName  = "banner_schema.banners_versions"
+	BannerChangesTableName    = "banner_schema.banner_changes"
+	WebhookSubscriptionsName  = "banner_schema.webhook_subscriptions"
+	WebhookOutboxTableName    = "banner_schema.webhook_outbox"
+	TagsTableName             = "banner_schema.tags"
+	FeaturesTableName         = "banner_schema.features"
+	SlugAliasesTableName      = "banner_schema.slug_aliases"
+	JobsTableName             = "banner_schema.jobs"
+	SearchSettingsTableName   = "banner_schema.search_settings"
+	BannerDraftsTableName     = "banner_schema.banner_drafts"
+	GooseVersionTableName     = "goose_db_version"
+	BannerIdColumnName        = "banner_id"
+	TitleColumnName           = "title"
+	TextColumnName            = "text"
+	UrlColumnName             = "url"
+	FeatureIdColumnName       = "feature_id"
+	CreatedAtColumnName       = "created_at"
+	UpdatedAtColumnName       = "updated_at"
+	IsActiveColumnName        = "is_active"
+	IdColumnName              = "id"
+	TagIdColumnName           = "tag_id"
+	TagIdsColumnName          = "tag_ids"
+	VersionColumnName         = "version"
+	ChangeIdColumnName        = "change_id"
+	EventTypeColumnName       = "event_type"
+	PayloadColumnName         = "payload"
+	SubscriptionIdColumnName  = "subscription_id"
+	DeliveryIdColumnName      = "delivery_id"
+	SecretColumnName          = "secret"
+	FeatureIdsColumnName      = "feature_ids"
+	EventTypesColumnName      = "event_types"
+	StatusColumnName          = "status"
+	AttemptsColumnName        = "attempts"
+	NextAttemptAtColumnName   = "next_attempt_at"
+	LastErrorColumnName       = "last_error"
+	DeliveredAtColumnName     = "delivered_at"
+	NameColumnName            = "name"
+	DescriptionColumnName     = "description"
+	OwnerColumnName           = "owner"
+	SlugColumnName            = "slug"
+	KindColumnName            = "kind"
+	JobIdColumnName           = "job_id"
+	FinishedAtColumnName      = "finished_at"
+	TypeColumnName            = "type"
+	ProgressColumnName        = "progress"
+	MaxAttemptsColumnName     = "max_attempts"
+	RunAtColumnName           = "run_at"
+	LockedUntilColumnName     = "locked_until"
+	CancelRequestedColumnName = "cancel_requested"
+	UniqueKeyColumnName       = "unique_key"
+	LeaseOwnerColumnName      = "lease_owner"
+	LanguageColumnName        = "language"
+	SearchVectorColumnName    = "search_vector"
+	DeletedAtColumnName       = "deleted_at"
+	DeletedByColumnName       = "deleted_by"
+	DraftIdColumnName         = "draft_id"
+	BaseVersionColumnName     = "base_version"
+	AuthorColumnName          = "author"
+	ReviewedByColumnName      = "reviewed_by"
+	ReviewedAtColumnName      = "reviewed_at"
+	CommentColumnName         = "comment"
+	PublishedByColumnName     = "published_by"
+	PublishedAtColumnName     = "published_at"
+	BannerStatusColumnName    = "banner_status"
 )
 
+// BannerChangesLockId ключ advisory lock, под которым пишется журнал изменений
+const BannerChangesLockId = 20241019
+
 var (
 	GetBannerColumnsWithInnerJoin = []string{
 		"b.banner_id",
@@ -28,6 +82,7 @@ var (
 		CreatedAtColumnName,
 		UpdatedAtColumnName,
 		IsActiveColumnName,
+		StatusColumnName,
 		VersionColumnName,
 	}
 	GetFullBannerColumns = []string{
@@ -35,11 +90,13 @@ var (
 		TitleColumnName,
 		TextColumnName,
 		UrlColumnName,
-		TagIdColumnName,
+		// у баннера, с которого сняли все тэги, строка одна и tag_id = 0
+		"COALESCE(bxt.tag_id, 0) AS tag_id",
 		FeatureIdColumnName,
 		CreatedAtColumnName,
 		UpdatedAtColumnName,
 		IsActiveColumnName,
+		StatusColumnName,
 		VersionColumnName,
 	}
 	SelectBannerColumns = []string{
@@ -49,9 +106,12 @@ var (
 		TextColumnName,
 		UrlColumnName,
 		IsActiveColumnName,
+		StatusColumnName,
 		CreatedAtColumnName,
 		UpdatedAtColumnName,
 		VersionColumnName,
+		DeletedAtColumnName,
+		DeletedByColumnName,
 	}
 	SelectVersionColumns = []string{
 		BannerIdColumnName,
@@ -61,6 +121,7 @@ var (
 		UrlColumnName,
 		TagIdsColumnName,
 		IsActiveColumnName,
+		StatusColumnName,
 		CreatedAtColumnName,
 		UpdatedAtColumnName,
 		VersionColumnName,
@@ -73,6 +134,7 @@ var (
 		CreatedAtColumnName,
 		UpdatedAtColumnName,
 		IsActiveColumnName,
+		StatusColumnName,
 		VersionColumnName,
 	}
 	InsertVersionColumns = []string{
@@ -85,10 +147,150 @@ var (
 		CreatedAtColumnName,
 		UpdatedAtColumnName,
 		IsActiveColumnName,
+		StatusColumnName,
 		VersionColumnName,
 	}
+	InsertChangeColumns = []string{
+		BannerIdColumnName,
+		EventTypeColumnName,
+		PayloadColumnName,
+		CreatedAtColumnName,
+	}
+	SelectChangeColumns = []string{
+		ChangeIdColumnName,
+		BannerIdColumnName,
+		EventTypeColumnName,
+		PayloadColumnName,
+		CreatedAtColumnName,
+	}
+	InsertSubscriptionColumns = []string{
+		UrlColumnName,
+		SecretColumnName,
+		FeatureIdsColumnName,
+		EventTypesColumnName,
+		CreatedAtColumnName,
+	}
+	SelectSubscriptionColumns = []string{
+		SubscriptionIdColumnName,
+		UrlColumnName,
+		SecretColumnName,
+		FeatureIdsColumnName,
+		EventTypesColumnName,
+		CreatedAtColumnName,
+	}
+	SelectDeliveryColumns = []string{
+		"o.delivery_id",
+		"o.subscription_id",
+		"s.url",
+		"s.secret",
+		"o.event_type",
+		"o.payload",
+		"o.status",
+		"o.attempts",
+		"o.next_attempt_at",
+		"o.last_error",
+		"o.created_at",
+		"o.delivered_at",
+	}
+	SelectRegistryColumns = []string{
+		SlugColumnName,
+		NameColumnName,
+		DescriptionColumnName,
+		OwnerColumnName,
+		CreatedAtColumnName,
+		UpdatedAtColumnName,
+	}
+	InsertJobColumns = []string{
+		TypeColumnName,
+		PayloadColumnName,
+		StatusColumnName,
+		MaxAttemptsColumnName,
+		RunAtColumnName,
+		UniqueKeyColumnName,
+		CreatedAtColumnName,
+		UpdatedAtColumnName,
+	}
+	SelectJobColumns = []string{
+		JobIdColumnName,
+		TypeColumnName,
+		PayloadColumnName,
+		StatusColumnName,
+		ProgressColumnName,
+		AttemptsColumnName,
+		MaxAttemptsColumnName,
+		RunAtColumnName,
+		LockedUntilColumnName,
+		LeaseOwnerColumnName,
+		CancelRequestedColumnName,
+		LastErrorColumnName,
+		CreatedAtColumnName,
+		UpdatedAtColumnName,
+		FinishedAtColumnName,
+	}
+	InsertSlugAliasColumns = []string{
+		KindColumnName,
+		SlugColumnName,
+		IdColumnName,
+		CreatedAtColumnName,
+	}
+	InsertDraftColumns = []string{
+		BannerIdColumnName,
+		BaseVersionColumnName,
+		FeatureIdColumnName,
+		TagIdsColumnName,
+		TitleColumnName,
+		TextColumnName,
+		UrlColumnName,
+		IsActiveColumnName,
+		BannerStatusColumnName,
+		StatusColumnName,
+		AuthorColumnName,
+		CreatedAtColumnName,
+		UpdatedAtColumnName,
+	}
+	SelectDraftColumns = []string{
+		"d.draft_id",
+		"d.banner_id",
+		"d.base_version",
+		"d.feature_id",
+		"d.tag_ids",
+		"d.title",
+		"d.text",
+		"d.url",
+		"d.is_active",
+		"d.banner_status",
+		"d.status",
+		"d.author",
+		"d.reviewed_by",
+		"d.reviewed_at",
+		"d.comment",
+		"d.published_by",
+		"d.published_at",
+		"d.created_at",
+		"d.updated_at",
+	}
 	InsertTagColumns = []string{
 		BannerIdColumnName,
 		TagIdColumnName,
 	}
 )
+
+// Registry описание таблицы реестра и таблицы, в которой на него ссылаются баннеры
+type Registry struct {
+	TableName      string
+	IdColumnName   string
+	UsageTableName string
+}
+
+var (
+	TagsRegistry = Registry{
+		TableName:      TagsTableName,
+		IdColumnName:   TagIdColumnName,
+		UsageTableName: BannersXTagsTableName,
+	}
+	FeaturesRegistry = Registry{
+		TableName:      FeaturesTableName,
+		IdColumnName:   FeatureIdColumnName,
+		UsageTableName: BannersTableName,
+	}
+)
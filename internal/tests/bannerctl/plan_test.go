@@ -0,0 +1,136 @@
+package bannerctl
+
+import (
+	"avito/assignment/internal/bannerctl"
+	"avito/assignment/internal/models"
+	"github.com/gofiber/fiber/v2/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var registry = &bannerctl.Registry{
+	Tags:     map[string]int64{"moscow": 1, "spb": 2, "kazan": 3},
+	Features: map[string]int64{"main-page": 10},
+}
+
+func newLive(bannerId models.BannerId, title string, tagIds ...models.TagId) bannerctl.Live {
+	live := bannerctl.Live{BannerId: bannerId, TagIds: tagIds, FeatureId: 10, IsActive: true}
+	live.Content.Title = title
+	live.Content.Text = "text"
+	live.Content.Url = "url"
+	return live
+}
+
+func loadSpecs(t *testing.T, content string) []bannerctl.Spec {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "banners.yaml"), []byte(content), 0o644)
+	utils.AssertEqual(t, nil, err, "WriteFile")
+	specs, err := bannerctl.LoadSpecs(dir)
+	utils.AssertEqual(t, nil, err, "LoadSpecs")
+	return specs
+}
+
+func Test_BuildPlan(t *testing.T) {
+	specs := loadSpecs(t, `
+feature: main-page
+tags: [moscow]
+content: {title: same, text: text, url: url}
+---
+feature: main-page
+tags: [spb]
+content: {title: new title, text: text, url: url}
+---
+feature: main-page
+tags: [kazan]
+content: {title: created, text: text, url: url}
+is_active: false
+`)
+	live := []bannerctl.Live{newLive(1, "same", 1), newLive(2, "old title", 2), newLive(3, "orphan", 4)}
+
+	plan := bannerctl.BuildPlan(specs, registry, live, false)
+	utils.AssertEqual(t, 0, len(plan.Conflicts), "Conflicts")
+	utils.AssertEqual(t, 1, plan.Unchanged, "Unchanged")
+	utils.AssertEqual(t, 1, plan.Unmanaged, "Unmanaged")
+	utils.AssertEqual(t, 2, len(plan.Actions), "Actions")
+	utils.AssertEqual(t, bannerctl.ActionUpdate, plan.Actions[0].Type, "UpdateType")
+	utils.AssertEqual(t, []bannerctl.FieldDiff{{Field: bannerctl.FieldTitle, From: `"old title"`, To: `"new title"`}}, plan.Actions[0].Diffs, "UpdateDiffs")
+	utils.AssertEqual(t, bannerctl.ActionCreate, plan.Actions[1].Type, "CreateType")
+	utils.AssertEqual(t, false, plan.Actions[1].Desired.IsActive, "CreateIsActive")
+
+	plan = bannerctl.BuildPlan(specs, registry, live, true)
+	utils.AssertEqual(t, bannerctl.ActionDelete, plan.Actions[0].Type, "PruneType")
+	utils.AssertEqual(t, models.BannerId(3), plan.Actions[0].BannerId, "PruneBannerId")
+}
+
+func Test_BuildPlanConflicts(t *testing.T) {
+	specs := loadSpecs(t, `
+feature: main-page
+tags: [moscow, spb]
+content: {title: two banners, text: text, url: url}
+---
+banner_id: 3
+feature: main-page
+tags: [kazan]
+content: {title: taken pair, text: text, url: url}
+---
+feature: unknown
+tags: [kazan]
+content: {title: unknown, text: text, url: url}
+`)
+	live := []bannerctl.Live{newLive(1, "one", 1), newLive(2, "two", 2), newLive(3, "three", 4), newLive(4, "four", 3)}
+
+	plan := bannerctl.BuildPlan(specs, registry, live, false)
+	utils.AssertEqual(t, 3, len(plan.Conflicts), "Conflicts")
+	utils.AssertEqual(t, "matches several banners [1 2], set banner_id", plan.Conflicts[0].Message, "SeveralBanners")
+	utils.AssertEqual(t, "tag 3 and feature 10 already belong to banner 4", plan.Conflicts[1].Message, "TakenPair")
+	utils.AssertEqual(t, "unknown feature slug unknown", plan.Conflicts[2].Message, "UnknownSlug")
+
+	// с prune баннер 4 удаляется и освобождает пару, а баннеры 1 и 2 закреплены за спекой с конфликтом и остаются
+	plan = bannerctl.BuildPlan(specs, registry, live, true)
+	utils.AssertEqual(t, 2, len(plan.Conflicts), "PruneConflicts")
+	utils.AssertEqual(t, 2, len(plan.Actions), "PruneActions")
+	utils.AssertEqual(t, bannerctl.ActionDelete, plan.Actions[0].Type, "PruneDeleteType")
+	utils.AssertEqual(t, models.BannerId(4), plan.Actions[0].BannerId, "PruneDeleteBannerId")
+	utils.AssertEqual(t, bannerctl.ActionUpdate, plan.Actions[1].Type, "PruneUpdateType")
+	utils.AssertEqual(t, models.BannerId(3), plan.Actions[1].BannerId, "PruneUpdateBannerId")
+}
+
+func Test_BuildPlanSwap(t *testing.T) {
+	specs := loadSpecs(t, `
+banner_id: 1
+feature: main-page
+tags: [spb]
+content: {title: one, text: text, url: url}
+---
+banner_id: 2
+feature: main-page
+tags: [moscow]
+content: {title: two, text: text, url: url}
+---
+feature: main-page
+tags: [kazan]
+content: {title: takes the moved pair, text: text, url: url}
+---
+banner_id: 3
+feature: main-page
+tag_ids: [4]
+content: {title: three, text: text, url: url}
+`)
+	live := []bannerctl.Live{newLive(1, "one", 1), newLive(2, "two", 2), newLive(3, "three", 3)}
+
+	// 1 и 2 меняются парами, 3 уходит с kazan, и его пару занимает новый баннер
+	plan := bannerctl.BuildPlan(specs, registry, live, false)
+	utils.AssertEqual(t, 0, len(plan.Conflicts), "Conflicts")
+	utils.AssertEqual(t, 3, plan.Count(bannerctl.ActionUpdate), "Updates")
+	utils.AssertEqual(t, 1, plan.Count(bannerctl.ActionCreate), "Creates")
+
+	// без спеки баннер 2 свою пару не отдает, пока его не удалит prune
+	plan = bannerctl.BuildPlan(specs[:1], registry, live, false)
+	utils.AssertEqual(t, 1, len(plan.Conflicts), "NotReleased")
+	utils.AssertEqual(t, "tag 2 and feature 10 already belong to banner 2", plan.Conflicts[0].Message, "NotReleased")
+
+	plan = bannerctl.BuildPlan(specs[:1], registry, live, true)
+	utils.AssertEqual(t, 0, len(plan.Conflicts), "ReleasedByPrune")
+	utils.AssertEqual(t, 2, plan.Count(bannerctl.ActionDelete), "ReleasedByPrune")
+}
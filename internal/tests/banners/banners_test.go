@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gofiber/fiber/v2/utils"
+	"io"
 	"log"
 	"net/http"
+	"os"
 	"testing"
 )
 
@@ -27,6 +29,44 @@ type TestStruct struct {
 	manyResponseBody []map[string]interface{}
 }
 
+// TestMain заводит в реестрах тэги и фичи, на которые ссылаются тесты, повторная регистрация просто вернет 400
+func TestMain(m *testing.M) {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"token":        "admin_token",
+	}
+	registries := map[string][]int64{
+		"/tags":     {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 100, 101, 102},
+		"/features": {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 100},
+	}
+	// 200-259 для сценариев, которые заводят свои баннеры и не зависят от остальных тестов
+	for id := int64(200); id < 260; id++ {
+		registries["/tags"] = append(registries["/tags"], id)
+		registries["/features"] = append(registries["/features"], id)
+	}
+	for endpoint, ids := range registries {
+		for _, id := range ids {
+			requestBody, _ := json.Marshal(map[string]interface{}{
+				"id":   id,
+				"slug": fmt.Sprintf("%s_%d", endpoint[1:len(endpoint)-1], id),
+				"name": fmt.Sprintf("%s_%d", endpoint[1:], id),
+			})
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:8892%s", endpoint), bytes.NewBuffer(requestBody))
+			if err != nil {
+				log.Fatalln(err)
+			}
+			for key, value := range headers {
+				req.Header.Set(key, value)
+			}
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+
+	os.Exit(m.Run())
+}
+
 func Test_MiddleWare(t *testing.T) {
 	testsMiddleWare := []TestStruct{
 		{
@@ -191,6 +231,58 @@ func Test_AddBanner(t *testing.T) {
 				"error_value": "these banners already exists [{1 1} {2 1} {3 1}]",
 			},
 		},
+		{
+			name:     "BadRequestUnknownTemplateVariable",
+			method:   http.MethodPost,
+			endpoint: "/banner",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+			},
+			reqBody: map[string]interface{}{
+				"tag_ids":    []int64{1, 2, 3},
+				"feature_id": 100,
+				"content": map[string]string{
+					"title": "Hello, {{user.age}}",
+					"text":  "some_text",
+					"url":   "some_url",
+				},
+				"is_active": true,
+			},
+
+			statusCode: 400,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersUC.AddBanner.InvalidTemplate",
+				"error_value": "invalid template in title: unknown variable \"user.age\"",
+			},
+		},
+		{
+			name:     "BadRequestUnknownFeature",
+			method:   http.MethodPost,
+			endpoint: "/banner",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+			},
+			reqBody: map[string]interface{}{
+				"tag_ids":    []int64{1, 2, 3},
+				"feature_id": 999,
+				"content": map[string]string{
+					"title": "some_title",
+					"text":  "some_text",
+					"url":   "some_url",
+				},
+				"is_active": true,
+			},
+
+			statusCode: 400,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersUC.AddBanner.UnknownReference",
+				"error_value": "unknown feature id 999",
+			},
+		},
 	}
 
 	for _, test := range testsSignUp {
@@ -362,6 +454,49 @@ func Test_PatchBanner(t *testing.T) {
 				"error_value": "these banners already exists {tag_id feature_id} [{1 3} {2 3} {3 3}]",
 			},
 		},
+		{
+			name:        "WeakIfMatch",
+			method:      http.MethodPatch,
+			endpoint:    "/banner",
+			paramsInput: "2",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+				"If-Match":     `W/"1"`,
+			},
+			reqBody: map[string]interface{}{
+				"is_active": false,
+			},
+
+			statusCode: 400,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersHandlers.PatchBanner.WrongExpectedVersion",
+				"error_value": "If-Match must be a single strong ETag with the banner version or *",
+			},
+		},
+		{
+			name:        "IfMatchAndExpectedVersionDiffer",
+			method:      http.MethodPatch,
+			endpoint:    "/banner",
+			paramsInput: "2",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+				"If-Match":     `"1"`,
+			},
+			reqBody: map[string]interface{}{
+				"is_active":        false,
+				"expected_version": 2,
+			},
+
+			statusCode: 400,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersHandlers.PatchBanner.WrongExpectedVersion",
+				"error_value": "If-Match and expected_version point to different versions",
+			},
+		},
 	}
 
 	for _, test := range testsPatchBanner {
@@ -455,6 +590,219 @@ func Test_DeleteBanner(t *testing.T) {
 	}
 }
 
+func Test_RestoreBanner(t *testing.T) {
+	testsRestoreBanner := []TestStruct{
+		{
+			name:        "WrongParams",
+			method:      http.MethodPost,
+			endpoint:    "/banner",
+			paramsInput: "a/restore",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+			},
+			reqBody: map[string]interface{}{},
+
+			statusCode: 400,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersHandlers.RestoreBanner.WrongBannerParams",
+				"error_value": "%!!(MISSING)s(<nil>)",
+			},
+		},
+		{
+			name:        "NotInTrash",
+			method:      http.MethodPost,
+			endpoint:    "/banner",
+			paramsInput: "100/restore",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+			},
+			reqBody: map[string]interface{}{},
+
+			statusCode: 404,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersUC.RestoreBanner.DoNotExist",
+				"error_value": "impossible to restore, banner with id 100 is not in trash",
+			},
+		},
+	}
+
+	for _, test := range testsRestoreBanner {
+		t.Run(test.name, func(t *testing.T) {
+			runTest(test, t)
+		})
+	}
+}
+
+func Test_Drafts(t *testing.T) {
+	testsDrafts := []TestStruct{
+		{
+			name:        "WrongDraftParams",
+			method:      http.MethodPost,
+			endpoint:    "/banner/drafts",
+			paramsInput: "a/publish",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+			},
+			reqBody: map[string]interface{}{},
+
+			statusCode: 400,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersHandlers.PublishDraft.WrongDraftParams",
+				"error_value": "%!!(MISSING)s(<nil>)",
+			},
+		},
+		{
+			name:        "DraftDoesntExist",
+			method:      http.MethodPost,
+			endpoint:    "/banner/drafts",
+			paramsInput: "100000/approve",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "bob_admin_token",
+			},
+			reqBody: map[string]interface{}{},
+
+			statusCode: 404,
+			responseBody: map[string]interface{}{
+				"error_place": "BannersUC.ApproveDraft.DoNotExist",
+				"error_value": "draft with id 100000 doesnt exist",
+			},
+		},
+	}
+
+	for _, test := range testsDrafts {
+		t.Run(test.name, func(t *testing.T) {
+			runTest(test, t)
+		})
+	}
+}
+
+// Test_DraftApproval одобрить черновик может только другой админ со своим токеном
+func Test_DraftApproval(t *testing.T) {
+	bannerId := addTestBanner(t, []int64{200}, 200)
+
+	draft := map[string]interface{}{"content": map[string]string{"title": "new_title"}}
+	status, body := doRequest(t, http.MethodPut, fmt.Sprintf("/banner/%d/draft", bannerId), "admin_token", draft)
+	utils.AssertEqual(t, 403, status, "SharedTokenSave")
+	utils.AssertEqual(t, "BannersUC.SaveDraft.NoActor", body["error_place"], "SharedTokenSave")
+
+	status, body = doRequest(t, http.MethodPut, fmt.Sprintf("/banner/%d/draft", bannerId), "alice_admin_token", draft)
+	utils.AssertEqual(t, 200, status, "SaveDraft")
+	draftId := int64(body["draft_id"].(float64))
+	approve := fmt.Sprintf("/banner/drafts/%d/approve", draftId)
+
+	status, body = doRequest(t, http.MethodPost, approve, "admin_token", nil)
+	utils.AssertEqual(t, 403, status, "SharedTokenApprove")
+	utils.AssertEqual(t, "BannersUC.ApproveDraft.NoActor", body["error_place"], "SharedTokenApprove")
+
+	status, body = doRequest(t, http.MethodPost, approve, "alice_admin_token", nil)
+	utils.AssertEqual(t, 403, status, "SelfApproval")
+	utils.AssertEqual(t, "BannersUC.ApproveDraft.SelfApproval", body["error_place"], "SelfApproval")
+
+	status, _ = doRequest(t, http.MethodPost, approve, "bob_admin_token", nil)
+	utils.AssertEqual(t, 200, status, "Approve")
+}
+
+func Test_Idempotency(t *testing.T) {
+	testsIdempotency := []TestStruct{
+		{
+			name:     "AddBanner",
+			method:   http.MethodPost,
+			endpoint: "/banner",
+			prepare:  true,
+
+			headers: map[string]string{
+				"Content-Type":    "application/json",
+				"token":           "admin_token",
+				"Idempotency-Key": "test-idempotency-key",
+			},
+			reqBody: map[string]interface{}{
+				"tag_ids":    []int64{256},
+				"feature_id": 256,
+				"content": map[string]string{
+					"title": "some_title",
+					"text":  "some_text",
+					"url":   "some_url",
+				},
+				"is_active": true,
+			},
+		},
+		{
+			name:     "KeyReused",
+			method:   http.MethodPost,
+			endpoint: "/banner",
+
+			headers: map[string]string{
+				"Content-Type":    "application/json",
+				"token":           "admin_token",
+				"Idempotency-Key": "test-idempotency-key",
+			},
+			reqBody: map[string]interface{}{
+				"tag_ids":    []int64{257},
+				"feature_id": 256,
+				"content": map[string]string{
+					"title": "some_title",
+					"text":  "some_text",
+					"url":   "some_url",
+				},
+				"is_active": true,
+			},
+
+			statusCode: 422,
+			responseBody: map[string]interface{}{
+				"error_place": "MDWManager.Idempotency.KeyReused",
+				"error_value": "idempotency key test-idempotency-key was already used for a different request",
+			},
+		},
+	}
+
+	for _, test := range testsIdempotency {
+		t.Run(test.name, func(t *testing.T) {
+			runTest(test, t)
+		})
+	}
+}
+
+// Test_IdempotencyReplay повтор отдает сохраненный ответ, ключи разных админов друг другу не мешают
+func Test_IdempotencyReplay(t *testing.T) {
+	addBanner := func(token string, featureId int64) (*http.Response, []byte) {
+		return doRawRequest(t, http.MethodPost, "/banner", map[string]string{
+			"token":           token,
+			"Idempotency-Key": "test-idempotency-replay",
+		}, map[string]interface{}{
+			"tag_ids":    []int64{203},
+			"feature_id": featureId,
+			"content": map[string]string{
+				"title": "some_title",
+				"text":  "some_text",
+				"url":   "some_url",
+			},
+			"is_active": true,
+		})
+	}
+
+	first, firstBody := addBanner("alice_admin_token", 203)
+	utils.AssertEqual(t, 201, first.StatusCode, "First")
+	utils.AssertEqual(t, "", first.Header.Get("Idempotent-Replayed"), "First")
+
+	replay, replayBody := addBanner("alice_admin_token", 203)
+	utils.AssertEqual(t, 201, replay.StatusCode, "Replay")
+	utils.AssertEqual(t, "true", replay.Header.Get("Idempotent-Replayed"), "Replay")
+	utils.AssertEqual(t, first.Header.Get("Content-Type"), replay.Header.Get("Content-Type"), "Replay")
+	utils.AssertEqual(t, string(firstBody), string(replayBody), "Replay")
+
+	other, _ := addBanner("bob_admin_token", 204)
+	utils.AssertEqual(t, 201, other.StatusCode, "OtherAdmin")
+	utils.AssertEqual(t, "", other.Header.Get("Idempotent-Replayed"), "OtherAdmin")
+}
+
 func Test_GetBanner(t *testing.T) {
 	testsGetBanner := []TestStruct{
 		{
@@ -523,6 +871,28 @@ func Test_GetBanner(t *testing.T) {
 				"url":   "some_url",
 			},
 		},
+		{
+			name:     "OKBySlug",
+			method:   http.MethodGet,
+			endpoint: "/user_banner",
+
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"token":        "admin_token",
+			},
+			reqBody: map[string]interface{}{
+				"tag":              "tag_1",
+				"feature":          "feature_5",
+				"use_last_version": false,
+			},
+
+			statusCode: 200,
+			responseBody: map[string]interface{}{
+				"title": "some_title",
+				"text":  "some_text",
+				"url":   "some_url",
+			},
+		},
 		{
 			name:     "BadRequest",
 			method:   http.MethodGet,
@@ -984,3 +1354,46 @@ func runTest(test TestStruct, t *testing.T) {
 		utils.AssertEqual(t, test.responseBody["error_value"], body["error_value"], "ResponseBody")
 	}
 }
+
+// doRequest для сценариев, где следующий шаг зависит от ответа предыдущего. Тело ответа разбирается как объект
+func doRequest(t *testing.T, method, endpoint, token string, reqBody interface{}) (int, map[string]interface{}) {
+	resp, respBody := doRawRequest(t, method, endpoint, map[string]string{"token": token}, reqBody)
+
+	body := map[string]interface{}{}
+	if len(respBody) != 0 {
+		_ = json.Unmarshal(respBody, &body)
+	}
+	return resp.StatusCode, body
+}
+
+// doRawRequest запрос с произвольными заголовками, ответ отдается как есть
+func doRawRequest(t *testing.T, method, endpoint string, headers map[string]string, reqBody interface{}) (*http.Response, []byte) {
+	var requestBody []byte
+	if reqBody != nil {
+		var err error
+		if requestBody, err = json.Marshal(reqBody); err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://localhost:8892%s", endpoint), bytes.NewBuffer(requestBody))
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	return resp, respBody
+}
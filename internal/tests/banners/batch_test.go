@@ -0,0 +1,93 @@
+package banners
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"testing"
+)
+
+type batchResponse struct {
+	Results []struct {
+		Index    int    `json:"index"`
+		Op       string `json:"op"`
+		BannerId int64  `json:"banner_id"`
+	} `json:"results"`
+	FailedOperation struct {
+		Index      int    `json:"index"`
+		Op         string `json:"op"`
+		ErrorPlace string `json:"error_place"`
+	} `json:"failed_operation"`
+}
+
+func doBatch(t *testing.T, operations []map[string]interface{}) (int, batchResponse) {
+	resp, respBody := doRawRequest(t, http.MethodPost, "/banner/batch", map[string]string{"token": "admin_token"},
+		map[string]interface{}{"operations": operations})
+	var response batchResponse
+	_ = json.Unmarshal(respBody, &response)
+	return resp.StatusCode, response
+}
+
+func batchBanner(tagId, featureId int64, title string) map[string]interface{} {
+	return map[string]interface{}{
+		"tag_ids":    []int64{tagId},
+		"feature_id": featureId,
+		"content":    map[string]string{"title": title, "text": "some_text", "url": "some_url"},
+		"is_active":  true,
+	}
+}
+
+// Test_BatchMixed add, patch, rollback и delete в одном батче, откат видит версию, созданную патчем этого же батча
+func Test_BatchMixed(t *testing.T) {
+	patchedId := addTestBanner(t, []int64{216}, 216)
+	deletedId := addTestBanner(t, []int64{218}, 216)
+
+	status, response := doBatch(t, []map[string]interface{}{
+		{"op": "add", "banner": batchBanner(217, 216, "added")},
+		{"op": "patch", "banner_id": patchedId, "banner": map[string]interface{}{"content": map[string]string{"title": "batch_title"}}},
+		{"op": "rollback", "banner_id": patchedId, "version": 1},
+		{"op": "delete", "banner_id": deletedId},
+	})
+	utils.AssertEqual(t, 200, status, "Batch")
+	utils.AssertEqual(t, 4, len(response.Results), "Results")
+	addedId := response.Results[0].BannerId
+
+	banners := getBulkPatchBanners(t, 216)
+	utils.AssertEqual(t, 2, len(banners), "Banners")
+	utils.AssertEqual(t, "added", banners[addedId].Content.Title, "Added")
+	utils.AssertEqual(t, "some_title", banners[patchedId].Content.Title, "RolledBack")
+	utils.AssertEqual(t, int64(3), banners[patchedId].Version, "RolledBack")
+}
+
+// Test_BatchRollback ошибка любой операции откатывает весь батч и возвращает номер операции
+func Test_BatchRollback(t *testing.T) {
+	patchedId := addTestBanner(t, []int64{219}, 219)
+
+	status, response := doBatch(t, []map[string]interface{}{
+		{"op": "add", "banner": batchBanner(220, 219, "rolled_back")},
+		{"op": "patch", "banner_id": patchedId, "banner": map[string]interface{}{"content": map[string]string{"title": "rolled_back"}}},
+		{"op": "delete", "banner_id": 999999999},
+	})
+	utils.AssertEqual(t, 404, status, "Batch")
+	utils.AssertEqual(t, 2, response.FailedOperation.Index, "FailedOperation")
+	utils.AssertEqual(t, "delete", response.FailedOperation.Op, "FailedOperation")
+
+	banners := getBulkPatchBanners(t, 219)
+	utils.AssertEqual(t, 1, len(banners), "NothingAdded")
+	utils.AssertEqual(t, "some_title", banners[patchedId].Content.Title, "NothingPatched")
+	utils.AssertEqual(t, int64(1), banners[patchedId].Version, "NothingPatched")
+}
+
+// Test_BatchValidation payload patch проверяется так же, как в PATCH /banner/:banner_id
+func Test_BatchValidation(t *testing.T) {
+	patchedId := addTestBanner(t, []int64{221}, 221)
+
+	status, response := doBatch(t, []map[string]interface{}{
+		{"op": "add", "banner": batchBanner(222, 221, "not_added")},
+		{"op": "patch", "banner_id": patchedId, "banner": map[string]interface{}{"status": "unknown"}},
+	})
+	utils.AssertEqual(t, 400, status, "Batch")
+	utils.AssertEqual(t, 1, response.FailedOperation.Index, "FailedOperation")
+	utils.AssertEqual(t, "BannersHandlers.Batch.ReadBanner", response.FailedOperation.ErrorPlace, "FailedOperation")
+	utils.AssertEqual(t, 1, len(getBulkPatchBanners(t, 221)), "NothingAdded")
+}
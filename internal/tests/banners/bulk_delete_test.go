@@ -0,0 +1,92 @@
+package banners
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// waitBulkDeleteJob опрашивает задачу, пока раннер ее не завершит
+func waitBulkDeleteJob(t *testing.T, jobId int64) map[string]interface{} {
+	for i := 0; i < 50; i++ {
+		status, body := doRequest(t, http.MethodGet, fmt.Sprintf("/banner/bulk_delete/%d", jobId), "admin_token", nil)
+		utils.AssertEqual(t, 200, status, "GetBulkDeleteJob")
+		if body["status"] != "pending" && body["status"] != "running" {
+			return body
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	t.Fatalf("bulk delete job %d is not finished", jobId)
+	return nil
+}
+
+// Test_BulkDelete удаляются все баннеры фичи, баннер, удаленный до запуска задачи, ей не мешает
+func Test_BulkDelete(t *testing.T) {
+	first := addTestBanner(t, []int64{205}, 205)
+	addTestBanner(t, []int64{206}, 205)
+	addTestBanner(t, []int64{207}, 205)
+
+	status, _ := doRequest(t, http.MethodDelete, fmt.Sprintf("/banner/%d", first), "admin_token", nil)
+	utils.AssertEqual(t, 204, status, "DeleteBanner")
+
+	status, body := doRequest(t, http.MethodPost, "/banner/bulk_delete", "admin_token", map[string]interface{}{
+		"feature_id": 205,
+	})
+	utils.AssertEqual(t, 202, status, "BulkDelete")
+
+	job := waitBulkDeleteJob(t, int64(body["job_id"].(float64)))
+	utils.AssertEqual(t, "done", job["status"], "Status")
+	utils.AssertEqual(t, float64(2), job["total"], "Total")
+	utils.AssertEqual(t, float64(2), job["deleted"], "Deleted")
+	utils.AssertEqual(t, []interface{}{}, job["errors"], "Errors")
+
+	for _, tagId := range []int64{206, 207} {
+		status, _ = doRequest(t, http.MethodGet, "/user_banner", "user_token", map[string]interface{}{
+			"tag_id":           tagId,
+			"feature_id":       205,
+			"use_last_version": true,
+		})
+		utils.AssertEqual(t, 404, status, "UserBanner")
+	}
+}
+
+func Test_BulkDeleteEmptyFilter(t *testing.T) {
+	status, body := doRequest(t, http.MethodPost, "/banner/bulk_delete", "admin_token", map[string]interface{}{})
+	utils.AssertEqual(t, 400, status, "BulkDelete")
+	utils.AssertEqual(t, "BannersUC.BulkDelete.EmptyFilter", body["error_place"], "BulkDelete")
+
+	status, _ = doRequest(t, http.MethodGet, "/banner/bulk_delete/999999999", "admin_token", nil)
+	utils.AssertEqual(t, 404, status, "GetBulkDeleteJob")
+}
+
+// Test_BulkDeleteTagless снять с баннера все тэги нельзя ни патчем, ни в батче, поэтому bulk delete не встречает
+// баннеров без тэгов через API, задача заканчивается и Deleted не больше Total
+func Test_BulkDeleteTagless(t *testing.T) {
+	taglessId := addTestBanner(t, []int64{252}, 252)
+	addTestBanner(t, []int64{253}, 252)
+
+	status, body := doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", taglessId), "admin_token", map[string]interface{}{
+		"tag_ids": []int64{},
+	})
+	utils.AssertEqual(t, 400, status, "PatchBanner")
+	utils.AssertEqual(t, "BannersHandlers.PatchBanner.NilTagIds", body["error_place"], "PatchBanner")
+
+	status, response := doBatch(t, []map[string]interface{}{
+		{"op": "patch", "banner_id": taglessId, "banner": map[string]interface{}{"tag_ids": []int64{}}},
+	})
+	utils.AssertEqual(t, 400, status, "BatchPatch")
+	utils.AssertEqual(t, "BannersHandlers.Batch.NilTagIds", response.FailedOperation.ErrorPlace, "BatchPatch")
+
+	status, body = doRequest(t, http.MethodPost, "/banner/bulk_delete", "admin_token", map[string]interface{}{
+		"feature_id": 252,
+	})
+	utils.AssertEqual(t, 202, status, "BulkDelete")
+
+	job := waitBulkDeleteJob(t, int64(body["job_id"].(float64)))
+	utils.AssertEqual(t, "done", job["status"], "Status")
+	utils.AssertEqual(t, float64(2), job["total"], "Total")
+	utils.AssertEqual(t, float64(2), job["deleted"], "Deleted")
+	utils.AssertEqual(t, 0, len(getBulkPatchBanners(t, 252)), "NothingLeft")
+}
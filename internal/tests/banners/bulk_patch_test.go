@@ -0,0 +1,105 @@
+package banners
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/models"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"sort"
+	"testing"
+)
+
+func Test_BulkPatchToPatchBanner(t *testing.T) {
+	banner := &models.FullBanner{BannerId: 1, TagIds: []models.TagId{1}, FeatureId: 2, IsActive: true}
+	banner.Content.Url = "https://old.example.com/promo?from=https://old.example.com"
+
+	bulkPatch := &banners_usecase.BulkPatch{UrlRewrite: &banners_usecase.UrlRewrite{From: "old.example.com", To: "new.example.com"}}
+	patch := bulkPatch.ToPatchBanner(banner)
+	utils.AssertEqual(t, "https://new.example.com/promo?from=https://new.example.com", *patch.Url, "UrlRewrite")
+	utils.AssertEqual(t, "https://new.example.com/promo?from=https://new.example.com", patch.Apply(*banner).Content.Url, "Apply")
+
+	active := true
+	bulkPatch = &banners_usecase.BulkPatch{IsActive: &active, UrlRewrite: &banners_usecase.UrlRewrite{From: "other.example.com", To: "new.example.com"}}
+	utils.AssertEqual(t, true, bulkPatch.ToPatchBanner(banner) == nil, "Unchanged")
+}
+
+type bulkPatchBanner struct {
+	BannerId int64   `json:"banner_id"`
+	TagIds   []int64 `json:"tag_ids"`
+	Content  struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+		Url   string `json:"url"`
+	} `json:"content"`
+	IsActive bool   `json:"is_active"`
+	Status   string `json:"status"`
+	Version  int64  `json:"version"`
+}
+
+type bulkPatchResponse struct {
+	Matched   int64   `json:"matched"`
+	Affected  int     `json:"affected"`
+	DryRun    bool    `json:"dry_run"`
+	BannerIds []int64 `json:"banner_ids"`
+	Preview   []struct {
+		BannerId int64           `json:"banner_id"`
+		Before   bulkPatchBanner `json:"before"`
+		After    bulkPatchBanner `json:"after"`
+	} `json:"preview"`
+}
+
+func getBulkPatchBanners(t *testing.T, featureId int64) map[int64]bulkPatchBanner {
+	resp, respBody := doRawRequest(t, http.MethodGet, "/banner", map[string]string{"token": "admin_token"},
+		map[string]interface{}{"feature_id": featureId})
+	utils.AssertEqual(t, 200, resp.StatusCode, "GetManyBanner")
+	var listed []bulkPatchBanner
+	if err := json.Unmarshal(respBody, &listed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	banners := make(map[int64]bulkPatchBanner, len(listed))
+	for _, banner := range listed {
+		banners[banner.BannerId] = banner
+	}
+	return banners
+}
+
+func doBulkPatch(t *testing.T, reqBody map[string]interface{}) bulkPatchResponse {
+	resp, respBody := doRawRequest(t, http.MethodPost, "/banner/bulk_patch", map[string]string{"token": "admin_token"}, reqBody)
+	utils.AssertEqual(t, 200, resp.StatusCode, "BulkPatch")
+	var response bulkPatchResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	sort.Slice(response.BannerIds, func(i, j int) bool { return response.BannerIds[i] < response.BannerIds[j] })
+	return response
+}
+
+// Test_BulkPatchDryRun превью dry run совпадает с тем, что потом записывает настоящий запуск, а сам dry run ничего не меняет
+func Test_BulkPatchDryRun(t *testing.T) {
+	addTestBanner(t, []int64{213}, 213)
+	addTestBanner(t, []int64{214}, 213)
+	before := getBulkPatchBanners(t, 213)
+
+	patch := map[string]interface{}{
+		"feature_id": 213,
+		"content":    map[string]string{"title": "bulk_title"},
+		"status":     "paused",
+		"dry_run":    true,
+	}
+	dryRun := doBulkPatch(t, patch)
+	utils.AssertEqual(t, true, dryRun.DryRun, "DryRun")
+	utils.AssertEqual(t, 2, dryRun.Affected, "DryRun")
+	utils.AssertEqual(t, before, getBulkPatchBanners(t, 213), "DryRunWritesNothing")
+
+	patch["dry_run"] = false
+	realRun := doBulkPatch(t, patch)
+	utils.AssertEqual(t, dryRun.Matched, realRun.Matched, "Matched")
+	utils.AssertEqual(t, dryRun.BannerIds, realRun.BannerIds, "BannerIds")
+
+	after := getBulkPatchBanners(t, 213)
+	for _, preview := range dryRun.Preview {
+		utils.AssertEqual(t, before[preview.BannerId], preview.Before, "PreviewBefore")
+		utils.AssertEqual(t, after[preview.BannerId], preview.After, "PreviewAfter")
+	}
+}
@@ -0,0 +1,130 @@
+package banners
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+type changeEntry struct {
+	ChangeId  int64  `json:"change_id"`
+	BannerId  int64  `json:"banner_id"`
+	EventType string `json:"event_type"`
+	Payload   struct {
+		Content struct {
+			Title string `json:"title"`
+		} `json:"content"`
+		Version int64 `json:"version"`
+	} `json:"payload"`
+}
+
+type changesResponse struct {
+	Changes    []changeEntry `json:"changes"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+func getChanges(t *testing.T, since string, limit int) changesResponse {
+	resp, respBody := doRawRequest(t, http.MethodGet, fmt.Sprintf("/changes?since=%s&limit=%d", since, limit),
+		map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 200, resp.StatusCode, "GetChanges")
+	var response changesResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	return response
+}
+
+// tailCursor курсор конца журнала, дальше по нему приходят только события теста
+func tailCursor(t *testing.T) string {
+	cursor := ""
+	for {
+		response := getChanges(t, cursor, 1000)
+		if len(response.Changes) == 0 {
+			return response.NextCursor
+		}
+		cursor = response.NextCursor
+	}
+}
+
+// bannerChanges события баннера после курсора, страницы по limit
+func bannerChanges(t *testing.T, since string, limit int, bannerId int64) []changeEntry {
+	var changes []changeEntry
+	for {
+		response := getChanges(t, since, limit)
+		if len(response.Changes) == 0 {
+			utils.AssertEqual(t, since, response.NextCursor, "CursorStays")
+			return changes
+		}
+		after, _ := strconv.ParseInt(since, 10, 64)
+		for _, change := range response.Changes {
+			utils.AssertEqual(t, true, change.ChangeId > after, "AfterSince")
+			if change.BannerId == bannerId {
+				changes = append(changes, change)
+			}
+		}
+		utils.AssertEqual(t, strconv.FormatInt(response.Changes[len(response.Changes)-1].ChangeId, 10), response.NextCursor, "NextCursor")
+		since = response.NextCursor
+	}
+}
+
+// Test_ChangesOrder события идут строго после курсора в порядке change_id, постранично ничего не теряется и не повторяется
+func Test_ChangesOrder(t *testing.T) {
+	cursor := tailCursor(t)
+
+	bannerId := addTestBanner(t, []int64{243}, 243)
+	status, _ := doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId), "admin_token",
+		map[string]interface{}{"content": map[string]string{"title": "changed_title"}})
+	utils.AssertEqual(t, 200, status, "PatchBanner")
+	status, _ = doRequest(t, http.MethodDelete, fmt.Sprintf("/banner/%d", bannerId), "admin_token", nil)
+	utils.AssertEqual(t, 204, status, "DeleteBanner")
+
+	changes := bannerChanges(t, cursor, 1, bannerId)
+	utils.AssertEqual(t, 3, len(changes), "Changes")
+	var eventTypes []string
+	for i, change := range changes {
+		eventTypes = append(eventTypes, change.EventType)
+		if i != 0 {
+			utils.AssertEqual(t, true, change.ChangeId > changes[i-1].ChangeId, "Order")
+		}
+	}
+	utils.AssertEqual(t, []string{"created", "updated", "deleted"}, eventTypes, "EventTypes")
+	utils.AssertEqual(t, changes, bannerChanges(t, cursor, 100, bannerId), "SamePages")
+
+	resp, respBody := doRawRequest(t, http.MethodGet, "/changes?since=-1", map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 400, resp.StatusCode, "WrongCursor")
+	body := map[string]interface{}{}
+	_ = json.Unmarshal(respBody, &body)
+	utils.AssertEqual(t, "BannersHandlers.GetChanges.WrongCursor", body["error_place"], "WrongCursor")
+}
+
+// Test_ChangesRetention удаление оставляет в журнале последнее состояние баннера, откаченная транзакция не оставляет ничего
+func Test_ChangesRetention(t *testing.T) {
+	bannerId := addTestBanner(t, []int64{244}, 244)
+	cursor := tailCursor(t)
+
+	status, _ := doBatch(t, []map[string]interface{}{
+		{"op": "patch", "banner_id": bannerId, "banner": map[string]interface{}{"content": map[string]string{"title": "rolled_back"}}},
+		{"op": "delete", "banner_id": 999999999},
+	})
+	utils.AssertEqual(t, 404, status, "Batch")
+	utils.AssertEqual(t, 0, len(bannerChanges(t, cursor, 100, bannerId)), "NothingLogged")
+
+	status, _ = doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId), "admin_token",
+		map[string]interface{}{"content": map[string]string{"title": "last_title"}})
+	utils.AssertEqual(t, 200, status, "PatchBanner")
+	status, _ = doRequest(t, http.MethodDelete, fmt.Sprintf("/banner/%d", bannerId), "admin_token", nil)
+	utils.AssertEqual(t, 204, status, "DeleteBanner")
+
+	changes := bannerChanges(t, cursor, 100, bannerId)
+	utils.AssertEqual(t, 2, len(changes), "Changes")
+	utils.AssertEqual(t, "deleted", changes[1].EventType, "Deleted")
+	utils.AssertEqual(t, "last_title", changes[1].Payload.Content.Title, "Deleted")
+	utils.AssertEqual(t, int64(2), changes[1].Payload.Version, "Deleted")
+
+	created := bannerChanges(t, "", 1000, bannerId)
+	utils.AssertEqual(t, "created", created[0].EventType, "Retained")
+	utils.AssertEqual(t, "some_title", created[0].Payload.Content.Title, "Retained")
+}
@@ -0,0 +1,76 @@
+package banners
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"testing"
+)
+
+type cloneResponse struct {
+	BannerId  int64 `json:"banner_id"`
+	Conflicts []struct {
+		TagId     int64 `json:"tag_id"`
+		FeatureId int64 `json:"feature_id"`
+	} `json:"conflicts"`
+	ErrorPlace string `json:"error_place"`
+}
+
+func doClone(t *testing.T, bannerId int64, reqBody map[string]interface{}) (int, cloneResponse) {
+	resp, respBody := doRawRequest(t, http.MethodPost, fmt.Sprintf("/banner/%d/clone", bannerId),
+		map[string]string{"token": "admin_token"}, reqBody)
+	var response cloneResponse
+	_ = json.Unmarshal(respBody, &response)
+	return resp.StatusCode, response
+}
+
+// Test_CloneConflicts занятые пары (тэг, фича) копии возвращаются списком, копия не создается
+func Test_CloneConflicts(t *testing.T) {
+	sourceId := addTestBanner(t, []int64{238}, 238)
+	addTestBanner(t, []int64{239}, 239)
+
+	status, response := doClone(t, sourceId, map[string]interface{}{"tag_ids": []int64{239, 240}, "feature_id": 239})
+	utils.AssertEqual(t, 400, status, "Conflict")
+	utils.AssertEqual(t, 1, len(response.Conflicts), "Conflict")
+	utils.AssertEqual(t, int64(239), response.Conflicts[0].TagId, "Conflict")
+	utils.AssertEqual(t, int64(239), response.Conflicts[0].FeatureId, "Conflict")
+	utils.AssertEqual(t, 1, len(getBulkPatchBanners(t, 239)), "NothingCloned")
+
+	status, response = doClone(t, 999999999, map[string]interface{}{"feature_id": 239})
+	utils.AssertEqual(t, 404, status, "DoNotExist")
+	utils.AssertEqual(t, "BannersUC.CloneBanner.DoNotExist", response.ErrorPlace, "DoNotExist")
+}
+
+// Test_CloneVersions копия берет последний контент исходного баннера, а история версий исходного баннера не копируется
+func Test_CloneVersions(t *testing.T) {
+	sourceId := addTestBanner(t, []int64{241}, 241)
+	for _, title := range []string{"second_title", "third_title"} {
+		status, _ := doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", sourceId), "admin_token",
+			map[string]interface{}{"content": map[string]string{"title": title}})
+		utils.AssertEqual(t, 200, status, "PatchBanner")
+	}
+
+	status, response := doClone(t, sourceId, map[string]interface{}{"feature_id": 242})
+	utils.AssertEqual(t, 201, status, "Clone")
+
+	cloned := getBulkPatchBanners(t, 242)[response.BannerId]
+	utils.AssertEqual(t, "third_title", cloned.Content.Title, "Content")
+	utils.AssertEqual(t, []int64{241}, cloned.TagIds, "TagIds")
+	utils.AssertEqual(t, int64(1), cloned.Version, "Version")
+
+	utils.AssertEqual(t, 3, countVersions(t, sourceId), "SourceVersions")
+	utils.AssertEqual(t, 1, countVersions(t, response.BannerId), "ClonedVersions")
+
+	utils.AssertEqual(t, int64(3), getBulkPatchBanners(t, 241)[sourceId].Version, "SourceUntouched")
+}
+
+// countVersions версии баннера вместе с текущей
+func countVersions(t *testing.T, bannerId int64) int {
+	resp, respBody := doRawRequest(t, http.MethodGet, fmt.Sprintf("/banner_versions/%d", bannerId),
+		map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 200, resp.StatusCode, "ViewVersions")
+	var versions []map[string]interface{}
+	_ = json.Unmarshal(respBody, &versions)
+	return len(versions)
+}
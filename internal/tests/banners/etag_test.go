@@ -0,0 +1,45 @@
+package banners
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"testing"
+)
+
+// Test_ETag ETag приходит и на чтение, и на патч, с ним If-Match проходит, а устаревшая версия дает 412
+func Test_ETag(t *testing.T) {
+	bannerId := addTestBanner(t, []int64{212}, 212)
+	adminHeaders := map[string]string{"token": "admin_token"}
+
+	resp, _ := doRawRequest(t, http.MethodGet, "/user_banner", adminHeaders, map[string]interface{}{
+		"tag_id":           212,
+		"feature_id":       212,
+		"use_last_version": true,
+	})
+	utils.AssertEqual(t, 200, resp.StatusCode, "GetBanner")
+	utils.AssertEqual(t, `"1"`, resp.Header.Get("ETag"), "GetBanner")
+
+	resp, _ = doRawRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId),
+		map[string]string{"token": "admin_token", "If-Match": `"1"`}, map[string]interface{}{"is_active": false})
+	utils.AssertEqual(t, 200, resp.StatusCode, "PatchBanner")
+	utils.AssertEqual(t, `"2"`, resp.Header.Get("ETag"), "PatchBanner")
+
+	resp, respBody := doRawRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId),
+		map[string]string{"token": "admin_token", "If-Match": `"1"`}, map[string]interface{}{"is_active": true})
+	utils.AssertEqual(t, 412, resp.StatusCode, "StaleIfMatch")
+	body := map[string]interface{}{}
+	_ = json.Unmarshal(respBody, &body)
+	utils.AssertEqual(t, "BannersUC.PatchBanner.VersionMismatch", body["error_place"], "StaleIfMatch")
+
+	resp, _ = doRawRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId), adminHeaders,
+		map[string]interface{}{"is_active": true, "expected_version": 1})
+	utils.AssertEqual(t, 412, resp.StatusCode, "StaleExpectedVersion")
+
+	resp, _ = doRawRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId),
+		map[string]string{"token": "admin_token", "Content-Type": "application/merge-patch+json", "If-Match": `"2"`},
+		map[string]interface{}{"content": map[string]string{"title": "merged_title"}})
+	utils.AssertEqual(t, 200, resp.StatusCode, "MergePatch")
+	utils.AssertEqual(t, `"3"`, resp.Header.Get("ETag"), "MergePatch")
+}
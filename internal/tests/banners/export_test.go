@@ -0,0 +1,100 @@
+package banners
+
+import (
+	banners_http "avito/assignment/internal/banners/banners_delivery/http"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// lastExportLine служебная запись, которой заканчивается выгрузка
+func lastExportLine(body []byte) string {
+	var last string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			last = line
+		}
+	}
+	return last
+}
+
+// Test_Export выгрузка фичи целиком и с фильтром по тэгу, в конце запись export_end с числом баннеров
+func Test_Export(t *testing.T) {
+	addTestBanner(t, []int64{208}, 208)
+	addTestBanner(t, []int64{209, 210}, 208)
+
+	resp, body := doRawRequest(t, http.MethodGet, "/banner/export?feature_id=208", map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Export")
+	utils.AssertEqual(t, "application/x-ndjson", resp.Header.Get("Content-Type"), "ContentType")
+	utils.AssertEqual(t, 2, countExportRows(body), "Rows")
+
+	trailer := banners_http.ExportTrailerResponse{}
+	utils.AssertEqual(t, nil, json.Unmarshal([]byte(lastExportLine(body)), &trailer), "Trailer")
+	utils.AssertEqual(t, true, trailer.ExportEnd, "ExportEnd")
+	utils.AssertEqual(t, int64(2), trailer.Rows, "TrailerRows")
+
+	resp, body = doRawRequest(t, http.MethodGet, "/banner/export?feature_id=208&tag_id=210", map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 200, resp.StatusCode, "FilteredExport")
+	utils.AssertEqual(t, 1, countExportRows(body), "FilteredRows")
+
+	// выгрузку можно импортировать как есть, служебная запись пропускается
+	rows, err := banners_http.ParseImportNDJSON(bytes.NewReader(body))
+	utils.AssertEqual(t, nil, err, "ParseImportNDJSON")
+	utils.AssertEqual(t, 1, len(rows), "ImportRows")
+	utils.AssertEqual(t, nil, rows[0].Err, "ImportErr")
+}
+
+func Test_ExportCSV(t *testing.T) {
+	addTestBanner(t, []int64{211}, 211)
+
+	resp, body := doRawRequest(t, http.MethodGet, "/banner/export?format=csv&feature_id=211", map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Export")
+	utils.AssertEqual(t, "text/csv", resp.Header.Get("Content-Type"), "ContentType")
+	utils.AssertEqual(t, "#export_end,rows=1", lastExportLine(body), "Trailer")
+
+	rows, err := banners_http.ParseImportCSV(bytes.NewReader(body))
+	utils.AssertEqual(t, nil, err, "ParseImportCSV")
+	utils.AssertEqual(t, 1, len(rows), "ImportRows")
+	utils.AssertEqual(t, nil, rows[0].Err, "ImportErr")
+}
+
+// Test_ExportFilters выгрузка принимает те же фильтры и сортировку, что и GetManyBanner
+func Test_ExportFilters(t *testing.T) {
+	firstId := addTestBanner(t, []int64{254}, 254)
+	secondId := addTestBanner(t, []int64{255}, 254)
+	pausedId := addTestBanner(t, []int64{255}, 254)
+
+	status, _ := doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", pausedId), "admin_token", map[string]interface{}{
+		"is_active": false,
+		"content":   map[string]string{"title": "paused_title"},
+	})
+	utils.AssertEqual(t, 200, status, "PatchBanner")
+
+	exportIds := func(query string) []int64 {
+		resp, body := doRawRequest(t, http.MethodGet, "/banner/export?"+query, map[string]string{"token": "admin_token"}, nil)
+		utils.AssertEqual(t, 200, resp.StatusCode, query)
+		var bannerIds []int64
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			row := map[string]interface{}{}
+			if json.Unmarshal(scanner.Bytes(), &row) == nil && row["banner_id"] != nil {
+				bannerIds = append(bannerIds, int64(row["banner_id"].(float64)))
+			}
+		}
+		return bannerIds
+	}
+
+	utils.AssertEqual(t, []int64{secondId, firstId}, exportIds("feature_ids=254&is_active=true&sort_by=banner_id&sort_order=desc"), "IsActive")
+	utils.AssertEqual(t, []int64{firstId, secondId}, exportIds("tag_ids=254&tag_ids=255&status=active"), "TagIds")
+	utils.AssertEqual(t, []int64{pausedId}, exportIds("feature_id=254&title=paused"), "Title")
+	utils.AssertEqual(t, 0, len(exportIds("feature_id=254&created_after=2999-01-01T00:00:00Z")), "CreatedAfter")
+
+	resp, _ := doRawRequest(t, http.MethodGet, "/banner/export?feature_id=254&sort_by=rank", map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 400, resp.StatusCode, "RankWithoutSearch")
+}
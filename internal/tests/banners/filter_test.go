@@ -0,0 +1,103 @@
+package banners
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func addContentBanner(t *testing.T, tagId, featureId int64, title, text, url string, isActive bool) int64 {
+	status, body := doRequest(t, http.MethodPost, "/banner", "admin_token", map[string]interface{}{
+		"tag_ids":    []int64{tagId},
+		"feature_id": featureId,
+		"content":    map[string]string{"title": title, "text": text, "url": url},
+		"is_active":  isActive,
+	})
+	if status != 201 {
+		t.Fatalf("AddBanner: status %d, body %v", status, body)
+	}
+	return int64(body["banner_id"].(float64))
+}
+
+// filterBannerIds айди баннеров страницы GET /banner в порядке выдачи
+func filterBannerIds(t *testing.T, filter map[string]interface{}) []int64 {
+	resp, respBody := doRawRequest(t, http.MethodGet, "/banner", map[string]string{"token": "admin_token"}, filter)
+	utils.AssertEqual(t, 200, resp.StatusCode, "GetManyBanner")
+	var listed []bulkPatchBanner
+	if err := json.Unmarshal(respBody, &listed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	bannerIds := make([]int64, len(listed))
+	for i, banner := range listed {
+		bannerIds[i] = banner.BannerId
+	}
+	return bannerIds
+}
+
+func sortedIds(bannerIds ...int64) []int64 {
+	sort.Slice(bannerIds, func(i, j int) bool { return bannerIds[i] < bannerIds[j] })
+	return bannerIds
+}
+
+// Test_GetManyBannerFilters подстрока title и url без учета регистра, is_active, несколько тэгов и сортировка по версии
+func Test_GetManyBannerFilters(t *testing.T) {
+	alphaId := addContentBanner(t, 223, 223, "Alpha promo", "text", "https://alpha.example.com", true)
+	betaId := addContentBanner(t, 224, 223, "beta", "text", "https://beta.example.com/100%", false)
+	gammaId := addContentBanner(t, 225, 223, "gamma ALPHA", "text", "https://gamma.example.com", true)
+
+	utils.AssertEqual(t, sortedIds(alphaId, gammaId), filterBannerIds(t, map[string]interface{}{"feature_id": 223, "title": "alpha"}), "Title")
+	utils.AssertEqual(t, []int64{betaId}, filterBannerIds(t, map[string]interface{}{"feature_id": 223, "url": "beta.example"}), "Url")
+	utils.AssertEqual(t, []int64{betaId}, filterBannerIds(t, map[string]interface{}{"feature_id": 223, "url": "100%"}), "UrlEscaped")
+	utils.AssertEqual(t, 0, len(filterBannerIds(t, map[string]interface{}{"feature_id": 223, "url": "%"})), "UrlWildcardIsLiteral")
+	utils.AssertEqual(t, []int64{betaId}, filterBannerIds(t, map[string]interface{}{"feature_id": 223, "is_active": false}), "IsActive")
+	utils.AssertEqual(t, sortedIds(alphaId, gammaId), filterBannerIds(t, map[string]interface{}{"feature_id": 223, "tag_ids": []int64{223, 225}}), "TagIds")
+	utils.AssertEqual(t, 0, len(filterBannerIds(t, map[string]interface{}{"feature_id": 223, "created_after": "2100-01-01T00:00:00Z"})), "CreatedAfter")
+
+	for _, title := range []string{"Alpha promo 2", "Alpha promo 3"} {
+		status, _ := doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", gammaId), "admin_token", map[string]interface{}{
+			"content": map[string]string{"title": title},
+		})
+		utils.AssertEqual(t, 200, status, "PatchBanner")
+	}
+	utils.AssertEqual(t, []int64{gammaId}, filterBannerIds(t, map[string]interface{}{
+		"feature_id": 223, "sort_by": "version", "sort_order": "desc", "limit": 1,
+	}), "SortByVersion")
+}
+
+// Test_SearchRanking совпадение в заголовке весит больше, чем в тексте, совпадения подсвечиваются, фильтры работают вместе с поиском
+func Test_SearchRanking(t *testing.T) {
+	titleId := addContentBanner(t, 226, 226, "кофе со скидкой", "только сегодня", "url", true)
+	textId := addContentBanner(t, 227, 226, "напитки", "горячий кофе и чай", "url", true)
+	addContentBanner(t, 228, 226, "выпечка", "свежие булочки", "url", true)
+	addContentBanner(t, 229, 226, "кофейные зерна", "кофе", "url", false)
+
+	resp, respBody := doRawRequest(t, http.MethodGet, "/banner", map[string]string{"token": "admin_token"}, map[string]interface{}{
+		"feature_id": 226,
+		"search":     "кофе",
+		"is_active":  true,
+	})
+	utils.AssertEqual(t, 200, resp.StatusCode, "Search")
+	var found []struct {
+		BannerId int64 `json:"banner_id"`
+		Search   struct {
+			Rank  float32 `json:"rank"`
+			Title string  `json:"title"`
+		} `json:"search"`
+	}
+	if err := json.Unmarshal(respBody, &found); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	utils.AssertEqual(t, 2, len(found), "Found")
+	utils.AssertEqual(t, titleId, found[0].BannerId, "TitleFirst")
+	utils.AssertEqual(t, textId, found[1].BannerId, "TextSecond")
+	utils.AssertEqual(t, true, found[0].Search.Rank > found[1].Search.Rank, "Rank")
+	utils.AssertEqual(t, true, strings.Contains(found[0].Search.Title, "<b>кофе</b>"), "Highlight")
+
+	utils.AssertEqual(t, []int64{textId, titleId}, filterBannerIds(t, map[string]interface{}{
+		"feature_id": 226, "search": "кофе", "is_active": true, "sort_by": "rank", "sort_order": "asc",
+	}), "RankAsc")
+}
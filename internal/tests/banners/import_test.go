@@ -0,0 +1,153 @@
+package banners
+
+import (
+	banners_http "avito/assignment/internal/banners/banners_delivery/http"
+	"avito/assignment/internal/models"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2/utils"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_ParseImportCSV(t *testing.T) {
+	file := "title,text,url,tag_ids,feature_id,is_active,tags\n" +
+		"t1,x1,u1,1;2,3,true,\n" +
+		"t2,x2,u2,,4,,tag_5\n" +
+		"t3,x3,u3,one,4,false,\n"
+
+	rows, err := banners_http.ParseImportCSV(strings.NewReader(file))
+	utils.AssertEqual(t, nil, err, "ParseImportCSV")
+	utils.AssertEqual(t, 3, len(rows), "Rows")
+
+	utils.AssertEqual(t, 2, rows[0].Line, "FirstLine")
+	utils.AssertEqual(t, nil, rows[0].Err, "FirstErr")
+	utils.AssertEqual(t, []models.TagId{1, 2}, rows[0].Banner.TagIds, "FirstTagIds")
+	utils.AssertEqual(t, models.FeatureId(3), rows[0].Banner.FeatureId, "FirstFeatureId")
+	utils.AssertEqual(t, true, rows[0].Banner.IsActive, "FirstIsActive")
+	utils.AssertEqual(t, "t1", rows[0].Banner.Content.Title, "FirstTitle")
+
+	utils.AssertEqual(t, []string{"tag_5"}, rows[1].Banner.Tags, "SecondTags")
+	utils.AssertEqual(t, false, rows[1].Banner.IsActive, "SecondIsActive")
+
+	utils.AssertEqual(t, 4, rows[2].Line, "ThirdLine")
+	utils.AssertEqual(t, `invalid tag id "one"`, rows[2].Err.Error(), "ThirdErr")
+}
+
+func Test_ParseImportCSVMissingColumn(t *testing.T) {
+	_, err := banners_http.ParseImportCSV(strings.NewReader("title,text,tag_ids,feature_id\n"))
+	utils.AssertEqual(t, "csv header has no url column", err.Error(), "ParseImportCSV")
+}
+
+func Test_ParseImportNDJSON(t *testing.T) {
+	file := `{"tag_ids":[1],"feature_id":2,"content":{"title":"t","text":"x","url":"u"},"is_active":true}` + "\n" +
+		"\n" +
+		`{"tag_ids":[1],` + "\n"
+
+	rows, err := banners_http.ParseImportNDJSON(strings.NewReader(file))
+	utils.AssertEqual(t, nil, err, "ParseImportNDJSON")
+	utils.AssertEqual(t, 2, len(rows), "Rows")
+	utils.AssertEqual(t, 1, rows[0].Line, "FirstLine")
+	utils.AssertEqual(t, models.FeatureId(2), rows[0].Banner.FeatureId, "FirstFeatureId")
+	utils.AssertEqual(t, 3, rows[1].Line, "SecondLine")
+	utils.AssertEqual(t, true, rows[1].Err != nil, "SecondErr")
+}
+
+func Test_ParseImportSkipsExportTrailer(t *testing.T) {
+	ndjson := `{"tag_ids":[1],"feature_id":2,"content":{"title":"t","text":"x","url":"u"},"is_active":true}` + "\n" +
+		`{"export_end":true,"rows":1}` + "\n"
+	rows, err := banners_http.ParseImportNDJSON(strings.NewReader(ndjson))
+	utils.AssertEqual(t, nil, err, "ParseImportNDJSON")
+	utils.AssertEqual(t, 1, len(rows), "NDJSONRows")
+
+	csv := "title,text,url,tag_ids,feature_id\n" +
+		"t1,x1,u1,1,3\n" +
+		"#export_end,rows=1\n"
+	rows, err = banners_http.ParseImportCSV(strings.NewReader(csv))
+	utils.AssertEqual(t, nil, err, "ParseImportCSV")
+	utils.AssertEqual(t, 1, len(rows), "CSVRows")
+}
+
+type importResponse struct {
+	Total     int     `json:"total"`
+	Valid     int     `json:"valid"`
+	Applied   bool    `json:"applied"`
+	BannerIds []int64 `json:"banner_ids"`
+	Errors    []struct {
+		Line  int    `json:"line"`
+		Error string `json:"error"`
+	} `json:"errors"`
+}
+
+// doImport отправляет csv файл как есть, doRawRequest заворачивает тело в json
+func doImport(t *testing.T, query, file string) (int, importResponse) {
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:8892/banner/import"+query, strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("token", "admin_token")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	var response importResponse
+	_ = json.Unmarshal(respBody, &response)
+	return resp.StatusCode, response
+}
+
+// Test_ImportConflicts пары, занятые в базе или строкой выше в том же файле, дают ошибку строки с номером
+func Test_ImportConflicts(t *testing.T) {
+	addTestBanner(t, []int64{245}, 245)
+
+	status, response := doImport(t, "", "title,text,url,tag_ids,feature_id,is_active\n"+
+		"t1,x1,u1,246;247,245,true\n"+
+		"t2,x2,u2,245,245,true\n"+
+		"t3,x3,u3,247,245,true\n")
+	utils.AssertEqual(t, 400, status, "Import")
+	utils.AssertEqual(t, 3, response.Total, "Total")
+	utils.AssertEqual(t, 1, response.Valid, "Valid")
+	utils.AssertEqual(t, false, response.Applied, "Applied")
+	utils.AssertEqual(t, 2, len(response.Errors), "Errors")
+	utils.AssertEqual(t, 3, response.Errors[0].Line, "ExistingPair")
+	utils.AssertEqual(t, true, strings.HasPrefix(response.Errors[0].Error, "these banners already exists"), "ExistingPair")
+	utils.AssertEqual(t, 4, response.Errors[1].Line, "PairInFile")
+	utils.AssertEqual(t, "tag id 247 and feature id 245 are already used on line 2", response.Errors[1].Error, "PairInFile")
+}
+
+// Test_ImportAllOrNothing одна плохая строка - не записывается ни одна, после исправления записываются все сразу
+func Test_ImportAllOrNothing(t *testing.T) {
+	addTestBanner(t, []int64{248}, 248)
+	header := "title,text,url,tag_ids,feature_id,is_active\n"
+	valid := "t1,x1,u1,249,248,true\n" +
+		"t2,x2,u2,250,248,false\n"
+
+	status, response := doImport(t, "", header+valid+"t3,x3,u3,248,248,true\n")
+	utils.AssertEqual(t, 400, status, "Conflict")
+	utils.AssertEqual(t, 0, len(response.BannerIds), "Conflict")
+	utils.AssertEqual(t, 1, len(getBulkPatchBanners(t, 248)), "NothingImported")
+
+	status, response = doImport(t, "?dry_run=true", header+valid)
+	utils.AssertEqual(t, 200, status, "DryRun")
+	utils.AssertEqual(t, 2, response.Valid, "DryRun")
+	utils.AssertEqual(t, false, response.Applied, "DryRun")
+	utils.AssertEqual(t, 1, len(getBulkPatchBanners(t, 248)), "NothingImported")
+
+	status, response = doImport(t, "", header+valid)
+	utils.AssertEqual(t, 201, status, "Import")
+	utils.AssertEqual(t, true, response.Applied, "Import")
+	utils.AssertEqual(t, 2, len(response.BannerIds), "Import")
+
+	banners := getBulkPatchBanners(t, 248)
+	utils.AssertEqual(t, 3, len(banners), "Imported")
+	utils.AssertEqual(t, "t1", banners[response.BannerIds[0]].Content.Title, "Imported")
+	utils.AssertEqual(t, false, banners[response.BannerIds[1]].IsActive, "Imported")
+}
@@ -0,0 +1,99 @@
+package banners
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var nextLink = regexp.MustCompile(`<([^>]+)>; rel="next"`)
+
+func pageBannerIds(t *testing.T, body []byte) []int64 {
+	var listed []bulkPatchBanner
+	if err := json.Unmarshal(body, &listed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	bannerIds := make([]int64, len(listed))
+	for i, banner := range listed {
+		bannerIds[i] = banner.BannerId
+	}
+	return bannerIds
+}
+
+// Test_Pagination по ссылкам из Link без тела проходим всю выдачу по ключу, X-Total-Count считает все баннеры под фильтром
+func Test_Pagination(t *testing.T) {
+	var bannerIds []int64
+	for id := int64(230); id < 235; id++ {
+		bannerIds = append(bannerIds, addTestBanner(t, []int64{id}, 230))
+	}
+	adminHeaders := map[string]string{"token": "admin_token"}
+
+	resp, body := doRawRequest(t, http.MethodGet, "/banner?limit=2&with_total=true", adminHeaders, map[string]interface{}{
+		"feature_id": 230,
+		"sort_by":    "banner_id",
+		"sort_order": "desc",
+	})
+	var seen []int64
+	for pages := 1; ; pages++ {
+		utils.AssertEqual(t, 200, resp.StatusCode, "Page")
+		utils.AssertEqual(t, "5", resp.Header.Get("X-Total-Count"), "TotalCount")
+		seen = append(seen, pageBannerIds(t, body)...)
+
+		link := nextLink.FindStringSubmatch(resp.Header.Get("Link"))
+		if link == nil {
+			utils.AssertEqual(t, "", resp.Header.Get("X-Next-Cursor"), "LastPage")
+			utils.AssertEqual(t, 3, pages, "Pages")
+			break
+		}
+		nextUrl, err := url.Parse(link[1])
+		utils.AssertEqual(t, nil, err, "Link")
+		utils.AssertEqual(t, resp.Header.Get("X-Next-Cursor"), nextUrl.Query().Get("cursor"), "Link")
+		resp, body = doRawRequest(t, http.MethodGet, nextUrl.RequestURI(), adminHeaders, nil)
+	}
+	utils.AssertEqual(t, []int64{bannerIds[4], bannerIds[3], bannerIds[2], bannerIds[1], bannerIds[0]}, seen, "Keyset")
+
+	// баннер, добавленный между страницами, не сдвигает выдачу: курсор держит ключ, а не offset
+	resp, _ = doRawRequest(t, http.MethodGet, "/banner?limit=2", adminHeaders, map[string]interface{}{
+		"feature_id": 230,
+		"sort_by":    "banner_id",
+		"sort_order": "desc",
+	})
+	cursor := resp.Header.Get("X-Next-Cursor")
+	addTestBanner(t, []int64{235}, 230)
+	resp, body = doRawRequest(t, http.MethodGet, "/banner?cursor="+cursor, adminHeaders, nil)
+	utils.AssertEqual(t, []int64{bannerIds[2], bannerIds[1]}, pageBannerIds(t, body), "StableKeyset")
+	utils.AssertEqual(t, "", resp.Header.Get("X-Total-Count"), "NoTotal")
+}
+
+// Test_PaginationCursorTampered курсор подписан: подмена фильтров или подписи дает 400
+func Test_PaginationCursorTampered(t *testing.T) {
+	addTestBanner(t, []int64{236}, 236)
+	addTestBanner(t, []int64{237}, 236)
+	adminHeaders := map[string]string{"token": "admin_token"}
+
+	resp, _ := doRawRequest(t, http.MethodGet, "/banner?limit=1", adminHeaders, map[string]interface{}{"feature_id": 236})
+	cursor := resp.Header.Get("X-Next-Cursor")
+	payload, signature, ok := strings.Cut(cursor, ".")
+	utils.AssertEqual(t, true, ok, "Signed")
+
+	for name, tampered := range map[string]string{
+		"Unsigned":       payload,
+		"WrongSignature": payload + "." + strings.Repeat("A", len(signature)),
+		"OtherPayload":   base64.RawURLEncoding.EncodeToString([]byte(`{"request":{"feature_id":1}}`)) + "." + signature,
+		"Garbage":        "not a cursor",
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, body := doRawRequest(t, http.MethodGet, fmt.Sprintf("/banner?cursor=%s", url.QueryEscape(tampered)), adminHeaders, nil)
+			utils.AssertEqual(t, 400, resp.StatusCode, name)
+			response := map[string]interface{}{}
+			_ = json.Unmarshal(body, &response)
+			utils.AssertEqual(t, "BannersHandlers.GetManyBanner.InvalidCursor", response["error_place"], name)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package banners
+
+import (
+	banners_http "avito/assignment/internal/banners/banners_delivery/http"
+	reqvalidator "avito/assignment/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+// Test_SlugOnlyRequestValidation запрос только со слагами валиден сам по себе, без айди и без слага - нет
+func Test_SlugOnlyRequestValidation(t *testing.T) {
+	app := fiber.New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	getBanner := banners_http.GetBannerRequest{Tag: "tag_1", Feature: "feature_5"}
+	utils.AssertEqual(t, nil, reqvalidator.Validate(c, &getBanner), "GetBannerSlugs")
+	getBanner = banners_http.GetBannerRequest{Tag: "tag_1"}
+	utils.AssertEqual(t, true, reqvalidator.Validate(c, &getBanner) != nil, "GetBannerNoFeature")
+
+	addBanner := banners_http.AddBannerRequest{Tags: []string{"tag_1"}, Feature: "feature_5"}
+	addBanner.Content.Title, addBanner.Content.Text, addBanner.Content.Url = "title", "text", "url"
+	utils.AssertEqual(t, nil, reqvalidator.Validate(c, &addBanner), "AddBannerSlugs")
+	addBanner.Tags = nil
+	utils.AssertEqual(t, true, reqvalidator.Validate(c, &addBanner) != nil, "AddBannerNoTags")
+}
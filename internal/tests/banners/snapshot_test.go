@@ -0,0 +1,148 @@
+package banners
+
+import (
+	"archive/tar"
+	"avito/assignment/internal/banners/banners_snapshot"
+	"avito/assignment/internal/models"
+	"bytes"
+	"compress/gzip"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/lib/pq"
+	"io"
+	"testing"
+	"time"
+)
+
+func newSnapshotData() *models.SnapshotData {
+	createdAt := time.Date(2024, 4, 6, 15, 46, 28, 0, time.UTC)
+	return &models.SnapshotData{
+		Banners: []models.SnapshotBanner{
+			{BannerId: 1, FeatureId: 2, Title: "title", Text: "text", Url: "url", IsActive: true, CreatedAt: createdAt, UpdatedAt: createdAt, Version: 2},
+		},
+		TagLinks: []models.SnapshotTagLink{{BannerId: 1, TagId: 3}, {BannerId: 1, TagId: 4}},
+		Versions: []models.SnapshotVersion{
+			{BannerId: 1, FeatureId: 2, TagIds: pq.Int64Array{3}, Title: "old", Text: "text", Url: "url", CreatedAt: createdAt, UpdatedAt: createdAt, Version: 1},
+		},
+	}
+}
+
+func Test_SnapshotArchive(t *testing.T) {
+	var archive bytes.Buffer
+	manifest := &models.SnapshotManifest{SnapshotId: "snapshot_20240406T154628000", SchemaVersion: 20241019095000, CreatedAt: time.Now().UTC()}
+	err := banners_snapshot.WriteArchive(&archive, manifest, newSnapshotData())
+	utils.AssertEqual(t, nil, err, "WriteArchive")
+	utils.AssertEqual(t, banners_snapshot.FormatVersion, manifest.FormatVersion, "FormatVersion")
+	utils.AssertEqual(t, 2, manifest.Counts["banners_x_tags.ndjson"], "Counts")
+
+	readManifest, err := banners_snapshot.ReadManifest(bytes.NewReader(archive.Bytes()))
+	utils.AssertEqual(t, nil, err, "ReadManifest")
+	utils.AssertEqual(t, manifest.SchemaVersion, readManifest.SchemaVersion, "SchemaVersion")
+
+	_, data, err := banners_snapshot.ReadArchive(bytes.NewReader(archive.Bytes()))
+	utils.AssertEqual(t, nil, err, "ReadArchive")
+	utils.AssertEqual(t, newSnapshotData(), data, "Data")
+}
+
+func Test_SnapshotArchiveChecksumMismatch(t *testing.T) {
+	var archive bytes.Buffer
+	manifest := &models.SnapshotManifest{SnapshotId: "snapshot_20240406T154628000", CreatedAt: time.Now().UTC()}
+	utils.AssertEqual(t, nil, banners_snapshot.WriteArchive(&archive, manifest, newSnapshotData()), "WriteArchive")
+
+	// переписываем архив, подменив строку баннера, manifest остается прежним
+	gzipReader, _ := gzip.NewReader(&archive)
+	tarReader := tar.NewReader(gzipReader)
+	var tampered bytes.Buffer
+	gzipWriter := gzip.NewWriter(&tampered)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		content, _ := io.ReadAll(tarReader)
+		if header.Name == "banners.ndjson" {
+			content = bytes.Replace(content, []byte(`"title":"title"`), []byte(`"title":"other"`), 1)
+			header.Size = int64(len(content))
+		}
+		_ = tarWriter.WriteHeader(header)
+		_, _ = tarWriter.Write(content)
+	}
+	_ = tarWriter.Close()
+	_ = gzipWriter.Close()
+
+	_, _, err := banners_snapshot.ReadArchive(&tampered)
+	utils.AssertEqual(t, "checksum mismatch for banners.ndjson", err.Error(), "ReadArchive")
+}
+
+func Test_SnapshotStore(t *testing.T) {
+	store := banners_snapshot.NewStore(t.TempDir())
+
+	older := banners_snapshot.NewSnapshotId(time.Date(2024, 4, 6, 15, 46, 28, 0, time.UTC))
+	newer := banners_snapshot.NewSnapshotId(time.Date(2024, 4, 7, 15, 46, 28, 0, time.UTC))
+	for _, snapshotId := range []string{older, newer} {
+		err := store.Save(snapshotId, func(w io.Writer) error {
+			_, err := w.Write([]byte(snapshotId))
+			return err
+		})
+		utils.AssertEqual(t, nil, err, "Save")
+	}
+
+	snapshotIds, err := store.List()
+	utils.AssertEqual(t, nil, err, "List")
+	utils.AssertEqual(t, []string{newer, older}, snapshotIds, "Order")
+
+	_, err = store.Open("../config")
+	utils.AssertEqual(t, banners_snapshot.ErrNotFound, err, "PathTraversal")
+
+	utils.AssertEqual(t, nil, store.Delete(older), "Delete")
+	utils.AssertEqual(t, banners_snapshot.ErrNotFound, store.Delete(older), "DeleteTwice")
+}
+
+func Test_SnapshotIdSameMillisecond(t *testing.T) {
+	store := banners_snapshot.NewStore(t.TempDir())
+
+	now := time.Date(2024, 4, 6, 15, 46, 28, 0, time.UTC)
+	first, second := banners_snapshot.NewSnapshotId(now), banners_snapshot.NewSnapshotId(now)
+	utils.AssertEqual(t, true, first != second, "Unique")
+
+	for _, snapshotId := range []string{first, second} {
+		err := store.Save(snapshotId, func(w io.Writer) error {
+			_, err := w.Write([]byte(snapshotId))
+			return err
+		})
+		utils.AssertEqual(t, nil, err, "Save")
+	}
+
+	snapshotIds, err := store.List()
+	utils.AssertEqual(t, nil, err, "List")
+	utils.AssertEqual(t, 2, len(snapshotIds), "Count")
+
+	utils.AssertEqual(t, true, banners_snapshot.ValidSnapshotId(first), "ValidId")
+	utils.AssertEqual(t, true, banners_snapshot.ValidSnapshotId("snapshot_20240406T154628000"), "LegacyId")
+}
+
+// Test_SnapshotOlderSchema снапшот до появления статуса восстанавливается со статусом из is_active, как в миграции
+func Test_SnapshotOlderSchema(t *testing.T) {
+	var archive bytes.Buffer
+	manifest := &models.SnapshotManifest{SnapshotId: "snapshot_20241019T095000000", SchemaVersion: 20241019095000, CreatedAt: time.Now().UTC()}
+	utils.AssertEqual(t, nil, banners_snapshot.WriteArchive(&archive, manifest, newSnapshotData()), "WriteArchive")
+
+	readManifest, data, err := banners_snapshot.ReadArchive(&archive)
+	utils.AssertEqual(t, nil, err, "ReadArchive")
+
+	err = banners_snapshot.UpgradeData(readManifest.SchemaVersion, 20241019107000, data)
+	utils.AssertEqual(t, nil, err, "UpgradeData")
+	utils.AssertEqual(t, models.BannerStatusActive, data.Banners[0].Status, "BannerStatus")
+	utils.AssertEqual(t, models.BannerStatusPaused, data.Versions[0].Status, "VersionStatus")
+	utils.AssertEqual(t, (*time.Time)(nil), data.Banners[0].DeletedAt, "DeletedAt")
+}
+
+func Test_SnapshotSchemaRange(t *testing.T) {
+	data := newSnapshotData()
+	data.Banners[0].Status = models.BannerStatusScheduled
+	utils.AssertEqual(t, nil, banners_snapshot.UpgradeData(20241019104000, 20241019107000, data), "AfterStatus")
+	utils.AssertEqual(t, models.BannerStatusScheduled, data.Banners[0].Status, "StatusKept")
+
+	utils.AssertEqual(t, true, banners_snapshot.UpgradeData(20241019107000, 20241019104000, data) != nil, "NewerSchema")
+	utils.AssertEqual(t, true, banners_snapshot.UpgradeData(20240406154628, 20241019107000, data) != nil, "BeforeSnapshots")
+}
@@ -0,0 +1,59 @@
+package banners
+
+import (
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/models"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"testing"
+)
+
+func Test_BannerStatusTransitions(t *testing.T) {
+	utils.AssertEqual(t, true, models.CanTransition(models.BannerStatusDraft, models.BannerStatusActive), "DraftToActive")
+	utils.AssertEqual(t, true, models.CanTransition(models.BannerStatusPaused, models.BannerStatusPaused), "Same")
+	utils.AssertEqual(t, false, models.CanTransition(models.BannerStatusArchived, models.BannerStatusActive), "ArchivedToActive")
+	utils.AssertEqual(t, false, models.CanTransition(models.BannerStatusExpired, models.BannerStatusPaused), "ExpiredToPaused")
+
+	utils.AssertEqual(t, models.BannerStatusActive, models.StatusFromIsActive(models.BannerStatusDraft, true), "IsActiveTrue")
+	utils.AssertEqual(t, models.BannerStatusPaused, models.StatusFromIsActive(models.BannerStatusActive, false), "PauseActive")
+	utils.AssertEqual(t, models.BannerStatusScheduled, models.StatusFromIsActive(models.BannerStatusScheduled, false), "KeepInactive")
+}
+
+func Test_PatchBannerApplyStatus(t *testing.T) {
+	banner := models.FullBanner{BannerId: 1, IsActive: true, Status: models.BannerStatusActive}
+
+	inactive := false
+	patched := (&banners_usecase.PatchBanner{IsActive: &inactive}).Apply(banner)
+	utils.AssertEqual(t, models.BannerStatusPaused, patched.Status, "IsActiveOnly")
+
+	archived := models.BannerStatusArchived
+	patched = (&banners_usecase.PatchBanner{Status: &archived}).Apply(banner)
+	utils.AssertEqual(t, false, patched.IsActive, "StatusOnly")
+}
+
+// Test_RollbackStatus откат возвращает статус версии, даже если обычным патчем в него не перейти
+func Test_RollbackStatus(t *testing.T) {
+	bannerId := addTestBanner(t, []int64{215}, 215)
+	bannerStatus := func() string {
+		return getBulkPatchBanners(t, 215)[bannerId].Status
+	}
+
+	status, _ := doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId), "admin_token", map[string]interface{}{"status": "archived"})
+	utils.AssertEqual(t, 200, status, "Archive")
+	status, body := doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId), "admin_token", map[string]interface{}{"status": "active"})
+	utils.AssertEqual(t, 400, status, "PatchArchivedToActive")
+	utils.AssertEqual(t, "BannersUC.PatchBanner.WrongStatus", body["error_place"], "PatchArchivedToActive")
+
+	status, _ = doRequest(t, http.MethodPut, fmt.Sprintf("/banner_rollback/%d/1", bannerId), "admin_token", nil)
+	utils.AssertEqual(t, 200, status, "Rollback")
+	utils.AssertEqual(t, "active", bannerStatus(), "Rollback")
+
+	status, _ = doRequest(t, http.MethodPatch, fmt.Sprintf("/banner/%d", bannerId), "admin_token", map[string]interface{}{"status": "archived"})
+	utils.AssertEqual(t, 200, status, "ArchiveAgain")
+	status, _ = doRequest(t, http.MethodPost, "/banner/batch", "admin_token", map[string]interface{}{
+		"operations": []map[string]interface{}{{"op": "rollback", "banner_id": bannerId, "version": 3}},
+	})
+	utils.AssertEqual(t, 200, status, "BatchRollback")
+	utils.AssertEqual(t, "active", bannerStatus(), "BatchRollback")
+}
@@ -0,0 +1,29 @@
+package banners
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Test_TemplateAttributes атрибуты приходят query параметрами attr.*, как у стрима, экранируется только url
+func Test_TemplateAttributes(t *testing.T) {
+	addContentBanner(t, 251, 251, "Привет, {{user.name}}", "Город: {{user.city}}", "https://shop.ru/?code={{promo.code}}", true)
+
+	query := url.Values{
+		"attr.user.name":  {"Tom & Jerry"},
+		"attr.user.city":  {"<Москва>"},
+		"attr.promo.code": {"A&B"},
+	}
+	resp, respBody := doRawRequest(t, http.MethodGet, "/user_banner?"+query.Encode(), map[string]string{"token": "user_token"},
+		map[string]interface{}{"tag_id": 251, "feature_id": 251, "use_last_version": true})
+	utils.AssertEqual(t, 200, resp.StatusCode, "GetBanner")
+
+	content := map[string]string{}
+	_ = json.Unmarshal(respBody, &content)
+	utils.AssertEqual(t, "Привет, Tom & Jerry", content["title"], "Title")
+	utils.AssertEqual(t, "Город: <Москва>", content["text"], "Text")
+	utils.AssertEqual(t, "https://shop.ru/?code=A%26B", content["url"], "Url")
+}
@@ -0,0 +1,103 @@
+package banners
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"net/http"
+	"testing"
+)
+
+func addTestBanner(t *testing.T, tagIds []int64, featureId int64) int64 {
+	status, body := doRequest(t, http.MethodPost, "/banner", "admin_token", map[string]interface{}{
+		"tag_ids":    tagIds,
+		"feature_id": featureId,
+		"content": map[string]string{
+			"title": "some_title",
+			"text":  "some_text",
+			"url":   "some_url",
+		},
+		"is_active": true,
+	})
+	if status != 201 {
+		t.Fatalf("AddBanner: status %d, body %v", status, body)
+	}
+	return int64(body["banner_id"].(float64))
+}
+
+// Test_TrashHidden баннер в корзине не отдается пользователям, в списке и выгрузке, а в корзине видно, кто его удалил
+func Test_TrashHidden(t *testing.T) {
+	bannerId := addTestBanner(t, []int64{201}, 201)
+
+	status, _ := doRequest(t, http.MethodDelete, fmt.Sprintf("/banner/%d", bannerId), "alice_admin_token", nil)
+	utils.AssertEqual(t, 204, status, "DeleteBanner")
+
+	status, body := doRequest(t, http.MethodGet, "/user_banner", "user_token", map[string]interface{}{
+		"tag_id":           201,
+		"feature_id":       201,
+		"use_last_version": true,
+	})
+	utils.AssertEqual(t, 404, status, "UserBanner")
+	utils.AssertEqual(t, "BannersRepo.GetBanner.ErrNoRows", body["error_place"], "UserBanner")
+
+	resp, respBody := doRawRequest(t, http.MethodGet, "/banner", map[string]string{"token": "admin_token"},
+		map[string]interface{}{"feature_id": 201})
+	utils.AssertEqual(t, 200, resp.StatusCode, "GetManyBanner")
+	var listed []map[string]interface{}
+	_ = json.Unmarshal(respBody, &listed)
+	utils.AssertEqual(t, 0, len(listed), "GetManyBanner")
+
+	resp, respBody = doRawRequest(t, http.MethodGet, "/banner/export?feature_id=201", map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 200, resp.StatusCode, "Export")
+	utils.AssertEqual(t, 0, countExportRows(respBody), "Export")
+
+	resp, respBody = doRawRequest(t, http.MethodGet, "/banner/trash?limit=1000", map[string]string{"token": "admin_token"}, nil)
+	utils.AssertEqual(t, 200, resp.StatusCode, "GetTrash")
+	var trashed []map[string]interface{}
+	_ = json.Unmarshal(respBody, &trashed)
+	var deletedBy interface{}
+	for _, banner := range trashed {
+		if int64(banner["banner_id"].(float64)) == bannerId {
+			deletedBy = banner["deleted_by"]
+		}
+	}
+	utils.AssertEqual(t, "alice", deletedBy, "DeletedBy")
+}
+
+// Test_RestoreConflict пока баннер лежал в корзине, его пару занял новый баннер, восстановить можно только после его удаления
+func Test_RestoreConflict(t *testing.T) {
+	bannerId := addTestBanner(t, []int64{202}, 202)
+
+	status, _ := doRequest(t, http.MethodDelete, fmt.Sprintf("/banner/%d", bannerId), "admin_token", nil)
+	utils.AssertEqual(t, 204, status, "DeleteBanner")
+
+	otherId := addTestBanner(t, []int64{202}, 202)
+
+	status, body := doRequest(t, http.MethodPost, fmt.Sprintf("/banner/%d/restore", bannerId), "admin_token", nil)
+	utils.AssertEqual(t, 400, status, "Conflict")
+	utils.AssertEqual(t, "BannersUC.RestoreBanner.AlreadyExists", body["error_place"], "Conflict")
+
+	status, _ = doRequest(t, http.MethodDelete, fmt.Sprintf("/banner/%d", otherId), "admin_token", nil)
+	utils.AssertEqual(t, 204, status, "DeleteOther")
+
+	status, _ = doRequest(t, http.MethodPost, fmt.Sprintf("/banner/%d/restore", bannerId), "admin_token", nil)
+	utils.AssertEqual(t, 200, status, "Restore")
+
+	status, _ = doRequest(t, http.MethodPost, fmt.Sprintf("/banner/%d/restore", bannerId), "admin_token", nil)
+	utils.AssertEqual(t, 404, status, "RestoreTwice")
+}
+
+// countExportRows строки ndjson выгрузки с баннерами, служебные записи не считаются
+func countExportRows(body []byte) int {
+	rows := 0
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		row := map[string]interface{}{}
+		if json.Unmarshal(scanner.Bytes(), &row) == nil && row["banner_id"] != nil {
+			rows++
+		}
+	}
+	return rows
+}
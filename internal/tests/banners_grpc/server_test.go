@@ -0,0 +1,276 @@
+package banners_grpc
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/banners/banners_delivery/grpc"
+	"avito/assignment/internal/banners/banners_usecase"
+	"avito/assignment/internal/middleware"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/api/banners_v1"
+	"avito/assignment/pkg/constant"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// bannersUCStub юзкейс в памяти, ошибки заворачиваются так же, как в BannersUC
+type bannersUCStub struct {
+	banners map[models.BannerId]*models.FullBanner
+	deleted map[models.BannerId]string
+	nextId  models.BannerId
+}
+
+func newBannersUCStub() *bannersUCStub {
+	return &bannersUCStub{
+		banners: map[models.BannerId]*models.FullBanner{},
+		deleted: map[models.BannerId]string{},
+		nextId:  1,
+	}
+}
+
+func (b *bannersUCStub) getBanner(ctx context.Context, bannerId models.BannerId, place string) (*models.FullBanner, error) {
+	banner, ok := b.banners[bannerId]
+	if !ok {
+		return nil, traces.SpanSetErrWrap(trace.SpanFromContext(ctx), errlst.HttpErrNotFound,
+			fmt.Errorf("banner with id %d doesnt exist", bannerId), place)
+	}
+	return banner, nil
+}
+
+func (b *bannersUCStub) GetBanner(ctx context.Context, getBannerParams *banners_usecase.GetBanner) (*models.FullBanner, error) {
+	for _, banner := range b.banners {
+		if banner.FeatureId != getBannerParams.FeatureId {
+			continue
+		}
+		for _, tagId := range banner.TagIds {
+			if tagId == getBannerParams.TagId {
+				return banner, nil
+			}
+		}
+	}
+	return nil, traces.SpanSetErrWrap(trace.SpanFromContext(ctx), errlst.HttpErrNotFound, nil, "BannersRepo.GetBanner.ErrNoRows")
+}
+
+func (b *bannersUCStub) GetManyBanner(_ context.Context, _ *banners_usecase.GetManyBanner) (*[]models.FullBanner, error) {
+	banners := make([]models.FullBanner, 0, len(b.banners))
+	for bannerId := models.BannerId(1); bannerId < b.nextId; bannerId++ {
+		if banner, ok := b.banners[bannerId]; ok {
+			banners = append(banners, *banner)
+		}
+	}
+	return &banners, nil
+}
+
+func (b *bannersUCStub) AddBanner(_ context.Context, addBannerParams *banners_usecase.AddBanner) (models.BannerId, error) {
+	banner := &models.FullBanner{
+		BannerId:  b.nextId,
+		TagIds:    addBannerParams.TagIds,
+		FeatureId: addBannerParams.FeatureId,
+		IsActive:  addBannerParams.IsActive,
+		Version:   1,
+	}
+	banner.Content.Title = addBannerParams.Content.Title
+	banner.Content.Text = addBannerParams.Content.Text
+	banner.Content.Url = addBannerParams.Content.Url
+	b.banners[banner.BannerId] = banner
+	b.nextId++
+	return banner.BannerId, nil
+}
+
+func (b *bannersUCStub) PatchBanner(ctx context.Context, patchBannerParams *banners_usecase.PatchBanner) (int64, error) {
+	banner, err := b.getBanner(ctx, patchBannerParams.BannerId, "BannersUC.PatchBanner.ErrNotFound")
+	if err != nil {
+		return 0, err
+	}
+	if patchBannerParams.ExpectedVersion != nil && *patchBannerParams.ExpectedVersion != banner.Version {
+		return 0, traces.SpanSetErrWrap(trace.SpanFromContext(ctx), errlst.HttpErrPreconditionFailed,
+			fmt.Errorf("banner version is %d, expected %d", banner.Version, *patchBannerParams.ExpectedVersion),
+			"BannersUC.PatchBanner.VersionMismatch")
+	}
+	if patchBannerParams.Title != nil {
+		banner.Content.Title = *patchBannerParams.Title
+	}
+	if patchBannerParams.IsActive != nil {
+		banner.IsActive = *patchBannerParams.IsActive
+	}
+	banner.Version++
+	return banner.Version, nil
+}
+
+func (b *bannersUCStub) DeleteBanner(ctx context.Context, bannerId models.BannerId, actor string) error {
+	if _, err := b.getBanner(ctx, bannerId, "BannersUC.DeleteBanner.DoNotExist"); err != nil {
+		return err
+	}
+	delete(b.banners, bannerId)
+	b.deleted[bannerId] = actor
+	return nil
+}
+
+func (b *bannersUCStub) ViewVersions(ctx context.Context, bannerId models.BannerId) (*[]models.FullBanner, error) {
+	banner, err := b.getBanner(ctx, bannerId, "BannersUC.ViewVersions.DoNotExist")
+	if err != nil {
+		return nil, err
+	}
+	return &[]models.FullBanner{*banner}, nil
+}
+
+func (b *bannersUCStub) BannerRollback(ctx context.Context, bannerId models.BannerId, _ int64, expectedVersion *int64) error {
+	banner, err := b.getBanner(ctx, bannerId, "BannersUC.BannerRollback.DoNotExist")
+	if err != nil {
+		return err
+	}
+	if expectedVersion != nil && *expectedVersion != banner.Version {
+		return traces.SpanSetErrWrap(trace.SpanFromContext(ctx), errlst.HttpErrPreconditionFailed,
+			errors.New("banner version mismatch"), "BannersUC.BannerRollback.VersionMismatch")
+	}
+	banner.Version++
+	return nil
+}
+
+// newClient поднимает grpc сервер с теми же перехватчиками, что и в server.MapHandlers, поверх bufconn
+func newClient(t *testing.T, bannersUC banners_grpc.BannersUseCase) banners_v1.BannersServiceClient {
+	cfg := &config.Config{}
+	cfg.Auth.Admins = make([]struct {
+		Name  string `validate:"required"`
+		Token string `validate:"required"`
+	}, 1)
+	cfg.Auth.Admins[0].Name = "alice"
+	cfg.Auth.Admins[0].Token = "alice_admin_token"
+	mw := middleware.NewOfficiantMiddleware(cfg, nil)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		mw.GRPCTrace(),
+		mw.GRPCCheckAuthToken(banners_grpc.MethodRestrictions),
+	))
+	banners_grpc.MapBannersService(server, banners_grpc.NewBannersHandlers(bannersUC, cfg))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return banners_v1.NewBannersServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "token", token)
+}
+
+func addBanner(t *testing.T, client banners_v1.BannersServiceClient) int64 {
+	resp, err := client.AddBanner(withToken(constant.AdminToken), &banners_v1.AddBannerRequest{
+		TagIds:    []int64{1, 2},
+		FeatureId: 1,
+		Content:   &banners_v1.Content{Title: "some_title", Text: "some_text", Url: "some_url"},
+		IsActive:  true,
+	})
+	if err != nil {
+		t.Fatalf("AddBanner failed: %v", err)
+	}
+	return resp.GetBannerId()
+}
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func Test_GRPCAuth(t *testing.T) {
+	client := newClient(t, newBannersUCStub())
+
+	_, err := client.GetManyBanner(context.Background(), &banners_v1.GetManyBannerRequest{})
+	utils.AssertEqual(t, codes.Unauthenticated, status.Code(err), "NilToken")
+
+	_, err = client.GetManyBanner(withToken(constant.UserToken), &banners_v1.GetManyBannerRequest{})
+	utils.AssertEqual(t, codes.PermissionDenied, status.Code(err), "Forbidden")
+
+	_, err = client.GetManyBanner(withToken("alice_admin_token"), &banners_v1.GetManyBannerRequest{})
+	utils.AssertEqual(t, codes.OK, status.Code(err), "PersonalToken")
+}
+
+func Test_GRPCGetBanner(t *testing.T) {
+	client := newClient(t, newBannersUCStub())
+	addBanner(t, client)
+
+	resp, err := client.GetBanner(withToken(constant.UserToken), &banners_v1.GetBannerRequest{TagId: 2, FeatureId: 1})
+	utils.AssertEqual(t, nil, err, "GetBanner")
+	utils.AssertEqual(t, "some_title", resp.GetContent().GetTitle(), "GetBanner")
+
+	_, err = client.GetBanner(withToken(constant.UserToken), &banners_v1.GetBannerRequest{TagId: 3, FeatureId: 1})
+	utils.AssertEqual(t, codes.NotFound, status.Code(err), "NotFound")
+
+	_, err = client.GetBanner(withToken(constant.UserToken), &banners_v1.GetBannerRequest{FeatureId: 1})
+	utils.AssertEqual(t, codes.InvalidArgument, status.Code(err), "ReadRequest")
+}
+
+// Test_GRPCPatchBanner expected_version доходит до юзкейса, устаревшая версия дает FailedPrecondition
+func Test_GRPCPatchBanner(t *testing.T) {
+	client := newClient(t, newBannersUCStub())
+	bannerId := addBanner(t, client)
+	ctx := withToken(constant.AdminToken)
+
+	resp, err := client.PatchBanner(ctx, &banners_v1.PatchBannerRequest{
+		BannerId:        bannerId,
+		Content:         &banners_v1.PatchContent{Title: stringPtr("new_title")},
+		ExpectedVersion: int64Ptr(1),
+	})
+	utils.AssertEqual(t, nil, err, "PatchBanner")
+	utils.AssertEqual(t, int64(2), resp.GetVersion(), "PatchBanner")
+
+	_, err = client.PatchBanner(ctx, &banners_v1.PatchBannerRequest{
+		BannerId:        bannerId,
+		Content:         &banners_v1.PatchContent{Title: stringPtr("stale_title")},
+		ExpectedVersion: int64Ptr(1),
+	})
+	utils.AssertEqual(t, codes.FailedPrecondition, status.Code(err), "StaleExpectedVersion")
+
+	_, err = client.BannerRollback(ctx, &banners_v1.BannerRollbackRequest{BannerId: bannerId, Version: 1, ExpectedVersion: int64Ptr(1)})
+	utils.AssertEqual(t, codes.FailedPrecondition, status.Code(err), "StaleRollback")
+
+	_, err = client.PatchBanner(ctx, &banners_v1.PatchBannerRequest{BannerId: 999, IsActive: new(bool)})
+	utils.AssertEqual(t, codes.NotFound, status.Code(err), "NotFound")
+
+	versions, err := client.ViewVersions(ctx, &banners_v1.ViewVersionsRequest{BannerId: bannerId})
+	utils.AssertEqual(t, nil, err, "ViewVersions")
+	utils.AssertEqual(t, "new_title", versions.GetBanners()[0].GetContent().GetTitle(), "ViewVersions")
+	utils.AssertEqual(t, int64(2), versions.GetBanners()[0].GetVersion(), "ViewVersions")
+}
+
+// Test_GRPCDeleteBanner по персональному токену в корзину пишется имя админа
+func Test_GRPCDeleteBanner(t *testing.T) {
+	bannersUC := newBannersUCStub()
+	client := newClient(t, bannersUC)
+	bannerId := addBanner(t, client)
+
+	_, err := client.DeleteBanner(withToken("alice_admin_token"), &banners_v1.DeleteBannerRequest{BannerId: bannerId})
+	utils.AssertEqual(t, nil, err, "DeleteBanner")
+	utils.AssertEqual(t, "alice", bannersUC.deleted[models.BannerId(bannerId)], "Actor")
+
+	_, err = client.DeleteBanner(withToken(constant.AdminToken), &banners_v1.DeleteBannerRequest{BannerId: bannerId})
+	utils.AssertEqual(t, codes.NotFound, status.Code(err), "DeleteTwice")
+}
@@ -0,0 +1,336 @@
+package banners_stream
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/banners/banners_delivery/http"
+	"avito/assignment/internal/banners/banners_stream"
+	"avito/assignment/internal/middleware"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/constant"
+	"avito/assignment/pkg/error_handler"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"net"
+	nethttp "net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const waitTimeout = 5 * time.Second
+
+// changesRepoStub события приходят из канала live, история для Last-Event-ID - из backlog
+type changesRepoStub struct {
+	live    chan models.BannerChange
+	backlog []models.BannerChange
+}
+
+func newChangesRepoStub(backlog ...models.BannerChange) *changesRepoStub {
+	return &changesRepoStub{live: make(chan models.BannerChange), backlog: backlog}
+}
+
+func (r *changesRepoStub) SubscribeBannerChanges(ctx context.Context) <-chan models.BannerChange {
+	changes := make(chan models.BannerChange)
+	go func() {
+		defer close(changes)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change := <-r.live:
+				changes <- change
+			}
+		}
+	}()
+	return changes
+}
+
+func (r *changesRepoStub) GetBannerChanges(_ context.Context, afterEventId string) ([]models.BannerChange, error) {
+	var changes []models.BannerChange
+	for _, change := range r.backlog {
+		if banners_stream.IsAfter(change.EventId, afterEventId) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// recordingStream хаб, который сообщает об отписке клиента
+type recordingStream struct {
+	*banners_stream.Hub
+	unsubscribed chan struct{}
+}
+
+func (s *recordingStream) Unsubscribe(subscriber *banners_stream.Subscriber) {
+	s.Hub.Unsubscribe(subscriber)
+	s.unsubscribed <- struct{}{}
+}
+
+// bannersUCStub стриму из юзкейса нужен только PresentBanner
+type bannersUCStub struct {
+	banners_http.BannersUseCase
+}
+
+func (b *bannersUCStub) PresentBanner(_ context.Context, fullBanner *models.FullBanner, _ string, _ map[string]string) (*models.FullBanner, error) {
+	return fullBanner, nil
+}
+
+func startHub(t *testing.T, repo *changesRepoStub) *banners_stream.Hub {
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := banners_stream.NewHub(repo)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		hub.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	return hub
+}
+
+func newChange(eventId string, tagIds []models.TagId, featureId models.FeatureId, title string) models.BannerChange {
+	banner := &models.FullBanner{BannerId: 1, TagIds: tagIds, FeatureId: featureId, IsActive: true}
+	banner.Content.Title = title
+	return models.BannerChange{EventId: eventId, Type: models.WebhookEventUpdated, BannerId: 1, Banner: banner}
+}
+
+func receive(t *testing.T, changes <-chan models.BannerChange) (models.BannerChange, bool) {
+	select {
+	case change, ok := <-changes:
+		return change, ok
+	case <-time.After(waitTimeout):
+		t.Fatal("no change received")
+		return models.BannerChange{}, false
+	}
+}
+
+// Test_HubNotify событие из redis получают все подписчики, отписанный - нет
+func Test_HubNotify(t *testing.T) {
+	repo := newChangesRepoStub()
+	hub := startHub(t, repo)
+
+	first, second := hub.Subscribe(), hub.Subscribe()
+	repo.live <- newChange("1-0", []models.TagId{1}, 1, "first")
+
+	change, _ := receive(t, first.Changes)
+	utils.AssertEqual(t, "1-0", change.EventId, "First")
+	change, _ = receive(t, second.Changes)
+	utils.AssertEqual(t, "1-0", change.EventId, "Second")
+
+	hub.Unsubscribe(second)
+	_, ok := receive(t, second.Changes)
+	utils.AssertEqual(t, false, ok, "Unsubscribed")
+	hub.Unsubscribe(second)
+
+	repo.live <- newChange("2-0", []models.TagId{1}, 1, "second")
+	change, _ = receive(t, first.Changes)
+	utils.AssertEqual(t, "2-0", change.EventId, "AfterUnsubscribe")
+}
+
+// Test_HubStop после остановки хаба каналы подписчиков закрыты, новые подписки не принимаются
+func Test_HubStop(t *testing.T) {
+	repo := newChangesRepoStub()
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := banners_stream.NewHub(repo)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		hub.Run(ctx)
+	}()
+
+	subscriber := hub.Subscribe()
+	cancel()
+	<-stopped
+
+	_, ok := receive(t, subscriber.Changes)
+	utils.AssertEqual(t, false, ok, "Closed")
+	utils.AssertEqual(t, (*banners_stream.Subscriber)(nil), hub.Subscribe(), "SubscribeAfterStop")
+}
+
+// Test_HubSlowSubscriber переполненный буфер медленного клиента не тормозит остальных
+func Test_HubSlowSubscriber(t *testing.T) {
+	repo := newChangesRepoStub()
+	hub := startHub(t, repo)
+
+	slow, fast := hub.Subscribe(), hub.Subscribe()
+	for i := 0; i < cap(slow.Changes)+1; i++ {
+		repo.live <- newChange(fmt.Sprintf("%d-0", i+1), []models.TagId{1}, 1, "title")
+		receive(t, fast.Changes)
+	}
+	utils.AssertEqual(t, cap(slow.Changes), len(slow.Changes), "Dropped")
+}
+
+type sseClient struct {
+	reader *bufio.Reader
+	cancel context.CancelFunc
+}
+
+type sseEvent struct {
+	id   string
+	data banners_http.StreamBannerEvent
+}
+
+func startServer(t *testing.T, hub *banners_stream.Hub) (string, *recordingStream) {
+	cfg := &config.Config{}
+	cfg.Stream.HeartbeatSeconds = 1
+	stream := &recordingStream{Hub: hub, unsubscribed: make(chan struct{}, 1)}
+	handlers := banners_http.NewUserHandler(&bannersUCStub{}, stream, nil, cfg)
+	mw := middleware.NewOfficiantMiddleware(cfg, nil)
+
+	// error_place отдается только на dev хостах, как у тестового сервера из config.json
+	constant.Host = constant.DevHosts[0]
+	app := fiber.New(fiber.Config{DisableStartupMessage: true, ErrorHandler: error_handler.FiberErrorHandler})
+	app.Get("/user_banner/stream", mw.CheckAuthToken(constant.AllRoles), handlers.StreamBanners())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go func() {
+		_ = app.Listener(listener)
+	}()
+	t.Cleanup(func() {
+		_ = app.ShutdownWithTimeout(time.Second)
+	})
+	return "http://" + listener.Addr().String(), stream
+}
+
+// connect открывает стрим и дожидается retry, после него клиент уже подписан на хаб
+func connect(t *testing.T, url, lastEventId string) *sseClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	req.Header.Set("token", constant.UserToken)
+	if lastEventId != "" {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	utils.AssertEqual(t, 200, resp.StatusCode, "Stream")
+	utils.AssertEqual(t, "text/event-stream", resp.Header.Get("Content-Type"), "Stream")
+	t.Cleanup(func() {
+		cancel()
+		_ = resp.Body.Close()
+	})
+
+	client := &sseClient{reader: bufio.NewReader(resp.Body), cancel: cancel}
+	line, err := client.reader.ReadString('\n')
+	utils.AssertEqual(t, nil, err, "Retry")
+	utils.AssertEqual(t, "retry: 1000\n", line, "Retry")
+	return client
+}
+
+// next следующее событие banner, heartbeat пропускаются
+func (c *sseClient) next(t *testing.T) sseEvent {
+	var event sseEvent
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("ReadString failed: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+		case line == "" && event.id != "":
+			return event
+		}
+	}
+}
+
+// Test_StreamNotify клиент получает только изменения своих пар, снятие баннера с пары приходит как deleted
+func Test_StreamNotify(t *testing.T) {
+	repo := newChangesRepoStub()
+	url, _ := startServer(t, startHub(t, repo))
+	client := connect(t, url+"/user_banner/stream?pair=1:1", "")
+
+	repo.live <- newChange("1-0", []models.TagId{1, 2}, 1, "first")
+	event := client.next(t)
+	utils.AssertEqual(t, "1-0", event.id, "Created")
+	utils.AssertEqual(t, models.TagId(1), event.data.TagId, "Created")
+	utils.AssertEqual(t, false, event.data.Deleted, "Created")
+	utils.AssertEqual(t, "first", event.data.Content.Title, "Created")
+
+	repo.live <- newChange("2-0", []models.TagId{1}, 2, "other_pair")
+	moved := newChange("3-0", []models.TagId{2}, 1, "moved")
+	moved.PrevTagIds, moved.PrevFeatureId = []models.TagId{1, 2}, 1
+	repo.live <- moved
+
+	event = client.next(t)
+	utils.AssertEqual(t, "3-0", event.id, "Moved")
+	utils.AssertEqual(t, true, event.data.Deleted, "Moved")
+	utils.AssertEqual(t, (*banners_http.GetBannerResponse)(nil), event.data.Content, "Moved")
+}
+
+// Test_StreamReplay по Last-Event-ID досылается только то, что клиент еще не видел
+func Test_StreamReplay(t *testing.T) {
+	repo := newChangesRepoStub(
+		newChange("1-0", []models.TagId{1}, 1, "seen"),
+		newChange("2-0", []models.TagId{1}, 1, "missed"),
+	)
+	url, _ := startServer(t, startHub(t, repo))
+	client := connect(t, url+"/user_banner/stream?tag=1", "1-0")
+
+	event := client.next(t)
+	utils.AssertEqual(t, "2-0", event.id, "Replay")
+	utils.AssertEqual(t, "missed", event.data.Content.Title, "Replay")
+
+	repo.live <- newChange("2-0", []models.TagId{1}, 1, "duplicate")
+	repo.live <- newChange("3-0", []models.TagId{1}, 1, "live")
+	event = client.next(t)
+	utils.AssertEqual(t, "3-0", event.id, "Live")
+}
+
+// Test_StreamDisconnect отключившийся клиент отписывается от хаба на ближайшем heartbeat
+func Test_StreamDisconnect(t *testing.T) {
+	repo := newChangesRepoStub()
+	url, stream := startServer(t, startHub(t, repo))
+	client := connect(t, url+"/user_banner/stream?pair=1:1", "")
+
+	client.cancel()
+	select {
+	case <-stream.unsubscribed:
+	case <-time.After(waitTimeout):
+		t.Fatal("subscriber was not removed after disconnect")
+	}
+}
+
+// Test_StreamWrongLastEventId Last-Event-ID не в формате id redis stream отклоняется до подписки
+func Test_StreamWrongLastEventId(t *testing.T) {
+	url, _ := startServer(t, startHub(t, newChangesRepoStub()))
+
+	for _, lastEventId := range []string{"abc", "1-", "-1", "1-2-3", "1.5"} {
+		req, err := nethttp.NewRequest(nethttp.MethodGet, url+"/user_banner/stream?tag=1", nil)
+		if err != nil {
+			t.Fatalf("NewRequest failed: %v", err)
+		}
+		req.Header.Set("token", constant.UserToken)
+		req.Header.Set("Last-Event-ID", lastEventId)
+		resp, err := nethttp.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		_ = resp.Body.Close()
+		utils.AssertEqual(t, 400, resp.StatusCode, lastEventId)
+		utils.AssertEqual(t, "BannersHandlers.StreamBanners.WrongLastEventId", body["error_place"], lastEventId)
+	}
+
+	client := connect(t, url+"/user_banner/stream?tag=1", "5")
+	client.cancel()
+}
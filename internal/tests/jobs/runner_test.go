@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"avito/assignment/internal/jobs/jobs_repository"
+	"avito/assignment/internal/jobs/jobs_usecase"
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/tests/testutil"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2/utils"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testJobType = "test.job"
+
+type testPayload struct {
+	Value string `json:"value"`
+}
+
+type runnerRepoStub struct {
+	mu              sync.Mutex
+	job             *models.Job
+	cancelRequested bool
+	extendErr       error
+	progress        []string
+	finished        []jobs_repository.FinishPostgresJob
+	retried         []jobs_repository.RetryPostgresJob
+	released        []jobs_repository.JobLease
+}
+
+func (r *runnerRepoStub) ClaimJob(_ context.Context, _ []string, _ time.Duration, _ string) (*models.Job, error) {
+	job := r.job
+	r.job = nil
+	if job != nil {
+		job.Attempts++
+	}
+	return job, nil
+}
+
+func (r *runnerRepoStub) ExtendLease(_ context.Context, _ *jobs_repository.JobLease, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelRequested, r.extendErr
+}
+
+func (r *runnerRepoStub) UpdateProgress(_ context.Context, _ *jobs_repository.JobLease, progress []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress = append(r.progress, string(progress))
+	return nil
+}
+
+func (r *runnerRepoStub) FinishJob(_ context.Context, finishPostgresJobParams *jobs_repository.FinishPostgresJob) error {
+	r.finished = append(r.finished, *finishPostgresJobParams)
+	return nil
+}
+
+func (r *runnerRepoStub) RetryJob(_ context.Context, retryPostgresJobParams *jobs_repository.RetryPostgresJob) error {
+	r.retried = append(r.retried, *retryPostgresJobParams)
+	return nil
+}
+
+func (r *runnerRepoStub) ReleaseJob(_ context.Context, jobLease *jobs_repository.JobLease) error {
+	r.released = append(r.released, *jobLease)
+	return nil
+}
+
+func newJob(attempts int) *models.Job {
+	payload, _ := json.Marshal(testPayload{Value: "banner"})
+	return &models.Job{
+		JobId:       1,
+		Type:        testJobType,
+		Payload:     payload,
+		Status:      models.JobQueued,
+		Attempts:    attempts,
+		MaxAttempts: 3,
+	}
+}
+
+func newRunner(repo *runnerRepoStub, handler jobs_usecase.HandlerFunc[testPayload]) *jobs_usecase.Runner {
+	runner := jobs_usecase.NewRunner(testutil.NewConfig(), repo)
+	runner.Register(testJobType, handler, runner.DefaultPolicy())
+	return runner
+}
+
+func TestRunner_Succeeded(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(0)}
+	var got string
+	runner := newRunner(repo, func(ctx context.Context, payload testPayload, progress jobs_usecase.Progress) error {
+		got = payload.Value
+		return progress.Report(ctx, map[string]int{"done": 1})
+	})
+
+	processed, err := runner.RunOnce(context.Background())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, processed)
+	utils.AssertEqual(t, "banner", got)
+	utils.AssertEqual(t, []string{`{"done":1}`}, repo.progress)
+	utils.AssertEqual(t, 1, len(repo.finished))
+	utils.AssertEqual(t, models.JobSucceeded, repo.finished[0].Status)
+}
+
+func TestRunner_RetryWithBackoff(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(1)}
+	runner := newRunner(repo, func(context.Context, testPayload, jobs_usecase.Progress) error {
+		return errors.New("redis is down")
+	})
+
+	before := time.Now()
+	_, err := runner.RunOnce(context.Background())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(repo.finished))
+	utils.AssertEqual(t, 1, len(repo.retried))
+	utils.AssertEqual(t, "redis is down", repo.retried[0].LastError)
+	// вторая попытка провалилась, следующая через base * 2
+	utils.AssertEqual(t, true, repo.retried[0].RunAt.Sub(before) >= 10*time.Second)
+}
+
+func TestRunner_FailedAfterMaxAttempts(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(2)}
+	runner := newRunner(repo, func(context.Context, testPayload, jobs_usecase.Progress) error {
+		return errors.New("still broken")
+	})
+
+	_, err := runner.RunOnce(context.Background())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(repo.retried))
+	utils.AssertEqual(t, models.JobFailed, repo.finished[0].Status)
+	utils.AssertEqual(t, "still broken", *repo.finished[0].LastError)
+}
+
+func TestRunner_PermanentError(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(0)}
+	runner := newRunner(repo, func(context.Context, testPayload, jobs_usecase.Progress) error {
+		return jobs_usecase.Permanent(errors.New("feature is gone"))
+	})
+
+	_, err := runner.RunOnce(context.Background())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(repo.retried))
+	utils.AssertEqual(t, models.JobFailed, repo.finished[0].Status)
+}
+
+func TestRunner_CancelRequested(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(0), cancelRequested: true}
+	runner := newRunner(repo, func(ctx context.Context, _ testPayload, _ jobs_usecase.Progress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	_, err := runner.RunOnce(context.Background())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, models.JobCanceled, repo.finished[0].Status)
+}
+
+func TestRunner_ReleasedOnShutdown(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(0)}
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := newRunner(repo, func(ctx context.Context, _ testPayload, _ jobs_usecase.Progress) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	_, err := runner.RunOnce(ctx)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(repo.finished))
+	utils.AssertEqual(t, 1, len(repo.released))
+	utils.AssertEqual(t, models.JobId(1), repo.released[0].JobId)
+}
+
+func TestRunner_LeaseLost(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(0), extendErr: jobs_repository.ErrLeaseLost}
+	runner := newRunner(repo, func(ctx context.Context, _ testPayload, _ jobs_usecase.Progress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	_, err := runner.RunOnce(context.Background())
+	utils.AssertEqual(t, true, errors.Is(err, jobs_repository.ErrLeaseLost))
+	utils.AssertEqual(t, 0, len(repo.finished))
+	utils.AssertEqual(t, 0, len(repo.retried))
+	utils.AssertEqual(t, 0, len(repo.released))
+}
+
+func TestRunner_ReleasedOnHeartbeatFailure(t *testing.T) {
+	repo := &runnerRepoStub{job: newJob(0), extendErr: errors.New("connection reset")}
+	runner := newRunner(repo, func(ctx context.Context, _ testPayload, _ jobs_usecase.Progress) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	_, err := runner.RunOnce(context.Background())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(repo.finished))
+	utils.AssertEqual(t, 1, len(repo.released))
+}
@@ -0,0 +1,59 @@
+package jsonpatch
+
+import (
+	"avito/assignment/pkg/jsonpatch"
+	"errors"
+	"github.com/gofiber/fiber/v2/utils"
+	"strings"
+	"testing"
+)
+
+func Test_Apply(t *testing.T) {
+	document := `{"tag_ids":[1,5,7],"content":{"title":"old","text":"text"},"version":3}`
+
+	tests := []struct {
+		name   string
+		patch  string
+		result string
+	}{
+		{"RemoveTag", `[{"op":"remove","path":"/tag_ids/1"}]`, `{"tag_ids":[1,7],"content":{"title":"old","text":"text"},"version":3}`},
+		{"AppendTag", `[{"op":"add","path":"/tag_ids/-","value":9}]`, `{"tag_ids":[1,5,7,9],"content":{"title":"old","text":"text"},"version":3}`},
+		{"InsertTag", `[{"op":"add","path":"/tag_ids/0","value":9}]`, `{"tag_ids":[9,1,5,7],"content":{"title":"old","text":"text"},"version":3}`},
+		{"TestAndReplace", `[{"op":"test","path":"/version","value":3},{"op":"replace","path":"/content/title","value":"new"}]`,
+			`{"tag_ids":[1,5,7],"content":{"title":"new","text":"text"},"version":3}`},
+		{"Move", `[{"op":"move","from":"/content/title","path":"/content/text"}]`, `{"tag_ids":[1,5,7],"content":{"text":"old"},"version":3}`},
+		{"Copy", `[{"op":"copy","from":"/tag_ids/2","path":"/tag_ids/0"}]`, `{"tag_ids":[7,1,5,7],"content":{"title":"old","text":"text"},"version":3}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := jsonpatch.Apply([]byte(document), []byte(test.patch))
+			utils.AssertEqual(t, nil, err, "Apply")
+			utils.AssertEqual(t, test.result, string(result), "Result")
+		})
+	}
+}
+
+func Test_ApplyErrors(t *testing.T) {
+	document := []byte(`{"tag_ids":[1,5],"version":3}`)
+
+	_, err := jsonpatch.Apply(document, []byte(`[{"op":"test","path":"/version","value":2},{"op":"remove","path":"/tag_ids/0"}]`))
+	utils.AssertEqual(t, true, errors.Is(err, jsonpatch.ErrTestFailed), "TestFailed")
+
+	_, err = jsonpatch.Apply(document, []byte(`[{"op":"remove","path":"/tag_ids/2"}]`))
+	utils.AssertEqual(t, true, errors.Is(err, jsonpatch.ErrInvalidIndex), "OutOfRange")
+	utils.AssertEqual(t, true, strings.HasPrefix(err.Error(), "operation 0 (remove /tag_ids/2): "), "OutOfRange")
+
+	_, err = jsonpatch.Apply(document, []byte(`[{"op":"replace","path":"/content/title","value":"x"}]`))
+	utils.AssertEqual(t, true, errors.Is(err, jsonpatch.ErrPathNotFound), "PathNotFound")
+
+	_, err = jsonpatch.Apply(document, []byte(`[{"op":"add","path":"/tag_ids/-"}]`))
+	utils.AssertEqual(t, true, err != nil && strings.Contains(err.Error(), "missing value field"), "NoValue")
+}
+
+func Test_MergePatch(t *testing.T) {
+	document := []byte(`{"content":{"title":"old","text":"text"},"is_active":true,"tag_ids":[1,5]}`)
+
+	result, err := jsonpatch.MergePatch(document, []byte(`{"content":{"title":"new","text":null},"tag_ids":[2]}`))
+	utils.AssertEqual(t, nil, err, "MergePatch")
+	utils.AssertEqual(t, `{"content":{"title":"new"},"is_active":true,"tag_ids":[2]}`, string(result), "Result")
+}
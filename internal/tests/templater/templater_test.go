@@ -0,0 +1,108 @@
+package templater
+
+import (
+	"avito/assignment/pkg/templater"
+	"github.com/gofiber/fiber/v2/utils"
+	"testing"
+)
+
+func Test_Render(t *testing.T) {
+	attributes := map[string]string{
+		"user.name":  "Иван",
+		"user.city":  "<b>Москва</b>",
+		"promo.code": "A&B 10%",
+	}
+
+	tests := []struct {
+		name   string
+		src    string
+		escape templater.Escaper
+		result string
+	}{
+		{"PlainText", "Привет", nil, "Привет"},
+		{"Substitution", "Привет, {{user.name}}!", nil, "Привет, Иван!"},
+		{"Spaces", "Привет, {{ user.name }}!", nil, "Привет, Иван!"},
+		{"Several", "{{user.name}} из {{user.city}}", nil, "Иван из <b>Москва</b>"},
+		{"HTMLEscape", "Город: {{user.city}}", templater.HTMLEscaper, "Город: &lt;b&gt;Москва&lt;/b&gt;"},
+		{"URLEscape", "https://shop.ru/?code={{promo.code}}", templater.URLEscaper, "https://shop.ru/?code=A%26B+10%25"},
+		{"TextIsNotEscaped", "<i>{{user.name}}</i>", templater.HTMLEscaper, "<i>Иван</i>"},
+		{"MissingVariable", "Привет, {{user.surname}}!", nil, "Привет, !"},
+		{"MissingWithDefault", `Привет, {{user.surname|default:"друг"}}!`, nil, "Привет, друг!"},
+		{"DefaultWithQuote", `{{user.surname|default:"\"друг\""}}`, nil, `"друг"`},
+		{"PresentIgnoresDefault", `{{user.name|default:"друг"}}`, nil, "Иван"},
+		{"DefaultIsEscaped", `{{user.surname|default:"<друг>"}}`, templater.HTMLEscaper, "&lt;друг&gt;"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := templater.Render(test.src, attributes, test.escape)
+			utils.AssertEqual(t, nil, err, "Render")
+			utils.AssertEqual(t, test.result, result, "Result")
+		})
+	}
+}
+
+func Test_ParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{"Unclosed", "Привет, {{user.name", `unclosed placeholder at "{{user.name"`},
+		{"EmptyVariable", "{{}}", `invalid variable name ""`},
+		{"InvalidVariable", "{{user..name}}", `invalid variable name "user..name"`},
+		{"InvalidModifier", "{{user.name|upper}}", `invalid modifier "upper" for variable "user.name", expected default:"value"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := templater.Parse(test.src)
+			utils.AssertEqual(t, test.err, errString(err), "Parse")
+		})
+	}
+}
+
+func Test_RenderOrRaw(t *testing.T) {
+	attributes := map[string]string{"user.name": "Иван"}
+
+	tests := []struct {
+		name   string
+		src    string
+		result string
+	}{
+		{"Template", "Привет, {{user.name}}", "Привет, Иван"},
+		{"NoMarker", "Скидка 50%", "Скидка 50%"},
+		{"NotATemplate", "Формула {{x+y}} и {{", "Формула {{x+y}} и {{"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			utils.AssertEqual(t, test.result, templater.RenderOrRaw(test.src, attributes, templater.HTMLEscaper), "RenderOrRaw")
+		})
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	known := []string{"user.name", "user.city"}
+
+	tests := []struct {
+		name   string
+		src    string
+		strict bool
+		err    string
+	}{
+		{"Known", "{{user.name}} из {{user.city}}", true, ""},
+		{"UnknownStrict", "{{user.age}}", true, `unknown variable "user.age"`},
+		{"UnknownLenient", "{{user.age}}", false, ""},
+		{"SyntaxLenient", "{{user.name", false, `unclosed placeholder at "{{user.name"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			utils.AssertEqual(t, test.err, errString(templater.Validate(test.src, known, test.strict)), "Validate")
+		})
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,21 @@
+package testutil
+
+import "avito/assignment/config"
+
+// NewConfig конфиг для тестов раннера и диспетчера без базы: короткая аренда, быстрый опрос, небольшие backoff
+func NewConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Jobs.Workers = 1
+	cfg.Jobs.PollIntervalMs = 10
+	cfg.Jobs.LeaseSeconds = 1
+	cfg.Jobs.MaxAttempts = 3
+	cfg.Jobs.BaseBackoffSeconds = 5
+	cfg.Jobs.MaxBackoffSeconds = 60
+	cfg.Webhooks.BatchSize = 10
+	cfg.Webhooks.MaxAttempts = 3
+	cfg.Webhooks.BaseBackoffSeconds = 5
+	cfg.Webhooks.MaxBackoffSeconds = 60
+	cfg.Webhooks.TimeoutSeconds = 5
+	cfg.Webhooks.LeaseSeconds = 60
+	return cfg
+}
@@ -0,0 +1,153 @@
+package webhooks
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/tests/testutil"
+	"avito/assignment/internal/webhooks/webhooks_repository"
+	"avito/assignment/internal/webhooks/webhooks_usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+type deliveryRepoStub struct {
+	pending   []models.WebhookDelivery
+	delivered []models.DeliveryId
+	failed    []webhooks_repository.MarkFailed
+	markErr   map[models.DeliveryId]error
+}
+
+func (r *deliveryRepoStub) ClaimPendingDeliveries(_ context.Context, limit int, _ time.Duration) ([]models.WebhookDelivery, error) {
+	if len(r.pending) > limit {
+		return r.pending[:limit], nil
+	}
+	return r.pending, nil
+}
+
+func (r *deliveryRepoStub) MarkDelivered(_ context.Context, deliveryId models.DeliveryId, _ int) error {
+	if err := r.markErr[deliveryId]; err != nil {
+		return err
+	}
+	r.delivered = append(r.delivered, deliveryId)
+	return nil
+}
+
+func (r *deliveryRepoStub) MarkFailed(_ context.Context, markFailedParams *webhooks_repository.MarkFailed) error {
+	r.failed = append(r.failed, *markFailedParams)
+	return nil
+}
+
+func newDelivery(url string, attempts int) models.WebhookDelivery {
+	payload, _ := json.Marshal(webhooks_repository.WebhookPayload{
+		EventType: models.WebhookEventCreated,
+		BannerId:  1,
+	})
+	return models.WebhookDelivery{
+		DeliveryId:     1,
+		SubscriptionId: 1,
+		Url:            url,
+		Secret:         testSecret,
+		EventType:      models.WebhookEventCreated,
+		Payload:        payload,
+		Attempts:       attempts,
+	}
+}
+
+func Test_DispatchSigned(t *testing.T) {
+	var received bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = webhooks_usecase.VerifySignature(testSecret, r.Header.Get(webhooks_usecase.TimestampHeader),
+			r.Header.Get(webhooks_usecase.SignatureHeader), body)
+		utils.AssertEqual(t, models.WebhookEventCreated, r.Header.Get(webhooks_usecase.EventTypeHeader), "EventType")
+		utils.AssertEqual(t, "1", r.Header.Get(webhooks_usecase.DeliveryIdHeader), "DeliveryId")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := &deliveryRepoStub{pending: []models.WebhookDelivery{newDelivery(receiver.URL, 0)}}
+	dispatcher := webhooks_usecase.NewDispatcher(testutil.NewConfig(), repo)
+
+	processed, err := dispatcher.DispatchBatch(context.Background())
+	utils.AssertEqual(t, nil, err, "DispatchBatch")
+	utils.AssertEqual(t, 1, processed, "Processed")
+	utils.AssertEqual(t, true, received, "SignatureVerified")
+	utils.AssertEqual(t, []models.DeliveryId{1}, repo.delivered, "Delivered")
+	utils.AssertEqual(t, 0, len(repo.failed), "Failed")
+}
+
+func Test_DispatchRetry(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		name     string
+		attempts int
+		dead     bool
+	}{
+		{name: "FirstFailure", attempts: 0, dead: false},
+		{name: "LastAttempt", attempts: 2, dead: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := &deliveryRepoStub{pending: []models.WebhookDelivery{newDelivery(receiver.URL, test.attempts)}}
+			dispatcher := webhooks_usecase.NewDispatcher(testutil.NewConfig(), repo)
+
+			before := time.Now()
+			_, err := dispatcher.DispatchBatch(context.Background())
+			utils.AssertEqual(t, nil, err, "DispatchBatch")
+			utils.AssertEqual(t, 0, len(repo.delivered), "Delivered")
+			utils.AssertEqual(t, 1, len(repo.failed), "Failed")
+
+			failed := repo.failed[0]
+			utils.AssertEqual(t, test.attempts+1, failed.Attempts, "Attempts")
+			utils.AssertEqual(t, test.dead, failed.Dead, "Dead")
+			utils.AssertEqual(t, "subscriber responded with status 500", failed.LastError, "LastError")
+			utils.AssertEqual(t, true, !failed.NextAttemptAt.Before(before.Add(dispatcher.Backoff(failed.Attempts))), "NextAttemptAt")
+		})
+	}
+}
+
+// Test_DispatchMarkFailure не записанный результат одной доставки не откатывает и не переотправляет остальные
+func Test_DispatchMarkFailure(t *testing.T) {
+	var received int
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	first, second := newDelivery(receiver.URL, 0), newDelivery(receiver.URL, 0)
+	second.DeliveryId = 2
+	repo := &deliveryRepoStub{
+		pending: []models.WebhookDelivery{first, second},
+		markErr: map[models.DeliveryId]error{1: errors.New("connection reset")},
+	}
+	dispatcher := webhooks_usecase.NewDispatcher(testutil.NewConfig(), repo)
+
+	processed, err := dispatcher.DispatchBatch(context.Background())
+	utils.AssertEqual(t, true, err != nil, "DispatchBatch")
+	utils.AssertEqual(t, 1, processed, "Processed")
+	utils.AssertEqual(t, 2, received, "Received")
+	utils.AssertEqual(t, []models.DeliveryId{2}, repo.delivered, "Delivered")
+}
+
+func Test_Backoff(t *testing.T) {
+	dispatcher := webhooks_usecase.NewDispatcher(testutil.NewConfig(), &deliveryRepoStub{})
+
+	utils.AssertEqual(t, 5*time.Second, dispatcher.Backoff(1), "FirstAttempt")
+	utils.AssertEqual(t, 10*time.Second, dispatcher.Backoff(2), "SecondAttempt")
+	utils.AssertEqual(t, 40*time.Second, dispatcher.Backoff(4), "FourthAttempt")
+	utils.AssertEqual(t, 60*time.Second, dispatcher.Backoff(10), "Capped")
+}
@@ -0,0 +1,88 @@
+package webhooks_http
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/webhooks/webhooks_usecase"
+	"avito/assignment/pkg/utilities"
+	"encoding/json"
+	"time"
+)
+
+type AddSubscriptionRequest struct {
+	Url        string             `json:"url" validate:"required,url"`
+	Secret     string             `json:"secret" validate:"required,min=16"`
+	FeatureIds []models.FeatureId `json:"feature_ids"`
+	EventTypes []string           `json:"event_types" validate:"required,min=1"`
+}
+
+func (s *AddSubscriptionRequest) ToAddSubscription() *webhooks_usecase.AddSubscription {
+	return &webhooks_usecase.AddSubscription{
+		Url:        s.Url,
+		Secret:     s.Secret,
+		FeatureIds: utilities.RemoveDuplicates[models.FeatureId](s.FeatureIds),
+		EventTypes: utilities.RemoveDuplicates[string](s.EventTypes),
+	}
+}
+
+type GetDeadDeliveriesRequest struct {
+	Limit  int `query:"limit" validate:"gte=0"`
+	Offset int `query:"offset" validate:"gte=0"`
+}
+
+func (d *GetDeadDeliveriesRequest) ToGetDeadDeliveries() *webhooks_usecase.GetDeadDeliveries {
+	return &webhooks_usecase.GetDeadDeliveries{
+		Limit:  d.Limit,
+		Offset: d.Offset,
+	}
+}
+
+// SubscriptionResponse секрет наружу не отдаем
+type SubscriptionResponse struct {
+	SubscriptionId models.SubscriptionId `json:"subscription_id"`
+	Url            string                `json:"url"`
+	FeatureIds     []int64               `json:"feature_ids"`
+	EventTypes     []string              `json:"event_types"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+func ToSubscriptionsResponse(subscriptions []models.WebhookSubscription) []SubscriptionResponse {
+	response := make([]SubscriptionResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		response[i] = SubscriptionResponse{
+			SubscriptionId: subscription.SubscriptionId,
+			Url:            subscription.Url,
+			FeatureIds:     subscription.FeatureIds,
+			EventTypes:     subscription.EventTypes,
+			CreatedAt:      subscription.CreatedAt,
+		}
+	}
+	return response
+}
+
+type DeliveryResponse struct {
+	DeliveryId     models.DeliveryId     `json:"delivery_id"`
+	SubscriptionId models.SubscriptionId `json:"subscription_id"`
+	Url            string                `json:"url"`
+	EventType      string                `json:"event_type"`
+	Payload        json.RawMessage       `json:"payload"`
+	Attempts       int                   `json:"attempts"`
+	LastError      *string               `json:"last_error"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+func ToDeliveriesResponse(deliveries []models.WebhookDelivery) []DeliveryResponse {
+	response := make([]DeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		response[i] = DeliveryResponse{
+			DeliveryId:     delivery.DeliveryId,
+			SubscriptionId: delivery.SubscriptionId,
+			Url:            delivery.Url,
+			EventType:      delivery.EventType,
+			Payload:        delivery.Payload,
+			Attempts:       delivery.Attempts,
+			LastError:      delivery.LastError,
+			CreatedAt:      delivery.CreatedAt,
+		}
+	}
+	return response
+}
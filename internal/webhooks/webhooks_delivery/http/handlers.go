@@ -0,0 +1,120 @@
+package webhooks_http
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	reqvalidator "avito/assignment/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+type WebhooksHandlers struct {
+	webhooksUC WebhooksUseCase
+	cfg        *config.Config
+}
+
+func NewWebhooksHandlers(webhooksUC WebhooksUseCase, cfg *config.Config) *WebhooksHandlers {
+	return &WebhooksHandlers{
+		webhooksUC: webhooksUC,
+		cfg:        cfg,
+	}
+}
+
+func (w *WebhooksHandlers) AddSubscription() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "WebhooksHandlers.AddSubscription")
+		defer span.End()
+
+		addSubscription := AddSubscriptionRequest{}
+		if err := reqvalidator.ReadRequest(c, &addSubscription); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "WebhooksHandlers.AddSubscription.ReadRequest")
+		}
+
+		subscriptionId, err := w.webhooksUC.AddSubscription(ctx, addSubscription.ToAddSubscription())
+		if err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusCreated)
+		return c.JSON(fiber.Map{
+			"subscription_id": subscriptionId,
+		})
+	}
+}
+
+func (w *WebhooksHandlers) GetSubscriptions() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "WebhooksHandlers.GetSubscriptions")
+		defer span.End()
+
+		subscriptions, err := w.webhooksUC.GetSubscriptions(ctx)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToSubscriptionsResponse(subscriptions))
+	}
+}
+
+func (w *WebhooksHandlers) DeleteSubscription() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "WebhooksHandlers.DeleteSubscription")
+		defer span.End()
+
+		subscriptionId, err := strconv.Atoi(c.Params("subscription_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "WebhooksHandlers.DeleteSubscription.WrongSubscriptionParams")
+		}
+
+		if err = w.webhooksUC.DeleteSubscription(ctx, models.SubscriptionId(subscriptionId)); err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusNoContent)
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
+
+func (w *WebhooksHandlers) GetDeadDeliveries() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "WebhooksHandlers.GetDeadDeliveries")
+		defer span.End()
+
+		getDeadDeliveries := GetDeadDeliveriesRequest{}
+		if err := reqvalidator.ReadQuery(c, &getDeadDeliveries); err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, err, "WebhooksHandlers.GetDeadDeliveries.ReadQuery")
+		}
+
+		deliveries, err := w.webhooksUC.GetDeadDeliveries(ctx, getDeadDeliveries.ToGetDeadDeliveries())
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(ToDeliveriesResponse(deliveries))
+	}
+}
+
+func (w *WebhooksHandlers) Redeliver() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, span := traces.StartFiberTrace(c, "WebhooksHandlers.Redeliver")
+		defer span.End()
+
+		deliveryId, err := strconv.Atoi(c.Params("delivery_id"))
+		if err != nil {
+			return traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest, nil, "WebhooksHandlers.Redeliver.WrongDeliveryParams")
+		}
+
+		if err = w.webhooksUC.Redeliver(ctx, models.DeliveryId(deliveryId)); err != nil {
+			return err
+		}
+
+		c.Status(fiber.StatusAccepted)
+		return c.JSON(fiber.Map{
+			"message": "Success",
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package webhooks_http
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/webhooks/webhooks_usecase"
+	"context"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handlers interface {
+	AddSubscription() fiber.Handler
+	GetSubscriptions() fiber.Handler
+	DeleteSubscription() fiber.Handler
+	GetDeadDeliveries() fiber.Handler
+	Redeliver() fiber.Handler
+}
+
+type WebhooksUseCase interface {
+	AddSubscription(ctx context.Context, addSubscriptionParams *webhooks_usecase.AddSubscription) (models.SubscriptionId, error)
+	GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionId models.SubscriptionId) error
+	GetDeadDeliveries(ctx context.Context, getDeadDeliveriesParams *webhooks_usecase.GetDeadDeliveries) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryId models.DeliveryId) error
+}
@@ -0,0 +1,15 @@
+package webhooks_http
+
+import (
+	"avito/assignment/internal/middleware"
+	"avito/assignment/pkg/constant"
+	"github.com/gofiber/fiber/v2"
+)
+
+func MapWebhooksRoutes(group fiber.Router, h Handlers, mw *middleware.MDWManager) {
+	group.Post("/webhooks", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.AddSubscription())
+	group.Get("/webhooks", mw.CheckAuthToken(constant.AdminRoles), h.GetSubscriptions())
+	group.Get("/webhooks/dead_letters", mw.CheckAuthToken(constant.AdminRoles), h.GetDeadDeliveries())
+	group.Delete("/webhooks/:subscription_id", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.DeleteSubscription())
+	group.Post("/webhooks/deliveries/:delivery_id/redeliver", mw.CheckAuthToken(constant.AdminRoles), mw.Idempotency(), h.Redeliver())
+}
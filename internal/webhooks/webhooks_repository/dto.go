@@ -0,0 +1,38 @@
+package webhooks_repository
+
+import (
+	"avito/assignment/internal/models"
+	"time"
+)
+
+type AddPostgresSubscription struct {
+	Url        string
+	Secret     string
+	FeatureIds []models.FeatureId
+	EventTypes []string
+}
+
+type MarkFailed struct {
+	DeliveryId    models.DeliveryId
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	Dead          bool
+}
+
+// WebhookPayload тело запроса, которое получает подписчик
+type WebhookPayload struct {
+	EventType  string                `json:"event_type"`
+	BannerId   models.BannerId       `json:"banner_id"`
+	Banner     *models.BannerPayload `json:"banner"`
+	OccurredAt time.Time             `json:"occurred_at"`
+}
+
+func ToWebhookPayload(event *models.WebhookEvent) *WebhookPayload {
+	return &WebhookPayload{
+		EventType:  event.EventType,
+		BannerId:   event.BannerId,
+		Banner:     event.Banner,
+		OccurredAt: event.OccurredAt,
+	}
+}
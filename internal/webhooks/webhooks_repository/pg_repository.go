@@ -0,0 +1,326 @@
+package webhooks_repository
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/store/sql_queries"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"context"
+	"encoding/json"
+	"fmt"
+	sq "github.com/Masterminds/squirrel"
+	trmsqlx "github.com/avito-tech/go-transaction-manager/sqlx"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"time"
+)
+
+type WebhooksRepo struct {
+	cfg      *config.Config
+	db       *sqlx.DB
+	txGetter *trmsqlx.CtxGetter
+}
+
+func NewWebhooksRepository(cfg *config.Config, db *sqlx.DB, txGetter *trmsqlx.CtxGetter) *WebhooksRepo {
+	return &WebhooksRepo{
+		cfg:      cfg,
+		db:       db,
+		txGetter: txGetter,
+	}
+}
+
+func (w *WebhooksRepo) AddSubscription(ctx context.Context, addPostgresSubscriptionParams *AddPostgresSubscription) (models.SubscriptionId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.AddSubscription")
+	defer span.End()
+
+	featureIds := make([]int64, len(addPostgresSubscriptionParams.FeatureIds))
+	for i, featureId := range addPostgresSubscriptionParams.FeatureIds {
+		featureIds[i] = int64(featureId)
+	}
+
+	query, args, err := sq.Insert(sql_queries.WebhookSubscriptionsName).
+		Columns(sql_queries.InsertSubscriptionColumns...).
+		Values(
+			addPostgresSubscriptionParams.Url,
+			addPostgresSubscriptionParams.Secret,
+			pq.Array(featureIds),
+			pq.Array(addPostgresSubscriptionParams.EventTypes),
+			time.Now(),
+		).
+		Suffix("RETURNING subscription_id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.AddSubscription.Insert")
+	}
+
+	var subscriptionId models.SubscriptionId
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	if err = tr.QueryRowxContext(ctx, query, args...).Scan(&subscriptionId); err != nil {
+		return 0, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.AddSubscription.QueryRowxContext")
+	}
+
+	return subscriptionId, nil
+}
+
+func (w *WebhooksRepo) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.GetSubscriptions")
+	defer span.End()
+
+	query, args, err := sq.Select(sql_queries.SelectSubscriptionColumns...).
+		From(sql_queries.WebhookSubscriptionsName).
+		OrderBy(sql_queries.SubscriptionIdColumnName).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.GetSubscriptions.Select")
+	}
+
+	var subscriptions []models.WebhookSubscription
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	if err = tr.SelectContext(ctx, &subscriptions, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.GetSubscriptions.SelectContext")
+	}
+
+	return subscriptions, nil
+}
+
+func (w *WebhooksRepo) DeleteSubscription(ctx context.Context, subscriptionId models.SubscriptionId) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.DeleteSubscription")
+	defer span.End()
+
+	query, args, err := sq.Delete(sql_queries.WebhookSubscriptionsName).
+		Where(sq.Eq{sql_queries.SubscriptionIdColumnName: subscriptionId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.DeleteSubscription.Delete")
+	}
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	result, err := tr.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.DeleteSubscription.ExecContext")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.DeleteSubscription.RowsAffected")
+	}
+
+	return affected != 0, nil
+}
+
+// Enqueue кладет событие в outbox для каждой подходящей подписки одним запросом
+// вызывается в транзакции изменения баннера, поэтому событие не потеряется и не появится без самого изменения
+func (w *WebhooksRepo) Enqueue(ctx context.Context, event *models.WebhookEvent) error {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.Enqueue")
+	defer span.End()
+
+	payload, err := json.Marshal(ToWebhookPayload(event))
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.Enqueue.Marshal")
+	}
+
+	featureIds := make([]int64, len(event.FeatureIds))
+	for i, featureId := range event.FeatureIds {
+		featureIds[i] = int64(featureId)
+	}
+
+	subscriptions := sq.Select(sql_queries.SubscriptionIdColumnName).
+		Column("?::text", event.EventType).
+		Column("?::jsonb", string(payload)).
+		Column("?::text", models.WebhookDeliveryPending).
+		Column("?::timestamp", event.OccurredAt).
+		Column("?::timestamp", event.OccurredAt).
+		From(sql_queries.WebhookSubscriptionsName).
+		Where(sq.And{
+			sq.Expr(fmt.Sprintf("? = ANY(%s)", sql_queries.EventTypesColumnName), event.EventType),
+			sq.Or{
+				sq.Expr(fmt.Sprintf("cardinality(%s) = 0", sql_queries.FeatureIdsColumnName)),
+				sq.Expr(fmt.Sprintf("%s && ?", sql_queries.FeatureIdsColumnName), pq.Array(featureIds)),
+			},
+		})
+
+	query, args, err := sq.Insert(sql_queries.WebhookOutboxTableName).
+		Columns(
+			sql_queries.SubscriptionIdColumnName,
+			sql_queries.EventTypeColumnName,
+			sql_queries.PayloadColumnName,
+			sql_queries.StatusColumnName,
+			sql_queries.NextAttemptAtColumnName,
+			sql_queries.CreatedAtColumnName,
+		).
+		Select(subscriptions).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.Enqueue.Insert")
+	}
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.Enqueue.ExecContext")
+	}
+
+	return nil
+}
+
+// ClaimPendingDeliveries арендует готовые к отправке доставки на lease и сразу коммитит аренду.
+// Пока аренда не истекла, другие реплики эти доставки не берут, SKIP LOCKED разводит реплики, пришедшие одновременно
+func (w *WebhooksRepo) ClaimPendingDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.ClaimPendingDeliveries")
+	defer span.End()
+
+	now := time.Now()
+	candidates := sq.Select(sql_queries.DeliveryIdColumnName).
+		From(sql_queries.WebhookOutboxTableName).
+		Where(sq.And{
+			sq.Eq{sql_queries.StatusColumnName: models.WebhookDeliveryPending},
+			sq.LtOrEq{sql_queries.NextAttemptAtColumnName: now},
+			sq.Or{
+				sq.Eq{sql_queries.LockedUntilColumnName: nil},
+				sq.Lt{sql_queries.LockedUntilColumnName: now},
+			},
+		}).
+		OrderBy(sql_queries.NextAttemptAtColumnName).
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	claim := sq.Update(sql_queries.WebhookOutboxTableName).
+		Set(sql_queries.LockedUntilColumnName, now.Add(lease)).
+		Where(sq.Expr(sql_queries.DeliveryIdColumnName+" IN (?)", candidates)).
+		Suffix("RETURNING *")
+
+	query, args, err := sq.Select(sql_queries.SelectDeliveryColumns...).
+		PrefixExpr(sq.Expr("WITH claimed AS (?)", claim)).
+		From("claimed o").
+		InnerJoin(fmt.Sprintf("%s s ON s.subscription_id = o.subscription_id", sql_queries.WebhookSubscriptionsName)).
+		OrderBy("o." + sql_queries.NextAttemptAtColumnName).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.ClaimPendingDeliveries.Select")
+	}
+
+	var deliveries []models.WebhookDelivery
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	if err = tr.SelectContext(ctx, &deliveries, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.ClaimPendingDeliveries.SelectContext")
+	}
+
+	return deliveries, nil
+}
+
+func (w *WebhooksRepo) MarkDelivered(ctx context.Context, deliveryId models.DeliveryId, attempts int) error {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.MarkDelivered")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.WebhookOutboxTableName).
+		Set(sql_queries.StatusColumnName, models.WebhookDeliveryDelivered).
+		Set(sql_queries.AttemptsColumnName, attempts).
+		Set(sql_queries.DeliveredAtColumnName, time.Now()).
+		Set(sql_queries.LastErrorColumnName, nil).
+		Set(sql_queries.LockedUntilColumnName, nil).
+		Where(sq.Eq{sql_queries.DeliveryIdColumnName: deliveryId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.MarkDelivered.Update")
+	}
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.MarkDelivered.ExecContext")
+	}
+
+	return nil
+}
+
+func (w *WebhooksRepo) MarkFailed(ctx context.Context, markFailedParams *MarkFailed) error {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.MarkFailed")
+	defer span.End()
+
+	status := models.WebhookDeliveryPending
+	if markFailedParams.Dead {
+		status = models.WebhookDeliveryDead
+	}
+
+	query, args, err := sq.Update(sql_queries.WebhookOutboxTableName).
+		Set(sql_queries.StatusColumnName, status).
+		Set(sql_queries.AttemptsColumnName, markFailedParams.Attempts).
+		Set(sql_queries.NextAttemptAtColumnName, markFailedParams.NextAttemptAt).
+		Set(sql_queries.LastErrorColumnName, markFailedParams.LastError).
+		Set(sql_queries.LockedUntilColumnName, nil).
+		Where(sq.Eq{sql_queries.DeliveryIdColumnName: markFailedParams.DeliveryId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.MarkFailed.Update")
+	}
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	if _, err = tr.ExecContext(ctx, query, args...); err != nil {
+		return traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.MarkFailed.ExecContext")
+	}
+
+	return nil
+}
+
+func (w *WebhooksRepo) GetDeadDeliveries(ctx context.Context, limit, offset int) ([]models.WebhookDelivery, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.GetDeadDeliveries")
+	defer span.End()
+
+	queryBuilder := sq.Select(sql_queries.SelectDeliveryColumns...).
+		From(fmt.Sprintf("%s o", sql_queries.WebhookOutboxTableName)).
+		InnerJoin(fmt.Sprintf("%s s ON s.subscription_id = o.subscription_id", sql_queries.WebhookSubscriptionsName)).
+		Where(sq.Eq{"o." + sql_queries.StatusColumnName: models.WebhookDeliveryDead}).
+		OrderBy("o." + sql_queries.DeliveryIdColumnName).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar)
+	if limit != 0 {
+		queryBuilder = queryBuilder.Limit(uint64(limit))
+	}
+
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.GetDeadDeliveries.Select")
+	}
+
+	var deliveries []models.WebhookDelivery
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	if err = tr.SelectContext(ctx, &deliveries, query, args...); err != nil {
+		return nil, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.GetDeadDeliveries.SelectContext")
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver возвращает доставку в очередь с нуля попыток, false - доставки не существует
+func (w *WebhooksRepo) Redeliver(ctx context.Context, deliveryId models.DeliveryId) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksRepo.Redeliver")
+	defer span.End()
+
+	query, args, err := sq.Update(sql_queries.WebhookOutboxTableName).
+		Set(sql_queries.StatusColumnName, models.WebhookDeliveryPending).
+		Set(sql_queries.AttemptsColumnName, 0).
+		Set(sql_queries.NextAttemptAtColumnName, time.Now()).
+		Set(sql_queries.DeliveredAtColumnName, nil).
+		Set(sql_queries.LockedUntilColumnName, nil).
+		Where(sq.Eq{sql_queries.DeliveryIdColumnName: deliveryId}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.Redeliver.Update")
+	}
+
+	tr := w.txGetter.DefaultTrOrDB(ctx, w.db)
+	result, err := tr.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.Redeliver.ExecContext")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, traces.SpanSetErrWrap(span, errlst.HttpServerError, err, "WebhooksRepo.Redeliver.RowsAffected")
+	}
+
+	return affected != 0, nil
+}
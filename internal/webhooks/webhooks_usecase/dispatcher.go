@@ -0,0 +1,153 @@
+package webhooks_usecase
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/webhooks/webhooks_repository"
+	"avito/assignment/pkg/utilities"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader  = "X-Banner-Signature"
+	TimestampHeader  = "X-Banner-Timestamp"
+	EventTypeHeader  = "X-Banner-Event"
+	DeliveryIdHeader = "X-Banner-Delivery"
+)
+
+// Dispatcher разбирает outbox и доставляет события подписчикам с экспоненциальным backoff
+type Dispatcher struct {
+	cfg          *config.Config
+	deliveryRepo DeliveryRepository
+	client       *http.Client
+}
+
+func NewDispatcher(cfg *config.Config, deliveryRepo DeliveryRepository) *Dispatcher {
+	return &Dispatcher{
+		cfg:          cfg,
+		deliveryRepo: deliveryRepo,
+		client:       &http.Client{Timeout: time.Duration(cfg.Webhooks.TimeoutSeconds) * time.Second},
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(d.cfg.Webhooks.PollIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.DispatchBatch(ctx); err != nil {
+				log.Errorf("Dispatcher.DispatchBatch: %s", err.Error())
+			}
+		}
+	}
+}
+
+// DispatchBatch
+// 1. Арендуем пачку готовых доставок, аренда коммитится сразу, транзакция не держится на время отправки
+// 2. Отправляем каждую вне транзакции, результат пишем отдельным коротким запросом
+// 3. Неуспешные откладываем по backoff, после MaxAttempts отправляем в dead letter
+// 4. Если результат записать не удалось, доставка вернется в работу после аренды, остальные пачки это не трогает
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "Dispatcher.DispatchBatch")
+	defer span.End()
+
+	lease := time.Duration(d.cfg.Webhooks.LeaseSeconds) * time.Second
+	deliveries, err := d.deliveryRepo.ClaimPendingDeliveries(ctx, d.cfg.Webhooks.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var processed int
+	var markErrs []error
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		attempts := delivery.Attempts + 1
+
+		sendErr := d.Send(ctx, delivery)
+		if sendErr == nil {
+			err = d.deliveryRepo.MarkDelivered(ctx, delivery.DeliveryId, attempts)
+		} else {
+			err = d.deliveryRepo.MarkFailed(ctx, &webhooks_repository.MarkFailed{
+				DeliveryId:    delivery.DeliveryId,
+				Attempts:      attempts,
+				NextAttemptAt: time.Now().Add(d.Backoff(attempts)),
+				LastError:     sendErr.Error(),
+				Dead:          attempts >= d.cfg.Webhooks.MaxAttempts,
+			})
+		}
+		if err != nil {
+			markErrs = append(markErrs, err)
+			continue
+		}
+		processed++
+	}
+
+	return processed, errors.Join(markErrs...)
+}
+
+// Send подписывает тело и отправляет его подписчику, успехом считается любой 2xx
+func (d *Dispatcher) Send(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, span := otel.Tracer("").Start(ctx, "Dispatcher.Send")
+	defer span.End()
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(DeliveryIdHeader, strconv.FormatInt(int64(delivery.DeliveryId), 10))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Backoff base * 2^(attempts-1), но не больше MaxBackoffSeconds
+func (d *Dispatcher) Backoff(attempts int) time.Duration {
+	return utilities.ExponentialBackoff(time.Duration(d.cfg.Webhooks.BaseBackoffSeconds)*time.Second,
+		time.Duration(d.cfg.Webhooks.MaxBackoffSeconds)*time.Second, attempts)
+}
+
+// Sign подпись sha256=hex(hmac(secret, timestamp + "." + body)), таймстемп в подписи защищает от повторов
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature проверка подписи на стороне получателя
+func VerifySignature(secret, timestamp, signature string, body []byte) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
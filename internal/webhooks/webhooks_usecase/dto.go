@@ -0,0 +1,27 @@
+package webhooks_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/webhooks/webhooks_repository"
+)
+
+type AddSubscription struct {
+	Url        string
+	Secret     string
+	FeatureIds []models.FeatureId
+	EventTypes []string
+}
+
+func (s *AddSubscription) ToAddPostgresSubscription() *webhooks_repository.AddPostgresSubscription {
+	return &webhooks_repository.AddPostgresSubscription{
+		Url:        s.Url,
+		Secret:     s.Secret,
+		FeatureIds: s.FeatureIds,
+		EventTypes: s.EventTypes,
+	}
+}
+
+type GetDeadDeliveries struct {
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,23 @@
+package webhooks_usecase
+
+import (
+	"avito/assignment/internal/models"
+	"avito/assignment/internal/webhooks/webhooks_repository"
+	"context"
+	"time"
+)
+
+type PostgresRepository interface {
+	AddSubscription(ctx context.Context, addPostgresSubscriptionParams *webhooks_repository.AddPostgresSubscription) (models.SubscriptionId, error)
+	GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, subscriptionId models.SubscriptionId) (bool, error)
+
+	GetDeadDeliveries(ctx context.Context, limit, offset int) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryId models.DeliveryId) (bool, error)
+}
+
+type DeliveryRepository interface {
+	ClaimPendingDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, deliveryId models.DeliveryId, attempts int) error
+	MarkFailed(ctx context.Context, markFailedParams *webhooks_repository.MarkFailed) error
+}
@@ -0,0 +1,88 @@
+package webhooks_usecase
+
+import (
+	"avito/assignment/config"
+	"avito/assignment/internal/models"
+	"avito/assignment/pkg/errlst"
+	"avito/assignment/pkg/traces"
+	"avito/assignment/pkg/utilities"
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+)
+
+type WebhooksUC struct {
+	cfg          *config.Config
+	webhooksRepo PostgresRepository
+}
+
+func NewWebhooksUC(cfg *config.Config, webhooksRepo PostgresRepository) *WebhooksUC {
+	return &WebhooksUC{
+		cfg:          cfg,
+		webhooksRepo: webhooksRepo,
+	}
+}
+
+// AddSubscription
+// 1. Проверяем, что все типы событий известны
+// 2. Сохраняем подписку, пустой список фич = подписка на все фичи
+func (w *WebhooksUC) AddSubscription(ctx context.Context, addSubscriptionParams *AddSubscription) (models.SubscriptionId, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksUC.AddSubscription")
+	defer span.End()
+
+	for _, eventType := range addSubscriptionParams.EventTypes {
+		if !utilities.InStringSlice(eventType, models.WebhookEventTypes) {
+			return 0, traces.SpanSetErrWrap(span, errlst.HttpErrInvalidRequest,
+				fmt.Errorf("unknown event type %s, possible types %v", eventType, models.WebhookEventTypes), "WebhooksUC.AddSubscription.UnknownEventType")
+		}
+	}
+
+	return w.webhooksRepo.AddSubscription(ctx, addSubscriptionParams.ToAddPostgresSubscription())
+}
+
+func (w *WebhooksUC) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksUC.GetSubscriptions")
+	defer span.End()
+
+	return w.webhooksRepo.GetSubscriptions(ctx)
+}
+
+func (w *WebhooksUC) DeleteSubscription(ctx context.Context, subscriptionId models.SubscriptionId) error {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksUC.DeleteSubscription")
+	defer span.End()
+
+	deleted, err := w.webhooksRepo.DeleteSubscription(ctx, subscriptionId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("impossible to delete, subscription with id %d doesnt exist", subscriptionId), "WebhooksUC.DeleteSubscription.DoNotExist")
+	}
+
+	return nil
+}
+
+func (w *WebhooksUC) GetDeadDeliveries(ctx context.Context, getDeadDeliveriesParams *GetDeadDeliveries) ([]models.WebhookDelivery, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksUC.GetDeadDeliveries")
+	defer span.End()
+
+	return w.webhooksRepo.GetDeadDeliveries(ctx, getDeadDeliveriesParams.Limit, getDeadDeliveriesParams.Offset)
+}
+
+// Redeliver возвращает доставку в очередь, диспетчер подхватит ее на следующем тике
+func (w *WebhooksUC) Redeliver(ctx context.Context, deliveryId models.DeliveryId) error {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhooksUC.Redeliver")
+	defer span.End()
+
+	found, err := w.webhooksRepo.Redeliver(ctx, deliveryId)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return traces.SpanSetErrWrap(span, errlst.HttpErrNotFound,
+			fmt.Errorf("impossible to redeliver, delivery with id %d doesnt exist", deliveryId), "WebhooksUC.Redeliver.DoNotExist")
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE banner_schema.banner_changes(
+    change_id  BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    banner_id  BIGINT,
+    event_type TEXT,
+    payload    JSONB,
+    created_at TIMESTAMP
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS banner_schema.banner_changes;
+
+-- +goose StatementEnd
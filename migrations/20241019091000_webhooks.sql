@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE banner_schema.webhook_subscriptions(
+    subscription_id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    url             TEXT NOT NULL,
+    secret          TEXT NOT NULL,
+    feature_ids     BIGINT ARRAY NOT NULL DEFAULT '{}',
+    event_types     TEXT ARRAY NOT NULL,
+    created_at      TIMESTAMP
+);
+
+CREATE TABLE banner_schema.webhook_outbox(
+    delivery_id     BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    subscription_id BIGINT,
+    event_type      TEXT,
+    payload         JSONB,
+    status          TEXT,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    last_error      TEXT,
+    created_at      TIMESTAMP,
+    delivered_at    TIMESTAMP,
+    FOREIGN KEY (subscription_id) REFERENCES banner_schema.webhook_subscriptions(subscription_id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_outbox_pending ON banner_schema.webhook_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_outbox_dead ON banner_schema.webhook_outbox(delivery_id) WHERE status = 'dead';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS banner_schema.webhook_outbox;
+DROP TABLE IF EXISTS banner_schema.webhook_subscriptions;
+
+-- +goose StatementEnd
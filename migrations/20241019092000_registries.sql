@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE banner_schema.tags(
+    tag_id      BIGINT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP,
+    updated_at  TIMESTAMP
+);
+
+CREATE TABLE banner_schema.features(
+    feature_id  BIGINT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP,
+    updated_at  TIMESTAMP
+);
+
+-- реестры заполняются всем, на что уже ссылаются баннеры и их версии, имена потом поправят админы
+INSERT INTO banner_schema.tags(tag_id, name, created_at, updated_at)
+SELECT tag_id, 'tag_' || tag_id, now(), now()
+FROM (
+    SELECT tag_id FROM banner_schema.banners_X_tags
+    UNION
+    SELECT unnest(tag_ids) FROM banner_schema.banners_versions
+) t
+WHERE tag_id IS NOT NULL;
+
+INSERT INTO banner_schema.features(feature_id, name, created_at, updated_at)
+SELECT feature_id, 'feature_' || feature_id, now(), now()
+FROM (
+    SELECT feature_id FROM banner_schema.banners
+    UNION
+    SELECT feature_id FROM banner_schema.banners_versions
+) f
+WHERE feature_id IS NOT NULL;
+
+ALTER TABLE banner_schema.banners_X_tags
+    ADD CONSTRAINT fk_banners_X_tags_tag_id FOREIGN KEY (tag_id) REFERENCES banner_schema.tags(tag_id);
+ALTER TABLE banner_schema.banners
+    ADD CONSTRAINT fk_banners_feature_id FOREIGN KEY (feature_id) REFERENCES banner_schema.features(feature_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE banner_schema.banners DROP CONSTRAINT IF EXISTS fk_banners_feature_id;
+ALTER TABLE banner_schema.banners_X_tags DROP CONSTRAINT IF EXISTS fk_banners_X_tags_tag_id;
+DROP TABLE IF EXISTS banner_schema.features;
+DROP TABLE IF EXISTS banner_schema.tags;
+
+-- +goose StatementEnd
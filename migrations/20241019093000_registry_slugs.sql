@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE banner_schema.tags ADD COLUMN slug TEXT;
+UPDATE banner_schema.tags SET slug = 'tag_' || tag_id;
+ALTER TABLE banner_schema.tags ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE banner_schema.tags ADD CONSTRAINT uq_tags_slug UNIQUE (slug);
+
+ALTER TABLE banner_schema.features ADD COLUMN slug TEXT;
+UPDATE banner_schema.features SET slug = 'feature_' || feature_id;
+ALTER TABLE banner_schema.features ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE banner_schema.features ADD CONSTRAINT uq_features_slug UNIQUE (slug);
+
+-- старые слаги после переименования, чтобы клиенты на прошлых версиях продолжали работать
+CREATE TABLE banner_schema.slug_aliases(
+    kind       TEXT NOT NULL,
+    slug       TEXT NOT NULL,
+    id         BIGINT NOT NULL,
+    created_at TIMESTAMP,
+    PRIMARY KEY (kind, slug)
+);
+
+CREATE INDEX idx_slug_aliases_kind_id ON banner_schema.slug_aliases(kind, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS banner_schema.slug_aliases;
+ALTER TABLE banner_schema.features DROP COLUMN IF EXISTS slug;
+ALTER TABLE banner_schema.tags DROP COLUMN IF EXISTS slug;
+
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE banner_schema.bulk_delete_jobs(
+    job_id      BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    feature_id  BIGINT,
+    tag_id      BIGINT,
+    status      TEXT NOT NULL,
+    total       BIGINT,
+    deleted     BIGINT NOT NULL DEFAULT 0,
+    errors      TEXT ARRAY NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMP,
+    updated_at  TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_bulk_delete_jobs_active ON banner_schema.bulk_delete_jobs(job_id) WHERE status IN ('pending', 'running');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS banner_schema.bulk_delete_jobs;
+
+-- +goose StatementEnd
@@ -0,0 +1,68 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE banner_schema.jobs(
+    job_id           BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    type             TEXT NOT NULL,
+    payload          JSONB NOT NULL DEFAULT '{}',
+    status           TEXT NOT NULL,
+    progress         JSONB,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    max_attempts     INTEGER NOT NULL,
+    run_at           TIMESTAMP NOT NULL,
+    locked_until     TIMESTAMP,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    last_error       TEXT,
+    created_at       TIMESTAMP,
+    updated_at       TIMESTAMP,
+    finished_at      TIMESTAMP
+);
+
+CREATE INDEX idx_jobs_queued ON banner_schema.jobs(run_at) WHERE status = 'queued';
+CREATE INDEX idx_jobs_running ON banner_schema.jobs(locked_until) WHERE status = 'running';
+CREATE INDEX idx_jobs_type_status ON banner_schema.jobs(type, status);
+
+-- массовые удаления переезжают в общую очередь под своими id, клиенты продолжают опрашивать их по старым ссылкам.
+-- Незавершенные выполнит уже новый раннер, прогресс и ошибки завершенных сохраняются
+INSERT INTO banner_schema.jobs(job_id, type, payload, status, progress, attempts, max_attempts, run_at, last_error, created_at, updated_at, finished_at)
+OVERRIDING SYSTEM VALUE
+SELECT job_id,
+       'banners.bulk_delete',
+       jsonb_strip_nulls(jsonb_build_object('feature_id', feature_id, 'tag_id', tag_id)),
+       CASE status WHEN 'done' THEN 'succeeded' WHEN 'failed' THEN 'failed' ELSE 'queued' END,
+       CASE WHEN total IS NULL THEN NULL ELSE jsonb_build_object('total', total, 'deleted', deleted) END,
+       0, 5, now(),
+       NULLIF(array_to_string(errors, '; '), ''),
+       created_at,
+       COALESCE(updated_at, now()),
+       finished_at
+FROM banner_schema.bulk_delete_jobs;
+
+SELECT setval(pg_get_serial_sequence('banner_schema.jobs', 'job_id'), COALESCE(MAX(job_id), 0) + 1, false)
+FROM banner_schema.jobs;
+
+DROP TABLE banner_schema.bulk_delete_jobs;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+CREATE TABLE banner_schema.bulk_delete_jobs(
+    job_id      BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    feature_id  BIGINT,
+    tag_id      BIGINT,
+    status      TEXT NOT NULL,
+    total       BIGINT,
+    deleted     BIGINT NOT NULL DEFAULT 0,
+    errors      TEXT ARRAY NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMP,
+    updated_at  TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_bulk_delete_jobs_active ON banner_schema.bulk_delete_jobs(job_id) WHERE status IN ('pending', 'running');
+
+DROP TABLE IF EXISTS banner_schema.jobs;
+
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- pg_trgm для поиска подстроки в title и url по GIN индексу. Расширение из contrib, создать его может только
+-- пользователь с правом CREATE на базе, иначе его заранее создает админ базы, см. README
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_banners_feature_id ON banner_schema.banners(feature_id);
+CREATE INDEX idx_tag_id_banners_X_tags ON banner_schema.banners_X_tags(tag_id, banner_id);
+CREATE INDEX idx_banners_created_at ON banner_schema.banners(created_at, banner_id);
+CREATE INDEX idx_banners_updated_at ON banner_schema.banners(updated_at, banner_id);
+CREATE INDEX idx_banners_version ON banner_schema.banners(version, banner_id);
+CREATE INDEX idx_banners_inactive ON banner_schema.banners(banner_id) WHERE NOT is_active;
+CREATE INDEX idx_banners_title_trgm ON banner_schema.banners USING GIN (title gin_trgm_ops);
+CREATE INDEX idx_banners_url_trgm ON banner_schema.banners USING GIN (url gin_trgm_ops);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS banner_schema.idx_banners_url_trgm;
+DROP INDEX IF EXISTS banner_schema.idx_banners_title_trgm;
+DROP INDEX IF EXISTS banner_schema.idx_banners_inactive;
+DROP INDEX IF EXISTS banner_schema.idx_banners_version;
+DROP INDEX IF EXISTS banner_schema.idx_banners_updated_at;
+DROP INDEX IF EXISTS banner_schema.idx_banners_created_at;
+DROP INDEX IF EXISTS banner_schema.idx_tag_id_banners_X_tags;
+DROP INDEX IF EXISTS banner_schema.idx_banners_feature_id;
+
+-- +goose StatementEnd
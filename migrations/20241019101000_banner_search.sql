@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE banner_schema.search_settings(
+    id       BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    language REGCONFIG NOT NULL
+);
+
+INSERT INTO banner_schema.search_settings(language) VALUES ('simple');
+
+CREATE FUNCTION banner_schema.search_language() RETURNS REGCONFIG
+    LANGUAGE sql STABLE AS $$ SELECT language FROM banner_schema.search_settings $$;
+
+CREATE FUNCTION banner_schema.banner_search_vector(language REGCONFIG, title TEXT, text TEXT) RETURNS TSVECTOR
+    LANGUAGE sql IMMUTABLE AS $$
+        SELECT setweight(to_tsvector(language, coalesce(title, '')), 'A') ||
+               setweight(to_tsvector(language, coalesce(text, '')), 'B')
+    $$;
+
+CREATE FUNCTION banner_schema.set_search_vector() RETURNS TRIGGER
+    LANGUAGE plpgsql AS $$
+        BEGIN
+            NEW.search_vector := banner_schema.banner_search_vector(banner_schema.search_language(), NEW.title, NEW.text);
+            RETURN NEW;
+        END
+    $$;
+
+ALTER TABLE banner_schema.banners ADD COLUMN search_vector TSVECTOR;
+ALTER TABLE banner_schema.banners_versions ADD COLUMN search_vector TSVECTOR;
+
+CREATE TRIGGER banners_search_vector BEFORE INSERT OR UPDATE OF title, text ON banner_schema.banners
+    FOR EACH ROW EXECUTE FUNCTION banner_schema.set_search_vector();
+CREATE TRIGGER banners_versions_search_vector BEFORE INSERT OR UPDATE OF title, text ON banner_schema.banners_versions
+    FOR EACH ROW EXECUTE FUNCTION banner_schema.set_search_vector();
+
+UPDATE banner_schema.banners SET search_vector = banner_schema.banner_search_vector('simple', title, text);
+UPDATE banner_schema.banners_versions SET search_vector = banner_schema.banner_search_vector('simple', title, text);
+
+CREATE INDEX idx_banners_search_vector ON banner_schema.banners USING GIN (search_vector);
+CREATE INDEX idx_banners_versions_search_vector ON banner_schema.banners_versions USING GIN (search_vector);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS banner_schema.idx_banners_versions_search_vector;
+DROP INDEX IF EXISTS banner_schema.idx_banners_search_vector;
+DROP TRIGGER IF EXISTS banners_versions_search_vector ON banner_schema.banners_versions;
+DROP TRIGGER IF EXISTS banners_search_vector ON banner_schema.banners;
+ALTER TABLE banner_schema.banners_versions DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE banner_schema.banners DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS banner_schema.set_search_vector();
+DROP FUNCTION IF EXISTS banner_schema.banner_search_vector(REGCONFIG, TEXT, TEXT);
+DROP FUNCTION IF EXISTS banner_schema.search_language();
+DROP TABLE IF EXISTS banner_schema.search_settings;
+
+-- +goose StatementEnd
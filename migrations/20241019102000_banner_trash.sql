@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE banner_schema.banners ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE banner_schema.banners ADD COLUMN deleted_by TEXT;
+
+CREATE INDEX idx_banners_trash ON banner_schema.banners(deleted_at, banner_id) WHERE deleted_at IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS banner_schema.idx_banners_trash;
+
+-- баннеры из корзины после отката стали бы живыми и могли бы занять чужие пары тэг-фича
+DELETE FROM banner_schema.banners_versions WHERE banner_id IN (SELECT banner_id FROM banner_schema.banners WHERE deleted_at IS NOT NULL);
+DELETE FROM banner_schema.banners_X_tags WHERE banner_id IN (SELECT banner_id FROM banner_schema.banners WHERE deleted_at IS NOT NULL);
+DELETE FROM banner_schema.banners WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE banner_schema.banners DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE banner_schema.banners DROP COLUMN IF EXISTS deleted_at;
+
+-- +goose StatementEnd
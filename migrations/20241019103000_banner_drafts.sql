@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- черновик хранит баннер целиком, base_version - версия живого баннера, поверх которой он сделан.
+-- При удалении баннера насовсем (очистка корзины, восстановление снапшота) черновики удаляются вместе с ним
+CREATE TABLE banner_schema.banner_drafts(
+    draft_id     BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    banner_id    BIGINT NOT NULL,
+    base_version INTEGER NOT NULL,
+    feature_id   BIGINT NOT NULL,
+    tag_ids      BIGINT ARRAY NOT NULL,
+    title        TEXT NOT NULL,
+    text         TEXT NOT NULL,
+    url          TEXT NOT NULL,
+    is_active    BOOLEAN NOT NULL,
+    status       TEXT NOT NULL,
+    author       TEXT NOT NULL,
+    reviewed_by  TEXT,
+    reviewed_at  TIMESTAMP,
+    comment      TEXT NOT NULL DEFAULT '',
+    published_by TEXT,
+    published_at TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL,
+    updated_at   TIMESTAMP NOT NULL,
+    FOREIGN KEY (banner_id) REFERENCES banner_schema.banners(banner_id) ON DELETE CASCADE
+);
+
+-- у баннера не больше одного открытого черновика, правки идут в него
+CREATE UNIQUE INDEX idx_banner_drafts_open ON banner_schema.banner_drafts(banner_id) WHERE status IN ('pending', 'approved');
+CREATE INDEX idx_banner_drafts_status ON banner_schema.banner_drafts(status, draft_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS banner_schema.banner_drafts;
+
+-- +goose StatementEnd
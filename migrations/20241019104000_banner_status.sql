@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- статус жизненного цикла баннера, is_active остается для старых клиентов и всегда равен status = 'active'
+ALTER TABLE banner_schema.banners ADD COLUMN status TEXT;
+UPDATE banner_schema.banners SET status = CASE WHEN is_active THEN 'active' ELSE 'paused' END;
+ALTER TABLE banner_schema.banners ALTER COLUMN status SET NOT NULL;
+ALTER TABLE banner_schema.banners ADD CONSTRAINT banners_status_check
+    CHECK (status IN ('draft', 'scheduled', 'active', 'paused', 'expired', 'archived'));
+
+ALTER TABLE banner_schema.banners_versions ADD COLUMN status TEXT;
+UPDATE banner_schema.banners_versions SET status = CASE WHEN is_active THEN 'active' ELSE 'paused' END;
+ALTER TABLE banner_schema.banners_versions ALTER COLUMN status SET NOT NULL;
+
+-- у черновика status - статус самого черновика, статус баннера лежит в banner_status
+ALTER TABLE banner_schema.banner_drafts ADD COLUMN banner_status TEXT;
+UPDATE banner_schema.banner_drafts SET banner_status = CASE WHEN is_active THEN 'active' ELSE 'paused' END;
+ALTER TABLE banner_schema.banner_drafts ALTER COLUMN banner_status SET NOT NULL;
+
+CREATE INDEX idx_banners_status ON banner_schema.banners(status, banner_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS banner_schema.idx_banners_status;
+
+ALTER TABLE banner_schema.banner_drafts DROP COLUMN IF EXISTS banner_status;
+ALTER TABLE banner_schema.banners_versions DROP COLUMN IF EXISTS status;
+ALTER TABLE banner_schema.banners DROP CONSTRAINT IF EXISTS banners_status_check;
+ALTER TABLE banner_schema.banners DROP COLUMN IF EXISTS status;
+
+-- +goose StatementEnd
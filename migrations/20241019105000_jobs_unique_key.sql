@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- задача с unique_key ставится, только если такой же нет в очереди или в работе.
+-- Так периодические задачи с нескольких реплик не копятся
+ALTER TABLE banner_schema.jobs ADD COLUMN unique_key TEXT;
+
+CREATE UNIQUE INDEX idx_jobs_unique_key ON banner_schema.jobs(unique_key) WHERE status IN ('queued', 'running');
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS banner_schema.idx_jobs_unique_key;
+ALTER TABLE banner_schema.jobs DROP COLUMN IF EXISTS unique_key;
+
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- диспетчер арендует доставки до отправки и отправляет их вне транзакции.
+-- Если реплика упала, не дописав результат, доставка вернется в работу после locked_until
+ALTER TABLE banner_schema.webhook_outbox ADD COLUMN locked_until TIMESTAMP;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE banner_schema.webhook_outbox DROP COLUMN IF EXISTS locked_until;
+
+-- +goose StatementEnd
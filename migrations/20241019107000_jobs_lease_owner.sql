@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- воркер пишет итог задачи, только пока аренда его. Если аренда истекла и задачу забрал другой воркер,
+-- запоздалый итог первого не перетрет работу второго
+ALTER TABLE banner_schema.jobs ADD COLUMN lease_owner TEXT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE banner_schema.jobs DROP COLUMN IF EXISTS lease_owner;
+
+-- +goose StatementEnd
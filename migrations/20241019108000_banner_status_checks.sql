@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- версии и черновики хранят тот же статус жизненного цикла, что и баннеры, откат и публикация переносят его в banners
+ALTER TABLE banner_schema.banners_versions ADD CONSTRAINT banners_versions_status_check
+    CHECK (status IN ('draft', 'scheduled', 'active', 'paused', 'expired', 'archived'));
+ALTER TABLE banner_schema.banner_drafts ADD CONSTRAINT banner_drafts_banner_status_check
+    CHECK (banner_status IN ('draft', 'scheduled', 'active', 'paused', 'expired', 'archived'));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE banner_schema.banner_drafts DROP CONSTRAINT IF EXISTS banner_drafts_banner_status_check;
+ALTER TABLE banner_schema.banners_versions DROP CONSTRAINT IF EXISTS banners_versions_status_check;
+
+-- +goose StatementEnd
@@ -0,0 +1,331 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: banners/v1/banners.proto
+
+package banners_v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BannersService_GetBanner_FullMethodName      = "/banners.v1.BannersService/GetBanner"
+	BannersService_GetManyBanner_FullMethodName  = "/banners.v1.BannersService/GetManyBanner"
+	BannersService_AddBanner_FullMethodName      = "/banners.v1.BannersService/AddBanner"
+	BannersService_PatchBanner_FullMethodName    = "/banners.v1.BannersService/PatchBanner"
+	BannersService_DeleteBanner_FullMethodName   = "/banners.v1.BannersService/DeleteBanner"
+	BannersService_ViewVersions_FullMethodName   = "/banners.v1.BannersService/ViewVersions"
+	BannersService_BannerRollback_FullMethodName = "/banners.v1.BannersService/BannerRollback"
+)
+
+// BannersServiceClient is the client API for BannersService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BannersServiceClient interface {
+	GetBanner(ctx context.Context, in *GetBannerRequest, opts ...grpc.CallOption) (*GetBannerResponse, error)
+	GetManyBanner(ctx context.Context, in *GetManyBannerRequest, opts ...grpc.CallOption) (*GetManyBannerResponse, error)
+	AddBanner(ctx context.Context, in *AddBannerRequest, opts ...grpc.CallOption) (*AddBannerResponse, error)
+	PatchBanner(ctx context.Context, in *PatchBannerRequest, opts ...grpc.CallOption) (*PatchBannerResponse, error)
+	DeleteBanner(ctx context.Context, in *DeleteBannerRequest, opts ...grpc.CallOption) (*DeleteBannerResponse, error)
+	ViewVersions(ctx context.Context, in *ViewVersionsRequest, opts ...grpc.CallOption) (*ViewVersionsResponse, error)
+	BannerRollback(ctx context.Context, in *BannerRollbackRequest, opts ...grpc.CallOption) (*BannerRollbackResponse, error)
+}
+
+type bannersServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBannersServiceClient(cc grpc.ClientConnInterface) BannersServiceClient {
+	return &bannersServiceClient{cc}
+}
+
+func (c *bannersServiceClient) GetBanner(ctx context.Context, in *GetBannerRequest, opts ...grpc.CallOption) (*GetBannerResponse, error) {
+	out := new(GetBannerResponse)
+	err := c.cc.Invoke(ctx, BannersService_GetBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannersServiceClient) GetManyBanner(ctx context.Context, in *GetManyBannerRequest, opts ...grpc.CallOption) (*GetManyBannerResponse, error) {
+	out := new(GetManyBannerResponse)
+	err := c.cc.Invoke(ctx, BannersService_GetManyBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannersServiceClient) AddBanner(ctx context.Context, in *AddBannerRequest, opts ...grpc.CallOption) (*AddBannerResponse, error) {
+	out := new(AddBannerResponse)
+	err := c.cc.Invoke(ctx, BannersService_AddBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannersServiceClient) PatchBanner(ctx context.Context, in *PatchBannerRequest, opts ...grpc.CallOption) (*PatchBannerResponse, error) {
+	out := new(PatchBannerResponse)
+	err := c.cc.Invoke(ctx, BannersService_PatchBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannersServiceClient) DeleteBanner(ctx context.Context, in *DeleteBannerRequest, opts ...grpc.CallOption) (*DeleteBannerResponse, error) {
+	out := new(DeleteBannerResponse)
+	err := c.cc.Invoke(ctx, BannersService_DeleteBanner_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannersServiceClient) ViewVersions(ctx context.Context, in *ViewVersionsRequest, opts ...grpc.CallOption) (*ViewVersionsResponse, error) {
+	out := new(ViewVersionsResponse)
+	err := c.cc.Invoke(ctx, BannersService_ViewVersions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bannersServiceClient) BannerRollback(ctx context.Context, in *BannerRollbackRequest, opts ...grpc.CallOption) (*BannerRollbackResponse, error) {
+	out := new(BannerRollbackResponse)
+	err := c.cc.Invoke(ctx, BannersService_BannerRollback_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BannersServiceServer is the server API for BannersService service.
+// All implementations must embed UnimplementedBannersServiceServer
+// for forward compatibility
+type BannersServiceServer interface {
+	GetBanner(context.Context, *GetBannerRequest) (*GetBannerResponse, error)
+	GetManyBanner(context.Context, *GetManyBannerRequest) (*GetManyBannerResponse, error)
+	AddBanner(context.Context, *AddBannerRequest) (*AddBannerResponse, error)
+	PatchBanner(context.Context, *PatchBannerRequest) (*PatchBannerResponse, error)
+	DeleteBanner(context.Context, *DeleteBannerRequest) (*DeleteBannerResponse, error)
+	ViewVersions(context.Context, *ViewVersionsRequest) (*ViewVersionsResponse, error)
+	BannerRollback(context.Context, *BannerRollbackRequest) (*BannerRollbackResponse, error)
+	mustEmbedUnimplementedBannersServiceServer()
+}
+
+// UnimplementedBannersServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBannersServiceServer struct {
+}
+
+func (UnimplementedBannersServiceServer) GetBanner(context.Context, *GetBannerRequest) (*GetBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBanner not implemented")
+}
+func (UnimplementedBannersServiceServer) GetManyBanner(context.Context, *GetManyBannerRequest) (*GetManyBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetManyBanner not implemented")
+}
+func (UnimplementedBannersServiceServer) AddBanner(context.Context, *AddBannerRequest) (*AddBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBanner not implemented")
+}
+func (UnimplementedBannersServiceServer) PatchBanner(context.Context, *PatchBannerRequest) (*PatchBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchBanner not implemented")
+}
+func (UnimplementedBannersServiceServer) DeleteBanner(context.Context, *DeleteBannerRequest) (*DeleteBannerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBanner not implemented")
+}
+func (UnimplementedBannersServiceServer) ViewVersions(context.Context, *ViewVersionsRequest) (*ViewVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ViewVersions not implemented")
+}
+func (UnimplementedBannersServiceServer) BannerRollback(context.Context, *BannerRollbackRequest) (*BannerRollbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BannerRollback not implemented")
+}
+func (UnimplementedBannersServiceServer) mustEmbedUnimplementedBannersServiceServer() {}
+
+// UnsafeBannersServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BannersServiceServer will
+// result in compilation errors.
+type UnsafeBannersServiceServer interface {
+	mustEmbedUnimplementedBannersServiceServer()
+}
+
+func RegisterBannersServiceServer(s grpc.ServiceRegistrar, srv BannersServiceServer) {
+	s.RegisterService(&BannersService_ServiceDesc, srv)
+}
+
+func _BannersService_GetBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannersServiceServer).GetBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannersService_GetBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannersServiceServer).GetBanner(ctx, req.(*GetBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannersService_GetManyBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetManyBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannersServiceServer).GetManyBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannersService_GetManyBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannersServiceServer).GetManyBanner(ctx, req.(*GetManyBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannersService_AddBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannersServiceServer).AddBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannersService_AddBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannersServiceServer).AddBanner(ctx, req.(*AddBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannersService_PatchBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannersServiceServer).PatchBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannersService_PatchBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannersServiceServer).PatchBanner(ctx, req.(*PatchBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannersService_DeleteBanner_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBannerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannersServiceServer).DeleteBanner(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannersService_DeleteBanner_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannersServiceServer).DeleteBanner(ctx, req.(*DeleteBannerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannersService_ViewVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ViewVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannersServiceServer).ViewVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannersService_ViewVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannersServiceServer).ViewVersions(ctx, req.(*ViewVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BannersService_BannerRollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BannerRollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BannersServiceServer).BannerRollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BannersService_BannerRollback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BannersServiceServer).BannerRollback(ctx, req.(*BannerRollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BannersService_ServiceDesc is the grpc.ServiceDesc for BannersService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BannersService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "banners.v1.BannersService",
+	HandlerType: (*BannersServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBanner",
+			Handler:    _BannersService_GetBanner_Handler,
+		},
+		{
+			MethodName: "GetManyBanner",
+			Handler:    _BannersService_GetManyBanner_Handler,
+		},
+		{
+			MethodName: "AddBanner",
+			Handler:    _BannersService_AddBanner_Handler,
+		},
+		{
+			MethodName: "PatchBanner",
+			Handler:    _BannersService_PatchBanner_Handler,
+		},
+		{
+			MethodName: "DeleteBanner",
+			Handler:    _BannersService_DeleteBanner_Handler,
+		},
+		{
+			MethodName: "ViewVersions",
+			Handler:    _BannersService_ViewVersions_Handler,
+		},
+		{
+			MethodName: "BannerRollback",
+			Handler:    _BannersService_BannerRollback_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "banners/v1/banners.proto",
+}
@@ -5,9 +5,13 @@ import (
 )
 
 var (
-	HttpErrUnauthorized   = fiber.NewError(fiber.StatusUnauthorized, fiber.ErrUnauthorized.Error())
-	HttpErrInvalidRequest = fiber.NewError(fiber.StatusBadRequest, fiber.ErrBadRequest.Error())
-	HttpErrNotFound       = fiber.NewError(fiber.StatusNotFound, fiber.ErrNotFound.Error())
-	HttpErrForbidden      = fiber.NewError(fiber.StatusForbidden, fiber.ErrForbidden.Error())
-	HttpServerError       = fiber.NewError(fiber.StatusInternalServerError, fiber.ErrInternalServerError.Error())
+	HttpErrUnauthorized       = fiber.NewError(fiber.StatusUnauthorized, fiber.ErrUnauthorized.Error())
+	HttpErrInvalidRequest     = fiber.NewError(fiber.StatusBadRequest, fiber.ErrBadRequest.Error())
+	HttpErrNotFound           = fiber.NewError(fiber.StatusNotFound, fiber.ErrNotFound.Error())
+	HttpErrForbidden          = fiber.NewError(fiber.StatusForbidden, fiber.ErrForbidden.Error())
+	HttpErrPreconditionFailed = fiber.NewError(fiber.StatusPreconditionFailed, fiber.ErrPreconditionFailed.Error())
+	HttpErrConflict           = fiber.NewError(fiber.StatusConflict, fiber.ErrConflict.Error())
+	HttpErrUnprocessable      = fiber.NewError(fiber.StatusUnprocessableEntity, fiber.ErrUnprocessableEntity.Error())
+	HttpServerError           = fiber.NewError(fiber.StatusInternalServerError, fiber.ErrInternalServerError.Error())
+	HttpErrServiceUnavailable = fiber.NewError(fiber.StatusServiceUnavailable, fiber.ErrServiceUnavailable.Error())
 )
@@ -0,0 +1,39 @@
+package jsonpatch
+
+import (
+	"fmt"
+	evanphx "github.com/evanphx/json-patch/v5"
+)
+
+var (
+	ErrPathNotFound = evanphx.ErrMissing
+	ErrInvalidIndex = evanphx.ErrInvalidIndex
+	ErrTestFailed   = evanphx.ErrTestFailed
+)
+
+// Apply накладывает json patch (RFC 6902) на документ. Операции применяются по порядку,
+// ошибка называет номер операции, на которой патч остановился, документ при этом не меняется
+func Apply(document, patch []byte) ([]byte, error) {
+	operations, err := evanphx.DecodePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("json patch must be an array of operations: %w", err)
+	}
+
+	for i, operation := range operations {
+		document, err = evanphx.Patch{operation}.Apply(document)
+		if err != nil {
+			path, _ := operation.Path()
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Kind(), path, err)
+		}
+	}
+	return document, nil
+}
+
+// MergePatch накладывает json merge patch (RFC 7396): null удаляет поле, объекты сливаются, остальное заменяется целиком
+func MergePatch(document, patch []byte) ([]byte, error) {
+	merged, err := evanphx.MergePatch(document, patch)
+	if err != nil {
+		return nil, fmt.Errorf("json merge patch: %w", err)
+	}
+	return merged, nil
+}
@@ -0,0 +1,173 @@
+package templater
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	openDelim  = "{{"
+	closeDelim = "}}"
+)
+
+var (
+	variableRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)*$`)
+	defaultRegexp  = regexp.MustCompile(`^default:"((?:[^"\\]|\\.)*)"$`)
+)
+
+// Escaper экранирует подставляемое значение в зависимости от поля, в которое оно попадает
+type Escaper func(string) string
+
+var (
+	HTMLEscaper Escaper = html.EscapeString
+	URLEscaper  Escaper = url.QueryEscape
+)
+
+// Placeholder один плейсхолдер вида {{user.name}} или {{user.name|default:"друг"}}
+type Placeholder struct {
+	Variable   string
+	Default    string
+	HasDefault bool
+}
+
+type segment struct {
+	text        string
+	placeholder *Placeholder
+}
+
+// Template разобранный шаблон, состоящий из обычного текста и плейсхолдеров
+type Template struct {
+	segments []segment
+}
+
+func Parse(src string) (*Template, error) {
+	tmpl := &Template{}
+	rest := src
+	for {
+		start := strings.Index(rest, openDelim)
+		if start == -1 {
+			if rest != "" {
+				tmpl.segments = append(tmpl.segments, segment{text: rest})
+			}
+			return tmpl, nil
+		}
+		if start != 0 {
+			tmpl.segments = append(tmpl.segments, segment{text: rest[:start]})
+		}
+
+		end := strings.Index(rest[start:], closeDelim)
+		if end == -1 {
+			return nil, fmt.Errorf("unclosed placeholder at %q", rest[start:])
+		}
+
+		placeholder, err := parsePlaceholder(rest[start+len(openDelim) : start+end])
+		if err != nil {
+			return nil, err
+		}
+		tmpl.segments = append(tmpl.segments, segment{placeholder: placeholder})
+		rest = rest[start+end+len(closeDelim):]
+	}
+}
+
+func parsePlaceholder(body string) (*Placeholder, error) {
+	variable, modifier, hasModifier := strings.Cut(body, "|")
+	variable = strings.TrimSpace(variable)
+	if !variableRegexp.MatchString(variable) {
+		return nil, fmt.Errorf("invalid variable name %q", variable)
+	}
+
+	placeholder := &Placeholder{Variable: variable}
+	if !hasModifier {
+		return placeholder, nil
+	}
+
+	matches := defaultRegexp.FindStringSubmatch(strings.TrimSpace(modifier))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid modifier %q for variable %q, expected default:\"value\"", modifier, variable)
+	}
+	placeholder.Default = strings.ReplaceAll(matches[1], `\"`, `"`)
+	placeholder.HasDefault = true
+
+	return placeholder, nil
+}
+
+func (t *Template) Placeholders() []Placeholder {
+	var placeholders []Placeholder
+	for _, s := range t.segments {
+		if s.placeholder != nil {
+			placeholders = append(placeholders, *s.placeholder)
+		}
+	}
+	return placeholders
+}
+
+// Render подставляет значения атрибутов, если атрибута нет - берется значение по умолчанию или пустая строка
+func (t *Template) Render(attributes map[string]string, escape Escaper) string {
+	var builder strings.Builder
+	for _, s := range t.segments {
+		if s.placeholder == nil {
+			builder.WriteString(s.text)
+			continue
+		}
+
+		value, ok := attributes[s.placeholder.Variable]
+		if !ok {
+			value = s.placeholder.Default
+		}
+		if escape != nil {
+			value = escape(value)
+		}
+		builder.WriteString(value)
+	}
+	return builder.String()
+}
+
+// Validate проверяет синтаксис шаблона, а в строгом режиме еще и то, что все переменные известны
+func Validate(src string, knownVariables []string, strict bool) error {
+	tmpl, err := Parse(src)
+	if err != nil {
+		return err
+	}
+	if !strict {
+		return nil
+	}
+
+	for _, placeholder := range tmpl.Placeholders() {
+		known := false
+		for _, variable := range knownVariables {
+			if variable == placeholder.Variable {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown variable %q", placeholder.Variable)
+		}
+	}
+	return nil
+}
+
+// Render разбирает и рендерит шаблон за один вызов
+func Render(src string, attributes map[string]string, escape Escaper) (string, error) {
+	tmpl, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Render(attributes, escape), nil
+}
+
+// RenderOrRaw рендер для выдачи: текст без плейсхолдеров и текст, который не разбирается как шаблон
+// (например, записанный до появления шаблонов), возвращается как есть
+func RenderOrRaw(src string, attributes map[string]string, escape Escaper) string {
+	if !strings.Contains(src, openDelim) {
+		return src
+	}
+	rendered, err := Render(src, attributes, escape)
+	if err != nil {
+		return src
+	}
+	return rendered
+}
@@ -0,0 +1,15 @@
+package utilities
+
+import "time"
+
+// ExponentialBackoff base * 2^(attempts-1), но не больше maxBackoff
+func ExponentialBackoff(base, maxBackoff time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
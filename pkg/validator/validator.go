@@ -3,12 +3,19 @@ package reqvalidator
 import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"regexp"
 )
 
 var validate *validator.Validate
 
+// slugRegexp слаг - нижний регистр, цифры, '_' и '-', начинается с буквы, чтобы не путать с айди
+var slugRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
 func init() {
 	validate = validator.New()
+	_ = validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugRegexp.MatchString(fl.Field().String())
+	})
 }
 
 func ReadRequest(c *fiber.Ctx, request interface{}) error {
@@ -18,3 +25,16 @@ func ReadRequest(c *fiber.Ctx, request interface{}) error {
 
 	return validate.StructCtx(c.Context(), request)
 }
+
+func ReadQuery(c *fiber.Ctx, request interface{}) error {
+	if err := c.QueryParser(request); err != nil {
+		return err
+	}
+
+	return validate.StructCtx(c.Context(), request)
+}
+
+// Validate валидирует уже разобранный запрос, когда между парсингом и валидацией его нужно дополнить
+func Validate(c *fiber.Ctx, request interface{}) error {
+	return validate.StructCtx(c.Context(), request)
+}